@@ -0,0 +1,84 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff determines the intervals between successive polls where noleak
+// polls by itself, such as Check and HaveLeakedWithin while giving goroutines
+// time to settle, or a Monitor. Backoff policies allow huge processes to be
+// polled gently, while small unit test suites can be polled aggressively.
+type Backoff interface {
+	// Next returns the interval to wait after the specified poll, where the
+	// first poll has number 0.
+	Next(poll int) time.Duration
+}
+
+// MinBackoffInterval is the shortest interval noleak waits between polls,
+// whatever a Backoff policy returns, so that zero or negative intervals don't
+// result in busy polling.
+const MinBackoffInterval = time.Millisecond
+
+// nextInterval returns the interval to wait after the specified poll according
+// to the specified Backoff policy, but not less than MinBackoffInterval.
+func nextInterval(b Backoff, poll int) time.Duration {
+	interval := b.Next(poll)
+	if interval < MinBackoffInterval {
+		interval = MinBackoffInterval
+	}
+	return interval
+}
+
+// ConstantBackoff returns a Backoff policy with the same fixed interval
+// between all polls.
+func ConstantBackoff(interval time.Duration) Backoff {
+	return ExponentialBackoff{Initial: interval, Multiplier: 1}
+}
+
+// ExponentialBackoff is a Backoff policy with intervals starting at Initial
+// and then growing by Multiplier after each poll, until reaching Max. A zero
+// Max doesn't limit the intervals, and a Multiplier less than 1 is taken as 1.
+// Jitter randomly varies each interval by up to the specified fraction in
+// either direction, such as 0.1 for ±10%, in order to avoid multiple pollers
+// polling in lockstep.
+type ExponentialBackoff struct {
+	Initial    time.Duration // interval after the first poll.
+	Max        time.Duration // maximum interval; zero means no maximum.
+	Multiplier float64       // factor to grow the interval by after each poll.
+	Jitter     float64       // maximum random variation as a fraction of the interval.
+}
+
+// Next returns the interval to wait after the specified poll.
+func (b ExponentialBackoff) Next(poll int) time.Duration {
+	interval := float64(b.Initial)
+	if b.Multiplier > 1 {
+		for ; poll > 0; poll-- {
+			interval *= b.Multiplier
+			if b.Max > 0 && interval >= float64(b.Max) {
+				break
+			}
+		}
+	}
+	if b.Max > 0 && interval > float64(b.Max) {
+		interval = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		interval += interval * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(interval)
+}
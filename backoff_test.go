@@ -0,0 +1,100 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// countingBackoff is a constant backoff policy counting the polls.
+type countingBackoff struct {
+	interval time.Duration
+	polls    int
+}
+
+func (b *countingBackoff) Next(poll int) time.Duration {
+	b.polls++
+	return b.interval
+}
+
+var _ = Describe("backoff policies", func() {
+
+	It("returns constant intervals", func() {
+		b := ConstantBackoff(42 * time.Millisecond)
+		Expect(b.Next(0)).To(Equal(42 * time.Millisecond))
+		Expect(b.Next(100)).To(Equal(42 * time.Millisecond))
+	})
+
+	It("returns exponentially growing intervals up to the maximum", func() {
+		b := ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+		Expect(b.Next(0)).To(Equal(time.Second))
+		Expect(b.Next(1)).To(Equal(2 * time.Second))
+		Expect(b.Next(2)).To(Equal(4 * time.Second))
+		Expect(b.Next(3)).To(Equal(5 * time.Second))
+		Expect(b.Next(1000000)).To(Equal(5 * time.Second))
+
+		b.Max = 0
+		Expect(b.Next(10)).To(Equal(1024 * time.Second))
+
+		b.Multiplier = 0.5
+		Expect(b.Next(10)).To(Equal(time.Second))
+	})
+
+	It("jitters", func() {
+		b := ExponentialBackoff{Initial: time.Second, Multiplier: 1, Jitter: 0.1}
+		intervals := map[time.Duration]struct{}{}
+		for i := 0; i < 100; i++ {
+			interval := b.Next(i)
+			Expect(interval).To(BeNumerically("~", time.Second, 100*time.Millisecond))
+			intervals[interval] = struct{}{}
+		}
+		Expect(len(intervals)).To(BeNumerically(">", 1))
+	})
+
+	It("clamps intervals to a minimum", func() {
+		Expect(nextInterval(ConstantBackoff(0), 0)).To(Equal(MinBackoffInterval))
+		Expect(nextInterval(ConstantBackoff(-time.Second), 1)).To(Equal(MinBackoffInterval))
+		Expect(nextInterval(ConstantBackoff(time.Second), 1)).To(Equal(time.Second))
+	})
+
+	It("settles HaveLeakedWithin according to its backoff", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		time.AfterFunc(50*time.Millisecond, func() { close(done) })
+		backoff := &countingBackoff{interval: 5 * time.Millisecond}
+		Expect(LazyGoroutines{}).NotTo(HaveLeakedWithin(time.Second, backoff, snapshot))
+		Expect(backoff.polls).To(BeNumerically(">=", 2))
+	})
+
+	It("reports leaks after the HaveLeakedWithin timeout", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		backoff := &countingBackoff{interval: 5 * time.Millisecond}
+		start := time.Now()
+		Expect(HaveLeakedWithin(50*time.Millisecond, backoff, snapshot).Match(LazyGoroutines{})).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(backoff.polls).To(BeNumerically(">=", 2))
+	})
+
+})
@@ -0,0 +1,123 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// births tracks when goroutines were first observed, in order to tell their
+// (approximate) ages. As Go's runtime doesn't record when a goroutine was
+// started, the first observation is the best approximation available.
+var births = birthRegistry{firstSeen: map[goroutine.Identity]time.Time{}}
+
+type birthRegistry struct {
+	mu        sync.Mutex
+	firstSeen map[goroutine.Identity]time.Time
+}
+
+// observe records the first observation of the specified goroutines, which
+// must be all goroutines of this process, and forgets about goroutines that
+// have ended in the meantime.
+func (r *birthRegistry) observe(gs []goroutine.Goroutine) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	firstSeen := make(map[goroutine.Identity]time.Time, len(gs))
+	for _, g := range gs {
+		id := g.Identity()
+		if seen, ok := r.firstSeen[id]; ok {
+			firstSeen[id] = seen
+			continue
+		}
+		firstSeen[id] = now
+	}
+	r.firstSeen = firstSeen
+}
+
+// age returns how long ago the specified goroutine was first observed. If the
+// goroutine hasn't been observed before, age records its first observation
+// now and thus returns a zero age.
+func (r *birthRegistry) age(g goroutine.Goroutine) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := g.Identity()
+	seen, ok := r.firstSeen[id]
+	if !ok {
+		r.firstSeen[id] = time.Now()
+		return 0
+	}
+	return time.Since(seen)
+}
+
+// IgnoringYoungerThan succeeds if an actual goroutine has been first observed
+// less than the specified duration ago. Goroutines are observed whenever
+// noleak captures goroutines, such as with Goroutines or TakeSnapshot, as well
+// as when IgnoringYoungerThan sees a goroutine for the first time.
+//
+// When used with Eventually, IgnoringYoungerThan gives newly started
+// goroutines a grace period to end, instead of fixed sleep-based settling, but
+// still reports them as leaks as soon as they outlive this grace period:
+//
+//   Eventually(Goroutines).WithTimeout(2 * time.Second).
+//       ShouldNot(HaveLeaked(snapshot, IgnoringYoungerThan(time.Second)))
+//
+// Please note that goroutines started before noleak observed goroutines for
+// the first time are considered to be born at this first observation.
+func IgnoringYoungerThan(d time.Duration) types.GomegaMatcher {
+	return &ignoringYoungerThanMatcher{minAge: d}
+}
+
+type ignoringYoungerThanMatcher struct {
+	minAge time.Duration
+}
+
+// Match succeeds if the actual goroutine has been first observed less than the
+// minimum age ago.
+func (matcher *ignoringYoungerThanMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := G(actual, "IgnoringYoungerThan")
+	if err != nil {
+		return false, err
+	}
+	return births.age(g) < matcher.minAge, nil
+}
+
+// FailureMessage returns a failure message if the actual goroutine has been
+// first observed at least the minimum age ago.
+func (matcher *ignoringYoungerThanMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("to have been first observed less than %s ago", matcher.minAge))
+}
+
+// NegatedFailureMessage returns a failure message if the actual goroutine has
+// been first observed less than the minimum age ago.
+func (matcher *ignoringYoungerThanMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("not to have been first observed less than %s ago", matcher.minAge))
+}
+
+// MatchMayChangeInTheFuture always returns true, as goroutines age.
+func (matcher *ignoringYoungerThanMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return true
+}
+
+// String returns the Go expression creating this matcher.
+func (matcher *ignoringYoungerThanMatcher) String() string {
+	return fmt.Sprintf("IgnoringYoungerThan(%s)", matcher.minAge)
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("goroutine births", func() {
+
+	It("tracks first observations", func() {
+		done := make(chan struct{})
+		defer close(done)
+		go worker(done)
+		gs := Goroutines()
+		w, ok := findWorker(gs)
+		Expect(ok).To(BeTrue())
+		time.Sleep(20 * time.Millisecond)
+		Expect(births.age(w)).To(BeNumerically(">=", 20*time.Millisecond))
+
+		// goroutines that ended are forgotten on the next capture.
+		g := goroutine.Goroutine{ID: 1 << 62, CreatorFunction: "main.main"}
+		Expect(births.age(g)).To(BeZero())
+		_ = Goroutines()
+		births.mu.Lock()
+		Expect(births.firstSeen).NotTo(HaveKey(g.Identity()))
+		Expect(births.firstSeen).To(HaveKey(w.Identity()))
+		births.mu.Unlock()
+	})
+
+	It("ignores young goroutines", func() {
+		m := IgnoringYoungerThan(50 * time.Millisecond)
+		g := goroutine.Goroutine{ID: 1<<62 + 1, CreatorFunction: "main.main", BornAt: "/main.go:42"}
+		Expect(m.Match(g)).To(BeTrue())
+		Expect(m.Match(g)).To(BeTrue())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match(g)).To(BeFalse())
+		Expect(m.Match(nil)).Error().To(HaveOccurred())
+	})
+
+	It("lets leaks settle until they become too old", func() {
+		snapshot := TakeSnapshot()
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		go worker(done)
+		Expect(Goroutines()).NotTo(HaveLeaked(snapshot, IgnoringYoungerThan(time.Minute)))
+		Eventually(Goroutines).Should(HaveLeaked(snapshot, IgnoringYoungerThan(50*time.Millisecond)))
+	})
+
+	It("returns failure messages", func() {
+		m := IgnoringYoungerThan(time.Second)
+		Expect(m.FailureMessage(goroutine.Goroutine{})).To(ContainSubstring(
+			"to have been first observed less than 1s ago"))
+		Expect(m.NegatedFailureMessage(goroutine.Goroutine{})).To(ContainSubstring(
+			"not to have been first observed less than 1s ago"))
+		Expect(describeFilter(m)).To(Equal("IgnoringYoungerThan(1s)"))
+	})
+
+})
+
+// findWorker returns the first worker goroutine in the specified list.
+func findWorker(gs []goroutine.Goroutine) (goroutine.Goroutine, bool) {
+	for _, g := range gs {
+		if g.TopFunction == "github.com/thediveo/noleak.worker" {
+			return g, true
+		}
+	}
+	return goroutine.Goroutine{}, false
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// CaptureTimeout limits how long HaveLeaked with LazyGoroutines and Check wait
+// for capturing the current goroutines, see GoroutinesWithin. Zero means to
+// wait for captures to finish, however long they take. CaptureTimeout can
+// also be set using the "-noleak.capture-timeout" go test flag.
+var CaptureTimeout time.Duration
+
+// ErrCaptureTimeout signals that capturing the current goroutines has been
+// abandoned, as it didn't finish in time.
+var ErrCaptureTimeout = errors.New("capturing goroutines timed out")
+
+// captureAll captures all goroutines; tests replace it in order to simulate
+// pathologically slow captures.
+var captureAll = goroutine.Goroutines
+
+// GoroutinesWithin returns information about all goroutines in the same way
+// as Goroutines, but abandons capturing the goroutines after the specified
+// timeout, returning an error wrapping ErrCaptureTimeout instead. Capturing
+// massive dumps, or capturing during heavy stop-the-world contention, might
+// otherwise hang tests indefinitely. A zero or negative timeout means to wait
+// for the capture to finish.
+//
+// As GoroutinesWithin returns an error as its second value, it works with
+// Eventually:
+//
+//   Eventually(func() ([]goroutine.Goroutine, error) {
+//       return GoroutinesWithin(5 * time.Second)
+//   }).ShouldNot(HaveLeaked(snapshot))
+//
+// The capture runs in a separate infrastructure goroutine (see
+// GoInfrastructure), which is never reported as leaked, even when abandoned
+// but still running. As long as an abandoned capture is still running, further
+// calls don't start new captures, but instead wait for the running capture and
+// then return its result; this avoids piling up captures under heavy
+// stop-the-world contention.
+func GoroutinesWithin(timeout time.Duration) ([]goroutine.Goroutine, error) {
+	preCaptureGrace()
+	gs, err := captureGoroutines(timeout)
+	if err != nil {
+		return nil, err
+	}
+	births.observe(gs)
+	return gs, nil
+}
+
+// capture is a capture of all goroutines running in a separate goroutine.
+type capture struct {
+	done chan struct{}         // closed when the capture has finished.
+	gs   []goroutine.Goroutine // captured goroutines, valid after done.
+}
+
+// inflight is the capture currently running, if any.
+var inflight struct {
+	mu      sync.Mutex
+	capture *capture
+}
+
+// captureGoroutines captures all goroutines in a separate goroutine, giving up
+// waiting for it after the specified timeout, if positive. If there is already
+// a capture running, captureGoroutines waits for it instead of starting
+// another one.
+func captureGoroutines(timeout time.Duration) ([]goroutine.Goroutine, error) {
+	if timeout <= 0 {
+		return captureAll(), nil
+	}
+	inflight.mu.Lock()
+	c := inflight.capture
+	if c == nil {
+		c = &capture{done: make(chan struct{})}
+		inflight.capture = c
+		GoInfrastructure(func() {
+			c.gs = captureAll()
+			inflight.mu.Lock()
+			inflight.capture = nil
+			inflight.mu.Unlock()
+			close(c.done)
+		})
+	}
+	inflight.mu.Unlock()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.done:
+		return c.gs, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%w after %s, abandoning the capture", ErrCaptureTimeout, timeout)
+	}
+}
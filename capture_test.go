@@ -0,0 +1,110 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("capturing goroutines with a deadline", func() {
+
+	// stallCaptures makes captures stall until the returned function gets
+	// called, which then lets any stalled captures finish.
+	stallCaptures := func() (unstall func()) {
+		old := captureAll
+		stall := make(chan struct{})
+		captureAll = func() []goroutine.Goroutine {
+			<-stall
+			return old()
+		}
+		DeferCleanup(func() { captureAll = old })
+		return func() { close(stall) }
+	}
+
+	It("captures in time", func() {
+		gs, err := GoroutinesWithin(time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(ContainElement(HaveField("TopFunction", "testing.(*T).Run")))
+
+		gs, err = GoroutinesWithin(0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).NotTo(BeEmpty())
+	})
+
+	It("abandons stalled captures", func() {
+		unstall := stallCaptures()
+		defer unstall()
+
+		start := time.Now()
+		_, err := GoroutinesWithin(50 * time.Millisecond)
+		Expect(err).To(MatchError(ErrCaptureTimeout))
+		Expect(err).To(MatchError(HaveSuffix("after 50ms, abandoning the capture")))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("keeps only one capture in flight", func() {
+		var captures int32
+		unstall := stallCaptures()
+		stalled := captureAll
+		captureAll = func() []goroutine.Goroutine {
+			atomic.AddInt32(&captures, 1)
+			return stalled()
+		}
+
+		for i := 0; i < 3; i++ {
+			_, err := GoroutinesWithin(10 * time.Millisecond)
+			Expect(err).To(MatchError(ErrCaptureTimeout))
+		}
+		Expect(atomic.LoadInt32(&captures)).To(Equal(int32(1)))
+
+		unstall()
+		gs, err := GoroutinesWithin(time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).NotTo(BeEmpty())
+		Expect(atomic.LoadInt32(&captures)).To(BeNumerically("<=", 2))
+	})
+
+	It("fails HaveLeaked and Check on stalled captures", func() {
+		snapshot := Goroutines()
+		unstall := stallCaptures()
+		defer unstall()
+
+		config := DefaultConfig()
+		config.CaptureTimeout = 50 * time.Millisecond
+		config.ForceFullCapture = true
+		detector := NewDetector(config)
+		_, err := detector.HaveLeaked(snapshot).Match(LazyGoroutines{})
+		Expect(err).To(MatchError(ErrCaptureTimeout))
+
+		_, err = detector.Check(snapshot, CheckOptions{})
+		Expect(err).To(MatchError(ErrCaptureTimeout))
+	})
+
+	It("doesn't report abandoned captures as leaked", func() {
+		snapshot := Goroutines()
+		unstall := stallCaptures()
+		_, err := GoroutinesWithin(10 * time.Millisecond)
+		Expect(err).To(MatchError(ErrCaptureTimeout))
+		gs := goroutine.Goroutines()
+		unstall()
+		Expect(gs).NotTo(HaveLeaked(snapshot))
+	})
+
+})
@@ -0,0 +1,154 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/onsi/gomega/gmeasure"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// DefaultCheckTimeout and DefaultCheckInterval are the timeout and polling
+// interval used by Check when neither CheckOptions nor SettleTimeout specify
+// them. They match Gomega's default Eventually settings.
+const (
+	DefaultCheckTimeout  = time.Second
+	DefaultCheckInterval = 10 * time.Millisecond
+)
+
+// CheckOptions control how Check checks for leaked goroutines.
+type CheckOptions struct {
+	// Ignoring lists additional non-leaky goroutine filters in all the forms
+	// accepted by HaveLeaked, such as top function names and filter matchers.
+	Ignoring []interface{}
+	// Timeout is the maximum time given to goroutines to wind down before they
+	// are considered leaked. A zero Timeout means to use SettleTimeout, or
+	// DefaultCheckTimeout if SettleTimeout isn't set either.
+	Timeout time.Duration
+	// Interval is the polling interval; zero means DefaultCheckInterval.
+	Interval time.Duration
+	// Backoff optionally determines the polling intervals instead of the
+	// fixed Interval.
+	Backoff Backoff
+	// Context optionally interrupts the settle period when done, such as when
+	// the deadline of a test has been reached.
+	Context context.Context
+	// Test optionally names the test to attribute leaks to in leak events and
+	// the exit summary.
+	Test string
+	// Experiment optionally records the duration and allocation cost of each
+	// capture+match cycle, see also Measuring.
+	Experiment *gmeasure.Experiment
+}
+
+// LeakReport describes the outcome of Check.
+type LeakReport struct {
+	Leaked  []goroutine.Goroutine // leaked goroutines, if any.
+	Message string                // human-readable description of the leaked goroutines.
+}
+
+// Check checks for leaked goroutines without requiring Gomega assertions, for
+// use with testify or the bare testing package. The baseline specifies the
+// expected goroutines in any of the forms accepted by HaveLeaked, such as a
+// []goroutine.Goroutine or a goroutine.Snapshot; a nil baseline only applies
+// the standard and optional filters. Check repeatedly captures the current
+// goroutines until either no leaked goroutines are left or the timeout
+// expires. In the latter case, Check returns a report of the leaked goroutines
+// together with a *LeakError, notifying any registered leak hooks. Any other
+// error signals an invalid filter, or a capture abandoned after
+// CaptureTimeout.
+//
+//   snapshot := noleak.Goroutines()
+//   DoSomething()
+//   if _, err := noleak.Check(snapshot, noleak.CheckOptions{}); err != nil {
+//       t.Fatal(err)
+//   }
+func Check(baseline interface{}, opts CheckOptions) (LeakReport, error) {
+	return check(nil, baseline, opts)
+}
+
+// check implements Check using the configuration of the specified detector,
+// or the package-level settings if nil.
+func check(detector *Detector, baseline interface{}, opts CheckOptions) (LeakReport, error) {
+	ignoring := opts.Ignoring
+	if baseline != nil {
+		ignoring = append([]interface{}{baseline}, ignoring...)
+	}
+	matcher := newHaveLeakedMatcher(detector, ignoring)
+	matcher.ctx = opts.Context
+	var done <-chan struct{}
+	if opts.Context != nil {
+		done = opts.Context.Done()
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = matcher.config().SettleTimeout
+		if timeout <= 0 {
+			timeout = DefaultCheckTimeout
+		}
+	}
+	backoff := opts.Backoff
+	if backoff == nil {
+		interval := opts.Interval
+		if interval <= 0 {
+			interval = DefaultCheckInterval
+		}
+		backoff = ConstantBackoff(interval)
+	}
+	captureTimeout := matcher.config().CaptureTimeout
+	deadline := time.Now().Add(timeout)
+	for poll := 0; ; poll++ {
+		var leaking bool
+		var err error
+		cycle := func() {
+			var gs []goroutine.Goroutine
+			if gs, err = GoroutinesWithin(captureTimeout); err == nil {
+				leaking, err = matcher.Match(gs)
+			}
+		}
+		if opts.Experiment != nil {
+			measureCycle(opts.Experiment, cycle)
+		} else {
+			cycle()
+		}
+		if err != nil {
+			return LeakReport{}, err
+		}
+		if !leaking {
+			return LeakReport{}, nil
+		}
+		interval := nextInterval(backoff, poll)
+		if !time.Now().Add(interval).Before(deadline) || matcher.interrupted() != nil {
+			break
+		}
+		select {
+		case <-time.After(interval):
+		case <-done:
+		}
+	}
+	matcher.recaptureLeaked()
+	matcher.test = opts.Test
+	matcher.finalize()
+	report := LeakReport{
+		Leaked: matcher.leaked,
+		Message: fmt.Sprintf("found %s%s:\n%s",
+			counted(len(matcher.leaked), "leaked goroutine", "leaked goroutines"),
+			matcher.baselineAge(), matcher.leakDetails(matcher.leaked)),
+	}
+	return report, &LeakError{Leaked: report.Leaked, Message: report.Message}
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("plain API", func() {
+
+	It("passes without leaks", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		time.AfterFunc(50*time.Millisecond, func() { close(done) })
+		report, err := Check(snapshot, CheckOptions{Timeout: 2 * time.Second})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Leaked).To(BeEmpty())
+	})
+
+	It("reports leaks", func() {
+		snapshot := TakeSnapshot()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		report, err := Check(snapshot, CheckOptions{Timeout: 50 * time.Millisecond})
+		Expect(err).To(MatchError(report.Message))
+		Expect(err).To(BeAssignableToTypeOf(&LeakError{}))
+		Expect(report.Leaked).To(ConsistOf(
+			HaveField("TopFunction", "github.com/thediveo/noleak.worker")))
+		Expect(report.Message).To(MatchRegexp(
+			`^found 1 leaked goroutine \(baseline snapshot taken .* ago\):\n    goroutine \d+ \[chan receive\]\n`))
+
+		_, err = Check(snapshot, CheckOptions{
+			Ignoring: []interface{}{"github.com/thediveo/noleak.worker"},
+			Timeout:  50 * time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+	})
+
+	It("polls using a backoff policy", func() {
+		snapshot := TakeSnapshot()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		backoff := &countingBackoff{interval: 10 * time.Millisecond}
+		_, err := Check(snapshot, CheckOptions{Timeout: 100 * time.Millisecond, Backoff: backoff})
+		Expect(err).To(HaveOccurred())
+		Expect(backoff.polls).To(BeNumerically(">=", 2))
+	})
+
+	It("reports invalid filters", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		report, err := Check(nil, CheckOptions{Ignoring: []interface{}{HaveLen(1)}})
+		Expect(err).To(HaveOccurred())
+		Expect(report.Leaked).To(BeEmpty())
+	})
+
+	It("stops checking when the context is done", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		report, err := Check(snapshot, CheckOptions{Timeout: 10 * time.Second, Context: ctx})
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		Expect(err).To(HaveOccurred())
+		Expect(report.Message).To(ContainSubstring(
+			"\n(the settle period was interrupted: context deadline exceeded)"))
+	})
+
+})
@@ -0,0 +1,63 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak"
+)
+
+// config is the JSON configuration of the filter rules for goroutines to be
+// ignored.
+type config struct {
+	Ignore struct {
+		TopFunctions []string `json:"topFunctions"`
+		Creators     []string `json:"creators"`
+		InBacktrace  []string `json:"inBacktrace"`
+	} `json:"ignore"`
+}
+
+// loadFilters reads the configuration file of the specified name and returns
+// the filter matchers for the goroutines to be ignored.
+func loadFilters(name string) ([]types.GomegaMatcher, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read configuration: %w", err)
+	}
+	var conf config
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", name, err)
+	}
+	return conf.filters(), nil
+}
+
+// filters returns the filter matchers for the goroutines to be ignored.
+func (c config) filters() []types.GomegaMatcher {
+	filters := []types.GomegaMatcher{}
+	for _, topfn := range c.Ignore.TopFunctions {
+		filters = append(filters, noleak.IgnoringTopFunction(topfn))
+	}
+	for _, creator := range c.Ignore.Creators {
+		filters = append(filters, noleak.IgnoringCreator(creator))
+	}
+	for _, fn := range c.Ignore.InBacktrace {
+		filters = append(filters, noleak.IgnoringInBacktrace(fn))
+	}
+	return filters
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Command noleak is a standalone goroutine leak canary. Its "watch" command
+periodically polls the net/http/pprof goroutine endpoint of a target process,
+such as a service in a staging environment, and alerts when the set of
+non-ignored goroutines grows persistently:
+
+    noleak watch -interval 30s -config rules.json http://localhost:6060
+
+Goroutines already present in the first poll are considered to be the
+baseline. When the alert triggers, noleak lists the leaked goroutines, posts
+an alert to the optional webhook, and exits with exit code 1.
+
+In order to poll huge processes gently, the polling interval can grow after
+each poll by the "-backoff" factor, up to "-max-interval", and randomly vary
+by the "-jitter" fraction:
+
+    noleak watch -interval 10s -backoff 2 -max-interval 5m -jitter 0.1 http://localhost:6060
+
+The optional JSON configuration file specifies filter rules for goroutines to
+be ignored, using the same notation as noleak's filter matchers:
+
+    {
+        "ignore": {
+            "topFunctions": ["foo.bar", "foo.baz..."],
+            "creators": ["foo.(*Pool).Start"],
+            "inBacktrace": ["foo.worker"]
+        }
+    }
+
+The "testjson" command summarizes the machine-readable leak entries found in
+"go test -json" output per test and per package, as Markdown tables for CI
+dashboards. Tests emit such leak entries when registering a leak entry hook:
+
+    DeferCleanup(noleak.AddLeakHook(noleak.NewLeakEntryHook(os.Stdout)))
+
+And then:
+
+    go test -json ./... | noleak testjson
+
+The "merge" command combines the JSON leak summaries written by many test
+binaries using noleak.VerifyTestMain, such as in a mono repository build, into
+a single summary with the leaks deduplicated by fingerprint, and exits with
+exit code 1 if there are any leaks:
+
+    for pkg in $(go list ./...); do
+        go test "$pkg" -args -noleak.summary="$PWD/leaks/$(echo "$pkg" | tr / _).json"
+    done
+    noleak merge leaks/*.json > leaks.json
+
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes of the noleak command.
+const (
+	exitOK      = 0
+	exitLeaking = 1
+	exitError   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the noleak command with the specified arguments (without the
+// program name) and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+	switch args[0] {
+	case "watch":
+		return watchCmd(args[1:], stdout, stderr)
+	case "testjson":
+		return testjsonCmd(args[1:], stdin, stdout, stderr)
+	case "merge":
+		return mergeCmd(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "noleak: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitError
+}
+
+// usage prints the top-level usage information.
+func usage(w io.Writer) {
+	fmt.Fprint(w, `usage: noleak <command> [arguments]
+
+commands:
+  watch    poll a pprof goroutine endpoint and alert on persistent leaks
+  testjson summarize leak entries in "go test -json" output
+  merge    merge JSON leak summaries of multiple test binaries
+  help     show this help
+
+Run "noleak <command> -h" for help on a particular command.
+`)
+}
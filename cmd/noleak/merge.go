@@ -0,0 +1,75 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thediveo/noleak/report"
+)
+
+// mergeCmd implements the "merge" command, combining the JSON leak summaries
+// read from the specified files into a single summary.
+func mergeCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: noleak merge FILE...")
+		fmt.Fprintln(stderr, "Merges JSON leak summaries (see -noleak.summary) into a single summary deduplicated by fingerprint.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	reports := make([]report.LeakReport, 0, fs.NArg())
+	for _, name := range fs.Args() {
+		r, err := readLeakReport(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "noleak: %s: %s\n", name, err)
+			return exitError
+		}
+		reports = append(reports, r)
+	}
+	merged := report.Merge(reports)
+	if err := merged.WriteJSON(stdout); err != nil {
+		fmt.Fprintf(stderr, "noleak: %s\n", err)
+		return exitError
+	}
+	if merged.Leaked > 0 {
+		return exitLeaking
+	}
+	return exitOK
+}
+
+// readLeakReport reads the JSON leak summary from the named file.
+func readLeakReport(name string) (report.LeakReport, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return report.LeakReport{}, err
+	}
+	defer f.Close()
+	return report.ReadLeakReport(f)
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const leakSummary = `{"checks":1,"leaked":2,"fingerprints":[{"fingerprint":"aa","leaked":2,"topFunction":"foo.bar","tests":["TestA"]}],"tests":[{"test":"TestA","checks":1,"leaked":2,"fingerprints":["aa"]}]}`
+
+var _ = Describe("merge command", func() {
+
+	It("merges leak summaries", func() {
+		dir := GinkgoT().TempDir()
+		leaky := filepath.Join(dir, "leaky.json")
+		Expect(os.WriteFile(leaky, []byte(leakSummary), 0o644)).To(Succeed())
+		clean := filepath.Join(dir, "clean.json")
+		Expect(os.WriteFile(clean, []byte(`{"checks":0,"leaked":0,"fingerprints":[],"tests":[]}`), 0o644)).To(Succeed())
+
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"merge", leaky, leaky}, nil, &stdout, &stderr)).To(Equal(exitLeaking))
+		Expect(stdout.String()).To(MatchJSON(`{"checks":2,"leaked":4,"fingerprints":[{"fingerprint":"aa","leaked":4,"topFunction":"foo.bar","tests":["TestA"]}],"tests":[{"test":"TestA","checks":2,"leaked":4,"fingerprints":["aa"]}]}`))
+
+		stdout.Reset()
+		Expect(run([]string{"merge", clean}, nil, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(stdout.String()).To(MatchJSON(`{"checks":0,"leaked":0,"fingerprints":[],"tests":[]}`))
+	})
+
+	It("reports errors", func() {
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"merge"}, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(run([]string{"merge", "/nonexisting"}, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(run([]string{"merge", "-h"}, nil, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(run([]string{"merge", "-foo"}, nil, &stdout, &stderr)).To(Equal(exitError))
+
+		broken := filepath.Join(GinkgoT().TempDir(), "broken.json")
+		Expect(os.WriteFile(broken, []byte("{"), 0o644)).To(Succeed())
+		stderr.Reset()
+		Expect(run([]string{"merge", broken}, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(stderr.String()).To(ContainSubstring("cannot read leak report"))
+	})
+
+})
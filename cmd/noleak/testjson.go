@@ -0,0 +1,69 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thediveo/noleak/report"
+)
+
+// testjsonCmd implements the "testjson" command, summarizing the leak entries
+// in "go test -json" output read from the specified files or stdin.
+func testjsonCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("testjson", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: noleak testjson [FILE...]")
+		fmt.Fprintln(stderr, "Summarizes the leak entries in \"go test -json\" output, read from stdin if no files are given.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	readers := []io.Reader{}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "noleak: %s\n", err)
+			return exitError
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, stdin)
+	}
+	tests, err := report.ParseTestJSON(io.MultiReader(readers...))
+	if err != nil {
+		fmt.Fprintf(stderr, "noleak: %s\n", err)
+		return exitError
+	}
+	if err := report.TestJSONSummary(stdout, tests); err != nil {
+		fmt.Fprintf(stderr, "noleak: %s\n", err)
+		return exitError
+	}
+	if len(tests) > 0 {
+		return exitLeaking
+	}
+	return exitOK
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const leakyTestOutput = `{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"noleak:leak {\"leaked\":2,\"fingerprints\":[\"aa\",\"bb\"],\"goroutines\":[]}\n"}
+`
+
+var _ = Describe("testjson command", func() {
+
+	It("summarizes stdin", func() {
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"testjson"}, strings.NewReader(leakyTestOutput), &stdout, &stderr)).
+			To(Equal(exitLeaking))
+		Expect(stdout.String()).To(ContainSubstring("| `example.org/a` | `TestA` | 1 | 2 | 2 |\n"))
+
+		stdout.Reset()
+		Expect(run([]string{"testjson"}, strings.NewReader(""), &stdout, &stderr)).
+			To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring("No leaked goroutines."))
+	})
+
+	It("summarizes files", func() {
+		name := filepath.Join(GinkgoT().TempDir(), "test.json")
+		Expect(os.WriteFile(name, []byte(leakyTestOutput), 0o644)).To(Succeed())
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"testjson", name, name}, nil, &stdout, &stderr)).To(Equal(exitLeaking))
+		Expect(stdout.String()).To(ContainSubstring("| `example.org/a` | `TestA` | 2 | 4 | 2 |\n"))
+
+		Expect(run([]string{"testjson", "/nonexisting"}, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(run([]string{"testjson", "-h"}, nil, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(run([]string{"testjson", "-foo"}, nil, &stdout, &stderr)).To(Equal(exitError))
+	})
+
+})
@@ -0,0 +1,212 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+	"github.com/thediveo/noleak/report"
+)
+
+// watcher polls a pprof goroutine endpoint and alerts when the non-ignored
+// goroutines grow persistently.
+type watcher struct {
+	target  string                    // URL of the pprof endpoint.
+	backoff noleak.ExponentialBackoff // polling intervals.
+	persist int                       // number of successive polls with leaks before alerting.
+	polls   int                       // maximum number of polls; zero for unlimited.
+	webhook string                    // optional webhook URL to post alerts to.
+	filters []types.GomegaMatcher     // goroutines to ignore.
+	client  *http.Client
+	out     io.Writer
+}
+
+// alert is the JSON payload posted to the webhook.
+type alert struct {
+	Target       string   `json:"target"`
+	Leaked       int      `json:"leaked"`
+	Fingerprints []string `json:"fingerprints"`
+	Report       string   `json:"report"`
+}
+
+// errLeaking signals that the watched target persistently leaks goroutines.
+var errLeaking = errors.New("target persistently leaks goroutines")
+
+// watchCmd implements the "watch" command.
+func watchCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: noleak watch [flags] URL")
+		fs.PrintDefaults()
+	}
+	w := watcher{client: http.DefaultClient, out: stdout}
+	fs.DurationVar(&w.backoff.Initial, "interval", 10*time.Second, "(initial) polling interval")
+	fs.DurationVar(&w.backoff.Max, "max-interval", 0, "maximum polling interval when backing off; 0 for no maximum")
+	fs.Float64Var(&w.backoff.Multiplier, "backoff", 1, "factor to grow the polling interval by after each poll")
+	fs.Float64Var(&w.backoff.Jitter, "jitter", 0, "maximum random variation of the polling interval as a fraction of it")
+	fs.IntVar(&w.persist, "persist", 3, "number of successive polls with non-decreasing leaks before alerting")
+	fs.IntVar(&w.polls, "polls", 0, "maximum number of polls, including the baseline poll; 0 for unlimited")
+	fs.StringVar(&w.webhook, "webhook", "", "URL to post JSON alerts to")
+	configName := fs.String("config", "", "JSON configuration file with filter rules")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+	w.target = fs.Arg(0)
+	if *configName != "" {
+		filters, err := loadFilters(*configName)
+		if err != nil {
+			fmt.Fprintf(stderr, "noleak: %s\n", err)
+			return exitError
+		}
+		w.filters = filters
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	switch err := w.watch(ctx); {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errLeaking):
+		fmt.Fprintf(stderr, "noleak: %s\n", err)
+		return exitLeaking
+	default:
+		fmt.Fprintf(stderr, "noleak: %s\n", err)
+		return exitError
+	}
+}
+
+// watch polls the target until it persistently leaks goroutines, the maximum
+// number of polls has been reached, or the context gets cancelled, backing off
+// between polls as configured. The goroutines of the first poll form the
+// baseline.
+func (w *watcher) watch(ctx context.Context) error {
+	baseline, err := goroutine.FromPprofURL(ctx, w.target, goroutine.WithHTTPClient(w.client))
+	if err != nil {
+		return err
+	}
+	filters := append([]types.GomegaMatcher{noleak.IgnoringGoroutines(baseline)}, w.filters...)
+	fmt.Fprintf(w.out, "watching %s, baseline of %d goroutines\n", w.target, len(baseline))
+
+	streak, last := 0, 0
+	for poll := 1; w.polls == 0 || poll < w.polls; poll++ {
+		interval := w.backoff.Next(poll - 1)
+		if interval < noleak.MinBackoffInterval {
+			interval = noleak.MinBackoffInterval
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		gs, err := goroutine.FromPprofURL(ctx, w.target, goroutine.WithHTTPClient(w.client))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		leaked, err := filter(gs, filters)
+		if err != nil {
+			return err
+		}
+		if len(leaked) > 0 && len(leaked) >= last {
+			streak++
+		} else {
+			streak = 0
+		}
+		last = len(leaked)
+		fmt.Fprintf(w.out, "%s: %d goroutines, %d leaked\n",
+			time.Now().Format(time.RFC3339), len(gs), len(leaked))
+		if streak >= w.persist {
+			return w.alert(ctx, leaked)
+		}
+	}
+	return nil
+}
+
+// alert reports the specified leaked goroutines and posts an alert to the
+// webhook, if any. It returns errLeaking unless posting the alert failed.
+func (w *watcher) alert(ctx context.Context, leaked []goroutine.Goroutine) error {
+	md := report.Markdown(leaked)
+	fmt.Fprint(w.out, md)
+	if w.webhook == "" {
+		return errLeaking
+	}
+	fingerprints := make([]string, len(leaked))
+	for idx, g := range leaked {
+		fingerprints[idx] = g.Fingerprint()
+	}
+	payload, _ := json.Marshal(alert{
+		Target:       w.target,
+		Leaked:       len(leaked),
+		Fingerprints: fingerprints,
+		Report:       md,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhook, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot post alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("cannot post alert: %s", resp.Status)
+	}
+	return errLeaking
+}
+
+// filter returns the goroutines not matched by any of the specified filters.
+func filter(gs []goroutine.Goroutine, filters []types.GomegaMatcher) ([]goroutine.Goroutine, error) {
+	leaked := []goroutine.Goroutine{}
+nextgoroutine:
+	for _, g := range gs {
+		for _, f := range filters {
+			matches, err := f.Match(g)
+			if err != nil {
+				return nil, err
+			}
+			if matches {
+				continue nextgoroutine
+			}
+		}
+		leaked = append(leaked, g)
+	}
+	return leaked, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// dump returns a goroutine dump with the specified number of "leaking"
+// goroutines in addition to a baseline goroutine and an ignored goroutine.
+func dump(leaking int) string {
+	var d strings.Builder
+	d.WriteString("goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1\n\n")
+	fmt.Fprintf(&d, "goroutine %d [chan receive]:\nfoo.ignored()\n\t/app/foo.go:5 +0x1\ncreated by main.main\n\t/app/main.go:11 +0x1\n\n", 100)
+	for i := 0; i < leaking; i++ {
+		fmt.Fprintf(&d, "goroutine %d [chan receive]:\nfoo.leaky()\n\t/app/foo.go:42 +0x1\ncreated by main.main\n\t/app/main.go:12 +0x1\n\n", 1000+i)
+	}
+	return d.String()
+}
+
+// pprofServer returns a fake pprof endpoint serving goroutine dumps with the
+// number of leaking goroutines returned by the specified function for each
+// successive poll.
+func pprofServer(leaking func(poll int) int) *httptest.Server {
+	var polls int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		poll := int(atomic.AddInt32(&polls, 1)) - 1
+		_, _ = io.WriteString(w, dump(leaking(poll)))
+	}))
+}
+
+var _ = Describe("watch command", func() {
+
+	var config string
+
+	BeforeEach(func() {
+		config = filepath.Join(GinkgoT().TempDir(), "rules.json")
+		Expect(os.WriteFile(config, []byte(`{"ignore":{"topFunctions":["foo.ignored"]}}`), 0o644)).To(Succeed())
+	})
+
+	It("shows usage", func() {
+		var stdout, stderr strings.Builder
+		Expect(run(nil, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(stderr.String()).To(HavePrefix("usage: noleak"))
+		Expect(run([]string{"help"}, nil, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(stdout.String()).To(HavePrefix("usage: noleak"))
+		Expect(run([]string{"foo"}, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(stderr.String()).To(ContainSubstring(`unknown command "foo"`))
+		Expect(run([]string{"watch"}, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(run([]string{"watch", "-h"}, nil, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(run([]string{"watch", "-foo"}, nil, &stdout, &stderr)).To(Equal(exitError))
+	})
+
+	It("rejects invalid configurations", func() {
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"watch", "-config", "/nonexisting", "http://localhost"}, nil, &stdout, &stderr)).
+			To(Equal(exitError))
+		Expect(stderr.String()).To(ContainSubstring("cannot read configuration"))
+		Expect(os.WriteFile(config, []byte("{"), 0o644)).To(Succeed())
+		Expect(run([]string{"watch", "-config", config, "http://localhost"}, nil, &stdout, &stderr)).
+			To(Equal(exitError))
+		Expect(stderr.String()).To(ContainSubstring("invalid configuration"))
+	})
+
+	It("reports unreachable targets", func() {
+		srv := pprofServer(func(int) int { return 0 })
+		srv.Close()
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"watch", srv.URL}, nil, &stdout, &stderr)).To(Equal(exitError))
+		Expect(stderr.String()).To(ContainSubstring("cannot fetch goroutine dump"))
+	})
+
+	It("stays calm when leaks don't persist", func() {
+		srv := pprofServer(func(poll int) int { return poll % 2 })
+		defer srv.Close()
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"watch", "-interval", "10ms", "-persist", "2", "-polls", "6", "-config", config, srv.URL}, nil,
+			&stdout, &stderr)).To(Equal(exitOK))
+		Expect(stdout.String()).To(HavePrefix("watching " + srv.URL + ", baseline of 2 goroutines\n"))
+		Expect(stdout.String()).To(ContainSubstring(", 1 leaked\n"))
+	})
+
+	It("backs off between polls", func() {
+		srv := pprofServer(func(poll int) int { return poll % 2 })
+		defer srv.Close()
+		var stdout, stderr strings.Builder
+		start := time.Now()
+		Expect(run([]string{"watch", "-interval", "5ms", "-backoff", "2", "-max-interval", "40ms", "-polls", "6", srv.URL}, nil,
+			&stdout, &stderr)).To(Equal(exitOK))
+		Expect(time.Since(start)).To(BeNumerically(">=", 5*time.Millisecond+10*time.Millisecond+20*time.Millisecond+2*40*time.Millisecond))
+	})
+
+	It("alerts on persistent leaks", func() {
+		var payload alert
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+		}))
+		defer hook.Close()
+		srv := pprofServer(func(poll int) int { return poll })
+		defer srv.Close()
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"watch", "-interval", "10ms", "-persist", "3", "-polls", "10", "-webhook", hook.URL, "-config", config, srv.URL}, nil,
+			&stdout, &stderr)).To(Equal(exitLeaking))
+		Expect(stdout.String()).To(ContainSubstring("## Leaked Goroutines\n\n3 leaked goroutines"))
+		Expect(stderr.String()).To(ContainSubstring(errLeaking.Error()))
+		Expect(payload.Target).To(Equal(srv.URL))
+		Expect(payload.Leaked).To(Equal(3))
+		Expect(payload.Fingerprints).To(HaveLen(3))
+		Expect(payload.Report).To(HavePrefix("## Leaked Goroutines"))
+	})
+
+	It("reports failing webhooks", func() {
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		defer hook.Close()
+		srv := pprofServer(func(poll int) int { return poll })
+		defer srv.Close()
+		var stdout, stderr strings.Builder
+		Expect(run([]string{"watch", "-interval", "10ms", "-persist", "1", "-polls", "10", "-webhook", hook.URL, srv.URL}, nil,
+			&stdout, &stderr)).To(Equal(exitError))
+		Expect(stderr.String()).To(ContainSubstring("cannot post alert: 418"))
+	})
+
+})
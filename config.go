@@ -0,0 +1,131 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"text/template"
+	"time"
+
+	"github.com/onsi/gomega/types"
+)
+
+// Config bundles the settings controlling how HaveLeaked detects and reports
+// leaked goroutines. The package-level settings, such as SuggestFilters and
+// ReportDir, form the default configuration used by HaveLeaked and Check. In
+// order to use independent configurations, such as in parallel suites or in
+// libraries embedding noleak, create a Detector with its own Config instead.
+//
+// HaveLeaked and Check take a snapshot of the package-level settings when
+// creating their matchers, so changing the settings later doesn't affect
+// matchers already created, such as a matcher polled by Eventually. However,
+// the package-level settings themselves aren't synchronized; set them before
+// checking for leaks, such as in TestMain or using the go test flags, but not
+// while leak checks run concurrently.
+//
+// Please note that the zero Config disables leak checking, so better start
+// from DefaultConfig and then adjust the settings as necessary.
+type Config struct {
+	Enabled                bool                  // see Enabled.
+	SettleTimeout          time.Duration         // see SettleTimeout.
+	CaptureTimeout         time.Duration         // see CaptureTimeout.
+	ReportFilenameWithPath bool                  // see ReportFilenameWithPath.
+	ForceFullCapture       bool                  // see ForceFullCapture.
+	SuggestFilters         bool                  // see SuggestFilters.
+	LockWaitThreshold      time.Duration         // see LockWaitThreshold.
+	HotSpotsThreshold      int                   // see HotSpotsThreshold.
+	MaxHotSpots            int                   // see MaxHotSpots.
+	DeduplicateReports     bool                  // see DeduplicateReports.
+	ReportDir              string                // see ReportDir.
+	MessageTemplate        *template.Template    // see MessageTemplate.
+	Ignoring               []types.GomegaMatcher // filters applied in addition to the standard filters.
+}
+
+// DefaultConfig returns the current package-level settings as a Config. The
+// filters specified using the "-noleak.ignore" and "-noleak.presets" go test
+// flags become the Config's Ignoring filters.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:                Enabled,
+		SettleTimeout:          SettleTimeout,
+		CaptureTimeout:         CaptureTimeout,
+		ReportFilenameWithPath: ReportFilenameWithPath,
+		ForceFullCapture:       ForceFullCapture,
+		SuggestFilters:         SuggestFilters,
+		LockWaitThreshold:      LockWaitThreshold,
+		HotSpotsThreshold:      HotSpotsThreshold,
+		MaxHotSpots:            MaxHotSpots,
+		DeduplicateReports:     DeduplicateReports,
+		ReportDir:              ReportDir,
+		MessageTemplate:        MessageTemplate,
+		Ignoring:               append([]types.GomegaMatcher(nil), ignoredTopFunctions...),
+	}
+}
+
+// Detector detects leaked goroutines using its own Config, independent of the
+// package-level settings and of other Detectors. A Detector additionally keeps
+// its own record of already reported leaks when deduplicating reports. The
+// matchers created by a Detector reference it explicitly, so changing the
+// package-level settings doesn't affect them, and vice versa.
+//
+//   cfg := noleak.DefaultConfig()
+//   cfg.SuggestFilters = false
+//   cfg.Ignoring = append(cfg.Ignoring, noleak.IgnoringTopFunction("foo.bar"))
+//   detector := noleak.NewDetector(cfg)
+//   ...
+//   Eventually(Goroutines).ShouldNot(detector.HaveLeaked(snapshot))
+//
+// Please note that the following package-level state isn't covered by Config
+// and thus is still shared: the built-in standard filters (see
+// DisableStandardFilters), the init-time baseline (see BaselineInit),
+// PreCaptureGrace, the registered leak hooks, and the exit summary (see
+// SummaryFile).
+type Detector struct {
+	config   Config
+	reported reportedLeaks
+}
+
+// NewDetector returns a new Detector using the specified configuration.
+func NewDetector(config Config) *Detector {
+	config.Ignoring = append([]types.GomegaMatcher(nil), config.Ignoring...)
+	return &Detector{
+		config:   config,
+		reported: reportedLeaks{keys: map[reportedKey]struct{}{}},
+	}
+}
+
+// Config returns the configuration of this Detector.
+func (d *Detector) Config() Config {
+	config := d.config
+	config.Ignoring = append([]types.GomegaMatcher(nil), config.Ignoring...)
+	return config
+}
+
+// HaveLeaked works as the package-level HaveLeaked, but using the
+// configuration of this Detector.
+func (d *Detector) HaveLeaked(ignoring ...interface{}) types.GomegaMatcher {
+	return newHaveLeakedMatcher(d, ignoring)
+}
+
+// Check works as the package-level Check, but using the configuration of this
+// Detector.
+func (d *Detector) Check(baseline interface{}, opts CheckOptions) (LeakReport, error) {
+	return check(d, baseline, opts)
+}
+
+// ForgetReportedLeaks forgets the leaks this Detector has already reported
+// when deduplicating reports, see also the package-level ForgetReportedLeaks.
+func (d *Detector) ForgetReportedLeaks() {
+	d.reported.forget()
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("configurations and detectors", func() {
+
+	leak := goroutine.Goroutine{
+		ID:          1<<62 + 1,
+		State:       "select",
+		TopFunction: "foo.leaking",
+		Backtrace:   "foo.leaking()\n\t/home/foo/bar/baz.go:42 +0x42\n",
+	}
+
+	It("returns the package-level settings as default configuration", func() {
+		defer func(old bool) { SuggestFilters = old }(SuggestFilters)
+		defer func(old string) { ReportDir = old }(ReportDir)
+		SuggestFilters = false
+		ReportDir = "/foo/bar"
+		Expect(DefaultConfig()).To(And(
+			HaveField("Enabled", Enabled),
+			HaveField("SuggestFilters", false),
+			HaveField("ReportDir", "/foo/bar"),
+			HaveField("HotSpotsThreshold", HotSpotsThreshold),
+			HaveField("Ignoring", BeEmpty())))
+	})
+
+	It("uses its own configuration independent of the package-level settings", func() {
+		config := DefaultConfig()
+		config.ReportFilenameWithPath = true
+		config.SuggestFilters = false
+		detector := NewDetector(config)
+
+		defer func(old bool) { SuggestFilters = old }(SuggestFilters)
+		SuggestFilters = true
+
+		m := detector.HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		message := m.NegatedFailureMessage(nil)
+		Expect(message).To(ContainSubstring("foo.leaking() at /home/foo/bar/baz.go:42"))
+		Expect(message).NotTo(ContainSubstring("consider ignoring them"))
+
+		m = HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		message = m.NegatedFailureMessage(nil)
+		Expect(message).To(ContainSubstring("foo.leaking() at bar/baz.go:42"))
+		Expect(message).To(ContainSubstring("consider ignoring them"))
+	})
+
+	It("can be disabled", func() {
+		config := DefaultConfig()
+		config.Enabled = false
+		Expect(NewDetector(config).HaveLeaked().Match([]goroutine.Goroutine{leak})).To(BeFalse())
+	})
+
+	It("applies its additional filters", func() {
+		config := DefaultConfig()
+		config.Ignoring = append(config.Ignoring, IgnoringTopFunction("foo.leaking"))
+		detector := NewDetector(config)
+		config.Ignoring[len(config.Ignoring)-1] = IgnoringTopFunction("foo.bar")
+
+		Expect(detector.Config().Ignoring).To(HaveLen(1))
+		Expect(detector.HaveLeaked().Match([]goroutine.Goroutine{leak})).To(BeFalse())
+		Expect(HaveLeaked().Match([]goroutine.Goroutine{leak})).To(BeTrue())
+	})
+
+	It("checks for leaks", func() {
+		config := DefaultConfig()
+		config.SettleTimeout = 50 * DefaultCheckInterval
+		detector := NewDetector(config)
+
+		done := make(chan struct{})
+		defer close(done)
+		snapshot := Goroutines()
+		go worker(done)
+		report, err := detector.Check(snapshot, CheckOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(report.Leaked).To(HaveLen(1))
+	})
+
+	It("deduplicates reports independently", func() {
+		config := DefaultConfig()
+		config.DeduplicateReports = true
+		d1 := NewDetector(config)
+		d2 := NewDetector(config)
+
+		m := d1.HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("previously-reported"))
+		m = d1.HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring("1 previously-reported leak still present"))
+		m = d2.HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("previously-reported"))
+
+		d1.ForgetReportedLeaks()
+		m = d1.HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("previously-reported"))
+	})
+
+	It("uses detectors concurrently", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			config := DefaultConfig()
+			config.HotSpotsThreshold = i
+			detector := NewDetector(config)
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				m := detector.HaveLeaked()
+				Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+				Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring("foo.leaking"))
+			}()
+		}
+		wg.Wait()
+	})
+
+})
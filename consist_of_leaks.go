@@ -0,0 +1,132 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/matchers/support/goraph/bipartitegraph"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// ConsistOfLeaks succeeds if after filtering out the expected goroutines the
+// remaining leaked goroutines consist exactly of goroutines matching the
+// specified leak matchers: each leak matcher must match its own leaked
+// goroutine and there must be no other leaked goroutines. This detects drift
+// in both directions in tests that deliberately leave a known set of daemon
+// goroutines running. The expected goroutines are specified in the same way
+// as with HaveLeaked. For instance:
+//
+//   Eventually(Goroutines).Should(ConsistOfLeaks(
+//       []types.GomegaMatcher{
+//           IgnoringTopFunction("foo.(*Daemon).run"),
+//           IgnoringTopFunction("foo.(*Daemon).watch"),
+//       },
+//       snapshot))
+func ConsistOfLeaks(leaks []types.GomegaMatcher, ignoring ...interface{}) types.GomegaMatcher {
+	return &consistOfLeaksMatcher{
+		leaks:   leaks,
+		matcher: HaveLeaked(ignoring...).(*HaveLeakedMatcher),
+	}
+}
+
+type consistOfLeaksMatcher struct {
+	leaks   []types.GomegaMatcher
+	matcher *HaveLeakedMatcher    // for determining the leaked goroutines.
+	extra   []goroutine.Goroutine // leaked goroutines not matched by any leak matcher.
+	missing []types.GomegaMatcher // leak matchers without matching leaked goroutines.
+}
+
+// Match succeeds if the leaked goroutines can be paired up one-to-one with the
+// leak matchers.
+func (matcher *consistOfLeaksMatcher) Match(actual interface{}) (success bool, err error) {
+	matcher.extra, matcher.missing = nil, nil
+	if _, err := matcher.matcher.Match(actual); err != nil {
+		return false, err
+	}
+	values := make([]interface{}, len(matcher.matcher.leaked))
+	for idx, g := range matcher.matcher.leaked {
+		values[idx] = g
+	}
+	leaks := make([]interface{}, len(matcher.leaks))
+	for idx, leak := range matcher.leaks {
+		leaks[idx] = leak
+	}
+	graph, err := bipartitegraph.NewBipartiteGraph(values, leaks,
+		func(g, leak interface{}) (bool, error) {
+			return leak.(types.GomegaMatcher).Match(g)
+		})
+	if err != nil {
+		return false, err
+	}
+	extra, missing := graph.FreeLeftRight(graph.LargestMatching())
+	for _, g := range extra {
+		matcher.extra = append(matcher.extra, g.(goroutine.Goroutine))
+	}
+	for _, leak := range missing {
+		matcher.missing = append(matcher.missing, leak.(types.GomegaMatcher))
+	}
+	return len(matcher.extra) == 0 && len(matcher.missing) == 0, nil
+}
+
+// FailureMessage returns a failure message listing the leaked goroutines not
+// expected as well as the expected leaks not found.
+func (matcher *consistOfLeaksMatcher) FailureMessage(actual interface{}) (message string) {
+	matcher.matcher.recaptureLeaked()
+	message = fmt.Sprintf("Expected %s to consist of %s",
+		counted(len(matcher.matcher.leaked), "leaked goroutine", "leaked goroutines"),
+		counted(len(matcher.leaks), "expected leak", "expected leaks"))
+	if len(matcher.extra) > 0 {
+		extra := matcher.extra
+		for _, g := range extra {
+			if g.IsLightweight() {
+				extra = goroutine.Recapture(extra)
+				break
+			}
+		}
+		message += fmt.Sprintf("\nthe extra leaked goroutines were:\n%s",
+			matcher.matcher.listGoroutines(extra, 1))
+	}
+	if len(matcher.missing) > 0 {
+		message += "\nthe missing expected leaks were:\n" + matcher.describe(matcher.missing)
+	}
+	return message
+}
+
+// NegatedFailureMessage returns a failure message if the leaked goroutines
+// consist exactly of the expected leaks.
+func (matcher *consistOfLeaksMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected %s not to consist of the expected leaks:\n%s",
+		counted(len(matcher.matcher.leaked), "leaked goroutine", "leaked goroutines"),
+		matcher.describe(matcher.leaks))
+}
+
+// MatchMayChangeInTheFuture returns true only if the actual value is
+// LazyGoroutines, for the same reasons as with HaveLeaked.
+func (matcher *consistOfLeaksMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return matcher.matcher.MatchMayChangeInTheFuture(actual)
+}
+
+// describe returns an indented list of the specified leak matchers.
+func (matcher *consistOfLeaksMatcher) describe(leaks []types.GomegaMatcher) string {
+	descriptions := make([]string, len(leaks))
+	for idx, leak := range leaks {
+		descriptions[idx] = format.Indent + describeFilter(leak)
+	}
+	return strings.Join(descriptions, "\n")
+}
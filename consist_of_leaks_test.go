@@ -0,0 +1,73 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("ConsistOfLeaks matcher", func() {
+
+	leakyGs := []goroutine.Goroutine{
+		{ID: 1<<62 + 1, State: "select", TopFunction: "daemon.run"},
+		{ID: 1<<62 + 2, State: "select", TopFunction: "daemon.watch"},
+		{ID: 1<<62 + 3, State: "chan receive", TopFunction: "foo.bar"},
+	}
+
+	It("returns errors", func() {
+		Expect(ConsistOfLeaks(nil).Match(nil)).Error().To(HaveOccurred())
+		Expect(ConsistOfLeaks([]types.GomegaMatcher{HaveLen(1)}).Match(leakyGs)).Error().To(HaveOccurred())
+	})
+
+	It("matches the exact set of leaks", func() {
+		Expect(leakyGs).To(ConsistOfLeaks([]types.GomegaMatcher{
+			IgnoringTopFunction("daemon.watch"),
+			IgnoringTopFunction("daemon..."),
+		}, "foo.bar"))
+		Expect(leakyGs).NotTo(ConsistOfLeaks([]types.GomegaMatcher{
+			IgnoringTopFunction("daemon..."),
+		}, "foo.bar"))
+		Expect(leakyGs).NotTo(ConsistOfLeaks([]types.GomegaMatcher{
+			IgnoringTopFunction("daemon..."),
+			IgnoringTopFunction("daemon..."),
+			IgnoringTopFunction("daemon..."),
+		}, "foo.bar"))
+		Expect(leakyGs).To(ConsistOfLeaks(nil, "foo.bar", "daemon..."))
+	})
+
+	It("returns failure messages", func() {
+		m := ConsistOfLeaks([]types.GomegaMatcher{
+			IgnoringTopFunction("daemon.run"),
+			IgnoringTopFunction("daemon.stop"),
+		}, "foo.bar")
+		Expect(m.Match(leakyGs)).To(BeFalse())
+		Expect(m.FailureMessage(leakyGs)).To(MatchRegexp(
+			`^Expected 2 leaked goroutines to consist of 2 expected leaks
+the extra leaked goroutines were:
+    goroutine 4611686018427387906 \[select\]\s+
+the missing expected leaks were:
+    IgnoringTopFunction\("daemon.stop"\)$`))
+
+		m = ConsistOfLeaks([]types.GomegaMatcher{IgnoringTopFunction("foo.bar")}, "daemon...")
+		Expect(m.Match(leakyGs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leakyGs)).To(Equal(
+			"Expected 1 leaked goroutine not to consist of the expected leaks:\n    IgnoringTopFunction(\"foo.bar\")"))
+		Expect(m.(*consistOfLeaksMatcher).MatchMayChangeInTheFuture(leakyGs)).To(BeFalse())
+	})
+
+})
@@ -0,0 +1,123 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// ContainLeak succeeds if after filtering out the expected goroutines the
+// remaining leaked goroutines contain at least one goroutine matching the
+// specified leak matcher. In contrast to HaveLeaked, ContainLeak is intended
+// for testing code that deliberately leaves specific goroutines running, such
+// as background workers. The expected goroutines are specified in the same
+// way as with HaveLeaked. For instance:
+//
+//   snapshot := Goroutines()
+//   StartMiddleware()
+//   Eventually(Goroutines).Should(ContainLeak(IgnoringTopFunction("mw.(*Middleware).run"), snapshot))
+func ContainLeak(leak types.GomegaMatcher, ignoring ...interface{}) types.GomegaMatcher {
+	return &containLeakMatcher{
+		leak:    leak,
+		count:   -1,
+		matcher: HaveLeaked(ignoring...).(*HaveLeakedMatcher),
+	}
+}
+
+// ContainLeaks succeeds if after filtering out the expected goroutines exactly
+// count of the remaining leaked goroutines match the specified leak matcher.
+// Other leaked goroutines not matching the leak matcher are not considered.
+//
+//   Eventually(Goroutines).Should(ContainLeaks(1, IgnoringTopFunction("mw.(*Middleware).run"), snapshot))
+func ContainLeaks(count int, leak types.GomegaMatcher, ignoring ...interface{}) types.GomegaMatcher {
+	if count < 0 {
+		panic(fmt.Sprintf("ContainLeaks expected a non-negative count, but got: %d", count))
+	}
+	return &containLeakMatcher{
+		leak:    leak,
+		count:   count,
+		matcher: HaveLeaked(ignoring...).(*HaveLeakedMatcher),
+	}
+}
+
+type containLeakMatcher struct {
+	leak     types.GomegaMatcher
+	count    int                   // expected number of matching leaks; -1 for at least one.
+	matcher  *HaveLeakedMatcher    // for determining the leaked goroutines.
+	matching []goroutine.Goroutine // leaked goroutines matching the leak matcher.
+}
+
+// Match succeeds if the actual goroutines contain the expected number of
+// leaked goroutines that match the leak matcher.
+func (matcher *containLeakMatcher) Match(actual interface{}) (success bool, err error) {
+	matcher.matching = nil
+	if _, err := matcher.matcher.Match(actual); err != nil {
+		return false, err
+	}
+	matcher.matching, err = matching(matcher.matcher.leaked, matcher.leak)
+	if err != nil {
+		return false, err
+	}
+	if matcher.count < 0 {
+		return len(matcher.matching) > 0, nil
+	}
+	return len(matcher.matching) == matcher.count, nil
+}
+
+// FailureMessage returns a failure message if the leaked goroutines don't
+// contain the expected number of matching goroutines.
+func (matcher *containLeakMatcher) FailureMessage(actual interface{}) (message string) {
+	matcher.matcher.recaptureLeaked()
+	message = fmt.Sprintf("Expected to leak %s matching %s, but found %d among %s",
+		matcher.expected(), describeFilter(matcher.leak), len(matcher.matching),
+		counted(len(matcher.matcher.leaked), "leaked goroutine", "leaked goroutines"))
+	if len(matcher.matcher.leaked) == 0 {
+		return message
+	}
+	return message + ":\n" + matcher.matcher.listGoroutines(matcher.matcher.leaked, 1)
+}
+
+// NegatedFailureMessage returns a failure message if the leaked goroutines
+// contain the expected number of matching goroutines.
+func (matcher *containLeakMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	matcher.matcher.recaptureLeaked()
+	if recaptured, err := matching(matcher.matcher.leaked, matcher.leak); err == nil {
+		matcher.matching = recaptured
+	}
+	message = fmt.Sprintf("Expected not to leak %s matching %s, but found %d",
+		matcher.expected(), describeFilter(matcher.leak), len(matcher.matching))
+	if len(matcher.matching) == 0 {
+		return message
+	}
+	return message + ":\n" + matcher.matcher.listGoroutines(matcher.matching, 1)
+}
+
+// MatchMayChangeInTheFuture returns true only if the actual value is
+// LazyGoroutines, as for the same reasons as with HaveLeaked.
+func (matcher *containLeakMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return matcher.matcher.MatchMayChangeInTheFuture(actual)
+}
+
+// expected returns a textual description of the expected number of matching
+// leaked goroutines, such as "exactly 2 goroutines".
+func (matcher *containLeakMatcher) expected() string {
+	if matcher.count < 0 {
+		return "at least 1 goroutine"
+	}
+	return "exactly " + counted(matcher.count, "goroutine", "goroutines")
+}
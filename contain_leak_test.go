@@ -0,0 +1,81 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("ContainLeak matchers", func() {
+
+	leakyGs := []goroutine.Goroutine{
+		{ID: 1<<62 + 1, State: "select", TopFunction: "mw.run"},
+		{ID: 1<<62 + 2, State: "select", TopFunction: "mw.run"},
+		{ID: 1<<62 + 3, State: "chan receive", TopFunction: "foo.bar"},
+	}
+
+	It("rejects invalid arguments", func() {
+		Expect(func() { ContainLeaks(-1, IgnoringTopFunction("mw.run")) }).To(
+			PanicWith("ContainLeaks expected a non-negative count, but got: -1"))
+		Expect(ContainLeak(IgnoringTopFunction("mw.run")).Match(nil)).Error().To(HaveOccurred())
+		Expect(ContainLeak(HaveLen(1)).Match(leakyGs)).Error().To(HaveOccurred())
+	})
+
+	It("matches leaked goroutines", func() {
+		Expect(leakyGs).To(ContainLeak(IgnoringTopFunction("mw.run")))
+		Expect(leakyGs).NotTo(ContainLeak(IgnoringTopFunction("mw.run"), "mw.run"))
+		Expect(leakyGs).To(ContainLeaks(2, IgnoringTopFunction("mw.run")))
+		Expect(leakyGs).NotTo(ContainLeaks(1, IgnoringTopFunction("mw.run")))
+		Expect(leakyGs).To(ContainLeaks(0, IgnoringTopFunction("baz")))
+	})
+
+	It("returns failure messages", func() {
+		m := ContainLeaks(1, IgnoringTopFunction("mw.run"), "foo.bar")
+		Expect(m.Match(leakyGs)).To(BeFalse())
+		Expect(m.FailureMessage(leakyGs)).To(MatchRegexp(
+			`^Expected to leak exactly 1 goroutine matching IgnoringTopFunction\("mw.run"\), but found 2 among 2 leaked goroutines:
+    goroutine \d+ \[select\]\s+goroutine \d+ \[select\]\s*$`))
+
+		m = ContainLeak(IgnoringTopFunction("mw.run"), "mw.run", "foo.bar")
+		Expect(m.Match(leakyGs)).To(BeFalse())
+		Expect(m.FailureMessage(leakyGs)).To(Equal(
+			`Expected to leak at least 1 goroutine matching IgnoringTopFunction("mw.run"), but found 0 among 0 leaked goroutines`))
+
+		m = ContainLeak(IgnoringTopFunction("foo.bar"))
+		Expect(m.Match(leakyGs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leakyGs)).To(MatchRegexp(
+			`^Expected not to leak at least 1 goroutine matching IgnoringTopFunction\("foo.bar"\), but found 1:
+    goroutine \d+ \[chan receive\]\s*$`))
+		Expect(m.(*containLeakMatcher).MatchMayChangeInTheFuture(leakyGs)).To(BeFalse())
+		Expect(m.(*containLeakMatcher).MatchMayChangeInTheFuture(LazyGoroutines{})).To(BeTrue())
+
+		m = ContainLeaks(0, IgnoringTopFunction("baz"))
+		Expect(m.Match(leakyGs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leakyGs)).To(Equal(
+			`Expected not to leak exactly 0 goroutines matching IgnoringTopFunction("baz"), but found 0`))
+	})
+
+	It("detects real background goroutines", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		Eventually(Goroutines).Should(ContainLeaks(1, IgnoringTopFunction("github.com/thediveo/noleak.worker"), snapshot))
+		close(done)
+		Eventually(Goroutines).ShouldNot(ContainLeak(IgnoringTopFunction("github.com/thediveo/noleak.worker"), snapshot))
+	})
+
+})
@@ -0,0 +1,96 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ctrlnoleak
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// StopTimeout is the maximum duration to wait for a stopped manager to return
+// from its Start method, as well as for its goroutines to finally end.
+var StopTimeout = 10 * time.Second
+
+// Filters returns the filter matchers for goroutines of controller-runtime,
+// client-go, and their dependencies, which are known to outlive a stopped
+// manager and thus aren't considered to be leaked.
+func Filters() []interface{} {
+	return []interface{}{
+		// klog's flush daemon gets started on first use and never stops.
+		noleak.IgnoringInBacktrace("k8s.io/klog/v2.(*flushDaemon).run"),
+		// Event broadcasters and recorders are shared and never shut down by
+		// managers.
+		noleak.IgnoringInBacktrace("k8s.io/apimachinery/pkg/watch.(*Broadcaster).loop"),
+		noleak.IgnoringCreator("k8s.io/client-go/tools/record.(*eventBroadcasterImpl).StartEventWatcher"),
+		noleak.IgnoringCreator("k8s.io/client-go/tools/record.(*eventBroadcasterImpl).StartRecordingToSink..."),
+		// Connections to the API server get kept alive by the client
+		// transports.
+		noleak.IgnoringInBacktrace("net/http.(*persistConn).readLoop"),
+		noleak.IgnoringInBacktrace("net/http.(*persistConn).writeLoop"),
+		noleak.IgnoringInBacktrace("net/http.(*http2ClientConn).readLoop"),
+		noleak.IgnoringInBacktrace("golang.org/x/net/http2.(*ClientConn).readLoop"),
+		// OpenCensus (still used by some client-go versions) starts its
+		// worker on import.
+		noleak.IgnoringInBacktrace("go.opencensus.io/stats/view.(*worker).start"),
+	}
+}
+
+// Manager is the part of a controller-runtime manager.Manager needed to start
+// and stop the manager. It is also implemented by any controller-runtime
+// manager.Runnable.
+type Manager interface {
+	Start(ctx context.Context) error
+}
+
+// Start takes a snapshot of the current goroutines and then starts the
+// specified manager in a separate goroutine, using a context derived from the
+// specified context. Start returns a function that stops the manager again by
+// cancelling its context, asserts that the manager's Start method returns
+// without error, and finally asserts that no goroutines have leaked since the
+// snapshot, ignoring the goroutines matched by Filters as well as by the
+// optional additional filter matchers. Failing assertions are reported using
+// gomega.Default.
+func Start(ctx context.Context, mgr Manager, ignoring ...interface{}) (stop func()) {
+	return StartWithGomega(ctx, gomega.Default, mgr, ignoring...)
+}
+
+// StartWithGomega works the same as Start, but reports failing assertions
+// using the specified Gomega.
+func StartWithGomega(ctx context.Context, g gomega.Gomega, mgr Manager, ignoring ...interface{}) (stop func()) {
+	snapshot := goroutine.TakeSnapshot()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- mgr.Start(ctx)
+	}()
+	return func() {
+		cancel()
+		g.Eventually(done).WithOffset(1).WithTimeout(StopTimeout).Should(
+			gomega.Receive(gomega.Or(
+				gomega.Not(gomega.HaveOccurred()),
+				gomega.WithTransform(func(err error) bool {
+					return errors.Is(err, context.Canceled)
+				}, gomega.BeTrue()))),
+			"manager didn't stop properly")
+		filters := append(Filters(), ignoring...)
+		g.Eventually(noleak.Goroutines).WithOffset(1).WithTimeout(StopTimeout).
+			ShouldNot(noleak.HaveLeaked(append(filters, snapshot)...))
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ctrlnoleak
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/noleak"
+)
+
+// fakeManager starts an "informer" goroutine that stops when the manager's
+// context gets cancelled, as well as optionally a goroutine that leaks.
+type fakeManager struct {
+	err  error
+	leak chan struct{}
+}
+
+func (m *fakeManager) Start(ctx context.Context) error {
+	go func() { <-ctx.Done() }()
+	if m.leak != nil {
+		go func() { <-m.leak }()
+	}
+	<-ctx.Done()
+	return m.err
+}
+
+var _ = Describe("controller-runtime managers", func() {
+
+	BeforeEach(func() {
+		oldTimeout := StopTimeout
+		StopTimeout = 500 * time.Millisecond
+		DeferCleanup(func() { StopTimeout = oldTimeout })
+	})
+
+	It("returns filters", func() {
+		Expect(Filters()).NotTo(BeEmpty())
+	})
+
+	It("stops a manager without leaks", func() {
+		stop := Start(context.Background(), &fakeManager{})
+		stop()
+		stop = Start(context.Background(), &fakeManager{err: context.Canceled})
+		stop()
+	})
+
+	It("reports a failing manager", func() {
+		var failures []string
+		g := NewGomega(func(message string, callerSkip ...int) {
+			failures = append(failures, message)
+		})
+		stop := StartWithGomega(context.Background(), g, &fakeManager{err: errors.New("D'oh!")})
+		stop()
+		Expect(failures).To(ConsistOf(ContainSubstring("manager didn't stop properly")))
+	})
+
+	It("reports leaked goroutines", func() {
+		snapshot := Goroutines()
+		leak := make(chan struct{})
+		defer func() {
+			close(leak)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		var failures []string
+		g := NewGomega(func(message string, callerSkip ...int) {
+			failures = append(failures, message)
+		})
+		stop := StartWithGomega(context.Background(), g, &fakeManager{leak: leak})
+		stop()
+		Expect(failures).To(ConsistOf(ContainSubstring("Expected not to leak 1 goroutines")))
+	})
+
+})
@@ -0,0 +1,31 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package ctrlnoleak helps checking Kubernetes operator tests using
+controller-runtime and envtest for leaked goroutines. Filters returns a preset
+of filter matchers for the well-known goroutines that controller-runtime and
+client-go leave behind even after a manager has been properly stopped, such as
+event broadcasters and the klog flush daemon. Start starts a manager and
+returns a function that stops the manager and then checks for leaks.
+
+    stop := ctrlnoleak.Start(ctx, mgr)
+    defer stop()
+
+Package ctrlnoleak doesn't depend on controller-runtime itself, so it doesn't
+pull in any Kubernetes dependencies.
+
+*/
+package ctrlnoleak
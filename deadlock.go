@@ -0,0 +1,101 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// deadlockHypotheses analyses the specified leaked goroutines for likely
+// deadlock patterns and returns textual hypotheses about them, if any. As the
+// analysis is based solely on goroutine states and creators, it can only give
+// hints, but not proof:
+//   - goroutines blocked on nil channels are blocked forever.
+//   - goroutines created by the same function and blocked sending as well as
+//     receiving on channels might be waiting on each other.
+//   - when all (but at least two) leaked goroutines are blocked on channel
+//     operations or waiting on locks, there's no runnable peer left among
+//     them to unblock the others. Other waits, such as sleeping or waiting
+//     for I/O, don't count, as something outside the leaked goroutines
+//     eventually ends them.
+func deadlockHypotheses(leaked []goroutine.Goroutine) []string {
+	var hypotheses []string
+
+	var nilchans []goroutine.Goroutine
+	for _, g := range leaked {
+		switch g.BaseState() {
+		case goroutine.StateChanReceiveNilChan, goroutine.StateChanSendNilChan:
+			nilchans = append(nilchans, g)
+		}
+	}
+	if len(nilchans) > 0 {
+		hypotheses = append(hypotheses, fmt.Sprintf(
+			"goroutines %s are blocked forever on nil channels", goids(nilchans)))
+	}
+
+	type chanOps struct{ senders, receivers []goroutine.Goroutine }
+	bycreator := map[string]*chanOps{}
+	for _, g := range leaked {
+		if g.CreatorFunction == "" {
+			continue
+		}
+		state := g.BaseState()
+		if state != goroutine.StateChanSend && state != goroutine.StateChanReceive {
+			continue
+		}
+		ops := bycreator[g.CreatorFunction]
+		if ops == nil {
+			ops = &chanOps{}
+			bycreator[g.CreatorFunction] = ops
+		}
+		if state == goroutine.StateChanSend {
+			ops.senders = append(ops.senders, g)
+		} else {
+			ops.receivers = append(ops.receivers, g)
+		}
+	}
+	creators := make([]string, 0, len(bycreator))
+	for creator, ops := range bycreator {
+		if len(ops.senders) > 0 && len(ops.receivers) > 0 {
+			creators = append(creators, creator)
+		}
+	}
+	sort.Strings(creators)
+	for _, creator := range creators {
+		ops := bycreator[creator]
+		hypotheses = append(hypotheses, fmt.Sprintf(
+			"goroutines %s blocked sending and goroutines %s blocked receiving, all created by %s, might be waiting on each other",
+			goids(ops.senders), goids(ops.receivers), creator))
+	}
+
+	if len(leaked) > 1 {
+		allBlocked := true
+		for _, g := range leaked {
+			if state := g.BaseState(); !state.IsChannelOp() && !state.IsLockWait() {
+				allBlocked = false
+				break
+			}
+		}
+		if allBlocked {
+			hypotheses = append(hypotheses, fmt.Sprintf(
+				"all %d leaked goroutines are blocked on channels or locks, without any runnable peer among them to unblock the others",
+				len(leaked)))
+		}
+	}
+	return hypotheses
+}
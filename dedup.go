@@ -0,0 +1,82 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"sync"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// DeduplicateReports controls whether HaveLeaked condenses the failure
+// messages for leaked goroutines that have already been reported in earlier
+// failure messages. This keeps the signal high when a leak from one spec
+// persists and thus causes all subsequent leak checks against a suite-wide
+// baseline to fail, too: instead of repeating the same details over and over
+// again, subsequent failure messages only note the number and IDs of the
+// previously-reported leaks still present.
+//
+// Deduplication is off by default; it can be enabled using the
+// -noleak.dedup flag, too.
+var DeduplicateReports = false
+
+// reported keeps track of the leaked goroutines already reported, for use with
+// DeduplicateReports.
+var reported = reportedLeaks{keys: map[reportedKey]struct{}{}}
+
+// ForgetReportedLeaks forgets about all previously reported leaks, so that
+// they get reported in full detail again when DeduplicateReports is enabled.
+func ForgetReportedLeaks() {
+	reported.forget()
+}
+
+// reportedLeaks is a set of already reported leaked goroutines.
+type reportedLeaks struct {
+	mu   sync.Mutex
+	keys map[reportedKey]struct{}
+}
+
+// reportedKey identifies a particular leaked goroutine, which is not only
+// characterized by its fingerprint, but also its ID, so that new leaks with
+// the same fingerprint are still reported in full detail.
+type reportedKey struct {
+	id          uint64
+	fingerprint string
+}
+
+// forget forgets about all previously reported leaks.
+func (r *reportedLeaks) forget() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = map[reportedKey]struct{}{}
+}
+
+// partition returns the specified leaked goroutines partitioned into the fresh
+// ones not reported before and the ones previously reported, and then records
+// all of them as having been reported.
+func (r *reportedLeaks) partition(leaked []goroutine.Goroutine) (fresh, previous []goroutine.Goroutine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range leaked {
+		key := reportedKey{id: g.ID, fingerprint: g.Fingerprint()}
+		if _, ok := r.keys[key]; ok {
+			previous = append(previous, g)
+			continue
+		}
+		r.keys[key] = struct{}{}
+		fresh = append(fresh, g)
+	}
+	return fresh, previous
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("deduplicating leak reports", func() {
+
+	BeforeEach(func() {
+		old := DeduplicateReports
+		DeduplicateReports = true
+		ForgetReportedLeaks()
+		DeferCleanup(func() {
+			DeduplicateReports = old
+			ForgetReportedLeaks()
+		})
+	})
+
+	It("condenses previously reported leaks", func() {
+		persistent := goroutine.Goroutine{ID: 1<<62 + 1, State: "select", TopFunction: "foo.persistent"}
+		fresh := goroutine.Goroutine{ID: 1<<62 + 2, State: "select", TopFunction: "foo.fresh"}
+
+		m := HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{persistent})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(MatchRegexp(
+			`^Expected not to leak 1 goroutines:\n    goroutine 4611686018427387905 \[select\]`))
+
+		m = HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{persistent})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(Equal(
+			"Expected not to leak 1 goroutines: 1 previously-reported leak still present (goroutine 4611686018427387905)"))
+
+		m = HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{persistent, fresh})).To(BeTrue())
+		message := m.NegatedFailureMessage(nil)
+		Expect(message).To(MatchRegexp(
+			`^Expected not to leak 2 goroutines:\n    goroutine 4611686018427387906 \[select\]`))
+		Expect(message).NotTo(ContainSubstring("goroutine 4611686018427387905 ["))
+		Expect(message).To(HaveSuffix(
+			"\n(plus 1 previously-reported leak still present: goroutine 4611686018427387905)"))
+
+		Expect(m.NegatedFailureMessage(nil)).To(Equal(message))
+
+		ForgetReportedLeaks()
+		m = HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{persistent})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("previously-reported"))
+	})
+
+})
@@ -0,0 +1,93 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package dlvnoleak
+
+// The following types mirror the (subset of) types of delve's JSON-RPC API
+// version 2 that we need, in order to avoid depending on delve itself.
+
+type listGoroutinesIn struct {
+	Start int
+	Count int
+}
+
+type listGoroutinesOut struct {
+	Goroutines []*dlvGoroutine
+	Nextg      int
+}
+
+type stacktraceIn struct {
+	ID    int64 `json:"Id"`
+	Depth int
+}
+
+type stacktraceOut struct {
+	Locations []dlvStackframe
+}
+
+type evalIn struct {
+	Scope evalScope
+	Expr  string
+	Cfg   *loadConfig
+}
+
+type evalScope struct {
+	GoroutineID  int64
+	Frame        int
+	DeferredCall int
+}
+
+type loadConfig struct {
+	FollowPointers     bool
+	MaxVariableRecurse int
+	MaxStringLen       int
+	MaxArrayValues     int
+	MaxStructFields    int
+}
+
+type evalOut struct {
+	Variable *dlvVariable
+}
+
+type dlvVariable struct {
+	Value      string        `json:"value"`
+	Children   []dlvVariable `json:"children"`
+	Unreadable string        `json:"unreadable"`
+}
+
+type dlvGoroutine struct {
+	ID             int64       `json:"id"`
+	CurrentLoc     dlvLocation `json:"currentLoc"`
+	GoStatementLoc dlvLocation `json:"goStatementLoc"`
+	Status         uint64      `json:"status"`
+	WaitReason     int64       `json:"waitReason"`
+	Unreadable     string      `json:"unreadable"`
+}
+
+type dlvLocation struct {
+	PC       uint64       `json:"pc"`
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Function *dlvFunction `json:"function,omitempty"`
+}
+
+type dlvStackframe struct {
+	dlvLocation
+	Bottom bool `json:"Bottom,omitempty"`
+}
+
+type dlvFunction struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package dlvnoleak
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// StackDepth is the maximum number of frames retrieved for each goroutine.
+// Deeper backtraces are cut off, as marked by an elision marker.
+var StackDepth = 50
+
+// Client retrieves goroutines from a headless delve server.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the headless delve server listening at the specified TCP
+// address, such as "127.0.0.1:4040".
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to delve server: %w", err)
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient returns a new Client talking to a headless delve server over the
+// specified connection.
+func NewClient(conn io.ReadWriteCloser) *Client {
+	return &Client{rpc: jsonrpc.NewClient(conn)}
+}
+
+// Close closes the connection to the delve server, leaving the debugged target
+// alone.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Goroutines returns the goroutines of the debugged target, such as a core
+// dump or attached process. Goroutines that delve cannot read are skipped.
+//
+// As delve doesn't report call arguments, the backtraces of the returned
+// goroutines list all function calls without arguments. Additionally, the
+// State of waiting goroutines never contains the number of minutes waited.
+// The wait reasons are read from the runtime of the debugged target; if they
+// cannot be read, waiting goroutines are in the generic "waiting" State.
+func (c *Client) Goroutines() ([]goroutine.Goroutine, error) {
+	waitReasons := c.waitReasons()
+	var dump strings.Builder
+	for start := 0; ; {
+		var out listGoroutinesOut
+		if err := c.rpc.Call("RPCServer.ListGoroutines",
+			listGoroutinesIn{Start: start}, &out); err != nil {
+			return nil, fmt.Errorf("cannot list goroutines: %w", err)
+		}
+		for _, g := range out.Goroutines {
+			if g == nil || g.Unreadable != "" {
+				continue
+			}
+			var trace stacktraceOut
+			if err := c.rpc.Call("RPCServer.Stacktrace",
+				stacktraceIn{ID: g.ID, Depth: StackDepth}, &trace); err != nil {
+				return nil, fmt.Errorf("cannot retrieve stack of goroutine %d: %w", g.ID, err)
+			}
+			writeGoroutine(&dump, g, trace.Locations, waitReasons)
+		}
+		if out.Nextg < 0 || out.Nextg == start {
+			break
+		}
+		start = out.Nextg
+	}
+	gs, err := goroutine.Parse([]byte(dump.String()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert goroutines: %w", err)
+	}
+	return gs, nil
+}
+
+// writeGoroutine writes the specified goroutine with its stack frames in the
+// textual format of Go's runtime goroutine dumps, so that the goroutine
+// package can parse it.
+func writeGoroutine(w *strings.Builder, g *dlvGoroutine, frames []dlvStackframe, waitReasons []string) {
+	fmt.Fprintf(w, "goroutine %d [%s]:\n", g.ID, state(g, waitReasons))
+	bottom := false
+	for _, frame := range frames {
+		writeLocation(w, frame.dlvLocation, "", "()")
+		bottom = bottom || frame.Bottom
+	}
+	if len(frames) == 0 {
+		// The goroutine package insists on a topmost function.
+		writeLocation(w, g.CurrentLoc, "", "()")
+	} else if !bottom && len(frames) > StackDepth {
+		w.WriteString("...additional frames elided...\n")
+	}
+	if g.GoStatementLoc.Function != nil {
+		writeLocation(w, g.GoStatementLoc, "created by ", "")
+	}
+	w.WriteRune('\n')
+}
+
+// writeLocation writes the specified location as a pair of function and
+// file/line lines.
+func writeLocation(w *strings.Builder, loc dlvLocation, prefix, suffix string) {
+	fn, entry := "???", loc.PC
+	if loc.Function != nil {
+		fn, entry = loc.Function.Name, loc.Function.Value
+	}
+	fmt.Fprintf(w, "%s%s%s\n\t%s:%d +0x%x\n", prefix, fn, suffix, loc.File, loc.Line, loc.PC-entry)
+}
+
+// Goroutine status values as used by Go's runtime and reported by delve.
+const (
+	gIdle      = 0
+	gRunnable  = 1
+	gRunning   = 2
+	gSyscall   = 3
+	gWaiting   = 4
+	gCopystack = 8
+	gPreempted = 9
+)
+
+// waitReasons returns the textual representations of the wait reasons of
+// waiting goroutines, as known to the runtime of the debugged target. If the
+// wait reasons cannot be read from the target, waitReasons returns nil.
+func (c *Client) waitReasons() []string {
+	var out evalOut
+	if err := c.rpc.Call("RPCServer.Eval", evalIn{
+		Scope: evalScope{GoroutineID: -1},
+		Expr:  "runtime.waitReasonStrings",
+		Cfg:   &loadConfig{MaxStringLen: 64, MaxArrayValues: 256},
+	}, &out); err != nil || out.Variable == nil || out.Variable.Unreadable != "" {
+		return nil
+	}
+	reasons := make([]string, len(out.Variable.Children))
+	for idx, child := range out.Variable.Children {
+		reasons[idx] = child.Value
+	}
+	return reasons
+}
+
+// state returns the textual state of the specified goroutine, as used in
+// goroutine dumps, using the specified wait reasons of the debugged target.
+// Waiting goroutines with unknown wait reasons are in the generic "waiting"
+// state.
+func state(g *dlvGoroutine, waitReasons []string) string {
+	switch g.Status {
+	case gIdle:
+		return string(goroutine.StateIdle)
+	case gRunnable:
+		return string(goroutine.StateRunnable)
+	case gRunning:
+		return string(goroutine.StateRunning)
+	case gSyscall:
+		return string(goroutine.StateSyscall)
+	case gWaiting:
+		if g.WaitReason > 0 && g.WaitReason < int64(len(waitReasons)) && waitReasons[g.WaitReason] != "" {
+			return waitReasons[g.WaitReason]
+		}
+		return "waiting"
+	case gCopystack:
+		return string(goroutine.StateCopystack)
+	case gPreempted:
+		return string(goroutine.StatePreempted)
+	}
+	return "???"
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package dlvnoleak
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// RPCServer fakes the goroutine-related part of delve's JSON-RPC API.
+type RPCServer struct {
+	goroutines  []*dlvGoroutine
+	stacks      map[int64][]dlvStackframe
+	waitReasons []string // runtime.waitReasonStrings of the target, if any.
+	err         error
+}
+
+// net/rpc insists on exported argument and reply types.
+type (
+	ListGoroutinesIn  listGoroutinesIn
+	ListGoroutinesOut listGoroutinesOut
+	StacktraceIn      stacktraceIn
+	StacktraceOut     stacktraceOut
+	EvalIn            evalIn
+	EvalOut           evalOut
+)
+
+// ListGoroutines returns the fake goroutines one at a time, in order to
+// exercise paging.
+func (s *RPCServer) ListGoroutines(in ListGoroutinesIn, out *ListGoroutinesOut) error {
+	if s.err != nil {
+		return s.err
+	}
+	out.Nextg = -1
+	if in.Start < len(s.goroutines) {
+		out.Goroutines = s.goroutines[in.Start : in.Start+1]
+		out.Nextg = in.Start + 1
+	}
+	return nil
+}
+
+func (s *RPCServer) Stacktrace(in StacktraceIn, out *StacktraceOut) error {
+	out.Locations = s.stacks[in.ID]
+	if len(out.Locations) > in.Depth+1 {
+		out.Locations = out.Locations[:in.Depth+1]
+	}
+	return nil
+}
+
+// Eval only evaluates the wait reason strings of the runtime, if known.
+func (s *RPCServer) Eval(in EvalIn, out *EvalOut) error {
+	if in.Expr != "runtime.waitReasonStrings" || s.waitReasons == nil {
+		return errors.New("could not find symbol value for " + in.Expr)
+	}
+	out.Variable = &dlvVariable{}
+	for _, reason := range s.waitReasons {
+		out.Variable.Children = append(out.Variable.Children, dlvVariable{Value: reason})
+	}
+	return nil
+}
+
+// serve returns a Client connected to the specified fake delve server.
+func serve(s *RPCServer) *Client {
+	srv := rpc.NewServer()
+	Expect(srv.Register(s)).To(Succeed())
+	serverConn, clientConn := net.Pipe()
+	go srv.ServeCodec(jsonrpc.NewServerCodec(serverConn))
+	client := NewClient(clientConn)
+	DeferCleanup(func() {
+		client.Close()
+		Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked())
+	})
+	return client
+}
+
+func location(fn string, entry uint64, file string, line int, pc uint64) dlvLocation {
+	return dlvLocation{
+		PC: pc, File: file, Line: line,
+		Function: &dlvFunction{Name: fn, Value: entry},
+	}
+}
+
+var _ = Describe("delve goroutines", func() {
+
+	It("converts goroutines", func() {
+		client := serve(&RPCServer{
+			goroutines: []*dlvGoroutine{
+				{
+					ID:         1,
+					Status:     gWaiting,
+					WaitReason: 2,
+					CurrentLoc: location("runtime.gopark", 0x1000, "/go/runtime/proc.go", 42, 0x1010),
+				},
+				{
+					ID:             42,
+					Status:         gRunning,
+					CurrentLoc:     location("main.spin", 0x2000, "/home/foo/main.go", 6, 0x2028),
+					GoStatementLoc: location("main.main", 0x3000, "/home/foo/main.go", 5, 0x3064),
+				},
+				{ID: 666, Unreadable: "bad"},
+			},
+			waitReasons: []string{"", "sleep", "chan receive"},
+			stacks: map[int64][]dlvStackframe{
+				1: {
+					{dlvLocation: location("runtime.gopark", 0x1000, "/go/runtime/proc.go", 42, 0x1010)},
+					{dlvLocation: location("main.main", 0x3000, "/home/foo/main.go", 10, 0x3080), Bottom: true},
+				},
+				42: {
+					{dlvLocation: location("main.spin", 0x2000, "/home/foo/main.go", 6, 0x2028), Bottom: true},
+				},
+			},
+		})
+		gs, err := client.Goroutines()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(HaveLen(2))
+		Expect(gs[0]).To(And(
+			HaveField("ID", uint64(1)),
+			HaveField("State", "chan receive"),
+			HaveField("TopFunction", "runtime.gopark"),
+			HaveField("CreatorFunction", ""),
+			HaveField("Frames", HaveLen(2)),
+			HaveField("ElidedFrames", 0)))
+		Expect(gs[1]).To(And(
+			HaveField("ID", uint64(42)),
+			HaveField("State", "running"),
+			HaveField("TopFunction", "main.spin"),
+			HaveField("CreatorFunction", "main.main"),
+			HaveField("BornAt", "/home/foo/main.go:5")))
+		Expect(gs[1].Backtrace).To(ContainSubstring("\t/home/foo/main.go:6 +0x28\n"))
+	})
+
+	It("marks cut-off backtraces", func() {
+		oldDepth := StackDepth
+		StackDepth = 1
+		DeferCleanup(func() { StackDepth = oldDepth })
+		frame := dlvStackframe{dlvLocation: location("main.recurse", 0x1000, "/home/foo/main.go", 6, 0x1010)}
+		client := serve(&RPCServer{
+			goroutines: []*dlvGoroutine{{ID: 1, Status: gRunnable}},
+			stacks:     map[int64][]dlvStackframe{1: {frame, frame, frame}},
+		})
+		gs, err := client.Goroutines()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(ConsistOf(And(
+			HaveField("State", "runnable"),
+			HaveField("Frames", HaveLen(2)),
+			HaveField("ElidedFrames", -1))))
+	})
+
+	It("falls back to waiting without the target's wait reasons", func() {
+		client := serve(&RPCServer{
+			goroutines: []*dlvGoroutine{{ID: 1, Status: gWaiting, WaitReason: 2}},
+			stacks: map[int64][]dlvStackframe{1: {
+				{dlvLocation: location("runtime.gopark", 0x1000, "/go/runtime/proc.go", 42, 0x1010), Bottom: true},
+			}},
+		})
+		gs, err := client.Goroutines()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(ConsistOf(HaveField("State", "waiting")))
+	})
+
+	It("reports RPC errors", func() {
+		client := serve(&RPCServer{err: errors.New("D'OH!")})
+		Expect(client.Goroutines()).Error().To(MatchError(ContainSubstring("D'OH!")))
+	})
+
+	It("fails to dial nowhere", func() {
+		Expect(Dial("127.0.0.1:0")).Error().To(HaveOccurred())
+	})
+
+	It("maps goroutine states", func() {
+		reasons := []string{"", "sleep", ""}
+		Expect(state(&dlvGoroutine{Status: gIdle}, reasons)).To(Equal("idle"))
+		Expect(state(&dlvGoroutine{Status: gSyscall}, reasons)).To(Equal("syscall"))
+		Expect(state(&dlvGoroutine{Status: gCopystack}, reasons)).To(Equal("copystack"))
+		Expect(state(&dlvGoroutine{Status: gPreempted}, reasons)).To(Equal("preempted"))
+		Expect(state(&dlvGoroutine{Status: gWaiting, WaitReason: 1}, reasons)).To(Equal("sleep"))
+		Expect(state(&dlvGoroutine{Status: gWaiting, WaitReason: 2}, reasons)).To(Equal("waiting"))
+		Expect(state(&dlvGoroutine{Status: gWaiting, WaitReason: 1}, nil)).To(Equal("waiting"))
+		Expect(state(&dlvGoroutine{Status: gWaiting, WaitReason: 1000}, reasons)).To(Equal("waiting"))
+		Expect(state(&dlvGoroutine{Status: 42}, reasons)).To(Equal("???"))
+	})
+
+})
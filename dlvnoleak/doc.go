@@ -0,0 +1,42 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package dlvnoleak retrieves goroutines from core dumps and attached processes
+via the delve debugger, so that the same filters and reports as for live
+goroutines also work in post-mortem crash analysis.
+
+First, start delve as a headless server, such as for a core dump:
+
+    dlv core ./mybinary core.1234 --headless --api-version=2 --listen=127.0.0.1:4040
+
+or, alternatively, for an already running process:
+
+    dlv attach 1234 --headless --api-version=2 --listen=127.0.0.1:4040
+
+Then, retrieve the goroutines through delve and check them for leaks as usual:
+
+    client, err := dlvnoleak.Dial("127.0.0.1:4040")
+    ...
+    defer client.Close()
+    gs, err := client.Goroutines()
+    ...
+    Expect(gs).NotTo(noleak.HaveLeaked(...))
+
+Package dlvnoleak doesn't depend on delve itself, but instead talks delve's
+JSON-RPC API version 2.
+
+*/
+package dlvnoleak
@@ -39,7 +39,28 @@ A typical pattern to detect goroutines leaked in individual tests is as follows:
 
 Using Eventually instead of Expect ensures that there is some time given for
 temporary goroutines to finally wind down. Gomega's default values apply: the 1s
-timeout and 10ms polling interval.
+timeout and 10ms polling interval. In addition, Goroutines can give goroutines
+a last chance to end by running the garbage collector and yielding before
+capturing, when enabling PreCaptureGrace. Instead of relying on a fixed
+timeout, newly started goroutines can also be given a grace period of their own
+using IgnoringYoungerThan, which ignores goroutines first observed less than
+the specified duration ago.
+
+When a spec has a deadline, pass a context with this deadline to HaveLeaked in
+order to stop waiting for goroutines to wind down as soon as the context is
+done; the failure message then notes the interrupted settle period:
+
+    Eventually(LazyGoroutines{}).ShouldNot(HaveLeaked(ctx, ignoreGood))
+
+In order to not hang indefinitely on pathologically slow captures, such as of
+massive dumps, set CaptureTimeout or use GoroutinesWithin instead of
+Goroutines; both abandon captures not finishing in time with an error
+wrapping ErrCaptureTimeout.
+
+Make sure to pass the Goroutines function itself to Eventually, and not the
+result of calling it: Eventually(Goroutines). When passed a fixed list of
+goroutines instead, HaveLeaked tells Eventually that the outcome cannot change
+anymore, so Eventually gives up immediately instead of polling in vain.
 
 Please note that the form
 
@@ -49,6 +70,45 @@ is the same as the slightly longer, but also more expressive variant:
 
     HaveLeaked(IgnoringGoroutines(ignoreGood))
 
+Goroutines started by package init functions, such as loggers and metrics
+exporters, can be recorded once using BaselineInit in TestMain before running
+the tests. All HaveLeaked matchers then implicitly ignore these goroutines,
+unless passed IncludingInitGoroutines. Alternatively, VerifyTestMain records
+the init-time baseline, runs the tests, and then checks for goroutines leaked
+by the tests as a whole; it optionally writes a JSON summary of all reported
+leaks to SummaryFile at exit, for CI steps gating merges on leak counts.
+
+In order to also detect the unexpected disappearance of long-lived goroutines
+from the baseline, such as a crashed metrics pump, pass MustSurvive to
+HaveLeaked:
+
+    HaveLeaked(ignoreGood, MustSurvive(IgnoringTopFunction("metrics.(*Pump).run")))
+
+Pass CountingSystemGoroutines to HaveLeaked in order to additionally report
+the number of the Go runtime's system goroutines, which are never considered
+leaked, but help debugging runaway garbage collector or scavenger behavior.
+
+The goroutine package's Compare function and Snapshot.Compare method return
+both the goroutines that appeared and vanished since a baseline.
+
+Instead of a plain list of goroutines, you can also take a snapshot using
+
+    TakeSnapshot()
+
+that additionally records when and where (Go version, PID) the snapshot was
+taken. When passing such a snapshot to HaveLeaked, failure messages report the
+age of the baseline snapshot, making stale baselines easier to spot. Snapshots
+can be saved to and later loaded from files using Snapshot.Save and
+goroutine.LoadSnapshot, for instance, in order to reuse a baseline captured
+after expensive setup in a separate test phase.
+
+Multi-stage integration tests can tag snapshots with the names of their
+phases using TakeTaggedSnapshot, and then pass them to HaveLeaked wrapped in
+Phases, so that failure messages attribute the leaked goroutines to the phases
+in which they appeared:
+
+    HaveLeaked(baseline, Phases(afterSuiteSetup, afterFixtureX))
+
 Leak-Related Matchers
 
 Depending on your tests and the dependencies used, you might need to identify
@@ -63,11 +123,81 @@ arguments to HaveLeaked(...):
     IgnoringInBacktrace("foo.bar.baz")            // "foo.bar.baz" within the backtrace
     IgnoringCreator("foo.bar")                    // exact creator function name "foo.bar"
     IgnoringCreator("foo.bar...")                 // creator function name with prefix "foo.bar."
+    IgnoringCreatorLocation("pkg/foo.go", 10, 20) // created in "pkg/foo.go" at lines 10 to 20
+    IgnoringMethodOf("*pool.Worker")              // method of receiver type "*pool.Worker" within the backtrace
+    IgnoringCalledThrough("pkg.Supervisor.run")   // exactly "pkg.Supervisor.run" at any depth of the backtrace, or as creator
+    IgnoringVendored()                            // originating purely in vendor/ or third_party/ code
+    IgnoringGenerated()                           // originating purely in generated code, such as *.pb.go
+
+Instead of IgnoringTopFunction's string syntax, IgnoringTop accepts typed
+options, such as:
+
+    IgnoringTop(TopFunctionMatch("foo.bar"), TopPrefixMatch(), TopStateMatch("chan receive"))
+
+Exceptions can be carved out from broad filters using Except; for instance, in
+order to ignore all goroutines created by package foo, except for goroutines
+with the top function "foo.mustNotLinger":
+
+    Except(IgnoringCreator("foo..."), IgnoringTopFunction("foo.mustNotLinger"))
+
+Goroutines of test frameworks beyond Go's testing package can be ignored using
+named presets, such as IgnoringPreset(PresetTestify, PresetGocheck); please see
+Presets for the available presets.
 
 In addition, you can use any other GomegaMatcher, as long as it can work on a
 (single) goroutine.Goroutine. For instance, Gomega's HaveField and WithTransform
 matchers are good foundations for writing project-specific noleak matchers.
 
+For testing code that deliberately leaves goroutines running, ContainLeak and
+ContainLeaks assert that specific goroutines do exist beyond a baseline:
+
+    Eventually(Goroutines).Should(ContainLeaks(1, IgnoringTopFunction("mw.(*Middleware).run"), snapshot))
+
+ConsistOfLeaks additionally asserts that there are no other leaked goroutines
+besides the expected ones.
+
+To debug why a particular goroutine is or isn't reported as leaked,
+HaveLeakedMatcher.Explain returns the per-goroutine filter decisions:
+
+    m := HaveLeaked(snapshot).(*HaveLeakedMatcher)
+    for _, decision := range m.Explain(Goroutines()) {
+        fmt.Println(decision)
+    }
+
+The filter matchers can also be used in a positive sense with EnsureGone, in
+order to check that specific goroutines end within a timeout, such as after
+cancelling a worker's context:
+
+    cancel()
+    EnsureGone(IgnoringTopFunction("foo.(*Worker).run"), time.Second)
+
+Leak-Checked Ginkgo Suites
+
+ginkgonoleak.RunLeakCheckedSpecs replaces RunSpecs in order to check each spec
+of a Ginkgo suite as well as the suite as a whole for leaked goroutines,
+ignoring Ginkgo's own goroutines and writing the SummaryFile, if set:
+
+    func TestSuite(t *testing.T) {
+        ginkgonoleak.RunLeakCheckedSpecs(t, "my suite")
+    }
+
+Usage Without Gomega Assertions
+
+Projects using testify or just the bare testing package can use the same leak
+detection using Check, which returns a LeakReport and an error instead of
+failing a Gomega assertion. Package testingnoleak additionally provides
+VerifyNone, which fails a test:
+
+    func TestFoo(t *testing.T) {
+        snapshot := noleak.Goroutines()
+        defer testingnoleak.VerifyNone(t, snapshot, noleak.CheckOptions{})
+        ...
+    }
+
+For hierarchies of subtests, testingnoleak.Run replaces t.Run and checks each
+subtest, attributing leaks to the deepest subtest in whose window they
+appeared; for individual (sub)tests, see testingnoleak.VerifyTest.
+
 Leaked Goroutine Dump
 
 By default, when noleak's HaveLeaked matcher finds one or more leaked
@@ -86,6 +216,64 @@ will show full path names for each source file:
         main.foo.func1() at /home/go/foo/test.go:6
         created by main.foo at home/go/foo/test.go:5
 
+Configuration Using Flags
+
+RegisterFlags registers go test flags to adjust leak checking per CI job
+without touching test code; VerifyTestMain registers them automatically.
+Importing noleak alone doesn't register any flags.
+
+    func init() {
+        noleak.RegisterFlags(nil)
+    }
+
+The go test flags are:
+
+    -noleak.enable=false          disables leak checking (see Enabled)
+    -noleak.settle=5s             sets SettleTimeout
+    -noleak.capture-timeout=10s   abandons slow goroutine captures (see CaptureTimeout)
+    -noleak.ignore=foo.bar,baz... ignores goroutines with these top functions
+    -noleak.report-dir=DIR        writes Markdown leak reports (see ReportDir)
+    -noleak.dedup                 condenses repeated leak reports (see DeduplicateReports)
+    -noleak.presets=testify,...   ignores goroutines of these test frameworks (see IgnoringPreset)
+
+Independent Configurations
+
+The package-level settings, such as SuggestFilters and ReportDir, apply to all
+HaveLeaked matchers. Parallel suites and libraries embedding noleak can instead
+create a Detector with its own Config, starting from DefaultConfig, and then
+use the Detector's HaveLeaked and Check methods:
+
+    cfg := DefaultConfig()
+    cfg.ReportDir = "leaks/integration"
+    detector := NewDetector(cfg)
+    Eventually(Goroutines).ShouldNot(detector.HaveLeaked(snapshot))
+
+Continuous Monitoring
+
+For long-running soak tests, a Monitor created using NewMonitor periodically
+checks for leaked goroutines in the background, using the same goroutine
+filters as HaveLeaked. The monitor's own background goroutine is never reported
+as leaked; embedders can mark their own infrastructure goroutines in the same
+way by starting them using GoInfrastructure. Package promnoleak exposes the
+monitor statistics as Prometheus metrics; it is a separate Go module, so
+importing noleak doesn't add any Prometheus dependencies.
+
+Leak Hooks
+
+Functions registered using AddLeakHook get notified whenever a leak check
+finally fails or a Monitor detects new leaks, for instance, in order to record leaks in test observability
+pipelines. Package otelnoleak provides such a hook that records leaks as
+OpenTelemetry span events; it is a separate Go module, so importing noleak
+doesn't add any OpenTelemetry dependencies.
+
+Measuring Overhead
+
+In order to quantify the overhead noleak adds to a suite, Measuring wraps
+HaveLeaked so that the duration and allocation cost of each capture+match
+cycle get recorded into a gmeasure experiment; CheckOptions.Experiment does the
+same for Check. The recorded data then helps tuning the settle and polling
+settings.
+
 Acknowledgement
 
 noleak has been heavily inspired by the Goroutine leak detector
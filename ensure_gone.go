@@ -0,0 +1,72 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// EnsureGone asserts that all goroutines matching the specified filter matcher
+// end within the specified timeout. Any filter matcher can be used, such as
+// IgnoringTopFunction, but here in a positive sense to select the goroutines
+// that must be gone. EnsureGone returns true if the selected goroutines are
+// gone in time, otherwise it fails using gomega.Default and returns false. A
+// zero timeout means to use SettleTimeout instead, or Gomega's default
+// Eventually timeout if SettleTimeout isn't set either.
+//
+// In contrast to HaveLeaked, which checks for no leaked goroutines overall,
+// EnsureGone allows to verify that, say, cancelling a context actually stops a
+// particular worker goroutine:
+//
+//   cancel()
+//   EnsureGone(IgnoringTopFunction("foo.(*Worker).run"), time.Second)
+func EnsureGone(matcher types.GomegaMatcher, timeout time.Duration) bool {
+	if timeout <= 0 {
+		timeout = SettleTimeout
+	}
+	eventually := gomega.Default.Eventually(func() ([]goroutine.Goroutine, error) {
+		return matching(Goroutines(), matcher)
+	}).WithOffset(1)
+	if timeout > 0 {
+		eventually = eventually.WithTimeout(timeout)
+	}
+	return eventually.Should(gomega.BeEmpty(),
+		"goroutines expected to be gone are still present")
+}
+
+// matching returns those goroutines from the specified list of goroutines
+// that are matched by the specified matcher. The calling goroutine is never
+// included.
+func matching(goroutines []goroutine.Goroutine, matcher types.GomegaMatcher) ([]goroutine.Goroutine, error) {
+	gs := []goroutine.Goroutine{}
+	myID := goroutine.Current().ID
+	for _, g := range goroutines {
+		if g.ID == myID {
+			continue
+		}
+		matches, err := matcher.Match(g)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			gs = append(gs, g)
+		}
+	}
+	return gs, nil
+}
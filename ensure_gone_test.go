@@ -0,0 +1,62 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// worker is the top function of the goroutines started in the EnsureGone
+// tests.
+func worker(done <-chan struct{}) {
+	<-done
+}
+
+var _ = Describe("EnsureGone", func() {
+
+	It("succeeds when matching goroutines are gone in time", func() {
+		done := make(chan struct{})
+		go worker(done)
+		time.AfterFunc(50*time.Millisecond, func() { close(done) })
+		Expect(EnsureGone(IgnoringTopFunction("github.com/thediveo/noleak.worker"), 2*time.Second)).
+			To(BeTrue())
+	})
+
+	It("fails when matching goroutines are still present", func() {
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			EnsureGone(IgnoringTopFunction("github.com/thediveo/noleak.worker"), 2*time.Second)
+		}()
+		Eventually(Goroutines).Should(ContainElement(
+			HaveField("TopFunction", "github.com/thediveo/noleak.worker")))
+		failures := InterceptGomegaFailures(func() {
+			EnsureGone(IgnoringTopFunction("github.com/thediveo/noleak.worker"), 100*time.Millisecond)
+		})
+		Expect(failures).To(ConsistOf(ContainSubstring("goroutines expected to be gone are still present")))
+	})
+
+	It("never matches the calling goroutine", func() {
+		gs, err := matching([]goroutine.Goroutine{goroutine.Current()}, IgnoringGoroutines(Goroutines()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(BeEmpty())
+	})
+
+})
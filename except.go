@@ -0,0 +1,92 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// Except succeeds if the specified filter matcher succeeds for an actual
+// goroutine, but none of the exception matchers. This allows carving out
+// exceptions from broad filters. For instance, to ignore all goroutines
+// created by package foo, except for goroutines with the top function
+// "foo.mustNotLinger":
+//
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(
+//       Except(IgnoringCreator("foo..."), IgnoringTopFunction("foo.mustNotLinger"))))
+func Except(filter types.GomegaMatcher, exceptions ...types.GomegaMatcher) types.GomegaMatcher {
+	return &exceptMatcher{filter: filter, exceptions: exceptions}
+}
+
+type exceptMatcher struct {
+	filter     types.GomegaMatcher
+	exceptions []types.GomegaMatcher
+}
+
+// Match succeeds if the filter matches the actual goroutine, but none of the
+// exceptions.
+func (matcher *exceptMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := G(actual, "Except")
+	if err != nil {
+		return false, err
+	}
+	matches, err := matcher.filter.Match(g)
+	if err != nil || !matches {
+		return false, err
+	}
+	for _, exception := range matcher.exceptions {
+		excepted, err := exception.Match(g)
+		if err != nil {
+			return false, err
+		}
+		if excepted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FailureMessage returns a failure message if the actual goroutine doesn't
+// match the filter or is excepted.
+func (matcher *exceptMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, "to match "+matcher.String())
+}
+
+// NegatedFailureMessage returns a failure message if the actual goroutine
+// matches the filter and isn't excepted.
+func (matcher *exceptMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, "not to match "+matcher.String())
+}
+
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *exceptMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
+// String returns the Go expression creating this matcher, in as far as the
+// filter and exception matchers can be described.
+func (matcher *exceptMatcher) String() string {
+	descriptions := make([]string, 0, 1+len(matcher.exceptions))
+	descriptions = append(descriptions, describeFilter(matcher.filter))
+	for _, exception := range matcher.exceptions {
+		descriptions = append(descriptions, describeFilter(exception))
+	}
+	return fmt.Sprintf("Except(%s)", strings.Join(descriptions, ", "))
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("Except matcher", func() {
+
+	m := Except(IgnoringCreator("foo..."), IgnoringTopFunction("foo.mustNotLinger"))
+
+	It("returns an error for an invalid actual", func() {
+		Expect(m.Match(nil)).Error().To(MatchError(HavePrefix("Except matcher expects a goroutine.Goroutine")))
+		Expect(Except(HaveLen(1)).Match(goroutine.Goroutine{})).Error().To(HaveOccurred())
+		Expect(Except(IgnoringCreator("foo..."), HaveLen(1)).Match(
+			goroutine.Goroutine{CreatorFunction: "foo.bar"})).Error().To(HaveOccurred())
+	})
+
+	It("matches except for exceptions", func() {
+		Expect(m.Match(goroutine.Goroutine{
+			TopFunction: "foo.worker", CreatorFunction: "foo.Start",
+		})).To(BeTrue())
+		Expect(m.Match(goroutine.Goroutine{
+			TopFunction: "foo.mustNotLinger", CreatorFunction: "foo.Start",
+		})).To(BeFalse())
+		Expect(m.Match(goroutine.Goroutine{
+			TopFunction: "foo.worker", CreatorFunction: "bar.Start",
+		})).To(BeFalse())
+	})
+
+	It("returns failure messages", func() {
+		Expect(m.FailureMessage(goroutine.Goroutine{ID: 42})).To(HaveSuffix(
+			"\nto match Except(IgnoringCreator(\"foo...\"), IgnoringTopFunction(\"foo.mustNotLinger\"))"))
+		Expect(m.NegatedFailureMessage(goroutine.Goroutine{ID: 42})).To(HaveSuffix(
+			"\nnot to match Except(IgnoringCreator(\"foo...\"), IgnoringTopFunction(\"foo.mustNotLinger\"))"))
+		Expect(describeFilter(m)).To(HavePrefix("Except("))
+	})
+
+})
@@ -0,0 +1,122 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Decision explains HaveLeaked's decision about a single goroutine: whether
+// it is considered to be leaked, and why.
+type Decision struct {
+	Goroutine goroutine.Goroutine // the goroutine decided on.
+	Leaked    bool                // true if the goroutine is considered to be leaked.
+	Reason    string              // why the goroutine was kept or dropped.
+}
+
+// String returns a single-line textual representation of this decision.
+func (d Decision) String() string {
+	return fmt.Sprintf("goroutine %d [%s] %s: %s",
+		d.Goroutine.ID, d.Goroutine.State, d.Goroutine.TopFunction, d.Reason)
+}
+
+// Explain returns the decisions this matcher makes for each of the specified
+// goroutines, in order to debug why a particular goroutine is or isn't
+// reported as leaked. A goroutine is dropped by the first filter that matches
+// it; the remaining goroutines are kept and thus considered to be leaked. For
+// instance:
+//
+//   m := HaveLeaked(snapshot, "foo.bar...").(*HaveLeakedMatcher)
+//   for _, d := range m.Explain(Goroutines()) {
+//       fmt.Println(d)
+//   }
+//
+// prints decisions such as:
+//
+//   goroutine 42 [chan receive] foo.bar.baz: dropped by IgnoringTopFunction("foo.bar...")
+//   goroutine 43 [select] main.worker: kept: no filter matched
+func (matcher *HaveLeakedMatcher) Explain(goroutines []goroutine.Goroutine) []Decision {
+	decisions := make([]Decision, 0, len(goroutines))
+	myID := goroutine.Current().ID
+nextgoroutine:
+	for _, g := range goroutines {
+		if g.ID == myID {
+			decisions = append(decisions, Decision{
+				Goroutine: g,
+				Reason:    "dropped: calling goroutine",
+			})
+			continue
+		}
+		for _, filter := range matcher.filters {
+			matches, err := filter.Match(g)
+			if err != nil {
+				decisions = append(decisions, Decision{
+					Goroutine: g,
+					Reason:    fmt.Sprintf("error in %s: %s", describeFilter(filter), err),
+				})
+				continue nextgoroutine
+			}
+			if matches {
+				decisions = append(decisions, Decision{
+					Goroutine: g,
+					Reason:    "dropped by " + describeFilter(filter),
+				})
+				continue nextgoroutine
+			}
+		}
+		decisions = append(decisions, Decision{
+			Goroutine: g,
+			Leaked:    true,
+			Reason:    "kept: no filter matched",
+		})
+	}
+	return decisions
+}
+
+// describeFilter returns a textual description of the specified filter
+// matcher, in the form of the Go expression creating it if it is one of
+// noleak's built-in filter matchers.
+func describeFilter(filter types.GomegaMatcher) string {
+	switch m := filter.(type) {
+	case *ignoringTopFunctionMatcher:
+		switch {
+		case m.matchPrefix && m.expectedState != "":
+			return fmt.Sprintf("IgnoringTop(TopFunctionMatch(%q), TopPrefixMatch(), TopStateMatch(%q))",
+				strings.TrimSuffix(m.expectedTopFunction, "."), m.expectedState)
+		case m.matchPrefix:
+			return fmt.Sprintf("IgnoringTopFunction(%q)", m.expectedTopFunction+"..")
+		case m.expectedState != "":
+			return fmt.Sprintf("IgnoringTopFunction(%q)",
+				m.expectedTopFunction+" ["+m.expectedState+"]")
+		}
+		return fmt.Sprintf("IgnoringTopFunction(%q)", m.expectedTopFunction)
+	case *ignoringCreator:
+		if m.matchPrefix {
+			return fmt.Sprintf("IgnoringCreator(%q)", m.expectedCreatorFunction+"..")
+		}
+		return fmt.Sprintf("IgnoringCreator(%q)", m.expectedCreatorFunction)
+	case *ignoringInBacktraceMatcher:
+		return fmt.Sprintf("IgnoringInBacktrace(%q)", m.fname)
+	case *ignoringGoroutinesMatcher:
+		return "IgnoringGoroutines(<" + counted(len(m.ignoreGoids), "goroutine", "goroutines") + ">)"
+	case fmt.Stringer:
+		return m.String()
+	}
+	return fmt.Sprintf("%T", filter)
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("explaining filter decisions", func() {
+
+	It("describes filters", func() {
+		Expect(describeFilter(IgnoringTopFunction("foo.bar"))).To(Equal(`IgnoringTopFunction("foo.bar")`))
+		Expect(describeFilter(IgnoringTopFunction("foo.bar..."))).To(Equal(`IgnoringTopFunction("foo.bar...")`))
+		Expect(describeFilter(IgnoringTopFunction("foo.bar [chan receive]"))).To(Equal(`IgnoringTopFunction("foo.bar [chan receive]")`))
+		Expect(describeFilter(IgnoringTop(TopFunctionMatch("foo"), TopPrefixMatch(), TopStateMatch("select")))).To(Equal(`IgnoringTop(TopFunctionMatch("foo"), TopPrefixMatch(), TopStateMatch("select"))`))
+		Expect(describeFilter(IgnoringCreator("foo.bar"))).To(Equal(`IgnoringCreator("foo.bar")`))
+		Expect(describeFilter(IgnoringCreator("foo.bar..."))).To(Equal(`IgnoringCreator("foo.bar...")`))
+		Expect(describeFilter(IgnoringInBacktrace("foo.bar"))).To(Equal(`IgnoringInBacktrace("foo.bar")`))
+		Expect(describeFilter(IgnoringGoroutines([]goroutine.Goroutine{{ID: 1}, {ID: 2}}))).To(Equal(`IgnoringGoroutines(<2 goroutines>)`))
+		Expect(describeFilter(IgnoringGoroutines([]goroutine.Goroutine{{ID: 1}}))).To(Equal(`IgnoringGoroutines(<1 goroutine>)`))
+		Expect(describeFilter(BeNil())).To(Equal("*matchers.BeNilMatcher"))
+	})
+
+	It("explains decisions", func() {
+		m := HaveLeaked("foo.bar...", HaveLen(1)).(*HaveLeakedMatcher)
+		decisions := m.Explain([]goroutine.Goroutine{
+			goroutine.Current(),
+			{ID: 1<<62 + 1, State: "chan receive", TopFunction: "foo.bar.baz"},
+			{ID: 1<<62 + 2, State: "select", TopFunction: "main.worker"},
+		})
+		Expect(decisions).To(HaveLen(3))
+		Expect(decisions[0].Leaked).To(BeFalse())
+		Expect(decisions[0].Reason).To(Equal("dropped: calling goroutine"))
+		Expect(decisions[1].Leaked).To(BeFalse())
+		Expect(decisions[1].String()).To(Equal(
+			`goroutine 4611686018427387905 [chan receive] foo.bar.baz: dropped by IgnoringTopFunction("foo.bar...")`))
+		Expect(decisions[2].Leaked).To(BeFalse())
+		Expect(decisions[2].Reason).To(HavePrefix("error in *matchers.HaveLenMatcher: "))
+
+		m = HaveLeaked("foo.bar...").(*HaveLeakedMatcher)
+		decisions = m.Explain([]goroutine.Goroutine{
+			{ID: 1<<62 + 2, State: "select", TopFunction: "main.worker"},
+		})
+		Expect(decisions).To(ConsistOf(Decision{
+			Goroutine: goroutine.Goroutine{ID: 1<<62 + 2, State: "select", TopFunction: "main.worker"},
+			Leaked:    true,
+			Reason:    "kept: no filter matched",
+		}))
+	})
+
+})
@@ -0,0 +1,80 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fdnoleak
+
+import (
+	"sort"
+	"sync"
+)
+
+// Backend enumerates the resources of a particular kind of the current
+// process on a particular platform. Backends for the supported platforms
+// register themselves; Register adds backends for further platforms or
+// resource kinds, without needing to touch the matchers.
+type Backend interface {
+	// Kind returns the kind of resources enumerated.
+	Kind() Kind
+	// Name returns how to name the resources in messages, such as "handles"
+	// instead of "file descriptors" on Windows.
+	Name() string
+	// Enumerate returns the current resources. Backends that can list the
+	// individual resources set the Items, others only the Count.
+	Enumerate() (Resources, error)
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[Kind]Backend{}
+)
+
+// Register registers the specified backend for its kind of resources,
+// replacing any backend previously registered for the same kind.
+func Register(backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[backend.Kind()] = backend
+}
+
+// Kinds returns the kinds of resources with registered backends, in
+// alphabetical order.
+func Kinds() []Kind {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	kinds := make([]Kind, 0, len(backends))
+	for kind := range backends {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+// lookup returns the backend registered for the specified kind of resources.
+func lookup(kind Kind) (Backend, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	backend, ok := backends[kind]
+	return backend, ok
+}
+
+// backendFunc adapts an enumeration function to the Backend interface.
+type backendFunc struct {
+	kind      Kind
+	name      string
+	enumerate func() (Resources, error)
+}
+
+func (b backendFunc) Kind() Kind                    { return b.kind }
+func (b backendFunc) Name() string                  { return b.name }
+func (b backendFunc) Enumerate() (Resources, error) { return b.enumerate() }
@@ -0,0 +1,65 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin
+
+package fdnoleak
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	Register(backendFunc{kind: Threads, name: "threads", enumerate: threads})
+	Register(backendFunc{kind: Processes, name: "child processes", enumerate: childProcesses})
+}
+
+// threads counts the threads of the current process. Without cgo, macOS
+// doesn't allow a process to enumerate its own threads, so threads runs "ps
+// -M", which lists a header line followed by a line per thread.
+func threads() (Resources, error) {
+	out, err := exec.Command("ps", "-M", "-p", strconv.Itoa(os.Getpid())).Output()
+	if err != nil {
+		return Resources{}, err
+	}
+	lines := bytes.Count(bytes.TrimSpace(out), []byte("\n"))
+	return Resources{Count: lines}, nil
+}
+
+// childProcesses lists the direct child processes of the current process
+// together with their names.
+func childProcesses() (Resources, error) {
+	procs, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return Resources{}, err
+	}
+	self := int32(os.Getpid())
+	items := []Item{}
+	for _, proc := range procs {
+		if proc.Eproc.Ppid != self {
+			continue
+		}
+		comm := proc.Proc.P_comm[:]
+		if idx := bytes.IndexByte(comm, 0); idx >= 0 {
+			comm = comm[:idx]
+		}
+		items = append(items, Item{ID: int(proc.Proc.P_pid), Description: string(comm)})
+	}
+	return Resources{Items: items}, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package fdnoleak
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(backendFunc{kind: Threads, name: "threads", enumerate: threads})
+	Register(backendFunc{kind: Processes, name: "child processes", enumerate: childProcesses})
+}
+
+// threads lists the OS threads of the current process together with their
+// names.
+func threads() (Resources, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return Resources{}, err
+	}
+	items := make([]Item, 0, len(entries))
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// The thread might have terminated in the meantime, so don't fail.
+		comm, _ := os.ReadFile("/proc/self/task/" + entry.Name() + "/comm")
+		items = append(items, Item{ID: tid, Description: strings.TrimSpace(string(comm))})
+	}
+	return Resources{Items: items}, nil
+}
+
+// childProcesses lists the direct child processes of the current process
+// together with their names.
+func childProcesses() (Resources, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return Resources{}, err
+	}
+	self := os.Getpid()
+	items := []Item{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// Processes might have terminated in the meantime, so skip them.
+		stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		comm, ppid, ok := parseStat(string(stat))
+		if !ok || ppid != self {
+			continue
+		}
+		items = append(items, Item{ID: pid, Description: comm})
+	}
+	return Resources{Items: items}, nil
+}
+
+// parseStat returns the name and parent process ID from the contents of a
+// /proc/[PID]/stat file. As the name might contain spaces and parentheses, the
+// fields following the name are located from the last closing parenthesis.
+func parseStat(stat string) (comm string, ppid int, ok bool) {
+	open := strings.IndexByte(stat, '(')
+	close := strings.LastIndexByte(stat, ')')
+	if open < 0 || close < open {
+		return "", 0, false
+	}
+	fields := strings.Fields(stat[close+1:])
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return stat[open+1 : close], ppid, true
+}
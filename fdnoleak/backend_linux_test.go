@@ -0,0 +1,39 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package fdnoleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Linux resource backends", func() {
+
+	It("parses process stat", func() {
+		comm, ppid, ok := parseStat("42 (a (b) c) S 666 42 42 0")
+		Expect(ok).To(BeTrue())
+		Expect(comm).To(Equal("a (b) c"))
+		Expect(ppid).To(Equal(666))
+		_, _, ok = parseStat("42 foo")
+		Expect(ok).To(BeFalse())
+		_, _, ok = parseStat("42 (foo) S")
+		Expect(ok).To(BeFalse())
+		_, _, ok = parseStat("42 (foo) S x")
+		Expect(ok).To(BeFalse())
+	})
+
+})
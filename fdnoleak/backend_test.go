@@ -0,0 +1,112 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fdnoleak
+
+import (
+	"errors"
+	"net"
+	"os/exec"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("resource backends", func() {
+
+	It("registers and uses backends", func() {
+		const widgets = Kind("widgets")
+		Expect(Kinds()).NotTo(ContainElement(widgets))
+		Expect(CurrentOf(widgets)).Error().To(MatchError(ContainSubstring(
+			"cannot determine widgets: not supported on " + runtime.GOOS)))
+
+		count := 0
+		var err error
+		Register(backendFunc{kind: widgets, name: "gadgets", enumerate: func() (Resources, error) {
+			return Resources{Count: count}, err
+		}})
+		defer func() {
+			backendsMu.Lock()
+			defer backendsMu.Unlock()
+			delete(backends, widgets)
+		}()
+		Expect(Kinds()).To(ContainElement(widgets))
+
+		baseline, err := CurrentOf(widgets)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(baseline).To(Equal(Resources{Kind: widgets, Name: "gadgets"}))
+		count = 1
+		Expect(CurrentOf(widgets)).To(HaveLeakedResources(baseline))
+
+		err = errors.New("D'OH!")
+		Expect(CurrentOf(widgets)).Error().To(MatchError("cannot determine gadgets: D'OH!"))
+	})
+
+	It("sorts listed resources", func() {
+		const widgets = Kind("widgets")
+		Register(backendFunc{kind: widgets, name: "widgets", enumerate: func() (Resources, error) {
+			return Resources{Items: []Item{{ID: 42}, {ID: 1}}}, nil
+		}})
+		defer func() {
+			backendsMu.Lock()
+			defer backendsMu.Unlock()
+			delete(backends, widgets)
+		}()
+		Expect(CurrentOf(widgets)).To(Equal(Resources{
+			Kind: widgets, Name: "widgets", Count: 2, Items: []Item{{ID: 1}, {ID: 42}}}))
+	})
+
+	It("detects leaked sockets", func() {
+		if runtime.GOOS == "windows" {
+			Skip("no sockets backend on Windows")
+		}
+		sockets, err := CurrentOf(Sockets)
+		Expect(err).NotTo(HaveOccurred())
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(CurrentOf(Sockets)).To(HaveLeakedResources(sockets))
+		Expect(l.Close()).To(Succeed())
+		Eventually(func() (Resources, error) {
+			return CurrentOf(Sockets)
+		}).ShouldNot(HaveLeakedResources(sockets))
+	})
+
+	It("detects leaked child processes", func() {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+			Skip("child processes test only on Linux and macOS")
+		}
+		children, err := CurrentOf(Processes)
+		Expect(err).NotTo(HaveOccurred())
+		cmd := exec.Command("sleep", "10")
+		Expect(cmd.Start()).To(Succeed())
+		defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+		leaked, err := CurrentOf(Processes)
+		Expect(err).NotTo(HaveOccurred())
+		m := HaveLeakedResources(children)
+		Expect(m.Match(leaked)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leaked)).To(ContainSubstring(" → sleep"))
+	})
+
+	It("lists threads", func() {
+		if runtime.GOOS != "linux" {
+			Skip("threads backend only on Linux")
+		}
+		threads, err := CurrentOf(Threads)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(threads.Count).To(BeNumerically(">", 0))
+		Expect(threads.Items).NotTo(BeEmpty())
+	})
+
+})
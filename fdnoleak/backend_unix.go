@@ -0,0 +1,81 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package fdnoleak
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// fdDir is the directory listing the open file descriptors of the current
+// process; on Linux, this is a symbolic link to /proc/self/fd.
+const fdDir = "/dev/fd"
+
+func init() {
+	Register(backendFunc{kind: FileDescriptors, name: "file descriptors", enumerate: fileDescriptors})
+	Register(backendFunc{kind: Sockets, name: "sockets", enumerate: sockets})
+}
+
+// fileDescriptors lists the open file descriptors of the current process,
+// leaving out the file descriptor used for listing.
+func fileDescriptors() (Resources, error) {
+	dir, err := os.Open(fdDir)
+	if err != nil {
+		return Resources{}, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return Resources{}, err
+	}
+	self := int(dir.Fd())
+	items := make([]Item, 0, len(names))
+	for _, name := range names {
+		fd, err := strconv.Atoi(name)
+		if err != nil || fd == self {
+			continue
+		}
+		// Not all platforms support reading the targets of file descriptors,
+		// so ignore any errors.
+		target, _ := os.Readlink(filepath.Join(fdDir, name))
+		items = append(items, Item{ID: fd, Description: target})
+	}
+	return Resources{Items: items}, nil
+}
+
+// sockets lists the open file descriptors of the current process that refer
+// to sockets.
+func sockets() (Resources, error) {
+	fds, err := fileDescriptors()
+	if err != nil {
+		return Resources{}, err
+	}
+	items := []Item{}
+	for _, item := range fds.Items {
+		var stat syscall.Stat_t
+		// The file descriptor might have been closed in the meantime, so
+		// skip it.
+		if syscall.Fstat(item.ID, &stat) != nil ||
+			uint32(stat.Mode)&syscall.S_IFMT != syscall.S_IFSOCK {
+			continue
+		}
+		items = append(items, item)
+	}
+	return Resources{Items: items}, nil
+}
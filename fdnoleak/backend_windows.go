@@ -0,0 +1,114 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build windows
+
+package fdnoleak
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procGetProcessHandleCount = syscall.NewLazyDLL("kernel32.dll").NewProc("GetProcessHandleCount")
+
+func init() {
+	Register(backendFunc{kind: FileDescriptors, name: "handles", enumerate: handles})
+	Register(backendFunc{kind: Threads, name: "threads", enumerate: threads})
+	Register(backendFunc{kind: Processes, name: "child processes", enumerate: childProcesses})
+}
+
+// handles counts the open handles of the current process. Windows doesn't
+// support listing the individual handles without resorting to undocumented
+// NtQuerySystemInformation details, so only the count is returned.
+//
+// The Go runtime keeps a handle to each OS thread it creates, and these
+// threads never exit. Thus, handles doesn't count one handle per thread of the
+// process, as otherwise every new thread would show up as a leaked handle.
+func handles() (Resources, error) {
+	threads, err := threads()
+	if err != nil {
+		return Resources{}, err
+	}
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return Resources{}, err
+	}
+	var count uint32
+	ok, _, err := procGetProcessHandleCount.Call(uintptr(process), uintptr(unsafe.Pointer(&count)))
+	if ok == 0 {
+		return Resources{}, err
+	}
+	if n := int(count) - threads.Count; n > 0 {
+		return Resources{Count: n}, nil
+	}
+	return Resources{}, nil
+}
+
+// threads counts the threads of the current process.
+func threads() (Resources, error) {
+	entries, err := processEntries()
+	if err != nil {
+		return Resources{}, err
+	}
+	self := uint32(os.Getpid())
+	for _, entry := range entries {
+		if entry.ProcessID == self {
+			return Resources{Count: int(entry.Threads)}, nil
+		}
+	}
+	return Resources{}, errors.New("current process not found")
+}
+
+// childProcesses lists the direct child processes of the current process
+// together with their executable names.
+func childProcesses() (Resources, error) {
+	entries, err := processEntries()
+	if err != nil {
+		return Resources{}, err
+	}
+	self := uint32(os.Getpid())
+	items := []Item{}
+	for _, entry := range entries {
+		if entry.ParentProcessID != self {
+			continue
+		}
+		items = append(items, Item{
+			ID:          int(entry.ProcessID),
+			Description: syscall.UTF16ToString(entry.ExeFile[:]),
+		})
+	}
+	return Resources{Items: items}, nil
+}
+
+// processEntries returns the entries of a fresh process snapshot.
+func processEntries() ([]syscall.ProcessEntry32, error) {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(snapshot)
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	var entries []syscall.ProcessEntry32
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		entries = append(entries, entry)
+	}
+	if !errors.Is(err, syscall.ERROR_NO_MORE_FILES) {
+		return nil, err
+	}
+	return entries, nil
+}
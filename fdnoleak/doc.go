@@ -0,0 +1,43 @@
+/*
+
+Package fdnoleak checks for leaked file descriptors, or handles on Windows, in
+the same way as package noleak checks for leaked goroutines: take a snapshot of
+the open file descriptors before a test, and afterwards check that their number
+hasn't grown beyond the snapshot.
+
+    fds, err := fdnoleak.Current()
+    Expect(err).NotTo(HaveOccurred())
+    DoSomething()
+    Eventually(fdnoleak.Current).ShouldNot(fdnoleak.HaveLeakedFds(fds))
+
+On Linux and other Unix systems, the snapshots additionally list the individual
+file descriptors, so that failure messages can tell the leaked file
+descriptors and what they refer to. On Windows, the snapshots only count the
+open handles of the process, using GetProcessHandleCount, as enumerating the
+individual handles would need undocumented NtQuerySystemInformation details.
+HaveLeakedFds thus compares the numbers of file descriptors or handles on all
+platforms, so that cross-platform projects get the same semantics. As the Go
+runtime keeps a handle to each of its OS threads, which never exit, the handle
+counts on Windows don't include one handle per thread of the process, so that
+new threads don't show up as leaked handles.
+
+Backends
+
+Platform-specific backends implementing the Backend interface take the
+snapshots of the resources of a particular Kind, such as FileDescriptors.
+CurrentOf takes snapshots of any kind of resources with a registered backend,
+which HaveLeakedResources then checks for leaks. Besides file descriptors, the
+backends support Sockets, Threads, and child Processes, depending on the
+platform:
+
+  - Linux: file descriptors, sockets, threads, child processes.
+  - macOS: file descriptors, sockets, threads (count only), child processes.
+  - other Unix systems: file descriptors, sockets.
+  - Windows: handles, threads (count only), child processes.
+
+Kinds returns the kinds of resources supported on the current platform.
+Register adds backends for further kinds of resources or platforms, without
+needing to touch the matchers.
+
+*/
+package fdnoleak
@@ -0,0 +1,88 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fdnoleak
+
+import (
+	"fmt"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// HaveLeakedFds succeeds (or rather, "suckceeds") if the actual Resources, as
+// returned by Current, count more open file descriptors, or handles on
+// Windows, than the specified baseline. It is best paired with Eventually,
+// giving file descriptors closed in the background some time:
+//
+//   Eventually(fdnoleak.Current).ShouldNot(fdnoleak.HaveLeakedFds(fds))
+//
+// Where the platform supports listing the individual file descriptors, the
+// failure message lists the file descriptors not in the baseline.
+func HaveLeakedFds(baseline Resources) types.GomegaMatcher {
+	return HaveLeakedResources(baseline)
+}
+
+// HaveLeakedResources succeeds if the actual Resources, as returned by
+// CurrentOf, count more resources than the specified baseline of the same
+// kind. For instance:
+//
+//   fds, _ := fdnoleak.CurrentOf(fdnoleak.FileDescriptors)
+//   Eventually(func() (fdnoleak.Resources, error) {
+//       return fdnoleak.CurrentOf(fdnoleak.FileDescriptors)
+//   }).ShouldNot(fdnoleak.HaveLeakedResources(fds))
+func HaveLeakedResources(baseline Resources) types.GomegaMatcher {
+	return &haveLeakedResourcesMatcher{baseline: baseline}
+}
+
+type haveLeakedResourcesMatcher struct {
+	baseline Resources
+	actual   Resources
+}
+
+// Match succeeds if actual is a Resources value with more resources than the
+// baseline.
+func (matcher *haveLeakedResourcesMatcher) Match(actual interface{}) (bool, error) {
+	switch resources := actual.(type) {
+	case Resources:
+		matcher.actual = resources
+	case *Resources:
+		if resources == nil {
+			return false, fmt.Errorf("HaveLeakedResources matcher expects Resources, but got nil")
+		}
+		matcher.actual = *resources
+	default:
+		return false, fmt.Errorf("HaveLeakedResources matcher expects Resources.  Got:\n%s",
+			format.Object(actual, 1))
+	}
+	if matcher.actual.Kind != matcher.baseline.Kind {
+		return false, fmt.Errorf("HaveLeakedResources matcher expects %s, but got %s",
+			matcher.baseline.name(), matcher.actual.name())
+	}
+	return matcher.actual.Count > matcher.baseline.Count, nil
+}
+
+// FailureMessage returns a failure message if no resources leaked.
+func (matcher *haveLeakedResourcesMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected to leak %s, but %d now, %d in baseline",
+		matcher.baseline.name(), matcher.actual.Count, matcher.baseline.Count)
+}
+
+// NegatedFailureMessage returns a failure message if resources leaked.
+func (matcher *haveLeakedResourcesMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected not to leak %d %s (%d now, %d in baseline)%s",
+		matcher.actual.Count-matcher.baseline.Count, matcher.baseline.name(),
+		matcher.actual.Count, matcher.baseline.Count,
+		list(matcher.actual.appeared(matcher.baseline)))
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fdnoleak
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HaveLeakedResources matcher", func() {
+
+	const widgets = Kind("widgets")
+
+	It("rejects invalid actual values", func() {
+		m := HaveLeakedFds(Resources{Kind: FileDescriptors})
+		Expect(m.Match(nil)).Error().To(MatchError(ContainSubstring("expects Resources")))
+		Expect(m.Match((*Resources)(nil))).Error().To(MatchError(ContainSubstring("got nil")))
+		Expect(m.Match(42)).Error().To(MatchError(ContainSubstring("expects Resources")))
+		Expect(m.Match(Resources{Kind: widgets})).Error().To(MatchError(
+			"HaveLeakedResources matcher expects file descriptors, but got widgets"))
+	})
+
+	It("compares counts", func() {
+		baseline := Resources{Kind: widgets, Count: 2}
+		Expect(Resources{Kind: widgets, Count: 2}).NotTo(HaveLeakedResources(baseline))
+		Expect(&Resources{Kind: widgets, Count: 1}).NotTo(HaveLeakedResources(baseline))
+		Expect(Resources{Kind: widgets, Count: 3}).To(HaveLeakedResources(baseline))
+	})
+
+	It("lists leaked resources", func() {
+		baseline := Resources{Kind: FileDescriptors, Name: "handles", Count: 1,
+			Items: []Item{{ID: 0, Description: "/dev/null"}}}
+		actual := Resources{Kind: FileDescriptors, Name: "handles", Count: 3, Items: []Item{
+			{ID: 0, Description: "/dev/null"},
+			{ID: 3, Description: "/tmp/foo"},
+			{ID: 4},
+		}}
+		m := HaveLeakedFds(baseline)
+		Expect(m.Match(actual)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(actual)).To(Equal(
+			"Expected not to leak 2 handles (3 now, 1 in baseline)" +
+				"\n    3 → /tmp/foo\n    4"))
+		Expect(m.FailureMessage(actual)).To(Equal(
+			"Expected to leak handles, but 3 now, 1 in baseline"))
+	})
+
+	It("detects leaked file descriptors of this process", func() {
+		fds, err := Current()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fds.Count).To(BeNumerically(">", 0))
+
+		f, err := os.CreateTemp("", "fdnoleak-*")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(f.Name())
+
+		m := HaveLeakedFds(fds)
+		leaked, err := Current()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Match(leaked)).To(BeTrue())
+		if leaked.Items != nil {
+			Expect(m.NegatedFailureMessage(leaked)).To(ContainSubstring(f.Name()))
+		}
+
+		Expect(f.Close()).To(Succeed())
+		Eventually(Current).ShouldNot(HaveLeakedFds(fds))
+	})
+
+})
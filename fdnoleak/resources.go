@@ -0,0 +1,123 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fdnoleak
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind identifies a kind of operating system resource of the current process.
+type Kind string
+
+// The kinds of resources known to this package; which of them are actually
+// available depends on the backends registered for the platform.
+const (
+	FileDescriptors Kind = "file descriptors" // open file descriptors, or handles on Windows.
+	Sockets         Kind = "sockets"          // open sockets.
+	Threads         Kind = "threads"          // OS threads.
+	Processes       Kind = "child processes"  // direct child processes.
+)
+
+// Resources describes the resources of a particular kind of the current
+// process at a particular moment.
+type Resources struct {
+	Kind  Kind   // kind of resources.
+	Name  string // how to name the resources in messages, such as "handles".
+	Count int    // number of resources.
+	Items []Item // individual resources, if the platform backend supports listing them.
+}
+
+// Item describes an individual resource, such as an open file descriptor.
+type Item struct {
+	ID          int    // file descriptor number, thread ID, process ID, et cetera.
+	Description string // what the resource refers to, such as a file path; might be empty.
+}
+
+// String returns the resource ID together with its description, if known.
+func (item Item) String() string {
+	if item.Description == "" {
+		return strconv.Itoa(item.ID)
+	}
+	return strconv.Itoa(item.ID) + " → " + item.Description
+}
+
+// Current returns information about the currently open file descriptors, or
+// handles on Windows, of this process. Current returns an error if the
+// platform isn't supported or counting fails.
+func Current() (Resources, error) {
+	return CurrentOf(FileDescriptors)
+}
+
+// CurrentOf returns information about the current resources of the specified
+// kind, using the backend registered for this kind. CurrentOf returns an error
+// if there is no such backend or the backend fails.
+func CurrentOf(kind Kind) (Resources, error) {
+	backend, ok := lookup(kind)
+	if !ok {
+		return Resources{}, fmt.Errorf("cannot determine %s: not supported on %s",
+			kind, runtime.GOOS)
+	}
+	resources, err := backend.Enumerate()
+	if err != nil {
+		return Resources{}, fmt.Errorf("cannot determine %s: %w", backend.Name(), err)
+	}
+	resources.Kind = kind
+	resources.Name = backend.Name()
+	if resources.Items != nil {
+		resources.Count = len(resources.Items)
+		sort.Slice(resources.Items, func(i, j int) bool {
+			return resources.Items[i].ID < resources.Items[j].ID
+		})
+	}
+	return resources, nil
+}
+
+// appeared returns the resources in r that are not in baseline, as far as both
+// list their individual resources.
+func (r Resources) appeared(baseline Resources) []Item {
+	known := make(map[Item]struct{}, len(baseline.Items))
+	for _, item := range baseline.Items {
+		known[item] = struct{}{}
+	}
+	var fresh []Item
+	for _, item := range r.Items {
+		if _, ok := known[item]; !ok {
+			fresh = append(fresh, item)
+		}
+	}
+	return fresh
+}
+
+// name returns how to name the resources in messages.
+func (r Resources) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return string(r.Kind)
+}
+
+// list returns the specified resources, one per indented line.
+func list(items []Item) string {
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString("\n    ")
+		b.WriteString(item.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onsi/gomega/types"
+)
+
+// Enabled controls whether HaveLeaked checks for leaked goroutines at all.
+// When false, HaveLeaked never finds any leaked goroutines. Enabled can also be
+// set using the "-noleak.enable" go test flag.
+var Enabled = true
+
+// SettleTimeout is the default duration noleak's own Eventually-based helpers,
+// such as EnsureGone and testingnoleak.CheckFuzz, give goroutines to settle
+// before reporting leaks. Zero means to use Gomega's default Eventually
+// timeout. HaveLeaked also gives goroutines SettleTimeout to settle when passed
+// LazyGoroutines, and Check when not passed its own timeout. SettleTimeout can
+// also be set using the "-noleak.settle" go test flag.
+var SettleTimeout time.Duration
+
+// ReportDir specifies an optional directory where HaveLeaked writes a
+// Markdown leak report (see report.Markdown) for each failed assertion, such
+// as for archiving as CI job artifacts. ReportDir can also be set using the
+// "-noleak.report-dir" go test flag.
+var ReportDir = ""
+
+// ignoredTopFunctions lists additional filter matchers for the top functions
+// specified using the "-noleak.ignore" go test flag, which HaveLeaked applies
+// in addition to its standard filters.
+var ignoredTopFunctions []types.GomegaMatcher
+
+// RegisterFlags registers noleak's go test flags with the specified flag set,
+// or with flag.CommandLine if nil, so that leak checking can be configured per
+// CI job without touching test code. As importing noleak never registers any
+// flags by itself, test packages either call RegisterFlags from an init
+// function in one of their _test.go files, or use VerifyTestMain which
+// registers the flags on flag.CommandLine. Registering the flags more than once
+// with the same flag set is a no-op.
+//
+//   func init() {
+//       noleak.RegisterFlags(nil)
+//   }
+//
+// The go test flags are:
+//
+//   -noleak.enable=false          disables leak checking
+//   -noleak.settle=5s             sets SettleTimeout
+//   -noleak.capture-timeout=10s   sets CaptureTimeout
+//   -noleak.ignore=foo.bar,baz... ignores goroutines with these top functions
+//   -noleak.report-dir=DIR        sets ReportDir
+//   -noleak.summary=FILE          sets SummaryFile
+//   -noleak.dedup                 sets DeduplicateReports
+//   -noleak.presets=testify,...   ignores goroutines of these test frameworks
+//   -noleak.template=FILE         sets MessageTemplate from this file
+func RegisterFlags(fs *flag.FlagSet) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	if fs.Lookup("noleak.enable") != nil {
+		return
+	}
+	fs.BoolVar(&Enabled, "noleak.enable", Enabled,
+		"enable checking for leaked goroutines")
+	fs.DurationVar(&SettleTimeout, "noleak.settle", SettleTimeout,
+		"duration given to goroutines to settle before reporting leaks")
+	fs.DurationVar(&CaptureTimeout, "noleak.capture-timeout", CaptureTimeout,
+		"maximum duration to wait for capturing goroutines")
+	fs.Func("noleak.ignore",
+		"comma-separated list of top functions of goroutines to ignore, in IgnoringTopFunction syntax (can be repeated)",
+		func(s string) error {
+			for _, topfn := range strings.Split(s, ",") {
+				if topfn = strings.TrimSpace(topfn); topfn != "" {
+					ignoredTopFunctions = append(ignoredTopFunctions, IgnoringTopFunction(topfn))
+				}
+			}
+			return nil
+		})
+	fs.StringVar(&ReportDir, "noleak.report-dir", ReportDir,
+		"directory to write Markdown leak reports to")
+	fs.StringVar(&SummaryFile, "noleak.summary", SummaryFile,
+		"file to write a JSON leak summary to at exit of VerifyTestMain")
+	fs.Func("noleak.presets",
+		"comma-separated list of test framework presets to ignore: "+strings.Join(Presets(), ", ")+" (can be repeated)",
+		func(s string) error {
+			for _, name := range strings.Split(s, ",") {
+				if name = strings.TrimSpace(name); name == "" {
+					continue
+				}
+				if _, ok := presets[name]; !ok {
+					return fmt.Errorf("unknown preset %q", name)
+				}
+				ignoredTopFunctions = append(ignoredTopFunctions, IgnoringPreset(name))
+			}
+			return nil
+		})
+	fs.BoolVar(&DeduplicateReports, "noleak.dedup", DeduplicateReports,
+		"condense repeated reports of the same leaked goroutines")
+	fs.Func("noleak.template",
+		"file with a text/template for rendering failure messages",
+		func(s string) error {
+			tmpl, err := loadMessageTemplate(s)
+			if err != nil {
+				return err
+			}
+			MessageTemplate = tmpl
+			return nil
+		})
+}
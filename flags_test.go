@@ -0,0 +1,135 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("go test flags", func() {
+
+	BeforeEach(func() {
+		oldEnabled, oldSettle, oldDir, oldIgnored, oldDedup := Enabled, SettleTimeout, ReportDir, ignoredTopFunctions, DeduplicateReports
+		oldSummaryFile := SummaryFile
+		oldTemplate := MessageTemplate
+		oldCaptureTimeout := CaptureTimeout
+		DeferCleanup(func() {
+			CaptureTimeout = oldCaptureTimeout
+			Enabled, SettleTimeout, ReportDir, ignoredTopFunctions, DeduplicateReports = oldEnabled, oldSettle, oldDir, oldIgnored, oldDedup
+			MessageTemplate = oldTemplate
+			SummaryFile = oldSummaryFile
+		})
+	})
+
+	It("registers flags only on request", func() {
+		Expect(flag.CommandLine.Lookup("noleak.enable")).To(BeNil())
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		RegisterFlags(fs)
+		Expect(func() { RegisterFlags(fs) }).NotTo(Panic())
+		Expect(fs.Lookup("noleak.enable")).NotTo(BeNil())
+	})
+
+	It("configures the defaults", func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		RegisterFlags(fs)
+		Expect(fs.Parse([]string{
+			"-noleak.enable=false",
+			"-noleak.settle=42s",
+			"-noleak.capture-timeout=666ms",
+			"-noleak.ignore=foo.bar, foo.baz...",
+			"-noleak.ignore=foo.foo [chan receive]",
+			"-noleak.report-dir=/tmp/noleak",
+			"-noleak.summary=/tmp/noleak/leaks.json",
+			"-noleak.dedup",
+			"-noleak.presets=testify, gocheck",
+		})).To(Succeed())
+		Expect(Enabled).To(BeFalse())
+		Expect(SettleTimeout).To(Equal(42 * time.Second))
+		Expect(CaptureTimeout).To(Equal(666 * time.Millisecond))
+		Expect(ignoredTopFunctions).To(HaveLen(5))
+		Expect(ReportDir).To(Equal("/tmp/noleak"))
+		Expect(SummaryFile).To(Equal("/tmp/noleak/leaks.json"))
+		Expect(DeduplicateReports).To(BeTrue())
+	})
+
+	It("rejects unknown presets", func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		RegisterFlags(fs)
+		Expect(fs.Parse([]string{"-noleak.presets=foo"})).To(MatchError(ContainSubstring(`unknown preset "foo"`)))
+	})
+
+	It("loads message templates", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "message.tmpl")
+		Expect(os.WriteFile(path, []byte("{{ .Count }} leaks"), 0o644)).To(Succeed())
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		RegisterFlags(fs)
+		Expect(fs.Parse([]string{"-noleak.template=" + path})).To(Succeed())
+		Expect(MessageTemplate).NotTo(BeNil())
+
+		Expect(fs.Parse([]string{"-noleak.template=" + path + ".missing"})).To(
+			MatchError(ContainSubstring("cannot read message template")))
+		Expect(os.WriteFile(path, []byte("{{ .Count "), 0o644)).To(Succeed())
+		Expect(fs.Parse([]string{"-noleak.template=" + path})).To(
+			MatchError(ContainSubstring("invalid message template")))
+	})
+
+	It("disables leak checking", func() {
+		Enabled = false
+		Expect(HaveLeaked().Match([]goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.bar"}})).To(BeFalse())
+	})
+
+	It("ignores additional top functions", func() {
+		ignoredTopFunctions = nil
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		RegisterFlags(fs)
+		Expect(fs.Parse([]string{"-noleak.ignore=foo.bar"})).To(Succeed())
+		m := HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.bar"}})).To(BeFalse())
+		Expect(m.Match([]goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.baz"}})).To(BeTrue())
+		Expect(standardFilters).NotTo(ContainElement(BeIdenticalTo(ignoredTopFunctions[0])))
+	})
+
+	It("writes leak reports", func() {
+		ReportDir = filepath.Join(GinkgoT().TempDir(), "reports")
+		m := HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.bar"}})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("cannot write leak report"))
+		reports, err := filepath.Glob(filepath.Join(ReportDir, "noleak-*.md"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(1))
+		Expect(os.ReadFile(reports[0])).To(ContainSubstring("goroutine 4611686018427387904"))
+
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("cannot write leak report"))
+		reports, err = filepath.Glob(filepath.Join(ReportDir, "noleak-*.md"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(1), "wrote the same report twice")
+
+		ReportDir = "/dev/null/reports"
+		m = HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.bar"}})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring("cannot write leak report"))
+	})
+
+})
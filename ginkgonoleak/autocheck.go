@@ -0,0 +1,108 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// AllowLeaksLabel is the Ginkgo label that exempts specs from the leak checks
+// installed by CheckEachSpec. The label also applies to all specs inside
+// labelled containers.
+var AllowLeaksLabel = "allow-leaks"
+
+// ReportLeaksLabel is the Ginkgo label that softens the leak checks installed
+// by CheckEachSpec into reporting leaks only, without failing specs. The label
+// also applies to all specs inside labelled containers.
+var ReportLeaksLabel = "report-leaks"
+
+// checkMode tells how to check a spec for leaked goroutines.
+type checkMode int
+
+const (
+	enforce    checkMode = iota // fail specs leaking goroutines.
+	reportOnly                  // only report leaked goroutines.
+	skip                        // don't check for leaked goroutines at all.
+)
+
+// CheckEachSpec installs a leak check for each spec, failing specs that leak
+// goroutines. Each spec gets a snapshot of the goroutines taken just before it
+// runs, so that only goroutines started by the spec count as leaks. Further
+// goroutines to be ignored are specified in the same way as with HaveLeaked.
+// CheckEachSpec is typically called at the top level of a suite:
+//
+//   var _ = ginkgonoleak.CheckEachSpec()
+//
+// Known-leaky specs and containers can be grandfathered explicitly using
+// labels: specs labelled with AllowLeaksLabel aren't checked at all, while
+// specs labelled with ReportLeaksLabel only get their leaks reported as report
+// entries, but don't fail.
+//
+//   It("is legacy", Label(ginkgonoleak.ReportLeaksLabel), func() { ... })
+//
+// The leak checks give goroutines noleak.SettleTimeout to wind down.
+func CheckEachSpec(ignoring ...interface{}) bool {
+	return ginkgo.BeforeEach(func() {
+		mode := checkModeOf(ginkgo.CurrentSpecReport().Labels())
+		if mode == skip {
+			return
+		}
+		snapshot := noleak.TakeSnapshot()
+		ignoring := append(append([]interface{}{}, ignoring...), snapshot)
+		ginkgo.DeferCleanup(func() {
+			if mode == reportOnly {
+				reportLeaks(ignoring)
+				return
+			}
+			eventually := gomega.Eventually(noleak.Goroutines)
+			if noleak.SettleTimeout > 0 {
+				eventually = eventually.WithTimeout(noleak.SettleTimeout)
+			}
+			eventually.ShouldNot(noleak.HaveLeaked(ignoring...))
+		})
+	}, ginkgo.Offset(1))
+}
+
+// reportLeaks checks for leaked goroutines, reporting them as a report entry
+// instead of failing the current spec. As the check notifies the leak hooks,
+// the Hook additionally adds the structured leak findings, if installed.
+func reportLeaks(ignoring []interface{}) {
+	report, err := noleak.Check(nil, noleak.CheckOptions{Ignoring: ignoring})
+	if err == nil {
+		return
+	}
+	if len(report.Leaked) == 0 {
+		ginkgo.AddReportEntry("noleak: leak check failed", err.Error(), types.ReportEntryVisibilityAlways)
+		return
+	}
+	ginkgo.AddReportEntry("noleak: tolerated leaks", report.Message, types.ReportEntryVisibilityAlways)
+}
+
+// checkModeOf returns the check mode for a spec with the specified labels.
+func checkModeOf(labels []string) checkMode {
+	mode := enforce
+	for _, label := range labels {
+		switch label {
+		case AllowLeaksLabel:
+			return skip
+		case ReportLeaksLabel:
+			mode = reportOnly
+		}
+	}
+	return mode
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+var _ = Describe("per-spec leak checks", Ordered, func() {
+
+	BeforeEach(func() {
+		old := noleak.SettleTimeout
+		noleak.SettleTimeout = 50 * time.Millisecond
+		DeferCleanup(func() { noleak.SettleTimeout = old })
+	})
+
+	_ = CheckEachSpec()
+
+	// leak starts a goroutine that outlives the spec's leak check, but ends
+	// shortly afterwards.
+	var leaks sync.WaitGroup
+	leak := func() {
+		leaks.Add(1)
+		go func() {
+			defer leaks.Done()
+			time.Sleep(250 * time.Millisecond)
+		}()
+	}
+
+	It("passes leak-free specs", func() {
+		done := make(chan struct{})
+		go func() { <-done }()
+		DeferCleanup(func() { close(done) })
+	})
+
+	When("allowing leaks", Label(AllowLeaksLabel), func() {
+
+		It("doesn't check", func() {
+			leak()
+		})
+
+	})
+
+	It("reports leaks only", Label(ReportLeaksLabel), func() {
+		leak()
+	})
+
+	ReportAfterEach(func(report SpecReport) {
+		switch report.LeafNodeText {
+		case "reports leaks only":
+			Expect(report.ReportEntries).To(ContainElement(HaveField("Name", "noleak: tolerated leaks")))
+		default:
+			Expect(report.ReportEntries).To(BeEmpty())
+		}
+		// let the leaked goroutine end before the next spec.
+		leaks.Wait()
+	})
+
+	It("determines check modes", func() {
+		Expect(checkModeOf(nil)).To(Equal(enforce))
+		Expect(checkModeOf([]string{"foo", ReportLeaksLabel})).To(Equal(reportOnly))
+		Expect(checkModeOf([]string{ReportLeaksLabel, AllowLeaksLabel})).To(Equal(skip))
+	})
+
+})
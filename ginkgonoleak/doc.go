@@ -0,0 +1,74 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package ginkgonoleak integrates leak checking with Ginkgo. It embeds structured
+leak findings into Ginkgo's JSON reports, so that downstream report processors
+don't need to parse the console output of failed leak checks.
+JSONReportAfterSuite writes a Ginkgo JSON report where each spec that leaked
+goroutines carries a report entry named "noleak", listing the leaked
+goroutines:
+
+    var _ = ginkgonoleak.JSONReportAfterSuite("leaks.json")
+
+Report processors then retrieve the leak findings per spec using SpecLeaks,
+or by decoding the "noleak" report entries themselves.
+
+Guard protects a single spec against leaking goroutines:
+
+    It("doesn't leak", func() {
+        ginkgonoleak.Guard()
+        ...
+    })
+
+RunLeakCheckedSpecs replaces RunSpecs in order to check each spec of a suite
+as well as the suite as a whole for leaked goroutines:
+
+    func TestSuite(t *testing.T) {
+        ginkgonoleak.RunLeakCheckedSpecs(t, "my suite")
+    }
+
+CheckEachSpec checks each spec for leaked goroutines. Known-leaky legacy specs
+can be grandfathered explicitly by labelling them (or their containers) with
+AllowLeaksLabel to skip leak checking, or with ReportLeaksLabel to only report
+leaks without failing:
+
+    var _ = ginkgonoleak.CheckEachSpec()
+
+    var _ = Describe("legacy", Label(ginkgonoleak.ReportLeaksLabel), func() {
+        ...
+    })
+
+In huge suites, WarnEachSpec instead only warns about leaked goroutines after
+each spec, while VerifyWarnings in AfterSuite fails hard if goroutines with the
+same fingerprints are still present at the end of the suite:
+
+    var _ = ginkgonoleak.WarnEachSpec()
+
+    var _ = AfterSuite(func() {
+        ginkgonoleak.VerifyWarnings()
+    })
+
+RecordSpecMetrics records how the number of goroutines developed while each
+spec ran, including the sampled peak number and the number of new goroutines
+ignored by the leak filters, in order to spot specs that almost leak before
+they become flaky. The metrics end up in report entries, which
+MetricsCSVAfterSuite additionally writes to a CSV file:
+
+    var _ = ginkgonoleak.RecordSpecMetrics()
+    var _ = ginkgonoleak.MetricsCSVAfterSuite("metrics.csv")
+
+*/
+package ginkgonoleak
@@ -0,0 +1,156 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/reporters"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// EntryName is the name of the report entries carrying leak findings.
+const EntryName = "noleak"
+
+// Finding describes a single leaked goroutine in a report entry.
+type Finding struct {
+	ID              uint64 `json:"id"`
+	State           string `json:"state"`
+	TopFunction     string `json:"topFunction"`
+	CreatorFunction string `json:"creatorFunction,omitempty"`
+	BornAt          string `json:"bornAt,omitempty"`
+	Fingerprint     string `json:"fingerprint"`
+}
+
+// Findings are the leak findings of a single failed leak check.
+type Findings struct {
+	Leaked []Finding `json:"leaked"`
+}
+
+// String returns a short description of these findings for Ginkgo's console
+// output.
+func (f Findings) String() string {
+	if len(f.Leaked) == 1 {
+		return "1 leaked goroutine"
+	}
+	return fmt.Sprintf("%d leaked goroutines", len(f.Leaked))
+}
+
+// NewFindings returns the findings for the specified leaked goroutines.
+func NewFindings(leaked []goroutine.Goroutine) Findings {
+	findings := Findings{Leaked: make([]Finding, 0, len(leaked))}
+	for _, g := range leaked {
+		findings.Leaked = append(findings.Leaked, Finding{
+			ID:              g.ID,
+			State:           g.State,
+			TopFunction:     g.TopFunction,
+			CreatorFunction: g.CreatorFunction,
+			BornAt:          g.BornAt,
+			Fingerprint:     g.Fingerprint(),
+		})
+	}
+	return findings
+}
+
+// AddLeakEntry adds a report entry with the findings for the specified leaked
+// goroutines to the report of the current spec. As the leaked goroutines are
+// already part of the failure message, the report entry isn't shown on the
+// console. AddLeakEntry must be called while a spec is running.
+func AddLeakEntry(leaked []goroutine.Goroutine) {
+	ginkgo.AddReportEntry(EntryName, NewFindings(leaked), types.ReportEntryVisibilityNever, ginkgo.Offset(1))
+}
+
+// Hook returns a leak hook that adds the findings of failed leak checks to the
+// reports of the specs in which the leak checks failed. For instance:
+//
+//   BeforeEach(func() {
+//       DeferCleanup(noleak.AddLeakHook(ginkgonoleak.Hook()))
+//   })
+//
+// The hook must only be registered while specs are running, see also
+// JSONReportAfterSuite. As leaks detected by a Monitor in the background cannot
+// be attributed to the currently running spec, the hook ignores them.
+func Hook() noleak.LeakHook {
+	return func(e noleak.LeakEvent) {
+		if e.Monitor {
+			return
+		}
+		ginkgo.AddReportEntry(EntryName, NewFindings(e.Leaked), types.ReportEntryVisibilityNever)
+	}
+}
+
+var installHook sync.Once
+
+// JSONReportAfterSuite adds a ReportAfterSuite node writing a Ginkgo JSON
+// report to the specified destination, where the specs that failed leak checks
+// carry report entries with their leak findings. JSONReportAfterSuite must be
+// called at the top level of a suite:
+//
+//   var _ = ginkgonoleak.JSONReportAfterSuite("leaks.json")
+//
+// JSONReportAfterSuite registers the leak hook returned by Hook for each spec
+// of the suite, using a top-level BeforeEach node.
+func JSONReportAfterSuite(destination string) bool {
+	installHook.Do(func() {
+		ginkgo.BeforeEach(func() {
+			ginkgo.DeferCleanup(noleak.AddLeakHook(Hook()))
+		})
+	})
+	return ginkgo.ReportAfterSuite("noleak JSON report", func(report ginkgo.Report) {
+		if err := reporters.GenerateJSONReport(report, destination); err != nil {
+			ginkgo.Fail(fmt.Sprintf("cannot write noleak JSON report: %s", err.Error()))
+		}
+	})
+}
+
+// SpecLeaks returns the leak findings of the specs in the specified report,
+// indexed by the full texts of the specs. Specs without any leak findings are
+// not included. SpecLeaks works with reports passed to ReportAfterSuite nodes
+// as well as with reports decoded from Ginkgo JSON reports.
+func SpecLeaks(report types.Report) (map[string][]Findings, error) {
+	leaks := map[string][]Findings{}
+	for _, spec := range report.SpecReports {
+		for _, entry := range spec.ReportEntries {
+			if entry.Name != EntryName {
+				continue
+			}
+			findings, err := decodeFindings(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid leak findings of spec %q: %w", spec.FullText(), err)
+			}
+			leaks[spec.FullText()] = append(leaks[spec.FullText()], findings)
+		}
+	}
+	return leaks, nil
+}
+
+// decodeFindings returns the findings of the specified report entry, which
+// either still holds the original findings value or only its JSON encoding
+// when decoded from a JSON report.
+func decodeFindings(entry types.ReportEntry) (Findings, error) {
+	if findings, ok := entry.Value.GetRawValue().(Findings); ok {
+		return findings, nil
+	}
+	var findings Findings
+	if err := json.Unmarshal([]byte(entry.Value.AsJSON), &findings); err != nil {
+		return Findings{}, err
+	}
+	return findings, nil
+}
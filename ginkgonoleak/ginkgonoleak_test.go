@@ -0,0 +1,95 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/reporters"
+	"github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("Ginkgo JSON reports", func() {
+
+	leaked := []goroutine.Goroutine{{
+		ID:              1 << 62,
+		State:           "chan receive",
+		TopFunction:     "foo.bar",
+		CreatorFunction: "foo.baz",
+		BornAt:          "/foo.go:42",
+	}}
+
+	It("adds leak findings to the current spec report", func() {
+		AddLeakEntry(leaked)
+		entries := CurrentSpecReport().ReportEntries
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name).To(Equal(EntryName))
+		Expect(entries[0].Visibility).To(Equal(types.ReportEntryVisibilityNever))
+		Expect(entries[0].GetRawValue()).To(Equal(Findings{Leaked: []Finding{{
+			ID:              1 << 62,
+			State:           "chan receive",
+			TopFunction:     "foo.bar",
+			CreatorFunction: "foo.baz",
+			BornAt:          "/foo.go:42",
+			Fingerprint:     leaked[0].Fingerprint(),
+		}}}))
+		Expect(entries[0].StringRepresentation()).To(Equal("1 leaked goroutine"))
+	})
+
+	It("adds leak findings of failed leak checks", func() {
+		DeferCleanup(noleak.AddLeakHook(Hook()))
+		m := noleak.HaveLeaked()
+		Expect(m.Match(leaked)).To(BeTrue())
+		_ = m.NegatedFailureMessage(leaked)
+		Expect(CurrentSpecReport().ReportEntries).To(ConsistOf(HaveField("Name", EntryName)))
+	})
+
+	It("ignores leaks detected by monitors", func() {
+		Hook()(noleak.LeakEvent{Leaked: leaked, Monitor: true})
+		Expect(CurrentSpecReport().ReportEntries).To(BeEmpty())
+	})
+
+	It("retrieves leak findings from JSON reports", func() {
+		AddLeakEntry(leaked)
+		report := types.Report{SpecReports: types.SpecReports{
+			CurrentSpecReport(),
+			{LeafNodeText: "doesn't leak"},
+		}}
+		leaks, err := SpecLeaks(report)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaks).To(HaveKeyWithValue(CurrentSpecReport().FullText(),
+			ConsistOf(HaveField("Leaked", ConsistOf(HaveField("ID", uint64(1<<62)))))))
+		Expect(leaks).To(HaveLen(1))
+
+		path := filepath.Join(GinkgoT().TempDir(), "report.json")
+		Expect(reporters.GenerateJSONReport(report, path)).To(Succeed())
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		var reports []types.Report
+		Expect(json.Unmarshal(data, &reports)).To(Succeed())
+		Expect(reports).To(HaveLen(1))
+		Expect(SpecLeaks(reports[0])).To(Equal(leaks))
+
+		reports[0].SpecReports[0].ReportEntries[0].Value.AsJSON = "foo"
+		Expect(SpecLeaks(reports[0])).Error().To(MatchError(HavePrefix("invalid leak findings of spec ")))
+	})
+
+})
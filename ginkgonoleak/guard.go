@@ -0,0 +1,61 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	"github.com/thediveo/noleak"
+)
+
+// Guard protects the current Ginkgo spec against leaking goroutines with a
+// single line at the top of the spec: Guard immediately takes a snapshot of the
+// goroutines and then registers a DeferCleanup that checks for leaked
+// goroutines after the spec has run. The goroutines to be ignored in addition
+// to the snapshot are specified in the same way as with noleak.HaveLeaked. Leaked
+// goroutines are given noleak.SettleTimeout to end, if set.
+//
+//   It("doesn't leak", func() {
+//       ginkgonoleak.Guard()
+//       ...
+//   })
+//
+// As DeferCleanup callbacks run in reverse order of their registration, the
+// leak check runs after any cleanups the spec registers itself after calling
+// Guard, so goroutines stopped by these cleanups don't count as leaks.
+func Guard(ignoring ...interface{}) {
+	ginkgo.DeferCleanup(newGuard(ginkgo.Fail, ignoring...), ginkgo.Offset(1))
+}
+
+// newGuard takes a snapshot of the current goroutines and returns a function
+// checking for goroutines leaked since, attributing the leaks to the current
+// spec and reporting them using the specified fail function.
+func newGuard(fail func(message string, callerSkip ...int), ignoring ...interface{}) func() {
+	snapshot := noleak.TakeSnapshot()
+	ignoring = append(append([]interface{}{}, ignoring...), snapshot)
+	return func() {
+		report, err := noleak.Check(nil, noleak.CheckOptions{
+			Ignoring: ignoring,
+			Test:     ginkgo.CurrentSpecReport().FullText(),
+		})
+		if err == nil {
+			return
+		}
+		if len(report.Leaked) == 0 {
+			fail("spec leak check failed: " + err.Error())
+			return
+		}
+		fail("spec leaked goroutines: " + report.Message)
+	}
+}
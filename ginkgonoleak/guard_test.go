@@ -0,0 +1,71 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// worker blocks until done gets closed.
+func worker(done <-chan struct{}) {
+	<-done
+}
+
+var _ = Describe("leak guard", func() {
+
+	It("guards a spec", func() {
+		Guard()
+		done := make(chan struct{})
+		go worker(done)
+		DeferCleanup(func() { close(done) })
+	})
+
+	It("detects leaks", func() {
+		defer func(old time.Duration) { noleak.SettleTimeout = old }(noleak.SettleTimeout)
+		noleak.SettleTimeout = 50 * time.Millisecond
+		failures := []string{}
+		var events []noleak.LeakEvent
+		defer noleak.AddLeakHook(func(e noleak.LeakEvent) { events = append(events, e) })()
+
+		snapshot := noleak.TakeSnapshot()
+		check := newGuard(func(message string, _ ...int) { failures = append(failures, message) })
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked(snapshot))
+		}()
+		check()
+		Expect(failures).To(ConsistOf(HavePrefix("spec leaked goroutines: found 1 leaked goroutine")))
+		Expect(events).To(ConsistOf(HaveField("Test", CurrentSpecReport().FullText())))
+	})
+
+	It("ignores additional goroutines", func() {
+		failures := []string{}
+
+		check := newGuard(func(message string, _ ...int) { failures = append(failures, message) },
+			"github.com/thediveo/noleak/ginkgonoleak.worker")
+		done := make(chan struct{})
+		go worker(done)
+		defer close(done)
+		check()
+		Expect(failures).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,267 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// MetricsEntryName is the name of the report entries carrying per-spec
+// goroutine metrics.
+const MetricsEntryName = "noleak metrics"
+
+// MetricsSampleInterval is the interval in which RecordSpecMetrics samples
+// the number of goroutines while a spec runs, in order to determine the peak
+// number of goroutines. Shorter intervals catch shorter spikes, but wake up
+// the sampling goroutine more often throughout every spec.
+var MetricsSampleInterval = 10 * time.Millisecond
+
+// SpecMetrics describes how the number of goroutines developed while a single
+// spec ran. The metrics are taken right after the spec, without giving
+// goroutines any time to wind down, so that specs that almost leak goroutines
+// stand out before they become flaky.
+type SpecMetrics struct {
+	Spec    string `json:"spec"`    // full text of the spec.
+	Before  int    `json:"before"`  // number of goroutines before the spec.
+	After   int    `json:"after"`   // number of goroutines after the spec.
+	Peak    int    `json:"peak"`    // sampled peak number of goroutines while the spec ran.
+	New     int    `json:"new"`     // number of goroutines started by the spec and still running.
+	Ignored int    `json:"ignored"` // number of new goroutines ignored by the leak filters.
+}
+
+// Delta returns the change in the number of goroutines from before to after
+// the spec.
+func (m SpecMetrics) Delta() int { return m.After - m.Before }
+
+// Spike returns by how much the number of goroutines transiently grew while
+// the spec ran.
+func (m SpecMetrics) Spike() int { return m.Peak - m.Before }
+
+// String returns a short description of these metrics for Ginkgo's console
+// output.
+func (m SpecMetrics) String() string {
+	return fmt.Sprintf("goroutines: %d → %d (peak %d), %d new, %d ignored",
+		m.Before, m.After, m.Peak, m.New, m.Ignored)
+}
+
+// RecordSpecMetrics installs recording goroutine metrics for each spec,
+// adding them as report entries named MetricsEntryName that aren't shown on
+// the console. RecordSpecMetrics is typically called at the top level of a
+// suite, together with MetricsCSVAfterSuite or JSONReportAfterSuite:
+//
+//   var _ = ginkgonoleak.RecordSpecMetrics()
+//   var _ = ginkgonoleak.MetricsCSVAfterSuite("metrics.csv")
+//
+// The goroutines to be ignored when counting the ignored goroutines are
+// specified in the same way as with HaveLeaked. As the peak number of
+// goroutines is sampled every MetricsSampleInterval, very short-lived spikes
+// might go unnoticed.
+func RecordSpecMetrics(ignoring ...interface{}) bool {
+	return ginkgo.BeforeEach(func() {
+		recorder := startRecording(ignoring)
+		ginkgo.DeferCleanup(func() {
+			metrics := recorder.stop()
+			metrics.Spec = ginkgo.CurrentSpecReport().FullText()
+			ginkgo.AddReportEntry(MetricsEntryName, metrics, types.ReportEntryVisibilityNever)
+		})
+	}, ginkgo.Offset(1))
+}
+
+// bookkeeping matches the goroutines of Ginkgo and noleak, such as the
+// sampling goroutine, which thus don't count as new goroutines of a spec.
+var bookkeeping = gomega.SatisfyAny(
+	noleak.IgnoringPreset(noleak.PresetGinkgo),
+	noleak.IgnoringCreator("github.com/thediveo/noleak.GoInfrastructure"),
+)
+
+// metricsRecorder records the goroutine metrics of a single spec.
+type metricsRecorder struct {
+	ignoring []interface{}
+	before   map[uint64]struct{} // IDs of all goroutines before the spec.
+	count    int                 // number of goroutines before the spec, without bookkeeping.
+	offset   int                 // number of bookkeeping goroutines before the spec.
+	peak     chan int            // receives the peak number of goroutines when stopping.
+	stopping chan struct{}
+}
+
+// startRecording captures the goroutines before a spec and starts sampling
+// the number of goroutines.
+func startRecording(ignoring []interface{}) *metricsRecorder {
+	gs := goroutine.Goroutines()
+	r := &metricsRecorder{
+		ignoring: ignoring,
+		before:   make(map[uint64]struct{}, len(gs)),
+		peak:     make(chan int),
+		stopping: make(chan struct{}),
+	}
+	for _, g := range gs {
+		r.before[g.ID] = struct{}{}
+	}
+	r.count = countWithoutBookkeeping(gs)
+	r.offset = len(gs) - r.count
+	noleak.GoInfrastructure(r.sample)
+	return r
+}
+
+// sample samples the number of goroutines until stopped, and then hands over
+// the peak number. As sampling only counts goroutines, sample assumes the
+// number of bookkeeping goroutines to stay the same as before the spec,
+// plus itself.
+func (r *metricsRecorder) sample() {
+	peak := r.count
+	ticker := time.NewTicker(MetricsSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if n := runtime.NumGoroutine() - r.offset - 1; n > peak {
+				peak = n
+			}
+		case <-r.stopping:
+			r.peak <- peak
+			return
+		}
+	}
+}
+
+// stop stops sampling and returns the metrics, using the leak filters to
+// count the new goroutines ignored.
+func (r *metricsRecorder) stop() SpecMetrics {
+	close(r.stopping)
+	metrics := SpecMetrics{Before: r.count, Peak: <-r.peak}
+	gs := goroutine.Goroutines()
+	metrics.After = countWithoutBookkeeping(gs)
+	if metrics.After > metrics.Peak {
+		metrics.Peak = metrics.After
+	}
+	matcher := noleak.HaveLeaked(r.ignoring...).(*noleak.HaveLeakedMatcher)
+	for _, decision := range matcher.Explain(gs) {
+		if _, ok := r.before[decision.Goroutine.ID]; ok || isBookkeeping(decision.Goroutine) {
+			continue
+		}
+		metrics.New++
+		if !decision.Leaked {
+			metrics.Ignored++
+		}
+	}
+	return metrics
+}
+
+// isBookkeeping returns true if the specified goroutine belongs to Ginkgo or
+// noleak.
+func isBookkeeping(g goroutine.Goroutine) bool {
+	bookkeeping, _ := bookkeeping.Match(g)
+	return bookkeeping
+}
+
+// countWithoutBookkeeping returns the number of the specified goroutines that
+// don't belong to Ginkgo or noleak.
+func countWithoutBookkeeping(gs []goroutine.Goroutine) int {
+	count := 0
+	for _, g := range gs {
+		if !isBookkeeping(g) {
+			count++
+		}
+	}
+	return count
+}
+
+// SpecMetricsOf returns the goroutine metrics of the specs in the specified
+// report, in the order of the specs. Specs without metrics are not included.
+// SpecMetricsOf works with reports passed to ReportAfterSuite nodes as well as
+// with reports decoded from Ginkgo JSON reports.
+func SpecMetricsOf(report types.Report) ([]SpecMetrics, error) {
+	var metrics []SpecMetrics
+	for _, spec := range report.SpecReports {
+		for _, entry := range spec.ReportEntries {
+			if entry.Name != MetricsEntryName {
+				continue
+			}
+			m, ok := entry.Value.GetRawValue().(SpecMetrics)
+			if !ok {
+				if err := json.Unmarshal([]byte(entry.Value.AsJSON), &m); err != nil {
+					return nil, fmt.Errorf("invalid metrics of spec %q: %w", spec.FullText(), err)
+				}
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// WriteMetricsCSV writes the specified spec metrics in CSV format, with a
+// header line naming the columns.
+func WriteMetricsCSV(w io.Writer, metrics []SpecMetrics) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"spec", "before", "after", "delta", "peak", "spike", "new", "ignored"})
+	for _, m := range metrics {
+		_ = cw.Write([]string{
+			m.Spec,
+			strconv.Itoa(m.Before),
+			strconv.Itoa(m.After),
+			strconv.Itoa(m.Delta()),
+			strconv.Itoa(m.Peak),
+			strconv.Itoa(m.Spike()),
+			strconv.Itoa(m.New),
+			strconv.Itoa(m.Ignored),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// MetricsCSVAfterSuite adds a ReportAfterSuite node writing the goroutine
+// metrics recorded by RecordSpecMetrics to the specified destination file in
+// CSV format. MetricsCSVAfterSuite must be called at the top level of a suite:
+//
+//   var _ = ginkgonoleak.MetricsCSVAfterSuite("metrics.csv")
+func MetricsCSVAfterSuite(destination string) bool {
+	return ginkgo.ReportAfterSuite("noleak metrics CSV", func(report ginkgo.Report) {
+		if err := writeMetricsCSVFile(destination, report); err != nil {
+			ginkgo.Fail(fmt.Sprintf("cannot write noleak metrics CSV: %s", err.Error()))
+		}
+	})
+}
+
+// writeMetricsCSVFile writes the goroutine metrics in the specified report to
+// the destination file in CSV format.
+func writeMetricsCSVFile(destination string, report types.Report) error {
+	metrics, err := SpecMetricsOf(report)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	if err := WriteMetricsCSV(f, metrics); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/reporters"
+	"github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// lingerIgnored lingers for some time after having started; the per-spec
+// metrics are told to ignore it.
+func lingerIgnored(started, wg *sync.WaitGroup) {
+	defer wg.Done()
+	started.Done()
+	time.Sleep(100 * time.Millisecond)
+}
+
+var _ = Describe("per-spec metrics", Ordered, func() {
+
+	_ = RecordSpecMetrics(noleak.IgnoringInBacktrace("github.com/thediveo/noleak/ginkgonoleak.lingerIgnored"))
+
+	var lingering sync.WaitGroup
+
+	It("records spikes", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(20 * MetricsSampleInterval)
+			}()
+		}
+		wg.Wait()
+	})
+
+	It("records lingering goroutines", func() {
+		var started sync.WaitGroup
+		started.Add(2)
+		lingering.Add(2)
+		go lingerIgnored(&started, &lingering)
+		go func() {
+			defer lingering.Done()
+			started.Done()
+			time.Sleep(100 * time.Millisecond)
+		}()
+		started.Wait()
+	})
+
+	ReportAfterEach(func(report SpecReport) {
+		defer lingering.Wait()
+		if report.LeafNodeText == "decodes and writes metrics" {
+			return
+		}
+		var metrics []SpecMetrics
+		for _, entry := range report.ReportEntries {
+			if entry.Name == MetricsEntryName {
+				metrics = append(metrics, entry.Value.GetRawValue().(SpecMetrics))
+			}
+		}
+		Expect(metrics).To(HaveLen(1))
+		m := metrics[0]
+		Expect(m.Spec).To(Equal(report.FullText()))
+		switch report.LeafNodeText {
+		case "records spikes":
+			Expect(m.Spike()).To(BeNumerically(">=", 10))
+			Expect(m.New).To(BeZero())
+		case "records lingering goroutines":
+			Expect(m.Delta()).To(BeNumerically(">=", 2))
+			Expect(m.New).To(Equal(2))
+			Expect(m.Ignored).To(Equal(1))
+		}
+	})
+
+	It("decodes and writes metrics", func() {
+		metrics := SpecMetrics{Spec: "a, b", Before: 2, After: 3, Peak: 7, New: 1, Ignored: 1}
+		Expect(metrics.String()).To(Equal("goroutines: 2 → 3 (peak 7), 1 new, 1 ignored"))
+		AddReportEntry(MetricsEntryName, metrics, types.ReportEntryVisibilityNever)
+		report := types.Report{SpecReports: types.SpecReports{
+			CurrentSpecReport(),
+			{LeafNodeText: "no metrics"},
+		}}
+		Expect(SpecMetricsOf(report)).To(Equal([]SpecMetrics{metrics}))
+
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "report.json")
+		Expect(reporters.GenerateJSONReport(report, path)).To(Succeed())
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		var reports []types.Report
+		Expect(json.Unmarshal(data, &reports)).To(Succeed())
+		Expect(SpecMetricsOf(reports[0])).To(Equal([]SpecMetrics{metrics}))
+
+		csvPath := filepath.Join(dir, "metrics.csv")
+		Expect(writeMetricsCSVFile(csvPath, reports[0])).To(Succeed())
+		Expect(os.ReadFile(csvPath)).To(WithTransform(func(b []byte) string { return string(b) },
+			Equal("spec,before,after,delta,peak,spike,new,ignored\n\"a, b\",2,3,1,7,5,1,1\n")))
+		Expect(writeMetricsCSVFile(filepath.Join(dir, "nada", "m.csv"), reports[0])).NotTo(Succeed())
+
+		reports[0].SpecReports[0].ReportEntries[0].Value.AsJSON = "foo"
+		Expect(SpecMetricsOf(reports[0])).Error().To(MatchError(HavePrefix("invalid metrics of spec ")))
+		Expect(writeMetricsCSVFile(csvPath, reports[0])).NotTo(Succeed())
+	})
+
+})
@@ -0,0 +1,95 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// RunLeakCheckedSpecs runs the specs of a Ginkgo test suite with leak checks
+// included, replacing the usual RegisterFailHandler and RunSpecs boilerplate
+// with a single call:
+//
+//   func TestSuite(t *testing.T) {
+//       ginkgonoleak.RunLeakCheckedSpecs(t, "my suite")
+//   }
+//
+// RunLeakCheckedSpecs registers Ginkgo's Fail as Gomega's fail handler and
+// then checks each spec for leaked goroutines in the same way as Guard, with
+// the goroutines of Ginkgo's internals ignored using noleak.PresetGinkgo.
+// After all specs have run, RunLeakCheckedSpecs checks the suite as a whole
+// for goroutines leaked since it was called, such as goroutines started in
+// BeforeSuite and not stopped in AfterSuite. If noleak.SummaryFile is set,
+// RunLeakCheckedSpecs finally writes the summary of all leaks reported during
+// the suite run.
+//
+// Options that are Ginkgo Labels, SuiteConfig, or ReporterConfig are passed on
+// to RunSpecs, all other options specify further goroutines to be ignored in
+// the same way as with noleak.HaveLeaked. RunLeakCheckedSpecs returns true if all
+// specs passed and the suite didn't leak goroutines.
+func RunLeakCheckedSpecs(t ginkgo.GinkgoTestingT, description string, opts ...interface{}) bool {
+	gomega.RegisterFailHandler(ginkgo.Fail)
+	args, ignoring := splitSpecsOptions(opts)
+	snapshot := noleak.TakeSnapshot()
+	ginkgo.BeforeEach(func() {
+		Guard(ignoring...)
+	})
+	passed := ginkgo.RunSpecs(t, description, args...)
+	passed = checkSuite(t, description, snapshot, ignoring, os.Stderr) && passed
+	if err := noleak.WriteSummary(); err != nil {
+		fmt.Fprintf(os.Stderr, "noleak: %s\n", err)
+	}
+	return passed
+}
+
+// splitSpecsOptions splits the specified RunLeakCheckedSpecs options into the
+// arguments for RunSpecs and the goroutines to be ignored, including the
+// Ginkgo preset.
+func splitSpecsOptions(opts []interface{}) (args []interface{}, ignoring []interface{}) {
+	ignoring = []interface{}{noleak.IgnoringPreset(noleak.PresetGinkgo)}
+	for _, opt := range opts {
+		switch opt.(type) {
+		case ginkgo.Labels, types.SuiteConfig, types.ReporterConfig:
+			args = append(args, opt)
+		default:
+			ignoring = append(ignoring, opt)
+		}
+	}
+	return args, ignoring
+}
+
+// checkSuite checks for goroutines leaked by a suite as a whole since the
+// specified snapshot, reporting leaks to the specified writer and failing t.
+// checkSuite returns true if no goroutines leaked.
+func checkSuite(t ginkgo.GinkgoTestingT, description string, snapshot goroutine.Snapshot, ignoring []interface{}, w io.Writer) bool {
+	_, err := noleak.Check(nil, noleak.CheckOptions{
+		Ignoring: append(append([]interface{}{}, ignoring...), snapshot),
+		Test:     description,
+	})
+	if err == nil {
+		return true
+	}
+	fmt.Fprintf(w, "noleak: suite %q: %s\n", description, err)
+	t.Fail()
+	return false
+}
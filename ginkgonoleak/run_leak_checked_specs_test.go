@@ -0,0 +1,67 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// failingT counts how often a test failed.
+type failingT struct{ failed int }
+
+func (t *failingT) Fail() { t.failed++ }
+
+var _ = Describe("leak-checked specs", func() {
+
+	It("splits options", func() {
+		args, ignoring := splitSpecsOptions([]interface{}{
+			Label("foo"), "foo.bar", types.SuiteConfig{}, types.ReporterConfig{},
+		})
+		Expect(args).To(Equal([]interface{}{
+			Labels{"foo"}, types.SuiteConfig{}, types.ReporterConfig{},
+		}))
+		Expect(ignoring).To(HaveLen(2))
+		Expect(ignoring[0]).To(Equal(noleak.IgnoringPreset(noleak.PresetGinkgo)))
+		Expect(ignoring[1]).To(Equal("foo.bar"))
+	})
+
+	It("checks the suite as a whole", func() {
+		defer func(old time.Duration) { noleak.SettleTimeout = old }(noleak.SettleTimeout)
+		noleak.SettleTimeout = 50 * time.Millisecond
+
+		snapshot := noleak.TakeSnapshot()
+		_, ignoring := splitSpecsOptions(nil)
+		t := &failingT{}
+		var out strings.Builder
+		Expect(checkSuite(t, "suite", snapshot, ignoring, &out)).To(BeTrue())
+		Expect(t.failed).To(BeZero())
+		Expect(out.String()).To(BeEmpty())
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() { <-done }()
+		Expect(checkSuite(t, "suite", snapshot, ignoring, &out)).To(BeFalse())
+		Expect(t.failed).To(Equal(1))
+		Expect(out.String()).To(HavePrefix(`noleak: suite "suite": `))
+		Expect(out.String()).To(ContainSubstring("run_leak_checked_specs_test.go"))
+	})
+
+})
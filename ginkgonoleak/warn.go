@@ -0,0 +1,161 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// warnings keeps track of the identities of the leaked goroutines warned about
+// by the leak checks installed by WarnEachSpec.
+var warnings = warnedLeaks{identities: map[goroutine.Identity][]string{}}
+
+type warnedLeaks struct {
+	mu         sync.Mutex
+	identities map[goroutine.Identity][]string // identities to the specs warned about.
+	ignoring   []interface{}                   // filters of the per-spec leak checks.
+}
+
+// WarnEachSpec installs a leak check for each spec that only warns about
+// leaked goroutines instead of failing specs, similar to CheckEachSpec with
+// ReportLeaksLabel. The warnings are report entries that are always shown.
+// However, the identities of the leaked goroutines are remembered, so that
+// VerifyWarnings can later fail hard at the end of the suite if these very
+// goroutines are still present. This balances signal and flake
+// tolerance in huge suites, where goroutines sometimes need longer to wind
+// down than the settle timeout:
+//
+//   var _ = ginkgonoleak.WarnEachSpec()
+//
+//   var _ = AfterSuite(func() {
+//       ginkgonoleak.VerifyWarnings()
+//   })
+//
+// Specs labelled with AllowLeaksLabel aren't checked at all. Further
+// goroutines to be ignored are specified in the same way as with HaveLeaked;
+// VerifyWarnings ignores them too.
+func WarnEachSpec(ignoring ...interface{}) bool {
+	warnings.mu.Lock()
+	warnings.ignoring = append(warnings.ignoring, ignoring...)
+	warnings.mu.Unlock()
+	return ginkgo.BeforeEach(func() {
+		if checkModeOf(ginkgo.CurrentSpecReport().Labels()) == skip {
+			return
+		}
+		snapshot := noleak.TakeSnapshot()
+		ignoring := append(append([]interface{}{}, ignoring...), snapshot)
+		ginkgo.DeferCleanup(func() {
+			warnLeaks(ginkgo.CurrentSpecReport().FullText(), ignoring)
+		})
+	}, ginkgo.Offset(1))
+}
+
+// warnLeaks checks for leaked goroutines, warning about them in a report entry
+// and remembering their identities for VerifyWarnings.
+func warnLeaks(spec string, ignoring []interface{}) {
+	report, err := noleak.Check(nil, noleak.CheckOptions{Ignoring: ignoring, Test: spec})
+	if err == nil {
+		return
+	}
+	if len(report.Leaked) == 0 {
+		ginkgo.AddReportEntry("noleak: leak check failed", err.Error(), types.ReportEntryVisibilityAlways)
+		return
+	}
+	warnings.add(spec, report.Leaked)
+	ginkgo.AddReportEntry("noleak: leak warning", report.Message, types.ReportEntryVisibilityAlways)
+}
+
+// add remembers the identities of the specified goroutines leaked by the
+// specified spec.
+func (w *warnedLeaks) add(spec string, leaked []goroutine.Goroutine) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, g := range leaked {
+		id := g.Identity()
+		specs := w.identities[id]
+		if len(specs) == 0 || specs[len(specs)-1] != spec {
+			w.identities[id] = append(specs, spec)
+		}
+	}
+}
+
+// warned returns a copy of the identities warned about so far, together with
+// the filters of the per-spec leak checks.
+func (w *warnedLeaks) warned() (map[goroutine.Identity][]string, []interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	identities := make(map[goroutine.Identity][]string, len(w.identities))
+	for id, specs := range w.identities {
+		identities[id] = specs
+	}
+	return identities, append([]interface{}{}, w.ignoring...)
+}
+
+// VerifyWarnings fails the current node if any of the leaked goroutines
+// previously warned about by the leak checks installed using WarnEachSpec are
+// still present after noleak.SettleTimeout. Other goroutines, even if
+// originating from the same code as the goroutines warned about, such as
+// workers of a pool started in BeforeSuite, are ignored. The failure message
+// names the specs that originally leaked the remaining goroutines.
+// VerifyWarnings is meant to be called from AfterSuite.
+func VerifyWarnings() {
+	verifyWarnings(gomega.Default)
+}
+
+// verifyWarnings implements VerifyWarnings, reporting failures using the
+// specified Gomega.
+func verifyWarnings(g gomega.Gomega) {
+	identities, ignoring := warnings.warned()
+	if len(identities) == 0 {
+		return
+	}
+	notWarned := gomega.WithTransform(func(g goroutine.Goroutine) bool {
+		_, ok := identities[g.Identity()]
+		return !ok
+	}, gomega.BeTrue())
+	eventually := g.Eventually(noleak.Goroutines)
+	if noleak.SettleTimeout > 0 {
+		eventually = eventually.WithTimeout(noleak.SettleTimeout)
+	}
+	eventually.ShouldNot(noleak.HaveLeaked(append(ignoring, notWarned)...), func() string {
+		return "goroutines warned about are still present, originally leaked by:\n    " +
+			strings.Join(warnedSpecs(identities, noleak.Goroutines()), "\n    ")
+	})
+}
+
+// warnedSpecs returns the sorted names of the specs that leaked any of the
+// specified goroutines.
+func warnedSpecs(identities map[goroutine.Identity][]string, gs []goroutine.Goroutine) []string {
+	unique := map[string]struct{}{}
+	for _, g := range gs {
+		for _, spec := range identities[g.Identity()] {
+			unique[spec] = struct{}{}
+		}
+	}
+	specs := make([]string, 0, len(unique))
+	for spec := range unique {
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+	return specs
+}
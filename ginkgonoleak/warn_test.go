@@ -0,0 +1,89 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ginkgonoleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("warn-then-fail leak checks", Ordered, func() {
+
+	BeforeEach(func() {
+		old := noleak.SettleTimeout
+		noleak.SettleTimeout = 50 * time.Millisecond
+		DeferCleanup(func() { noleak.SettleTimeout = old })
+	})
+
+	BeforeAll(func() {
+		DeferCleanup(func() {
+			warnings.mu.Lock()
+			defer warnings.mu.Unlock()
+			warnings.identities = map[goroutine.Identity][]string{}
+		})
+	})
+
+	_ = WarnEachSpec()
+
+	done := make(chan struct{})
+	other := make(chan struct{})
+	// waitOn starts goroutines that share the same fingerprint.
+	waitOn := func(ch chan struct{}) { go func() { <-ch }() }
+
+	It("warns about leaks", func() {
+		waitOn(done)
+	})
+
+	It("doesn't check when allowing leaks", Label(AllowLeaksLabel), func() {
+		go func() { <-done }()
+	})
+
+	ReportAfterEach(func(report SpecReport) {
+		switch report.LeafNodeText {
+		case "warns about leaks":
+			Expect(report.ReportEntries).To(ContainElement(HaveField("Name", "noleak: leak warning")))
+		default:
+			Expect(report.ReportEntries).To(BeEmpty())
+		}
+	})
+
+	It("fails when goroutines warned about are still present", func() {
+		identities, _ := warnings.warned()
+		Expect(identities).To(HaveLen(1))
+		for _, specs := range identities {
+			Expect(specs).To(ConsistOf(HaveSuffix("warns about leaks")))
+		}
+
+		// a goroutine with the same fingerprint that hasn't been warned about.
+		waitOn(other)
+		defer close(other)
+
+		failures := []string{}
+		g := NewGomega(func(message string, _ ...int) { failures = append(failures, message) })
+		verifyWarnings(g)
+		Expect(failures).To(ConsistOf(MatchRegexp(
+			`\ngoroutines warned about are still present, originally leaked by:\n    .*warns about leaks\nExpected not to leak 1 goroutines`)), "only the goroutine warned about")
+
+		close(done)
+		failures = nil
+		verifyWarnings(g)
+		Expect(failures).To(BeEmpty())
+	})
+
+})
@@ -3,11 +3,16 @@ module github.com/thediveo/noleak
 
 go 1.18
 
-require github.com/onsi/gomega v1.20.0
+require (
+	github.com/onsi/gomega v1.20.0
+	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a
+)
 
 require (
-	github.com/google/go-cmp v0.5.8 // indirect
-	golang.org/x/sys v0.0.0-20220422013727-9388b58f7150 // indirect
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
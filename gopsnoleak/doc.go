@@ -0,0 +1,34 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package gopsnoleak retrieves the goroutines of local processes running the
+gops agent (https://github.com/google/gops), so that the same filters and
+reports as for the goroutines of the own process also work with other
+processes, yet without the need to expose pprof's HTTP endpoints.
+
+    gs, err := gopsnoleak.Goroutines(pid)
+    ...
+    Expect(gs).NotTo(noleak.HaveLeaked(...))
+
+The process is identified by its PID, looking up the port of its gops agent
+in the same way as the gops command does. Alternatively, GoroutinesAt talks to
+a gops agent listening at a known address.
+
+Package gopsnoleak doesn't depend on gops itself, but instead talks the gops
+agent protocol.
+
+*/
+package gopsnoleak
@@ -0,0 +1,102 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gopsnoleak
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Timeout is the maximum duration for retrieving the goroutines of a process
+// from its gops agent.
+var Timeout = 10 * time.Second
+
+// Environment variable overriding the directory where gops agents store their
+// port files.
+const gopsConfigDirEnv = "GOPS_CONFIG_DIR"
+
+// Signal byte requesting a goroutine dump from a gops agent.
+const signalStackTrace = byte(0x1)
+
+// Goroutines returns the goroutines of the local process with the specified
+// PID, which must run the gops agent.
+func Goroutines(pid int) ([]goroutine.Goroutine, error) {
+	addr, err := agentAddr(pid)
+	if err != nil {
+		return nil, err
+	}
+	return GoroutinesAt(addr)
+}
+
+// GoroutinesAt returns the goroutines of the process with its gops agent
+// listening at the specified TCP address, such as "127.0.0.1:12345".
+func GoroutinesAt(addr string) ([]goroutine.Goroutine, error) {
+	conn, err := net.DialTimeout("tcp", addr, Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to gops agent: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(Timeout))
+	if _, err := conn.Write([]byte{signalStackTrace}); err != nil {
+		return nil, fmt.Errorf("cannot request goroutines from gops agent: %w", err)
+	}
+	dump, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read goroutines from gops agent: %w", err)
+	}
+	gs, err := goroutine.Parse(dump)
+	if err != nil {
+		return nil, fmt.Errorf("malformed goroutine dump from gops agent: %w", err)
+	}
+	return gs, nil
+}
+
+// agentAddr returns the TCP address of the gops agent of the local process
+// with the specified PID.
+func agentAddr(pid int) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	port, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(pid)))
+	if err != nil {
+		return "", fmt.Errorf("cannot find gops agent of process %d: %w", pid, err)
+	}
+	return net.JoinHostPort("127.0.0.1", strings.TrimSpace(string(port))), nil
+}
+
+// configDir returns the directory where gops agents store their port files.
+func configDir() (string, error) {
+	if dir := os.Getenv(gopsConfigDirEnv); dir != "" {
+		return dir, nil
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "gops"), nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", errors.New("cannot determine gops configuration directory")
+	}
+	return filepath.Join(home, ".config", "gops"), nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gopsnoleak
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// fakeAgent serves a single goroutine dump request the same way a gops agent
+// does, returning the specified dump, and then returns the agent's address.
+func fakeAgent(dump []byte) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		signal := make([]byte, 1)
+		if _, err := conn.Read(signal); err != nil || signal[0] != signalStackTrace {
+			return
+		}
+		_, _ = conn.Write(dump)
+	}()
+	DeferCleanup(func() {
+		l.Close()
+		Eventually(done).Should(BeClosed())
+	})
+	return l.Addr().String()
+}
+
+const dump = `goroutine 1 [running]:
+main.main()
+	/home/foo/main.go:10 +0x27
+
+goroutine 42 [chan receive]:
+main.foo.func1()
+	/home/foo/main.go:6 +0x28
+created by main.foo in goroutine 1
+	/home/foo/main.go:5 +0x64
+`
+
+var _ = Describe("gops agent goroutines", func() {
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		oldDir, ok := os.LookupEnv(gopsConfigDirEnv)
+		Expect(os.Setenv(gopsConfigDirEnv, dir)).To(Succeed())
+		DeferCleanup(func() {
+			if ok {
+				os.Setenv(gopsConfigDirEnv, oldDir)
+			} else {
+				os.Unsetenv(gopsConfigDirEnv)
+			}
+		})
+	})
+
+	It("retrieves the goroutines of a process by PID", func() {
+		addr := fakeAgent([]byte(dump))
+		_, port, err := net.SplitHostPort(addr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(os.Getenv(gopsConfigDirEnv), "4242"),
+			[]byte(port+"\n"), 0600)).To(Succeed())
+		gs, err := Goroutines(4242)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(ConsistOf(
+			HaveField("ID", uint64(1)),
+			And(HaveField("ID", uint64(42)), HaveField("CreatorFunction", "main.foo"))))
+		Expect(gs).To(noleak.HaveLeaked(noleak.IgnoringTopFunction("main.main")))
+	})
+
+	It("reports processes without agent", func() {
+		Expect(Goroutines(4242)).Error().To(MatchError(ContainSubstring("cannot find gops agent of process 4242")))
+	})
+
+	It("reports unreachable agents", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		addr := l.Addr().String()
+		l.Close()
+		Expect(GoroutinesAt(addr)).Error().To(MatchError(ContainSubstring("cannot connect to gops agent")))
+	})
+
+	It("reports malformed dumps", func() {
+		addr := fakeAgent([]byte("goroutine foo [running]:\nmain.main()\n"))
+		Expect(GoroutinesAt(addr)).Error().To(MatchError(ContainSubstring("malformed goroutine dump")))
+	})
+
+	It("uses the user's configuration directory by default", func() {
+		os.Unsetenv(gopsConfigDirEnv)
+		dir, err := configDir()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Base(dir)).To(Equal("gops"))
+	})
+
+})
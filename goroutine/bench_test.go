@@ -0,0 +1,97 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// benchmarkDump returns a goroutine dump of the specified number of goroutines,
+// built from the goroutines in the Go 1.21 corpus dump. Goroutine IDs are
+// duplicated, which doesn't matter to parsing.
+func benchmarkDump(b *testing.B, goroutines int) []byte {
+	b.Helper()
+	dump, err := os.ReadFile("testdata/corpus/go1.21.dump")
+	if err != nil {
+		b.Fatal(err)
+	}
+	records := bytes.SplitAfter(bytes.TrimRight(dump, "\n"), []byte("\n\n"))
+	var buff bytes.Buffer
+	for n := 0; n < goroutines; n++ {
+		buff.Write(records[n%len(records)])
+		if n%len(records) == len(records)-1 {
+			buff.WriteString("\n\n")
+		}
+	}
+	return buff.Bytes()
+}
+
+func BenchmarkParseStack(b *testing.B) {
+	dump := benchmarkDump(b, 1000)
+	b.SetBytes(int64(len(dump)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = parseStack(dump)
+	}
+}
+
+func BenchmarkGoroutines(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Goroutines()
+	}
+}
+
+func BenchmarkHeader(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = new("goroutine 666 gp=0xc000002380 m=0 mp=0x5a4840 [chan receive, 2 minutes]:\n")
+	}
+}
+
+func BenchmarkFindCreator(b *testing.B) {
+	const backtrace = "main.foo.func1()\n\t/tmp/main.go:6 +0x28\ncreated by main.foo in goroutine 1\n\t/tmp/main.go:5 +0x64\n"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = FindCreator(backtrace)
+	}
+}
+
+func BenchmarkParseFrames(b *testing.B) {
+	const backtrace = "sync.runtime_SemacquireMutex(0xc00001c0b4?, 0x0?, 0x1?)\n" +
+		"\t/usr/local/go/src/runtime/sema.go:77 +0x25\n" +
+		"sync.(*Mutex).lockSlow(0xc00001c0b0)\n" +
+		"\t/usr/local/go/src/sync/mutex.go:171 +0x15d\n" +
+		"sync.(*Mutex).Lock(...)\n" +
+		"\t/usr/local/go/src/sync/mutex.go:90\n" +
+		"main.locker(0xc00001c0b0?)\n" +
+		"\t/home/user/leak/main.go:22 +0x2a\n" +
+		"created by main.main in goroutine 1\n" +
+		"\t/home/user/leak/main.go:29 +0xb1\n"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = parseFrames(backtrace)
+	}
+}
+
+func BenchmarkIsGoroutineHeader(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = isGoroutineHeader("goroutine 42 [chan receive, 2 minutes]:")
+	}
+}
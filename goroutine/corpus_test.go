@@ -0,0 +1,104 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "update the golden files of the goroutine dump corpus")
+
+// goldenGoroutine is the parsed information about a goroutine as recorded in
+// the golden files of the goroutine dump corpus.
+type goldenGoroutine struct {
+	ID              uint64   `json:"id"`
+	State           string   `json:"state"`
+	BaseState       State    `json:"baseState"`
+	Minutes         int      `json:"minutes,omitempty"`
+	Locked          bool     `json:"locked,omitempty"`
+	TopFunction     string   `json:"topFunction"`
+	CreatorFunction string   `json:"creatorFunction,omitempty"`
+	CreatorID       uint64   `json:"creatorID,omitempty"`
+	BornAt          string   `json:"bornAt,omitempty"`
+	Frames          []string `json:"frames"`
+	ElidedFrames    int      `json:"elidedFrames,omitempty"`
+}
+
+func golden(gs []Goroutine) []goldenGoroutine {
+	goldens := make([]goldenGoroutine, 0, len(gs))
+	for _, g := range gs {
+		state, minutes, locked := ParseState(g.State)
+		frames := make([]string, 0, len(g.Frames))
+		for _, frame := range g.Frames {
+			f := frame.Function + " at " + frame.Location
+			if frame.Inlined {
+				f += " (inlined)"
+			}
+			frames = append(frames, f)
+		}
+		goldens = append(goldens, goldenGoroutine{
+			ID:              g.ID,
+			State:           g.State,
+			BaseState:       state,
+			Minutes:         minutes,
+			Locked:          locked,
+			TopFunction:     g.TopFunction,
+			CreatorFunction: g.CreatorFunction,
+			CreatorID:       g.CreatorID,
+			BornAt:          g.BornAt,
+			Frames:          frames,
+			ElidedFrames:    g.ElidedFrames,
+		})
+	}
+	return goldens
+}
+
+var _ = Describe("goroutine dump corpus", func() {
+
+	dumps, _ := filepath.Glob("testdata/corpus/*.dump")
+
+	It("has dumps", func() {
+		Expect(dumps).NotTo(BeEmpty())
+	})
+
+	for _, dump := range dumps {
+		dump := dump
+		It("parses "+filepath.Base(dump)+" as expected", func() {
+			stacks, err := os.ReadFile(dump)
+			Expect(err).NotTo(HaveOccurred())
+			gs, err := Parse(stacks)
+			Expect(err).NotTo(HaveOccurred())
+			actual, err := json.MarshalIndent(golden(gs), "", "  ")
+			Expect(err).NotTo(HaveOccurred())
+			actual = append(actual, '\n')
+
+			goldenPath := strings.TrimSuffix(dump, ".dump") + ".golden"
+			if *updateGolden {
+				Expect(os.WriteFile(goldenPath, actual, 0644)).To(Succeed())
+			}
+			expected, err := os.ReadFile(goldenPath)
+			Expect(err).NotTo(HaveOccurred(), "missing golden file; rerun with -update-golden")
+			Expect(string(actual)).To(Equal(string(expected)))
+		})
+	}
+
+})
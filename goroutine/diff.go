@@ -0,0 +1,76 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+// Diff describes the changes between two lists of goroutines, such as a
+// baseline snapshot and the current goroutines.
+type Diff struct {
+	Appeared []Goroutine // goroutines not present before.
+	Vanished []Goroutine // goroutines present before, but gone now.
+}
+
+// Compare returns the goroutines that appeared and vanished between the
+// specified before and after lists of goroutines. Goroutines are identified by
+// their IDs, as well as their creators and creation locations, as a safeguard
+// against goroutine IDs being reused.
+func Compare(before, after []Goroutine) Diff {
+	key := Goroutine.Identity
+	befores := make(map[Identity]struct{}, len(before))
+	for _, g := range before {
+		befores[key(g)] = struct{}{}
+	}
+	afters := make(map[Identity]struct{}, len(after))
+	diff := Diff{}
+	for _, g := range after {
+		afters[key(g)] = struct{}{}
+		if _, ok := befores[key(g)]; !ok {
+			diff.Appeared = append(diff.Appeared, g)
+		}
+	}
+	for _, g := range before {
+		if _, ok := afters[key(g)]; !ok {
+			diff.Vanished = append(diff.Vanished, g)
+		}
+	}
+	return diff
+}
+
+// Compare returns the goroutines that appeared and vanished since this snapshot
+// was taken, given the current goroutines.
+func (s Snapshot) Compare(current []Goroutine) Diff {
+	return Compare(s.Goroutines, current)
+}
+
+// Identity identifies a goroutine even in face of goroutine ID reuse: in
+// addition to the goroutine ID, it consists of the goroutine's creator function
+// and creation location. As these never change during the lifetime of a
+// goroutine, a goroutine with a recycled ID but started elsewhere gets a
+// different identity.
+type Identity struct {
+	ID              uint64
+	CreatorFunction string
+	BornAt          string
+}
+
+// Identity returns the identity of this goroutine.
+func (g Goroutine) Identity() Identity {
+	return Identity{ID: g.ID, CreatorFunction: g.CreatorFunction, BornAt: g.BornAt}
+}
+
+// IsAnonymous returns true if this identity lacks any creator information,
+// such as for the main goroutine or goroutines only known by their IDs.
+func (i Identity) IsAnonymous() bool {
+	return i.CreatorFunction == "" && i.BornAt == ""
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("goroutine diffs", func() {
+
+	It("returns appeared and vanished goroutines", func() {
+		before := []Goroutine{
+			{ID: 1, CreatorFunction: "main.main", BornAt: "main.go:1"},
+			{ID: 2, CreatorFunction: "main.main", BornAt: "main.go:2"},
+			{ID: 3, CreatorFunction: "main.main", BornAt: "main.go:3"},
+		}
+		after := []Goroutine{
+			{ID: 1, CreatorFunction: "main.main", BornAt: "main.go:1"},
+			{ID: 3, CreatorFunction: "foo.bar", BornAt: "foo.go:42"},
+			{ID: 4, CreatorFunction: "main.main", BornAt: "main.go:4"},
+		}
+		diff := Compare(before, after)
+		Expect(diff.Appeared).To(ConsistOf(HaveField("ID", uint64(3)), HaveField("ID", uint64(4))))
+		Expect(diff.Appeared).To(ContainElement(HaveField("CreatorFunction", "foo.bar")))
+		Expect(diff.Vanished).To(ConsistOf(HaveField("ID", uint64(2)), HaveField("ID", uint64(3))))
+
+		Expect(Snapshot{Goroutines: before}.Compare(before)).To(Equal(Diff{}))
+	})
+
+	It("returns goroutine identities", func() {
+		g := Goroutine{ID: 42, State: "running", CreatorFunction: "main.main", BornAt: "main.go:1"}
+		Expect(g.Identity()).To(Equal(Identity{ID: 42, CreatorFunction: "main.main", BornAt: "main.go:1"}))
+		Expect(g.Identity().IsAnonymous()).To(BeFalse())
+		Expect(Goroutine{ID: 1}.Identity().IsAnonymous()).To(BeTrue())
+	})
+
+})
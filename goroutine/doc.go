@@ -5,7 +5,26 @@ or only the caller's goroutine. Information provided by the Goroutine type
 consists of a unique ID, the state, the name of the topmost (most recent)
 function in the call stack and the full backtrace. For goroutines other than the
 main goroutine (the one with ID 1) the creating function as well as location
-(file name and line number) are additionally provided.
+(file name and line number) are additionally provided, also in structured
+form as the CreatorFrame, so that there is no need to split "file:line"
+locations again. SplitLocation splits other locations. The backtrace is also
+available in parsed form as a list of call frames, including the call
+arguments.
+
+For tooling working with goroutine dumps from elsewhere, such as log analyzers
+and crash triage bots, Parse parses complete dumps, while ParseHeader,
+ParseBacktrace, and FindCreator parse the individual parts of goroutine
+records. In contrast to capturing the goroutines of the current process, they
+never panic on malformed input: Parse, ParseHeader, and ParseBacktrace return
+a *ParseError instead, while FindCreator returns empty results.
+
+Besides the goroutines of the current process, FromPprofURL fetches information
+about the goroutines of a remote process from its net/http/pprof endpoint.
+
+The Go runtime's own system goroutines, such as the garbage collector's
+background workers, never show up in captures, but CountSystemGoroutines
+counts them on Go versions supporting this. Goroutine.IsSystem tells system
+goroutines in dumps taken with GOTRACEBACK=system.
 
 */
 package goroutine
@@ -0,0 +1,43 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Fingerprint returns a short fingerprint of this goroutine that stays the
+// same across test runs as long as the goroutine is started from the same
+// location and parked in the same topmost function. Thus, the fingerprint
+// doesn't depend on the goroutine ID, state, call arguments, or code offsets,
+// but only on the creator function and location, as well as the topmost
+// function.
+//
+// As the fingerprint deliberately doesn't take the further frames of the
+// backtrace into account, goroutines captured using LightweightGoroutines get
+// the same fingerprints as their fully captured counterparts.
+//
+// Fingerprints are useful to track the same leaks across test runs, such as
+// for trend analysis, or to deduplicate leak reports.
+func (g Goroutine) Fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte(g.CreatorFunction))
+	h.Write([]byte{0})
+	h.Write([]byte(g.BornAt))
+	h.Write([]byte{0})
+	h.Write([]byte(g.TopFunction))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fingerprints", func() {
+
+	It("fingerprints goroutines independent of their IDs, states, and arguments", func() {
+		g1 := Goroutine{
+			ID:              42,
+			State:           "chan receive",
+			CreatorFunction: "main.foo",
+			BornAt:          "/home/foo/test.go:5",
+			Frames:          []Frame{{Function: "main.foo.func1", Args: []uint64{1}}},
+		}
+		g2 := Goroutine{
+			ID:              666,
+			State:           "chan receive, 42 minutes",
+			CreatorFunction: "main.foo",
+			BornAt:          "/home/foo/test.go:5",
+			Frames:          []Frame{{Function: "main.foo.func1", Args: []uint64{2}}},
+		}
+		Expect(g1.Fingerprint()).To(HaveLen(16))
+		Expect(g1.Fingerprint()).To(Equal(g2.Fingerprint()))
+
+		g2.BornAt = "/home/foo/test.go:6"
+		Expect(g1.Fingerprint()).NotTo(Equal(g2.Fingerprint()))
+
+		Expect(Goroutine{TopFunction: "main.main"}.Fingerprint()).NotTo(
+			Equal(Goroutine{TopFunction: "main.foo"}.Fingerprint()))
+	})
+
+	It("fingerprints lightweight and full captures the same", func() {
+		gs := Goroutines()
+		Expect(gs).NotTo(BeEmpty())
+		for _, g := range gs {
+			Expect(g.Frames).NotTo(BeEmpty())
+			Expect(g.lightweight().Fingerprint()).To(Equal(g.Fingerprint()))
+		}
+	})
+
+})
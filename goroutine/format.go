@@ -0,0 +1,76 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// GomegaMaxFrames is the maximum number of topmost call frames GomegaString
+// includes in the Gomega representation of a Goroutine, in order to show where
+// a goroutine is parked without dumping its full backtrace. The default of zero
+// doesn't include any frames.
+var GomegaMaxFrames = 0
+
+// GomegaMaxLength is the maximum length of the Gomega representation of a
+// Goroutine, as returned by GomegaString. It defaults to the default of
+// Gomega's format.MaxLength, while zero or a negative GomegaMaxLength disables
+// truncation altogether.
+//
+// As Gomega doesn't truncate representations returned by GomegaString, the
+// limit applies to each single Goroutine; Gomega's format.MaxLength and
+// format.MaxDepth still apply to slices and other values containing
+// Goroutines.
+var GomegaMaxLength = 4000
+
+// GomegaString returns the Gomega struct representation of a Goroutine, but
+// without a potentially rather lengthy backtrace. This Gomega object value
+// dumps getting happily truncated as to become more or less useless.
+//
+// See also GomegaMaxFrames and GomegaMaxLength for controlling the depth and
+// width of this representation.
+func (g Goroutine) GomegaString() string {
+	s := fmt.Sprintf(
+		"{ID: %d, State: %q, TopFunction: %q, CreatorFunction: %q, BornAt: %q",
+		g.ID, g.State, g.TopFunction, g.CreatorFunction, g.BornAt)
+	if GomegaMaxFrames > 0 && len(g.Frames) > 0 {
+		frames := make([]string, 0, GomegaMaxFrames+1)
+		for idx, frame := range g.Frames {
+			if idx >= GomegaMaxFrames {
+				frames = append(frames, fmt.Sprintf("...%d more", len(g.Frames)-idx))
+				break
+			}
+			frames = append(frames, fmt.Sprintf("%q", frame.Function+" "+frame.Location))
+		}
+		s += ", Frames: [" + strings.Join(frames, ", ") + "]"
+	}
+	return truncateGomegaString(s + "}")
+}
+
+// truncateGomegaString truncates the specified Gomega representation to the
+// maximum length configured by GomegaMaxLength.
+func truncateGomegaString(s string) string {
+	max := GomegaMaxLength
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	// don't cut a multi-byte character in half.
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "..."
+}
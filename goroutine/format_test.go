@@ -0,0 +1,67 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gomega representation", func() {
+
+	g := Goroutine{
+		ID:          42,
+		State:       "chan receive",
+		TopFunction: "main.foo",
+		Frames: []Frame{
+			{Function: "main.foo", Location: "/main.go:1"},
+			{Function: "main.bar", Location: "/main.go:2"},
+			{Function: "main.main", Location: "/main.go:3"},
+		},
+	}
+
+	AfterEach(func() {
+		GomegaMaxFrames = 0
+		GomegaMaxLength = 4000
+	})
+
+	It("includes the topmost frames", func() {
+		Expect(g.GomegaString()).NotTo(ContainSubstring("Frames"))
+		GomegaMaxFrames = 2
+		Expect(g.GomegaString()).To(HaveSuffix(
+			`, Frames: ["main.foo /main.go:1", "main.bar /main.go:2", ...1 more]}`))
+		GomegaMaxFrames = 3
+		Expect(g.GomegaString()).To(HaveSuffix(`"main.main /main.go:3"]}`))
+	})
+
+	It("truncates", func() {
+		GomegaMaxLength = 10
+		Expect(g.GomegaString()).To(Equal("{ID: 42, S..."))
+
+		GomegaMaxLength = 0
+		Expect(g.GomegaString()).To(HaveSuffix("}"))
+		GomegaMaxLength = -1
+		Expect(g.GomegaString()).To(HaveSuffix("}"))
+
+		GomegaMaxLength = 4
+		Expect(Goroutine{ID: 1, State: "ü"}.GomegaString()).To(Equal("{ID:..."))
+		GomegaMaxLength = 23
+		Expect(Goroutine{ID: 1, State: strings.Repeat("ü", 4)}.GomegaString()).To(Equal(
+			"{ID: 1, State: \"üüü..."))
+	})
+
+})
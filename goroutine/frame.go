@@ -0,0 +1,214 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Frame represents a single function call in a goroutine's backtrace.
+//
+// The arguments of a call are shown by Go's runtime as the raw machine words
+// passed to the function, in hexadecimal notation. Structured arguments appear
+// as groups in curly braces, a trailing "..." indicates that the runtime
+// stopped listing further arguments, and a "?" suffix marks a value that might
+// be inaccurate, as it might have been already overwritten in its register
+// (Go 1.18 and later).
+//
+// Calls inlined by the compiler appear in backtraces with their argument list
+// elided as "(...)" and without the call location hex offset, as the runtime
+// cannot recover the arguments of inlined calls. Such frames are tagged as
+// Inlined, with RawArgs "..." and without any Args.
+type Frame struct {
+	Function string   // fully qualified name of the called function, such as "main.(*T).foo"
+	RawArgs  string   // argument list as shown in the backtrace, without the enclosing parentheses
+	Args     []uint64 // argument words in order of appearance, with any groups flattened
+	Location string   // call location; format "file-path:line-number"
+	File     string   // file path of the call location
+	Line     int      // line number of the call location; zero if unknown
+	Inlined  bool     // call has been inlined by the compiler
+}
+
+// SplitLocation splits a location in the form of "file-path:line-number", as
+// used by Frame.Location and Goroutine.BornAt, into its file path and line
+// number. File paths with Windows drive letters are correctly taken care of.
+// If the location lacks a valid line number, SplitLocation returns the
+// location unchanged as the file path, together with a zero line number.
+func SplitLocation(location string) (file string, line int) {
+	idx := strings.LastIndex(location, ":")
+	if idx < 0 {
+		return location, 0
+	}
+	lineno := location[idx+1:]
+	if lineno == "" || strings.TrimLeft(lineno, "0123456789") != "" {
+		return location, 0
+	}
+	line, err := strconv.Atoi(lineno)
+	if err != nil {
+		return location, 0
+	}
+	return location[:idx], line
+}
+
+// newCreatorFrame returns the frame of the specified creator function and the
+// location of its "go" statement, or a zero Frame if there is no creator.
+func newCreatorFrame(creator, location string) Frame {
+	if creator == "" {
+		return Frame{}
+	}
+	file, line := SplitLocation(location)
+	return Frame{
+		Function: creator,
+		Location: location,
+		File:     file,
+		Line:     line,
+	}
+}
+
+// parseFrames parses the function call frames from the specified backtrace,
+// with the topmost (most recent) call first. The final "created by" entry isn't
+// considered to be a call frame and thus is not included in the result.
+// parseFrames additionally returns the number of frames elided from the
+// backtrace, see also parseElision.
+func parseFrames(backtrace string) (frames []Frame, elided int) {
+	frames = []Frame{}
+	// Scan the backtrace line by line in place, as splitting it into a slice
+	// of lines would cost an allocation per backtrace for nothing.
+	for rest := backtrace; rest != ""; {
+		var line string
+		line, rest, _ = nextLine(rest)
+		if line == "" || line[0] == ' ' || line[0] == '\t' ||
+			strings.HasPrefix(line, backtraceGoroutineCreator) {
+			continue
+		}
+		if n, ok := parseElision(line); ok {
+			if n < 0 || elided < 0 {
+				elided = -1
+			} else {
+				elided += n
+			}
+			continue
+		}
+		frame, ok := parseCall(line)
+		if !ok {
+			continue
+		}
+		// The location of the call is on the next, indented line.
+		if strings.HasPrefix(rest, "\t") {
+			line, rest, _ = nextLine(rest)
+			frame.Location = parseLocation(line)
+			frame.File, frame.Line = SplitLocation(frame.Location)
+		}
+		frames = append(frames, frame)
+	}
+	return
+}
+
+// Marker line in very deep backtraces where the runtime elided an unknown
+// number of additional frames at the end of the backtrace.
+const backtraceAdditionalFramesElided = "...additional frames elided..."
+
+// parseElision parses an elision marker line from a backtrace, returning the
+// number of frames elided and true. Go's runtime either elides a known number
+// of frames in the middle of a backtrace, as in "...42 frames elided...", or
+// an unknown number of additional frames at the end of the backtrace, as in
+// "...additional frames elided...". In the latter case, parseElision returns
+// -1. For any other line, it returns false.
+func parseElision(line string) (n int, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == backtraceAdditionalFramesElided {
+		return -1, true
+	}
+	if !strings.HasPrefix(line, "...") || !strings.HasSuffix(line, " frames elided...") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(line[3 : len(line)-len(" frames elided...")])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// inlinedArgs is the argument list of inlined calls, as in "main.foo(...)".
+const inlinedArgs = "..."
+
+// parseCall parses a function call line from a backtrace, such as
+// "main.foo(0xc0000b2000, 0x1)", into a Frame. It returns false if the line
+// isn't a function call line.
+func parseCall(line string) (Frame, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasSuffix(line, ")") {
+		return Frame{}, false
+	}
+	// Function names might contain parentheses themselves, such as in
+	// "main.(*T).foo", but the argument list never does.
+	idx := strings.LastIndex(line, "(")
+	if idx <= 0 {
+		return Frame{}, false
+	}
+	rawargs := line[idx+1 : len(line)-1]
+	return Frame{
+		Function: line[:idx],
+		RawArgs:  rawargs,
+		Args:     parseArgs(rawargs),
+		Inlined:  rawargs == inlinedArgs,
+	}, true
+}
+
+// parseArgs parses the hexadecimal argument words from the specified textual
+// argument list, ignoring any grouping, elision, and inaccuracy markers.
+func parseArgs(rawargs string) []uint64 {
+	var args []uint64
+	for len(rawargs) > 0 {
+		// Skip separators and grouping braces, then scan the next word.
+		if isArgSeparator(rawargs[0]) {
+			rawargs = rawargs[1:]
+			continue
+		}
+		end := 1
+		for end < len(rawargs) && !isArgSeparator(rawargs[end]) {
+			end++
+		}
+		word := strings.TrimSuffix(rawargs[:end], "?")
+		rawargs = rawargs[end:]
+		if !strings.HasPrefix(word, "0x") {
+			continue // "...", "_", et cetera.
+		}
+		arg, err := strconv.ParseUint(word[2:], 16, 64)
+		if err != nil {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// isArgSeparator returns true if the specified byte separates the words in a
+// textual argument list.
+func isArgSeparator(b byte) bool {
+	return b == ',' || b == ' ' || b == '{' || b == '}'
+}
+
+// parseLocation takes an indented location line from a backtrace and returns
+// only the "file-path:line-number" information, stripping off the optional
+// call location hex offset.
+func parseLocation(line string) string {
+	line = strings.TrimSpace(line)
+	if offsetpos := strings.LastIndex(line, " +0x"); offsetpos >= 0 {
+		line = line[:offsetpos]
+	}
+	return line
+}
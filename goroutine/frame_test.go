@@ -0,0 +1,160 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("frames", func() {
+
+	It("parses function calls with arguments", func() {
+		f, ok := parseCall("main.foo(0xc0000b2000, 0x1)")
+		Expect(ok).To(BeTrue())
+		Expect(f.Function).To(Equal("main.foo"))
+		Expect(f.RawArgs).To(Equal("0xc0000b2000, 0x1"))
+		Expect(f.Args).To(Equal([]uint64{0xc0000b2000, 0x1}))
+
+		f, ok = parseCall("main.(*T).foo(0x0?, {0x1, 0x2}, ...)")
+		Expect(ok).To(BeTrue())
+		Expect(f.Function).To(Equal("main.(*T).foo"))
+		Expect(f.RawArgs).To(Equal("0x0?, {0x1, 0x2}, ..."))
+		Expect(f.Args).To(Equal([]uint64{0, 1, 2}))
+
+		f, ok = parseCall("main.main()")
+		Expect(ok).To(BeTrue())
+		Expect(f.Function).To(Equal("main.main"))
+		Expect(f.RawArgs).To(BeEmpty())
+		Expect(f.Args).To(BeEmpty())
+		Expect(f.Inlined).To(BeFalse())
+	})
+
+	It("tags inlined calls", func() {
+		frames, elided := parseFrames(`main.inner(...)
+	/tmp/main.go:10
+main.outer(...)
+	/tmp/main.go:13
+main.main()
+	/tmp/main.go:15 +0x39
+`)
+		Expect(frames).To(Equal([]Frame{
+			{Function: "main.inner", RawArgs: "...", Location: "/tmp/main.go:10", File: "/tmp/main.go", Line: 10, Inlined: true},
+			{Function: "main.outer", RawArgs: "...", Location: "/tmp/main.go:13", File: "/tmp/main.go", Line: 13, Inlined: true},
+			{Function: "main.main", Location: "/tmp/main.go:15", File: "/tmp/main.go", Line: 15},
+		}))
+		Expect(elided).To(BeZero())
+
+		f, ok := parseCall("main.foo(0x1, ...)")
+		Expect(ok).To(BeTrue())
+		Expect(f.Inlined).To(BeFalse())
+	})
+
+	It("rejects non-call lines", func() {
+		Expect(parseCall("main.main")).Error().To(BeFalse())
+		Expect(parseCall("(0x1)")).Error().To(BeFalse())
+	})
+
+	It("skips unparseable argument words", func() {
+		Expect(parseArgs("_, 0xzz, 0x2a")).To(Equal([]uint64{42}))
+	})
+
+	It("parses locations", func() {
+		Expect(parseLocation("\t/home/foo/test.go:6 +0x28")).To(Equal("/home/foo/test.go:6"))
+		Expect(parseLocation("\t/home/foo/test.go:6")).To(Equal("/home/foo/test.go:6"))
+	})
+
+	It("splits locations", func() {
+		file, line := SplitLocation("C:/foo.go:42")
+		Expect(file).To(Equal("C:/foo.go"))
+		Expect(line).To(Equal(42))
+		file, line = SplitLocation("foo.go")
+		Expect(file).To(Equal("foo.go"))
+		Expect(line).To(BeZero())
+		file, line = SplitLocation("C:/foo.go")
+		Expect(file).To(Equal("C:/foo.go"))
+		Expect(line).To(BeZero())
+		file, line = SplitLocation("foo.go:+1")
+		Expect(file).To(Equal("foo.go:+1"))
+		Expect(line).To(BeZero())
+	})
+
+	It("returns structured creator frames", func() {
+		Expect(newCreatorFrame("", "")).To(BeZero())
+		Expect(newCreatorFrame("main.foo", "/tmp/main.go:5")).To(Equal(Frame{
+			Function: "main.foo", Location: "/tmp/main.go:5", File: "/tmp/main.go", Line: 5}))
+	})
+
+	It("parses all frames of a backtrace", func() {
+		frames, elided := parseFrames(`main.(*T).foo(0x0?, {0x1, 0x2})
+	/tmp/main.go:10 +0x3b
+main.main.func2(0x2a)
+	/tmp/main.go:16
+created by main.main in goroutine 1
+	/tmp/main.go:16 +0x11c
+`)
+		Expect(frames).To(Equal([]Frame{
+			{
+				Function: "main.(*T).foo",
+				RawArgs:  "0x0?, {0x1, 0x2}",
+				Args:     []uint64{0, 1, 2},
+				Location: "/tmp/main.go:10",
+				File:     "/tmp/main.go",
+				Line:     10,
+			},
+			{
+				Function: "main.main.func2",
+				RawArgs:  "0x2a",
+				Args:     []uint64{42},
+				Location: "/tmp/main.go:16",
+				File:     "/tmp/main.go",
+				Line:     16,
+			},
+		}))
+		Expect(elided).To(BeZero())
+	})
+
+	It("parses elision markers", func() {
+		n, ok := parseElision("...42 frames elided...")
+		Expect(ok).To(BeTrue())
+		Expect(n).To(Equal(42))
+		n, ok = parseElision("...additional frames elided...")
+		Expect(ok).To(BeTrue())
+		Expect(n).To(Equal(-1))
+		Expect(parseElision("...x frames elided...")).Error().To(BeFalse())
+		Expect(parseElision("main.main()")).Error().To(BeFalse())
+	})
+
+	It("counts elided frames", func() {
+		frames, elided := parseFrames(`main.recurse(0x2)
+	/tmp/main.go:10 +0x3b
+...42 frames elided...
+main.recurse(0x1)
+	/tmp/main.go:10 +0x3b
+created by main.main in goroutine 1
+	/tmp/main.go:16 +0x11c
+`)
+		Expect(frames).To(HaveLen(2))
+		Expect(elided).To(Equal(42))
+
+		frames, elided = parseFrames(`main.recurse(0x2)
+	/tmp/main.go:10 +0x3b
+...additional frames elided...
+`)
+		Expect(frames).To(HaveLen(1))
+		Expect(elided).To(Equal(-1))
+	})
+
+})
@@ -0,0 +1,126 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// addCorpusSeeds adds the dumps from the goroutine dump corpus as seeds.
+func addCorpusSeeds(f *testing.F) {
+	dumps, _ := filepath.Glob("testdata/corpus/*.dump")
+	for _, dump := range dumps {
+		stacks, err := os.ReadFile(dump)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(stacks)
+	}
+}
+
+// checkParseError fails the fuzz test if the specified error isn't a
+// ParseError, or if it stems from a runtime error, such as an out-of-range
+// index, instead of a deliberate diagnosis of malformed input.
+func checkParseError(t *testing.T, err error) {
+	if err == nil {
+		return
+	}
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *ParseError, got %T: %s", err, err)
+	}
+	if strings.HasPrefix(perr.Msg, "runtime error") {
+		t.Fatalf("parser bug: %s", err)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	addCorpusSeeds(f)
+	f.Add([]byte("goroutine 1 [running]:\n"))
+	f.Add([]byte("goroutine 1 [running]:\nmain.main(\n"))
+	f.Fuzz(func(t *testing.T, dump []byte) {
+		_, err := Parse(dump)
+		checkParseError(t, err)
+	})
+}
+
+func FuzzParseHeader(f *testing.F) {
+	f.Add("goroutine 42 [chan receive]:")
+	f.Add("goroutine 666 gp=0xc000002380 m=0 mp=0x5a4840 [chan receive, 2 minutes]:")
+	f.Add("goroutine 7 [sleep] bubble=3:")
+	f.Add("goroutine 1 [")
+	f.Fuzz(func(t *testing.T, header string) {
+		_, err := ParseHeader(header)
+		checkParseError(t, err)
+		_, err = Parse([]byte(header + "\nmain.main()\n\t/home/foo/main.go:1 +0x1\n"))
+		checkParseError(t, err)
+	})
+}
+
+func FuzzParseBacktrace(f *testing.F) {
+	f.Add("main.foo(0x1, {0x2, 0x3}, ...)\n\t/home/foo/main.go:6 +0x28\n" +
+		"...42 frames elided...\ncreated by main.main in goroutine 1\n\t/home/foo/main.go:5 +0x64\n")
+	f.Add("main.inner(...)\n\t/tmp/main.go:10\n...additional frames elided...\n")
+	f.Add("created by \n\t")
+	f.Fuzz(func(t *testing.T, backtrace string) {
+		_, _ = parseFrames(backtrace)
+		_, _ = FindCreator(backtrace)
+		_ = findCreatorID(backtrace)
+		_, _, err := ParseBacktrace(bufio.NewReader(strings.NewReader(backtrace)))
+		checkParseError(t, err)
+		_, err = Parse([]byte("goroutine 1 [running]:\n" + backtrace))
+		checkParseError(t, err)
+	})
+}
+
+func FuzzParseCreator(f *testing.F) {
+	f.Add("main.main in goroutine 1", "\t/home/foo/main.go:5 +0x64")
+	f.Add("main.main", "\t/home/foo/main.go:5")
+	f.Add("", "")
+	f.Fuzz(func(t *testing.T, creator string, location string) {
+		backtrace := "main.foo()\n\t/home/foo/main.go:6 +0x28\n" +
+			backtraceGoroutineCreator + creator + "\n" + location + "\n"
+		_, _ = FindCreator(backtrace)
+		_ = findCreatorID(backtrace)
+		_, err := Parse([]byte("goroutine 1 [running]:\n" + backtrace))
+		checkParseError(t, err)
+	})
+}
+
+func FuzzParseState(f *testing.F) {
+	f.Add("chan receive (durable), 12 minutes, locked to thread")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, state string) {
+		_, _, _ = ParseState(state)
+	})
+}
+
+func FuzzDumpScanner(f *testing.F) {
+	addCorpusSeeds(f)
+	f.Add([]byte("log line\ngoroutine 1 [running]:\nmain.main()\n\t/main.go:1 +0x1\n\nmore log\n"))
+	f.Fuzz(func(t *testing.T, text []byte) {
+		s := NewDumpScanner(bytes.NewReader(text))
+		for s.Scan() {
+			_ = s.Goroutines()
+		}
+		checkParseError(t, errors.Unwrap(s.Err()))
+	})
+}
@@ -71,13 +71,21 @@ import (
 //
 // Please note that the State field never contains the opening and closing
 // square brackets as used in plain stack dumps.
+//
+// Instead of comparing State strings directly, please consider using
+// BaseState, IsBlocked, et cetera, which cater for the subtle differences in
+// State descriptions between Go versions.
 type Goroutine struct {
-	ID              uint64 // unique goroutine ID ("goid" in Go's runtime parlance)
-	State           string // goroutine state, such as "running"
-	TopFunction     string // topmost function on goroutine's stack
-	CreatorFunction string // name of function creating this goroutine, if any
-	BornAt          string // location where the goroutine was started from, if any; format "file-path:line-number"
-	Backtrace       string // goroutine's backtrace (of the stack)
+	ID              uint64  // unique goroutine ID ("goid" in Go's runtime parlance)
+	State           string  // goroutine state, such as "running"
+	TopFunction     string  // topmost function on goroutine's stack
+	CreatorFunction string  // name of function creating this goroutine, if any
+	CreatorID       uint64  // ID of the goroutine creating this goroutine, if known (Go 1.21 and later)
+	BornAt          string  // location where the goroutine was started from, if any; format "file-path:line-number"
+	CreatorFrame    Frame   // creator function and location as a structured frame; zero if there is no creator
+	Backtrace       string  // goroutine's backtrace (of the stack)
+	Frames          []Frame // parsed function call frames of the backtrace, topmost first
+	ElidedFrames    int     // number of frames elided from the backtrace; -1 if unknown
 }
 
 // String returns a short textual description of this goroutine, but without the
@@ -93,15 +101,6 @@ func (g Goroutine) String() string {
 	return s
 }
 
-// GomegaString returns the Gomega struct representation of a Goroutine, but
-// without a potentially rather lengthy backtrace. This Gomega object value
-// dumps getting happily truncated as to become more or less useless.
-func (g Goroutine) GomegaString() string {
-	return fmt.Sprintf(
-		"{ID: %d, State: %q, TopFunction: %q, CreatorFunction: %q, BornAt: %q}",
-		g.ID, g.State, g.TopFunction, g.CreatorFunction, g.BornAt)
-}
-
 // Goroutines returns information about all goroutines.
 func Goroutines() []Goroutine {
 	return goroutines(true)
@@ -125,74 +124,255 @@ func goroutines(all bool) []Goroutine {
 // by runtime.Stack() and then returns a list of Goroutine descriptions based on
 // the dump.
 func parseStack(stacks []byte) []Goroutine {
-	gs := []Goroutine{}
-	r := bufio.NewReader(bytes.NewReader(stacks))
-	for {
+	return parseStackInto([]Goroutine{}, stacks)
+}
+
+// parseStackInto parses the stack dump of one or multiple goroutines, appending
+// the Goroutine descriptions to the specified slice, and then returns the
+// updated slice.
+func parseStackInto(gs []Goroutine, stacks []byte) []Goroutine {
+	return parseStackAt(gs, stacks, &position{})
+}
+
+// parseStackAt parses the stack dump of one or multiple goroutines, appending
+// the Goroutine descriptions to the specified slice, and then returns the
+// updated slice. While parsing, parseStackAt keeps track of the line currently
+// being parsed, so that callers recovering from parsing panics can tell where
+// in the dump parsing failed.
+func parseStackAt(gs []Goroutine, stacks []byte, pos *position) []Goroutine {
+	// Dumps produced on Windows might have found their way to us with CRLF
+	// line endings, so normalize them first.
+	if bytes.IndexByte(stacks, '\r') >= 0 {
+		stacks = bytes.ReplaceAll(stacks, []byte("\r\n"), []byte("\n"))
+	}
+	// Only the text of each goroutine record gets copied from the dump, as the
+	// record's backtrace is retained in the resulting Goroutine anyway; all
+	// other strings are sliced from this copy, or interned.
+	for len(stacks) > 0 {
+		n := recordLen(stacks)
+		record := string(stacks[:n])
+		stacks = stacks[n:]
 		// We expect a line describing a new "goroutine", everything else is a
-		// failure. And yes, if we get an EOF already with this line, bail out.
-		line, err := r.ReadString('\n')
-		if err == io.EOF {
+		// failure.
+		nlpos := strings.IndexByte(record, '\n')
+		if nlpos < 0 {
+			// A dangling goroutine header at the very end of a truncated dump
+			// must not silently disappear.
+			if strings.TrimSpace(record) != "" {
+				pos.advance(record)
+				panic("truncated goroutine record: missing backtrace")
+			}
 			break
 		}
-		g := new(line)
-		// Read the rest ... that is, the backtrace for this goroutine.
-		g.TopFunction, g.Backtrace = parseGoroutineBacktrace(r)
+		header, backtrace := record[:nlpos+1], record[nlpos+1:]
+		pos.advance(header)
+		g := new(header)
+		g.TopFunction = findTopFunctionAt(backtrace, len(stacks) == 0, pos)
+		g.Backtrace = backtrace
 		if strings.HasSuffix(g.Backtrace, "\n\n") {
 			g.Backtrace = g.Backtrace[:len(g.Backtrace)-1]
 		}
-		g.CreatorFunction, g.BornAt = findCreator(g.Backtrace)
+		g.CreatorFunction, g.BornAt = FindCreator(g.Backtrace)
+		g.CreatorFrame = newCreatorFrame(g.CreatorFunction, g.BornAt)
+		g.CreatorID = findCreatorID(g.Backtrace)
+		g.Frames, g.ElidedFrames = parseFrames(g.Backtrace)
+		internGoroutine(&g)
 		gs = append(gs, g)
 	}
 	return gs
 }
 
+// recordLen returns the length of the goroutine record at the beginning of the
+// specified stack dump, that is, the length of its header line together with
+// the backtrace lines up to the next goroutine header line, or the end of the
+// dump.
+func recordLen(stacks []byte) int {
+	end := 0
+	for {
+		nlpos := bytes.IndexByte(stacks[end:], '\n')
+		if nlpos < 0 {
+			return len(stacks)
+		}
+		end += nlpos + 1
+		if bytes.HasPrefix(stacks[end:], []byte(backtraceGoroutineHeader)) {
+			return end
+		}
+	}
+}
+
+// findTopFunctionAt returns the name of the topmost function in the specified
+// backtrace, skipping elision markers, while advancing the specified position
+// with each backtrace line. If the backtrace isn't followed by another
+// goroutine record, because it is at the end of the dump, then the backtrace
+// must not be missing.
+func findTopFunctionAt(backtrace string, last bool, pos *position) (topFn string) {
+	for rest := backtrace; rest != ""; {
+		line := rest
+		if nlpos := strings.IndexByte(rest, '\n'); nlpos >= 0 {
+			line = rest[:nlpos+1]
+		}
+		rest = rest[len(line):]
+		pos.advance(line)
+		if topFn == "" {
+			topFn = parseTopFunction(line)
+		}
+	}
+	if topFn == "" && last && (backtrace == "" || strings.HasSuffix(backtrace, "\n")) {
+		// Reaching the end of the dump we've run out of lines.
+		pos.advance("")
+		panic("truncated goroutine record: missing backtrace")
+	}
+	return topFn
+}
+
+// parseTopFunction returns the name of the function called in the specified
+// backtrace line, or an empty name for an elision marker line. It panics if
+// the line neither is an elision marker nor a function call.
+func parseTopFunction(line string) string {
+	if _, elision := parseElision(line); elision {
+		return ""
+	}
+	line = /*sic!*/ strings.TrimSpace(line)
+	if line == "" {
+		panic("truncated goroutine record: missing backtrace")
+	}
+	idx := strings.LastIndex(line, "(")
+	if idx <= 0 {
+		panic(fmt.Sprintf("invalid function call stack entry: %q", line))
+	}
+	return line[:idx]
+}
+
 // new takes a goroutine line from a stack dump and returns a Goroutine object
 // based on the information contained in the dump.
 func new(s string) Goroutine {
 	s = strings.TrimSuffix(s, ":\n")
-	fields := strings.SplitN(s, " ", 3)
-	if len(fields) != 3 {
+	idfield, state, ok := splitHeader(s)
+	if !ok {
 		panic(fmt.Sprintf("invalid stack header: %q", s))
 	}
-	id, err := strconv.ParseUint(fields[1], 10, 64)
+	id, err := strconv.ParseUint(idfield, 10, 64)
 	if err != nil {
-		panic(fmt.Sprintf("invalid stack header ID: %q, header: %q", fields[1], s))
+		panic(fmt.Sprintf("invalid stack header ID: %q, header: %q", idfield, s))
+	}
+	// With GOTRACEBACK=system and higher, the goroutine ID is followed by
+	// additional runtime-internal details before the bracketed state.
+	// Future Go versions might add further attributes after the bracketed
+	// state, so the state ends with the last closing bracket.
+	if idx := strings.Index(state, "["); idx > 0 {
+		state = state[idx:]
 	}
-	state := strings.TrimSuffix(strings.TrimPrefix(fields[2], "["), "]")
+	if idx := strings.LastIndex(state, "]"); idx > 0 {
+		state = state[:idx+1]
+	}
+	state = strings.TrimSuffix(strings.TrimPrefix(state, "["), "]")
 	return Goroutine{ID: id, State: state}
 }
 
+// splitHeader splits a goroutine header line into its ID field and the
+// remaining details, scanning the line in place instead of splitting it into
+// freshly allocated fields. The leading "goroutine" word isn't checked, as
+// only its separating space is relevant.
+func splitHeader(s string) (id string, details string, ok bool) {
+	start := strings.IndexByte(s, ' ')
+	if start < 0 {
+		return "", "", false
+	}
+	end := strings.IndexByte(s[start+1:], ' ')
+	if end < 0 {
+		return "", "", false
+	}
+	end += start + 1
+	return s[start+1 : end], s[end+1:], true
+}
+
 // Beginning of line indicating the creator of a Goroutine, if any. This
 // indication is missing for the main goroutine as it appeared in a big bang or
 // something similar.
 const backtraceGoroutineCreator = "created by "
 
-// findCreator solves the great mystery of Gokind, answering the question of who
-// created this goroutine? Given a backtrace, that is.
-func findCreator(backtrace string) (creator, location string) {
+// Separator between the creator function name and the ID of the creating
+// goroutine in a "created by" line.
+const backtraceCreatorGoroutine = " in goroutine "
+
+// FindCreator solves the great mystery of Gokind, answering the question of who
+// created this goroutine? Given a backtrace, that is. FindCreator returns the
+// name of the creator function and the location of the "go" statement in
+// "file-path:line-number" format, as found in the last "created by" entry of
+// the specified backtrace. The creating goroutine's ID, as added by Go 1.21 and
+// later, is not part of the returned creator function name. FindCreator
+// returns empty strings if the backtrace doesn't name a creator, such as for
+// the main goroutine, or if the creator entry is incomplete.
+func FindCreator(backtrace string) (creator, location string) {
 	pos := strings.LastIndex(backtrace, backtraceGoroutineCreator)
 	if pos < 0 {
 		return
 	}
-	// Split the "created by ..." line from the following line giving us the
+	// Separate the "created by ..." line from the following line giving us the
 	// (indented) file name:line number and the hex offset of the call location
 	// within the function.
-	details := strings.SplitN(backtrace[pos+len(backtraceGoroutineCreator):], "\n", 3)
-	if len(details) < 2 {
+	creatorLine, rest, ok := nextLine(backtrace[pos+len(backtraceGoroutineCreator):])
+	if !ok {
 		return
 	}
+	locationLine, _, _ := nextLine(rest)
 	// Split off the call location hex offset which is of no use to us, and only
 	// keep the file path and line number information. This will be useful for
 	// diagnosis, when dumping leaked goroutines.
-	offsetpos := strings.LastIndex(details[1], " +0x")
+	offsetpos := strings.LastIndex(locationLine, " +0x")
 	if offsetpos < 0 {
 		return
 	}
-	location = strings.TrimSpace(details[1][:offsetpos])
-	creator = details[0]
+	location = strings.TrimSpace(locationLine[:offsetpos])
+	// Since Go 1.21 the creator line additionally names the creating goroutine,
+	// as in "created by main.foo in goroutine 1".
+	creator = creatorLine
+	if idx := strings.Index(creator, backtraceCreatorGoroutine); idx >= 0 {
+		creator = creator[:idx]
+	}
 	return
 }
 
+// nextLine returns the first line of s without its line ending, as well as the
+// remaining text following this line. It returns false if s doesn't contain a
+// line ending, in which case line is all of s.
+func nextLine(s string) (line string, rest string, ok bool) {
+	nlpos := strings.IndexByte(s, '\n')
+	if nlpos < 0 {
+		return s, "", false
+	}
+	return s[:nlpos], s[nlpos+1:], true
+}
+
+// findCreatorID returns the ID of the goroutine that created the goroutine with
+// the specified backtrace. It returns zero if the backtrace doesn't contain
+// this information, as it is the case for Go versions before 1.21.
+func findCreatorID(backtrace string) uint64 {
+	pos := strings.LastIndex(backtrace, backtraceGoroutineCreator)
+	if pos < 0 {
+		return 0
+	}
+	creator := backtrace[pos:]
+	if nlpos := strings.IndexByte(creator, '\n'); nlpos >= 0 {
+		creator = creator[:nlpos]
+	}
+	idx := strings.Index(creator, backtraceCreatorGoroutine)
+	if idx < 0 {
+		return 0
+	}
+	// Tolerate any additional attributes following the goroutine ID that
+	// future Go versions might add.
+	creator = creator[idx+len(backtraceCreatorGoroutine):]
+	if end := strings.IndexFunc(creator, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+		creator = creator[:end]
+	}
+	id, err := strconv.ParseUint(creator, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
 // Beginning of header line introducing a (new) goroutine in a backtrace.
 const backtraceGoroutineHeader = "goroutine "
 
@@ -205,6 +385,12 @@ const backtraceGoroutineHeaderLen = len(backtraceGoroutineHeader)
 // header is NOT consumed so that callers can still read the next header from
 // the reader.
 func parseGoroutineBacktrace(r *bufio.Reader) (topFn string, backtrace string) {
+	return parseGoroutineBacktraceAt(r, &position{})
+}
+
+// parseGoroutineBacktraceAt works as parseGoroutineBacktrace, but additionally
+// advances the specified position with each line read.
+func parseGoroutineBacktraceAt(r *bufio.Reader, pos *position) (topFn string, backtrace string) {
 	bt := bytes.Buffer{}
 	// Read backtrace information belonging to this goroutine until we meet
 	// another goroutine header.
@@ -226,14 +412,11 @@ func parseGoroutineBacktrace(r *bufio.Reader) (topFn string, backtrace string) {
 			// decidedly panic now.
 			panic("parsing backtrace failed: " + err.Error())
 		}
-		// The first line after a goroutine header lists the "topmost" function.
+		pos.advance(line)
+		// The first line after a goroutine header lists the "topmost" function;
+		// elision markers are never function call lines, so skip them.
 		if topFn == "" {
-			line := /*sic!*/ strings.TrimSpace(line)
-			idx := strings.LastIndex(line, "(")
-			if idx <= 0 {
-				panic(fmt.Sprintf("invalid function call stack entry: %q", line))
-			}
-			topFn = line[:idx]
+			topFn = parseTopFunction(line)
 		}
 		// Always append the line read to the goroutine's backtrace.
 		bt.WriteString(line)
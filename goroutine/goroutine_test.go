@@ -16,8 +16,10 @@ package goroutine
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"io"
+	"os"
 	"strings"
 	"sync"
 	"testing/iotest"
@@ -77,6 +79,12 @@ main.main()
 			Expect(g.State).To(Equal("running"))
 		})
 
+		It("parses goroutine header with runtime-internal details", func() {
+			g := new("goroutine 666 gp=0xc000002380 m=0 mp=0x5a4840 [chan receive]:\n")
+			Expect(g.ID).To(Equal(uint64(666)))
+			Expect(g.State).To(Equal("chan receive"))
+		})
+
 		It("panics on malformed goroutine header", func() {
 			Expect(func() { _ = new("a") }).To(PanicWith(MatchRegexp(`invalid stack header: .*`)))
 			Expect(func() { _ = new("a b") }).To(PanicWith(MatchRegexp(`invalid stack header: .*`)))
@@ -109,6 +117,15 @@ main.main()
 			Expect(backtrace).To(Equal(stack))
 		})
 
+		It("skips elision markers when looking for the topmost function", func() {
+			r := bufio.NewReader(strings.NewReader("...42 frames elided...\n" + stack))
+			topF, _ := parseGoroutineBacktrace(r)
+			Expect(topF).To(Equal("runtime/debug.Stack"))
+
+			gs := parseStack([]byte(header + stack + "...additional frames elided...\n"))
+			Expect(gs).To(ConsistOf(HaveField("ElidedFrames", -1)))
+		})
+
 		It("panics on invalid function call stack entry", func() {
 			r := bufio.NewReader(strings.NewReader(`main.main
 	/somewhere/prog.go:123 +0x666
@@ -122,6 +139,8 @@ main.main()
 					iotest.ErrReader(errors.New("foo failure"))))
 			}).To(PanicWith("parsing backtrace failed: foo failure"))
 
+			// Lines longer than the reader's buffer are fine, but the reader
+			// failing midway isn't.
 			Expect(func() {
 				parseGoroutineBacktrace(
 					bufio.NewReaderSize(
@@ -142,27 +161,51 @@ main.main()
 				HaveField("ID", uint64(666)),
 				HaveField("State", "running"),
 				HaveField("TopFunction", "runtime/debug.Stack"),
-				HaveField("Backtrace", stack)))
+				HaveField("Backtrace", stack),
+				HaveField("Frames", HaveLen(3))))
 		})
 
 		It("finds its Creator", func() {
-			creator, location := findCreator(`
+			creator, location := FindCreator(`
 goroutine 42 [chan receive]:
 main.foo.func1()
 		/home/foo/test.go:6 +0x28
 created by main.foo
 		/home/foo/test.go:5 +0x64
+`)
+			Expect(creator).To(Equal("main.foo"))
+			Expect(location).To(Equal("/home/foo/test.go:5"))
+
+			creator, location = FindCreator(`
+goroutine 42 [chan receive]:
+main.foo.func1()
+		/home/foo/test.go:6 +0x28
+created by main.foo in goroutine 1
+		/home/foo/test.go:5 +0x64
 `)
 			Expect(creator).To(Equal("main.foo"))
 			Expect(location).To(Equal("/home/foo/test.go:5"))
 		})
 
+		It("finds its creator's ID", func() {
+			Expect(findCreatorID(`
+main.foo.func1()
+		/home/foo/test.go:6 +0x28
+created by main.foo in goroutine 42
+		/home/foo/test.go:5 +0x64
+`)).To(Equal(uint64(42)))
+			Expect(findCreatorID("created by main.foo in goroutine 42")).To(Equal(uint64(42)))
+			Expect(findCreatorID("created by main.foo in goroutine foo\n")).To(BeZero())
+			Expect(findCreatorID("created by main.foo\n")).To(BeZero())
+			Expect(findCreatorID("")).To(BeZero())
+		})
+
 		It("handles missing or invalid creator information", func() {
-			creator, location := findCreator("")
+			creator, location := FindCreator("")
 			Expect(creator).To(BeEmpty())
 			Expect(location).To(BeEmpty())
 
-			creator, location = findCreator(`
+			creator, location = FindCreator(`
 goroutine 42 [chan receive]:
 main.foo.func1()
 		/home/foo/test.go:6 +0x28
@@ -170,7 +213,7 @@ created by`)
 			Expect(creator).To(BeEmpty())
 			Expect(location).To(BeEmpty())
 
-			creator, location = findCreator(`
+			creator, location = FindCreator(`
 goroutine 42 [chan receive]:
 main.foo.func1()
 		/home/foo/test.go:6 +0x28
@@ -178,7 +221,7 @@ created by main.foo`)
 			Expect(creator).To(BeEmpty())
 			Expect(location).To(BeEmpty())
 
-			creator, location = findCreator(`
+			creator, location = FindCreator(`
 goroutine 42 [chan receive]:
 main.foo.func1()
 		/home/foo/test.go:6 +0x28
@@ -191,6 +234,47 @@ created by main.foo
 
 	})
 
+	Context("Windows-produced dumps", func() {
+
+		It("parses dumps with CRLF line endings and drive letters", func() {
+			dump, err := os.ReadFile("testdata/windows-crlf.dump")
+			Expect(err).NotTo(HaveOccurred())
+			gs := parseStack(dump)
+			Expect(gs).To(HaveLen(2))
+			Expect(gs[0]).To(And(
+				HaveField("ID", uint64(1)),
+				HaveField("State", "running"),
+				HaveField("TopFunction", "main.main"),
+				HaveField("Backtrace", "main.main()\n\tC:/Users/gopher/proj/main.go:10 +0x27\n"),
+				HaveField("Frames", ConsistOf(HaveField("Location", "C:/Users/gopher/proj/main.go:10")))))
+			Expect(gs[1]).To(And(
+				HaveField("ID", uint64(42)),
+				HaveField("State", "chan receive"),
+				HaveField("TopFunction", "main.foo.func1"),
+				HaveField("CreatorFunction", "main.foo"),
+				HaveField("BornAt", `C:\Users\gopher\proj\foo.go:5`),
+				HaveField("CreatorFrame", Equal(Frame{
+					Function: "main.foo",
+					Location: `C:\Users\gopher\proj\foo.go:5`,
+					File:     `C:\Users\gopher\proj\foo.go`,
+					Line:     5,
+				})),
+				HaveField("Frames", ConsistOf(And(
+					HaveField("Args", []uint64{0xc000012345}),
+					HaveField("Location", `C:\Users\gopher\proj\foo.go:6`))))))
+		})
+
+		It("scans dumps with CRLF line endings", func() {
+			dump, err := os.ReadFile("testdata/windows-crlf.dump")
+			Expect(err).NotTo(HaveOccurred())
+			s := NewDumpScanner(bytes.NewReader(dump))
+			Expect(s.Scan()).To(BeTrue())
+			Expect(s.Goroutines()).To(HaveLen(2))
+			Expect(s.Goroutines()[1].BornAt).To(Equal(`C:\Users\gopher\proj\foo.go:5`))
+		})
+
+	})
+
 	Context("live", func() {
 
 		It("discovers current goroutine information", func() {
@@ -212,6 +296,7 @@ created by main.foo
 			g := <-ch
 			Expect(g.CreatorFunction).NotTo(BeEmpty(), "no creator: %s", g.Backtrace)
 			Expect(g.BornAt).NotTo(BeEmpty())
+			Expect(g.CreatorID).To(Equal(Current().ID))
 		})
 
 		It("discovers all goroutine information", func() {
@@ -0,0 +1,72 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import "sync"
+
+// maxInterned is the maximum number of canonical strings kept at any time.
+const maxInterned = 16384
+
+// interned maps strings to their canonical instances. As the names of
+// functions, goroutine states, and source locations repeat massively across
+// the goroutines of a process and across successive captures, sharing their
+// canonical instances cuts down on the memory held by snapshots. In order to
+// not grow without bounds in long-running processes, such as when monitoring
+// for leaks, the canonical instances are dropped in favor of a new generation
+// whenever there are too many of them. Snapshots still referencing strings of
+// an older generation are unaffected, they just don't share them anymore with
+// newer captures.
+var interned = struct {
+	sync.Mutex
+	strs map[string]string
+}{strs: map[string]string{}}
+
+// intern returns the canonical instance of the specified string. The canonical
+// instance never references the memory of a larger string, such as the stack
+// dump the specified string might have been sliced from.
+func intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	interned.Lock()
+	defer interned.Unlock()
+	if canonical, ok := interned.strs[s]; ok {
+		return canonical
+	}
+	if len(interned.strs) >= maxInterned {
+		interned.strs = map[string]string{}
+	}
+	canonical := string([]byte(s)) // copy, as strings.Clone needs Go 1.20
+	interned.strs[canonical] = canonical
+	return canonical
+}
+
+// internGoroutine replaces the often repeated strings of the specified
+// goroutine with their canonical instances; this doesn't include the
+// goroutine's backtrace.
+func internGoroutine(g *Goroutine) {
+	g.State = intern(g.State)
+	g.TopFunction = intern(g.TopFunction)
+	g.CreatorFunction = intern(g.CreatorFunction)
+	g.BornAt = intern(g.BornAt)
+	g.CreatorFrame.Function = g.CreatorFunction
+	g.CreatorFrame.Location = g.BornAt
+	g.CreatorFrame.File = intern(g.CreatorFrame.File)
+	for idx := range g.Frames {
+		g.Frames[idx].Function = intern(g.Frames[idx].Function)
+		g.Frames[idx].Location = intern(g.Frames[idx].Location)
+		g.Frames[idx].File = intern(g.Frames[idx].File)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// stringData returns the address of the bytes of the specified string.
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+var _ = Describe("interning", func() {
+
+	It("interns strings", func() {
+		Expect(intern("")).To(BeEmpty())
+		dump := "foo.bar foo.bar"
+		s1, s2 := intern(dump[:7]), intern(dump[8:])
+		Expect(s1).To(Equal("foo.bar"))
+		Expect(stringData(s1)).To(Equal(stringData(s2)))
+		Expect(stringData(s1)).NotTo(Equal(stringData(dump)))
+	})
+
+	It("starts a new generation when full", func() {
+		s1 := intern("foo.bar")
+		for idx := 0; idx < maxInterned; idx++ {
+			_ = intern(strconv.Itoa(idx))
+		}
+		interned.Lock()
+		Expect(len(interned.strs)).To(BeNumerically("<=", maxInterned))
+		interned.Unlock()
+		Expect(stringData(intern("foo.bar"))).NotTo(Equal(stringData(s1)))
+	})
+
+	It("interns across parsed goroutines", func() {
+		gs := parseStack([]byte(`goroutine 42 [chan receive]:
+main.foo.func1()
+	/home/foo/test.go:6 +0x28
+created by main.foo in goroutine 1
+	/home/foo/test.go:5 +0x64
+
+goroutine 666 [chan receive]:
+main.foo.func1()
+	/home/foo/test.go:6 +0x28
+created by main.foo in goroutine 1
+	/home/foo/test.go:5 +0x64
+`))
+		Expect(gs).To(HaveLen(2))
+		for _, sel := range []func(g Goroutine) string{
+			func(g Goroutine) string { return g.State },
+			func(g Goroutine) string { return g.TopFunction },
+			func(g Goroutine) string { return g.CreatorFunction },
+			func(g Goroutine) string { return g.BornAt },
+			func(g Goroutine) string { return g.Frames[0].Function },
+			func(g Goroutine) string { return g.Frames[0].Location },
+		} {
+			Expect(stringData(sel(gs[0]))).To(Equal(stringData(sel(gs[1]))))
+		}
+	})
+
+})
@@ -0,0 +1,73 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+// LightweightGoroutines returns information about all goroutines, but retains
+// only their IDs, states, topmost functions, and creator information. In
+// particular, the returned goroutines lack their (potentially rather lengthy)
+// backtraces as well as the parsed call frames. This cuts down on memory
+// when matchers and filters never need to look at the raw stacks, such as when
+// repeatedly polling for leaks.
+//
+// Use Recapture to later retrieve the full information about, say, leaked
+// goroutines for the final failure message.
+func LightweightGoroutines() []Goroutine {
+	gs := Goroutines()
+	for idx := range gs {
+		gs[idx] = gs[idx].lightweight()
+	}
+	return gs
+}
+
+// IsLightweight returns true if this goroutine lacks its backtrace, such as
+// when it has been captured by LightweightGoroutines.
+func (g Goroutine) IsLightweight() bool {
+	return g.Backtrace == ""
+}
+
+// Recapture returns the full information about the specified goroutines,
+// including their backtraces, by capturing the goroutines of the current
+// process again. Goroutines that have ended in the meantime, as well as
+// goroutines with a different creator than their namesakes in this process,
+// are returned unchanged. Please note that Recapture only makes sense for goroutines
+// captured from the current process, but not for goroutines from remote
+// processes or loaded snapshots.
+func Recapture(gs []Goroutine) []Goroutine {
+	current := map[uint64]Goroutine{}
+	for _, g := range Goroutines() {
+		current[g.ID] = g
+	}
+	recaptured := make([]Goroutine, len(gs))
+	for idx, g := range gs {
+		// As a safeguard against goroutine information not stemming from
+		// this process, also check the creator, as this never changes
+		// during a goroutine's lifetime.
+		if full, ok := current[g.ID]; ok && g.IsLightweight() &&
+			full.CreatorFunction == g.CreatorFunction && full.BornAt == g.BornAt {
+			g = full
+		}
+		recaptured[idx] = g
+	}
+	return recaptured
+}
+
+// lightweight returns a copy of this goroutine without its backtrace and
+// parsed call frames.
+func (g Goroutine) lightweight() Goroutine {
+	g.Backtrace = ""
+	g.Frames = nil
+	g.ElidedFrames = 0
+	return g
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("lightweight goroutines", func() {
+
+	It("captures goroutines without backtraces", func() {
+		gs := LightweightGoroutines()
+		Expect(gs).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"ID": Equal(Current().ID),
+		})))
+		for _, g := range gs {
+			Expect(g.IsLightweight()).To(BeTrue())
+			Expect(g.Frames).To(BeNil())
+			Expect(g.TopFunction).NotTo(BeEmpty())
+		}
+	})
+
+	It("recaptures full goroutine information", func() {
+		me := Current()
+		gone := Goroutine{ID: ^uint64(0), TopFunction: "main.gone"}
+		alien := Goroutine{ID: me.ID, TopFunction: "main.alien", CreatorFunction: "main.main"}
+		gs := Recapture([]Goroutine{me.lightweight(), gone, alien})
+		Expect(gs).To(HaveLen(3))
+		Expect(gs[2]).To(Equal(alien))
+		Expect(gs[0].ID).To(Equal(me.ID))
+		Expect(gs[0].IsLightweight()).To(BeFalse())
+		Expect(gs[0].Frames).NotTo(BeEmpty())
+		Expect(gs[1]).To(Equal(gone))
+	})
+
+})
@@ -0,0 +1,73 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"path"
+	"strings"
+)
+
+// IsVendored returns true if the specified source location, in the form of
+// "file-path:line-number" as used by Frame.Location and Goroutine.BornAt, is
+// inside a "vendor" or "third_party" directory.
+func IsVendored(location string) bool {
+	location = "/" + strings.ReplaceAll(location, "\\", "/")
+	return strings.Contains(location, "/vendor/") ||
+		strings.Contains(location, "/third_party/")
+}
+
+// IsGenerated returns true if the specified source location, in the form of
+// "file-path:line-number" as used by Frame.Location and Goroutine.BornAt, is
+// in a source file following the usual naming conventions for generated code,
+// such as "foo.pb.go" and "foo_gen.go".
+func IsGenerated(location string) bool {
+	file := path.Base(strings.ReplaceAll(location, "\\", "/"))
+	if colon := strings.LastIndex(file, ":"); colon >= 0 {
+		file = file[:colon]
+	}
+	return strings.HasSuffix(file, ".pb.go") || strings.HasSuffix(file, "_gen.go")
+}
+
+// stdRoots are the first elements of the standard library package paths, as
+// listed by "go list std", across the supported Go versions.
+var stdRoots = map[string]struct{}{
+	"archive": {}, "bufio": {}, "bytes": {}, "cmp": {}, "compress": {},
+	"container": {}, "context": {}, "crypto": {}, "database": {}, "debug": {},
+	"embed": {}, "encoding": {}, "errors": {}, "expvar": {}, "flag": {},
+	"fmt": {}, "go": {}, "hash": {}, "html": {}, "image": {}, "index": {},
+	"internal": {}, "io": {}, "iter": {}, "log": {}, "maps": {}, "math": {},
+	"mime": {}, "net": {}, "os": {}, "path": {}, "plugin": {}, "reflect": {},
+	"regexp": {}, "runtime": {}, "slices": {}, "sort": {}, "strconv": {},
+	"strings": {}, "structs": {}, "sync": {}, "syscall": {}, "testing": {},
+	"text": {}, "time": {}, "unicode": {}, "unique": {}, "unsafe": {},
+	"uuid": {}, "vendor": {}, "weak": {},
+}
+
+// IsStdFunction returns true if the specified fully qualified function name
+// belongs to a standard library package, such as "runtime.gopark" or
+// "net/http.(*Server).Serve". The first element of the package path must be
+// one of the first elements of standard library package paths, so that
+// functions of dot-less module paths, such as "mycorp/pkg.foo", as well as of
+// the "main" package are not considered to be standard library functions.
+func IsStdFunction(fname string) bool {
+	first := fname
+	if slash := strings.Index(first, "/"); slash >= 0 {
+		first = first[:slash]
+	} else if dot := strings.Index(first, "."); dot >= 0 {
+		first = first[:dot]
+	}
+	_, ok := stdRoots[first]
+	return ok
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("source origins", func() {
+
+	DescribeTable("classifies vendored locations",
+		func(location string, expected bool) {
+			Expect(IsVendored(location)).To(Equal(expected))
+		},
+		Entry(nil, "/home/foo/src/bar/vendor/example.com/baz/baz.go:42", true),
+		Entry(nil, "vendor/example.com/baz/baz.go:42", true),
+		Entry(nil, "/src/bar/third_party/baz/baz.go:42", true),
+		Entry(nil, "C:\\src\\bar\\vendor\\baz\\baz.go:42", true),
+		Entry(nil, "/src/bar/vendors/baz.go:42", false),
+		Entry(nil, "/src/bar/baz.go:42", false),
+	)
+
+	DescribeTable("classifies generated locations",
+		func(location string, expected bool) {
+			Expect(IsGenerated(location)).To(Equal(expected))
+		},
+		Entry(nil, "/src/api/service.pb.go:42", true),
+		Entry(nil, "/src/api/mocks_gen.go:42", true),
+		Entry(nil, "/src/api/mocks_gen.go", true),
+		Entry(nil, "/src/api/pb.go:42", false),
+		Entry(nil, "/src/api_gen.go/main.go:42", false),
+	)
+
+	DescribeTable("classifies standard library functions",
+		func(fname string, expected bool) {
+			Expect(IsStdFunction(fname)).To(Equal(expected))
+		},
+		Entry(nil, "runtime.gopark", true),
+		Entry(nil, "net/http.(*Server).Serve", true),
+		Entry(nil, "main.main", false),
+		Entry(nil, "example.com/foo.bar", false),
+		Entry(nil, "mycorp/pkg.foo", false),
+		Entry(nil, "mycorp.foo", false),
+		Entry(nil, "vendor/golang.org/x/net/http2/hpack.(*Decoder).Write", true),
+		Entry(nil, "", false),
+	)
+
+})
@@ -0,0 +1,123 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// ParseError describes where and why parsing a goroutine dump failed.
+type ParseError struct {
+	Line   int    // number of the offending line, starting with 1.
+	Offset int    // byte offset of the start of the offending line in the dump.
+	Text   string // the offending line, without its line ending.
+	Msg    string // description of the parsing problem.
+}
+
+// Error returns the description of the parsing problem together with the
+// offending line and its position in the dump.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s, at line %d, byte offset %d: %q", e.Msg, e.Line, e.Offset, e.Text)
+}
+
+// Parse parses a goroutine dump, such as returned by runtime.Stack(buf, true),
+// into goroutine descriptions. In contrast to capturing the goroutines of the
+// own process using Goroutines, which panics in case of malformed stack dumps
+// as this indicates a serious incompatibility with the Go runtime, Parse
+// returns a *ParseError for malformed dumps instead. Parse thus is suitable
+// for dumps beyond the control of the caller, such as dumps from log files or
+// other processes.
+//
+// Parse never panics, whatever the input: besides malformed headers and
+// backtrace lines, this also covers truncated records, over-long lines, and
+// invalid UTF-8 sequences, which are all reported as *ParseError.
+//
+// CRLF line endings are normalized into LF line endings before parsing, so the
+// byte offset in a ParseError refers to the normalized dump.
+func Parse(dump []byte) (gs []Goroutine, err error) {
+	pos := &position{}
+	defer recoverParseError(pos, &err)
+	return parseStackAt([]Goroutine{}, dump, pos), nil
+}
+
+// ParseHeader parses the header line introducing a goroutine in a goroutine
+// dump, such as "goroutine 42 [chan receive, 2 minutes]:", and returns a
+// Goroutine with only the ID and State fields set. A trailing line ending is
+// optional. ParseHeader tolerates the additional runtime-internal details
+// following the goroutine ID in dumps taken with GOTRACEBACK=system, as well as
+// additional attributes after the bracketed state. ParseHeader returns a
+// *ParseError if the line isn't a valid goroutine header.
+func ParseHeader(line string) (g Goroutine, err error) {
+	pos := &position{}
+	pos.advance(line)
+	defer recoverParseError(pos, &err)
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, backtraceGoroutineHeader) {
+		panic(fmt.Sprintf("invalid stack header: %q", line))
+	}
+	return new(line + "\n"), nil
+}
+
+// ParseBacktrace reads the backtrace of a single goroutine from r, that is, the
+// lines following a goroutine header, until either the end of input or the
+// next goroutine header. The next goroutine header is not consumed, so that
+// callers can continue with reading the next header from r. ParseBacktrace
+// returns the name of the topmost function, skipping any elided frames marker,
+// as well as the backtrace text. Use FindCreator to find the creator in the
+// backtrace. Backtrace lines can be of arbitrary length, independent of the
+// buffer size of r.
+//
+// ParseBacktrace returns a *ParseError if the backtrace is missing or its
+// topmost entry isn't a function call; the line numbers and byte offsets in
+// the ParseError then are relative to the start of the backtrace.
+func ParseBacktrace(r *bufio.Reader) (topFn string, backtrace string, err error) {
+	pos := &position{}
+	defer recoverParseError(pos, &err)
+	topFn, backtrace = parseGoroutineBacktraceAt(r, pos)
+	return topFn, backtrace, nil
+}
+
+// recoverParseError recovers from a parsing panic, turning it into a
+// *ParseError at the specified position, which is then returned in err.
+func recoverParseError(pos *position, err *error) {
+	if r := recover(); r != nil {
+		*err = &ParseError{
+			Line:   pos.line,
+			Offset: pos.offset,
+			Text:   pos.text,
+			Msg:    fmt.Sprint(r),
+		}
+	}
+}
+
+// position keeps track of the line currently being parsed in a goroutine
+// dump.
+type position struct {
+	line   int    // current line number, starting with 1.
+	offset int    // byte offset of the current line.
+	text   string // current line, without its line ending.
+	next   int    // byte offset of the next line.
+}
+
+// advance moves the position to the specified line, which has just been read
+// from the dump.
+func (p *position) advance(line string) {
+	p.line++
+	p.offset = p.next
+	p.next += len(line)
+	p.text = strings.TrimSuffix(line, "\n")
+}
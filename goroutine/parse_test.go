@@ -0,0 +1,159 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parsing dumps", func() {
+
+	const dump = `goroutine 1 [running]:
+main.main()
+	/tmp/main.go:10 +0x27
+
+goroutine 42 [chan receive]:
+main.foo.func1()
+	/tmp/main.go:6 +0x28
+created by main.foo in goroutine 1
+	/tmp/main.go:5 +0x64
+`
+
+	It("parses well-formed dumps", func() {
+		gs, err := Parse([]byte(dump))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(ConsistOf(
+			HaveField("ID", uint64(1)),
+			And(HaveField("ID", uint64(42)), HaveField("CreatorID", uint64(1)))))
+	})
+
+	It("parses empty dumps", func() {
+		Expect(Parse(nil)).To(BeEmpty())
+	})
+
+	It("reports malformed headers with their position", func() {
+		gs, err := Parse([]byte(dump + "\ngoroutine foo [running]:\nmain.main()\n"))
+		Expect(gs).To(BeNil())
+		var perr *ParseError
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Line).To(Equal(11))
+		Expect(perr.Offset).To(Equal(len(dump) + 1))
+		Expect(perr.Text).To(Equal("goroutine foo [running]:"))
+		Expect(perr.Msg).To(HavePrefix("invalid stack header ID: "))
+		Expect(err).To(MatchError(MatchRegexp(
+			`^invalid stack header ID: .*, at line 11, byte offset \d+: "goroutine foo \[running\]:"$`)))
+	})
+
+	It("reports bad stack entries with their position", func() {
+		_, err := Parse([]byte("goroutine 1 [running]:\nmain.main\n"))
+		Expect(err).To(MatchError(`invalid function call stack entry: "main.main", at line 2, byte offset 23: "main.main"`))
+	})
+
+	It("reports truncated records", func() {
+		_, err := Parse([]byte(dump + "\ngoroutine 666 [running]:"))
+		Expect(err).To(MatchError(MatchRegexp(
+			`^truncated goroutine record: missing backtrace, at line 11, .*`)))
+		_, err = Parse([]byte(dump + "\ngoroutine 666 [running]:\n"))
+		Expect(err).To(MatchError(MatchRegexp(
+			`^truncated goroutine record: missing backtrace, at line 12, .*`)))
+	})
+
+	It("reports positions in CRLF dumps with respect to the normalized dump", func() {
+		_, err := Parse([]byte("goroutine 1 [running]:\r\nfoo\r\n"))
+		Expect(err).To(BeAssignableToTypeOf(&ParseError{}))
+		Expect(err.(*ParseError).Offset).To(Equal(23))
+	})
+
+	It("parses individual headers", func() {
+		Expect(ParseHeader("goroutine 42 [chan receive, 2 minutes]:\n")).To(Equal(
+			Goroutine{ID: 42, State: "chan receive, 2 minutes"}))
+		Expect(ParseHeader("goroutine 666 gp=0xc000002380 m=0 mp=0x5a4840 [select]:\r\n")).To(Equal(
+			Goroutine{ID: 666, State: "select"}))
+		Expect(ParseHeader("goroutine 7 [sleep]")).To(Equal(Goroutine{ID: 7, State: "sleep"}))
+
+		_, err := ParseHeader("goroutine foo [running]:")
+		Expect(err).To(MatchError(MatchRegexp(
+			`^invalid stack header ID: .*, at line 1, byte offset 0: "goroutine foo \[running\]:"$`)))
+		Expect(ParseHeader("main.main()")).Error().To(MatchError(HavePrefix("invalid stack header: ")))
+		Expect(ParseHeader("")).Error().To(BeAssignableToTypeOf(&ParseError{}))
+	})
+
+	It("parses individual backtraces", func() {
+		r := bufio.NewReader(strings.NewReader(dump[len("goroutine 1 [running]:\n"):]))
+		topFn, backtrace, err := ParseBacktrace(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(topFn).To(Equal("main.main"))
+		Expect(backtrace).To(Equal("main.main()\n\t/tmp/main.go:10 +0x27\n\n"))
+		Expect(r.ReadString('\n')).To(Equal("goroutine 42 [chan receive]:\n"))
+		topFn, backtrace, err = ParseBacktrace(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(topFn).To(Equal("main.foo.func1"))
+		creator, location := FindCreator(backtrace)
+		Expect(creator).To(Equal("main.foo"))
+		Expect(location).To(Equal("/tmp/main.go:5"))
+
+		_, _, err = ParseBacktrace(bufio.NewReader(strings.NewReader("main.foo.func1()\n\t/tmp/main.go:6 +0x28\nmain.main\n")))
+		Expect(err).NotTo(HaveOccurred(), "only the topmost entry must be a function call")
+		_, _, err = ParseBacktrace(bufio.NewReader(strings.NewReader("\tfoo\nmain.main()\n")))
+		Expect(err).To(MatchError(`invalid function call stack entry: "foo", at line 1, byte offset 0: "\tfoo"`))
+		_, _, err = ParseBacktrace(bufio.NewReader(strings.NewReader("\n")))
+		Expect(err).To(MatchError(HavePrefix("truncated goroutine record: missing backtrace")))
+	})
+
+	It("parses multi-kilobyte frames", func() {
+		fn := "example.org/" + strings.Repeat("very/long/", 1000) + "pkg.(*T).method"
+		file := "/" + strings.Repeat("deeply/nested/", 1000) + "t.go"
+		backtrace := fn + "(0xc000012345, 0x2a)\n\t" + file + ":42 +0x42\n" +
+			"created by " + fn + " in goroutine 1\n\t" + file + ":666 +0x666\n"
+		dump := "goroutine 42 [chan receive]:\n" + backtrace + "\ngoroutine 1 [running]:\nmain.main()\n\t/tmp/main.go:10 +0x27\n"
+
+		gs, err := Parse([]byte(dump))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(HaveLen(2))
+		Expect(gs[0]).To(And(
+			HaveField("TopFunction", fn),
+			HaveField("CreatorFunction", fn),
+			HaveField("BornAt", file+":666"),
+			HaveField("Frames", ConsistOf(And(
+				HaveField("File", file),
+				HaveField("Line", 42),
+				HaveField("Args", ConsistOf(uint64(0xc000012345), uint64(0x2a))))))))
+
+		r := bufio.NewReaderSize(strings.NewReader(backtrace+"goroutine 1 [running]:\n"), 16)
+		topFn, bt, err := ParseBacktrace(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(topFn).To(Equal(fn))
+		Expect(bt).To(Equal(backtrace))
+		Expect(r.ReadString('\n')).To(Equal("goroutine 1 [running]:\n"))
+
+		s := NewDumpScanner(strings.NewReader("log line\n" + dump + "log line\n"))
+		Expect(s.Scan()).To(BeTrue())
+		Expect(s.Goroutines()).To(ConsistOf(
+			HaveField("TopFunction", fn),
+			HaveField("TopFunction", "main.main")))
+		Expect(s.Scan()).To(BeFalse())
+		Expect(s.Err()).NotTo(HaveOccurred())
+	})
+
+	It("keeps panicking for live captures", func() {
+		Expect(func() { _ = parseStack([]byte("goroutine foo bar:\n")) }).To(Panic())
+	})
+
+})
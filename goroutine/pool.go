@@ -0,0 +1,78 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"runtime"
+	"sync"
+)
+
+// goroutinesPool recycles the slices of goroutines returned by
+// PooledGoroutines and handed back by ReleaseGoroutines. The pool stores
+// pointers to slices in order to avoid allocations when putting slices back.
+var goroutinesPool = sync.Pool{
+	New: func() interface{} { return &[]Goroutine{} },
+}
+
+// stackBufferPool recycles the buffers for the stack dumps of
+// PooledGoroutines. Once parsed, the stack dump isn't referenced anymore, so
+// its buffer can be immediately recycled.
+var stackBufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, startStackBufferSize)
+		return &buffer
+	},
+}
+
+// PooledGoroutines returns information about all goroutines, the same as
+// Goroutines, but reuses the slices previously handed back using
+// ReleaseGoroutines, as well as reusing the internal stack dump buffers. This
+// reduces pressure on the garbage collector in tests that repeatedly capture
+// goroutines in tight polling loops, such as leak-sensitive performance tests.
+//
+// Please note that using PooledGoroutines is optional and only makes sense in
+// combination with ReleaseGoroutines:
+//
+//   gs := goroutine.PooledGoroutines()
+//   defer goroutine.ReleaseGoroutines(gs)
+func PooledGoroutines() []Goroutine {
+	bufp := stackBufferPool.Get().(*[]byte)
+	defer stackBufferPool.Put(bufp)
+	for {
+		if n := runtime.Stack(*bufp, true); n < len(*bufp) {
+			gsp := goroutinesPool.Get().(*[]Goroutine)
+			return parseStackInto((*gsp)[:0], (*bufp)[:n])
+		}
+		*bufp = make([]byte, 2*len(*bufp))
+	}
+}
+
+// ReleaseGoroutines hands the specified slice of goroutines, as returned by
+// PooledGoroutines, back for reuse. The caller must not use the slice, nor any
+// of its elements, after releasing it. Releasing a nil or empty slice is a
+// no-op.
+func ReleaseGoroutines(gs []Goroutine) {
+	if cap(gs) == 0 {
+		return
+	}
+	// Drop the references to the (non-interned) backtraces and frames, so
+	// that they can be garbage collected even while the slice is pooled.
+	gs = gs[:cap(gs)]
+	for idx := range gs {
+		gs[idx] = Goroutine{}
+	}
+	gs = gs[:0]
+	goroutinesPool.Put(&gs)
+}
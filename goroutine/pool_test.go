@@ -0,0 +1,56 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("pooled goroutines", func() {
+
+	It("captures goroutines", func() {
+		gs := PooledGoroutines()
+		defer ReleaseGoroutines(gs)
+		Expect(gs).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"ID":        Equal(Current().ID),
+			"Backtrace": Not(BeEmpty()),
+		})))
+	})
+
+	It("clears released goroutines", func() {
+		gs := PooledGoroutines()
+		Expect(gs).NotTo(BeEmpty())
+		all := gs[:cap(gs)]
+		ReleaseGoroutines(gs)
+		for _, g := range all {
+			Expect(g).To(BeZero())
+		}
+		Expect(func() { ReleaseGoroutines(nil) }).NotTo(Panic())
+	})
+
+	It("reuses released slices", func() {
+		// sync.Pool doesn't give any guarantees, so we only check that
+		// repeatedly capturing and releasing works.
+		for i := 0; i < 10; i++ {
+			gs := PooledGoroutines()
+			Expect(gs).NotTo(BeEmpty())
+			Expect(gs[0].ID).NotTo(BeZero())
+			ReleaseGoroutines(gs)
+		}
+	})
+
+})
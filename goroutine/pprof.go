@@ -0,0 +1,139 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// PprofPath is the default path of the goroutine profile of the
+// net/http/pprof package.
+const PprofPath = "/debug/pprof/goroutine"
+
+// PprofOption configures fetching goroutine dumps from remote pprof endpoints
+// with FromPprofURL.
+type PprofOption func(*pprofConfig)
+
+type pprofConfig struct {
+	client   *http.Client
+	tlsconf  *tls.Config
+	username string
+	password string
+	token    string
+}
+
+// WithHTTPClient uses the specified HTTP client instead of
+// http.DefaultClient. WithHTTPClient takes precedence over WithTLSConfig.
+func WithHTTPClient(client *http.Client) PprofOption {
+	return func(c *pprofConfig) { c.client = client }
+}
+
+// WithTLSConfig uses the specified TLS configuration when connecting to the
+// remote pprof endpoint, such as for trusting a test CA or presenting a client
+// certificate.
+func WithTLSConfig(tlsconf *tls.Config) PprofOption {
+	return func(c *pprofConfig) { c.tlsconf = tlsconf }
+}
+
+// WithBasicAuth authenticates using the specified username and password.
+func WithBasicAuth(username, password string) PprofOption {
+	return func(c *pprofConfig) { c.username, c.password = username, password }
+}
+
+// WithBearerToken authenticates using the specified bearer token.
+func WithBearerToken(token string) PprofOption {
+	return func(c *pprofConfig) { c.token = token }
+}
+
+// FromPprofURL fetches the goroutine dump from the net/http/pprof endpoint of
+// a (remote) process and returns information about all its goroutines. If the
+// specified URL has an empty path, then PprofPath is used. FromPprofURL always
+// requests the goroutine dump in the runtime.Stack format, as using the
+// "debug=2" query parameter.
+//
+// This allows to check for goroutine leaks in services under integration test
+// instead of only in the test binary itself, for instance:
+//
+//   Eventually(func() ([]goroutine.Goroutine, error) {
+//       return goroutine.FromPprofURL(ctx, "http://localhost:6060")
+//   }).ShouldNot(HaveLeaked(baseline))
+func FromPprofURL(ctx context.Context, pprofurl string, opts ...PprofOption) ([]Goroutine, error) {
+	conf := pprofConfig{}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	u, err := url.Parse(pprofurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pprof URL: %w", err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = PprofPath
+	}
+	query := u.Query()
+	query.Set("debug", "2")
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pprof request: %w", err)
+	}
+	if conf.username != "" || conf.password != "" {
+		req.SetBasicAuth(conf.username, conf.password)
+	}
+	if conf.token != "" {
+		req.Header.Set("Authorization", "Bearer "+conf.token)
+	}
+	client := conf.client
+	if client == nil {
+		client = http.DefaultClient
+		if conf.tlsconf != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = conf.tlsconf
+			// Don't leave behind the idle connection goroutines of this
+			// one-shot transport, as they otherwise would show up as leaks.
+			defer transport.CloseIdleConnections()
+			client = &http.Client{Transport: transport}
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch goroutine dump: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch goroutine dump: %s", resp.Status)
+	}
+	dump, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch goroutine dump: %w", err)
+	}
+	return parseStackSafely(dump)
+}
+
+// parseStackSafely parses the specified goroutine dump, but returns an error
+// instead of panicking in case of a malformed dump. This is necessary for
+// dumps not produced by our own process and thus beyond our control.
+func parseStackSafely(stacks []byte) ([]Goroutine, error) {
+	gs, err := Parse(stacks)
+	if err != nil {
+		return nil, fmt.Errorf("malformed goroutine dump: %w", err)
+	}
+	return gs, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pprof endpoints", func() {
+
+	It("fetches goroutines from a pprof endpoint", func() {
+		mux := http.NewServeMux()
+		mux.Handle(PprofPath, pprof.Handler("goroutine"))
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		gs, err := FromPprofURL(context.Background(), srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).To(ContainElement(
+			HaveField("Backtrace", ContainSubstring("github.com/thediveo/noleak/goroutine.FromPprofURL"))))
+
+		gs, err = FromPprofURL(context.Background(), srv.URL+PprofPath+"?debug=1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs).NotTo(BeEmpty())
+	})
+
+	It("authenticates via TLS, basic auth, and bearer tokens", func() {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, pass, ok := r.BasicAuth(); ok && user == "foo" && pass == "bar" {
+				pprof.Handler("goroutine").ServeHTTP(w, r)
+				return
+			}
+			if r.Header.Get("Authorization") == "Bearer baz" {
+				pprof.Handler("goroutine").ServeHTTP(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		srv.Config.ErrorLog = log.New(io.Discard, "", 0) // silence TLS handshake errors
+		srv.StartTLS()
+		defer srv.Close()
+		certs := x509.NewCertPool()
+		certs.AddCert(srv.Certificate())
+		tlsconf := &tls.Config{RootCAs: certs}
+		clientConns := func() (n int) {
+			for _, g := range Goroutines() {
+				if g.TopFunction == "net/http.(*persistConn).readLoop" ||
+					g.TopFunction == "net/http.(*persistConn).writeLoop" {
+					n++
+				}
+			}
+			return
+		}
+		conns := clientConns()
+
+		Expect(FromPprofURL(context.Background(), srv.URL)).Error().To(HaveOccurred())
+		Expect(FromPprofURL(context.Background(), srv.URL,
+			WithTLSConfig(tlsconf))).Error().To(MatchError("cannot fetch goroutine dump: 401 Unauthorized"))
+		Expect(FromPprofURL(context.Background(), srv.URL,
+			WithTLSConfig(tlsconf), WithBasicAuth("foo", "bar"))).NotTo(BeEmpty())
+		Eventually(clientConns).Should(BeNumerically("<=", conns))
+		Expect(FromPprofURL(context.Background(), srv.URL,
+			WithHTTPClient(srv.Client()), WithBearerToken("baz"))).NotTo(BeEmpty())
+	})
+
+	It("reports malformed dumps and invalid URLs", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("goroutine foo bar:\n"))
+		}))
+		defer srv.Close()
+		Expect(FromPprofURL(context.Background(), srv.URL)).Error().To(
+			MatchError(MatchRegexp(`^malformed goroutine dump: invalid stack header ID: .*`)))
+
+		Expect(FromPprofURL(context.Background(), ":foo")).Error().To(
+			MatchError(MatchRegexp(`^invalid pprof URL: .*`)))
+		//nolint:staticcheck // SA1012 we need to provoke an error here
+		Expect(FromPprofURL(nil, srv.URL)).Error().To(
+			MatchError(MatchRegexp(`^invalid pprof request: .*`)))
+	})
+
+})
@@ -0,0 +1,82 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import "strings"
+
+// Receiver returns the method receiver type of the specified fully qualified
+// function name, together with whether the receiver is a pointer receiver. Go
+// renders method names either as "example.com/pool.(*Worker).run" for pointer
+// receivers or as "example.com/pool.Worker.run" for value receivers; function
+// literals inside methods, such as "example.com/pool.(*Worker).run.func1",
+// return the receiver type of their enclosing method. Any type parameters, as
+// in "example.com/pool.(*Worker[...]).run", are dropped. For the examples,
+// Receiver returns "example.com/pool.Worker". For plain functions, Receiver
+// returns "" and false.
+//
+// Please note that Receiver cannot reliably tell methods from functions when
+// the last element of a package path contains dots, such as in "gopkg.in/yaml.v3".
+func Receiver(fname string) (typ string, pointer bool) {
+	fname = strings.ReplaceAll(fname, "[...]", "")
+	pkgIdx := strings.LastIndex(fname, "/") + 1
+	// Pointer receivers are unambiguous, even for package paths with dots in
+	// their last element.
+	if ptrIdx := strings.Index(fname[pkgIdx:], ".(*"); ptrIdx >= 0 {
+		pkg := fname[:pkgIdx+ptrIdx]
+		rest := fname[pkgIdx+ptrIdx+3:]
+		end := strings.Index(rest, ")")
+		if end <= 0 {
+			return "", false
+		}
+		return pkg + "." + rest[:end], true
+	}
+	dotIdx := strings.Index(fname[pkgIdx:], ".")
+	if dotIdx < 0 {
+		return "", false
+	}
+	pkg := fname[:pkgIdx+dotIdx]
+	elems := strings.Split(fname[pkgIdx+dotIdx+1:], ".")
+	// A value receiver type is followed by at least the method name, where
+	// the method name is neither a function literal "funcN", a go or defer
+	// statement wrapper "gowrapN" or "deferwrapN", nor one of the numbered
+	// nested function literals "N". Package-level function literals
+	// are rendered as "glob..func1", leaving an empty method name element.
+	if len(elems) < 2 || elems[0] == "" || elems[1] == "" || isFuncLiteral(elems[1]) {
+		return "", false
+	}
+	return pkg + "." + elems[0], false
+}
+
+// isFuncLiteral returns true if the specified name element denotes a function
+// literal, such as "func1" or the "2" in "foo.func1.2". This includes the
+// compiler-generated wrappers of go and defer statements, such as "gowrap1"
+// and "deferwrap1", which are closures of their enclosing functions as well.
+func isFuncLiteral(elem string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if strings.HasPrefix(elem, prefix) {
+			elem = elem[len(prefix):]
+			break
+		}
+	}
+	if elem == "" {
+		return false
+	}
+	for _, r := range elem {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
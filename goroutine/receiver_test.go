@@ -0,0 +1,51 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("method receivers", func() {
+
+	DescribeTable("parses receiver types",
+		func(fname string, expectedTyp string, expectedPtr bool) {
+			typ, ptr := Receiver(fname)
+			Expect(typ).To(Equal(expectedTyp))
+			Expect(ptr).To(Equal(expectedPtr))
+		},
+		Entry(nil, "example.com/pool.(*Worker).run", "example.com/pool.Worker", true),
+		Entry(nil, "example.com/pool.(*Worker).run.func1.2", "example.com/pool.Worker", true),
+		Entry(nil, "example.com/pool.(*Worker[...]).run", "example.com/pool.Worker", true),
+		Entry(nil, "gopkg.in/yaml.v3.(*decoder).unmarshal", "gopkg.in/yaml.v3.decoder", true),
+		Entry(nil, "example.com/pool.Worker.run", "example.com/pool.Worker", false),
+		Entry(nil, "example.com/pool.Worker[...].run.func1", "example.com/pool.Worker", false),
+		Entry(nil, "main.T.foo", "main.T", false),
+		Entry(nil, "example.com/pool.run", "", false),
+		Entry(nil, "example.com/pool.run.func1", "", false),
+		Entry(nil, "example.com/pool.run.func1.2", "", false),
+		Entry(nil, "example.com/pool.glob..func1", "", false),
+		Entry(nil, "example.com/pool.Start.gowrap1", "", false),
+		Entry(nil, "example.com/pool.Start.deferwrap2", "", false),
+		Entry(nil, "example.com/pool.Start.func1.gowrap1", "", false),
+		Entry(nil, "example.com/pool.Worker.Start.gowrap1", "example.com/pool.Worker", false),
+		Entry(nil, "example.com/pool.(*Worker).Start.deferwrap1", "example.com/pool.Worker", true),
+		Entry(nil, "example.com/pool.Worker.gowrapper", "example.com/pool.Worker", false),
+		Entry(nil, "example.com/pool.(*Worker", "", false),
+		Entry(nil, "main", "", false),
+	)
+
+})
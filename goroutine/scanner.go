@@ -0,0 +1,182 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DumpScanner locates and extracts goroutine dumps embedded in arbitrary text
+// streams, such as application logs interleaved with panic output or SIGQUIT
+// goroutine dumps. Successive calls to Scan step through the goroutine dumps
+// found in the stream, skipping any text in between.
+//
+// The interface of DumpScanner follows bufio.Scanner, but without a maximum
+// line length, as backtraces of goroutines with very long function names or
+// source paths are still to be scanned:
+//
+//   s := goroutine.NewDumpScanner(logfile)
+//   for s.Scan() {
+//       for _, g := range s.Goroutines() {
+//           fmt.Println(g)
+//       }
+//   }
+//   if err := s.Err(); err != nil {
+//       ...
+//   }
+type DumpScanner struct {
+	r       *bufio.Reader
+	pending *string // line read ahead, but not yet processed.
+	dump    string
+	gs      []Goroutine
+	err     error
+}
+
+// NewDumpScanner returns a new DumpScanner to read from r.
+func NewDumpScanner(r io.Reader) *DumpScanner {
+	return &DumpScanner{r: bufio.NewReader(r)}
+}
+
+// Scan advances the scanner to the next goroutine dump in the stream, which
+// will then be available through the Goroutines and Dump methods. It returns
+// false when the scan stops, either by reaching the end of the stream or an
+// error. After Scan returns false, the Err method will return any error that
+// occurred during scanning, except that if it was io.EOF, Err will return nil.
+// Malformed goroutine dumps never cause Scan to panic, but instead result in
+// an error wrapping a *ParseError.
+func (s *DumpScanner) Scan() bool {
+	s.dump, s.gs = "", nil
+	if s.err != nil {
+		return false
+	}
+	// Skip over anything until we find the header of a goroutine, starting a
+	// new goroutine dump.
+	var dump strings.Builder
+	for {
+		line, ok := s.readLine()
+		if !ok {
+			return false
+		}
+		if isGoroutineHeader(line) {
+			dump.WriteString(line)
+			dump.WriteRune('\n')
+			break
+		}
+	}
+	// Now consume all lines belonging to this dump: these are either goroutine
+	// headers or backtrace lines. Empty lines separate goroutines, so these
+	// are only part of the dump if they are followed by another goroutine
+	// header.
+	blanks := 0
+	for {
+		line, ok := s.readLine()
+		if !ok {
+			break
+		}
+		if line == "" {
+			blanks++
+			continue
+		}
+		if isGoroutineHeader(line) {
+			dump.WriteString(strings.Repeat("\n", blanks))
+			blanks = 0
+			dump.WriteString(line)
+			dump.WriteRune('\n')
+			continue
+		}
+		if blanks > 0 || !isBacktraceLine(line) {
+			s.pending = &line
+			break
+		}
+		dump.WriteString(line)
+		dump.WriteRune('\n')
+	}
+	if s.err != nil {
+		return false
+	}
+	s.dump = dump.String()
+	s.gs, s.err = parseStackSafely([]byte(s.dump))
+	return s.err == nil
+}
+
+// Goroutines returns the goroutines of the most recent goroutine dump found
+// by a call to Scan.
+func (s *DumpScanner) Goroutines() []Goroutine {
+	return s.gs
+}
+
+// Dump returns the text of the most recent goroutine dump found by a call to
+// Scan.
+func (s *DumpScanner) Dump() string {
+	return s.dump
+}
+
+// Err returns the first non-EOF error that was encountered by the scanner.
+func (s *DumpScanner) Err() error {
+	return s.err
+}
+
+// readLine returns the next line from the stream, without the trailing
+// newline. It returns false when reaching the end of the stream or in case of
+// an error, which is then recorded.
+func (s *DumpScanner) readLine() (string, bool) {
+	if s.pending != nil {
+		line := *s.pending
+		s.pending = nil
+		return line, true
+	}
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		if err != io.EOF {
+			s.err = err
+			return "", false
+		}
+		if line == "" {
+			return "", false
+		}
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true
+}
+
+// isGoroutineHeader returns true if the specified line is a goroutine header
+// line, such as "goroutine 42 [chan receive]:".
+func isGoroutineHeader(line string) bool {
+	if !strings.HasPrefix(line, backtraceGoroutineHeader) || !strings.HasSuffix(line, "]:") {
+		return false
+	}
+	id, details, ok := splitHeader(line)
+	if !ok || strings.IndexByte(details, '[') < 0 {
+		return false
+	}
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
+
+// isBacktraceLine returns true if the specified line can be part of a
+// goroutine's backtrace, such as a function call line, an indented call
+// location line, a "created by" line, or an elision marker.
+func isBacktraceLine(line string) bool {
+	if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, backtraceGoroutineCreator) {
+		return true
+	}
+	if _, ok := parseElision(line); ok {
+		return true
+	}
+	_, ok := parseCall(line)
+	return ok
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing/iotest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("dump scanner", func() {
+
+	const mixedLog = `2022/06/01 12:00:00 starting up
+2022/06/01 12:00:01 doing things
+panic: foobar
+
+goroutine 1 [running]:
+main.main()
+	/tmp/main.go:10 +0x27
+exit status 2
+some more logging
+SIGQUIT: quit
+PC=0x46a1c1 m=0 sigcode=0
+
+goroutine 0 gp=0x5a3f40 m=0 mp=0x5a4840 [idle]:
+runtime.futex(0x5a4980, 0x80, 0x0, 0x0, 0x0, 0x0)
+	/usr/local/go/src/runtime/sys_linux_amd64.s:557 +0x21 fp=0x7ffd sp=0x7ffd pc=0x46a1c1
+
+goroutine 42 [chan receive, 5 minutes]:
+main.foo.func1()
+	/tmp/main.go:6 +0x28
+created by main.foo in goroutine 1
+	/tmp/main.go:5 +0x64
+
+rax    0xca
+`
+
+	It("extracts goroutine dumps from mixed logs", func() {
+		s := NewDumpScanner(strings.NewReader(mixedLog))
+		Expect(s.Scan()).To(BeTrue())
+		Expect(s.Dump()).To(Equal("goroutine 1 [running]:\nmain.main()\n\t/tmp/main.go:10 +0x27\n"))
+		Expect(s.Goroutines()).To(ConsistOf(And(
+			HaveField("ID", uint64(1)),
+			HaveField("TopFunction", "main.main"))))
+
+		Expect(s.Scan()).To(BeTrue())
+		Expect(s.Goroutines()).To(ConsistOf(
+			And(
+				HaveField("ID", uint64(0)),
+				HaveField("State", "idle"),
+				HaveField("TopFunction", "runtime.futex"),
+				HaveField("Frames", ConsistOf(HaveField("Location", "/usr/local/go/src/runtime/sys_linux_amd64.s:557")))),
+			And(
+				HaveField("ID", uint64(42)),
+				HaveField("State", "chan receive, 5 minutes"),
+				HaveField("CreatorFunction", "main.foo")),
+		))
+
+		Expect(s.Scan()).To(BeFalse())
+		Expect(s.Err()).NotTo(HaveOccurred())
+		Expect(s.Goroutines()).To(BeEmpty())
+	})
+
+	It("handles streams without dumps", func() {
+		s := NewDumpScanner(strings.NewReader("foo\nbar"))
+		Expect(s.Scan()).To(BeFalse())
+		Expect(s.Err()).NotTo(HaveOccurred())
+	})
+
+	It("reports errors", func() {
+		s := NewDumpScanner(io.MultiReader(
+			strings.NewReader("goroutine 1 [running]:\nmain.main()\n"),
+			iotest.ErrReader(errors.New("foo failure"))))
+		Expect(s.Scan()).To(BeFalse())
+		Expect(s.Err()).To(MatchError("foo failure"))
+		Expect(s.Scan()).To(BeFalse())
+
+		s = NewDumpScanner(strings.NewReader("goroutine 1 [running]:\n\tmain.main\n"))
+		Expect(s.Scan()).To(BeFalse())
+		Expect(s.Err()).To(MatchError(MatchRegexp(`^malformed goroutine dump: .*`)))
+	})
+
+	It("recognizes goroutine headers", func() {
+		Expect(isGoroutineHeader("goroutine 42 [running]:")).To(BeTrue())
+		Expect(isGoroutineHeader("goroutine 42 gp=0x0 m=nil [running]:")).To(BeTrue())
+		Expect(isGoroutineHeader("goroutine foo [running]:")).To(BeFalse())
+		Expect(isGoroutineHeader("goroutine 42 running]:")).To(BeFalse())
+		Expect(isGoroutineHeader("goroutine 42:")).To(BeFalse())
+	})
+
+})
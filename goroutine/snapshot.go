@@ -0,0 +1,114 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Snapshot represents the goroutines of a process at a particular moment,
+// together with some metadata about when and where the snapshot was taken.
+// Snapshots are useful as baselines to later check for leaked goroutines, with
+// the metadata helping to diagnose stale baselines.
+type Snapshot struct {
+	Goroutines []Goroutine // goroutines at the time of the snapshot
+	Taken      time.Time   // point in time when the snapshot was taken
+	GoVersion  string      // version of the Go runtime, such as "go1.18.3"
+	PID        int         // ID of the process the snapshot was taken from
+	Tag        string      // optional tag naming the test phase, such as "after-suite-setup"
+}
+
+// TakeSnapshot returns a snapshot of all goroutines of this process, including
+// metadata about the snapshot.
+func TakeSnapshot() Snapshot {
+	return Snapshot{
+		Goroutines: Goroutines(),
+		Taken:      time.Now(),
+		GoVersion:  runtime.Version(),
+		PID:        os.Getpid(),
+	}
+}
+
+// TakeTaggedSnapshot returns a snapshot of all goroutines of this process,
+// including metadata about the snapshot, tagged with the specified name of the
+// current test phase, such as "after-suite-setup" or "after-fixture-X".
+func TakeTaggedSnapshot(tag string) Snapshot {
+	s := TakeSnapshot()
+	s.Tag = tag
+	return s
+}
+
+// Count returns the number of goroutines in this snapshot.
+func (s Snapshot) Count() int {
+	return len(s.Goroutines)
+}
+
+// Age returns the time elapsed since this snapshot was taken.
+func (s Snapshot) Age() time.Duration {
+	return time.Since(s.Taken)
+}
+
+// String returns a short textual description of this snapshot, but without
+// the details of the individual goroutines.
+func (s Snapshot) String() string {
+	noun := "goroutines"
+	if s.Count() == 1 {
+		noun = "goroutine"
+	}
+	str := fmt.Sprintf("Snapshot of %d %s, taken: %s, Go version: %s, PID: %d",
+		s.Count(), noun, s.Taken.Format(time.RFC3339Nano), s.GoVersion, s.PID)
+	if s.Tag != "" {
+		str += fmt.Sprintf(", tag: %q", s.Tag)
+	}
+	return str
+}
+
+// Save writes this snapshot in JSON format to the specified writer, so that it
+// can be later loaded again using LoadSnapshot, such as in a different test
+// phase or test binary.
+func (s Snapshot) Save(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		return fmt.Errorf("cannot save snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot in JSON format from the specified reader, as
+// previously written by Snapshot.Save.
+func LoadSnapshot(r io.Reader) (Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return Snapshot{}, fmt.Errorf("cannot load snapshot: %w", err)
+	}
+	// Snapshots saved by older versions lack the structured creator and call
+	// locations, so fill them in.
+	for idx := range s.Goroutines {
+		g := &s.Goroutines[idx]
+		if g.CreatorFrame.Function == "" {
+			g.CreatorFrame = newCreatorFrame(g.CreatorFunction, g.BornAt)
+		}
+		for fidx := range g.Frames {
+			if f := &g.Frames[fidx]; f.File == "" && f.Location != "" {
+				f.File, f.Line = SplitLocation(f.Location)
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"testing/iotest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("snapshots", func() {
+
+	It("takes a snapshot including metadata", func() {
+		before := time.Now()
+		s := TakeSnapshot()
+		Expect(s.Goroutines).NotTo(BeEmpty())
+		Expect(s.Count()).To(Equal(len(s.Goroutines)))
+		Expect(s.Taken).To(BeTemporally(">=", before))
+		Expect(s.Age()).To(BeNumerically(">=", 0))
+		Expect(s.GoVersion).To(Equal(runtime.Version()))
+		Expect(s.PID).To(Equal(os.Getpid()))
+	})
+
+	It("takes tagged snapshots", func() {
+		s := TakeTaggedSnapshot("after-suite-setup")
+		Expect(s.Goroutines).NotTo(BeEmpty())
+		Expect(s.Tag).To(Equal("after-suite-setup"))
+	})
+
+	It("prints", func() {
+		s := Snapshot{
+			Goroutines: []Goroutine{{ID: 1}, {ID: 42}},
+			Taken:      time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC),
+			GoVersion:  "go1.18",
+			PID:        666,
+		}
+		Expect(s.String()).To(Equal(
+			"Snapshot of 2 goroutines, taken: 2022-06-01T12:00:00Z, Go version: go1.18, PID: 666"))
+		s.Tag = "after-suite-setup"
+		Expect(s.String()).To(HaveSuffix(`, PID: 666, tag: "after-suite-setup"`))
+		s.Goroutines = s.Goroutines[:1]
+		Expect(s.String()).To(HavePrefix("Snapshot of 1 goroutine, taken: "))
+	})
+	It("saves and loads snapshots", func() {
+		s := TakeSnapshot()
+		var buff bytes.Buffer
+		Expect(s.Save(&buff)).To(Succeed())
+		loaded, err := LoadSnapshot(&buff)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Taken.Equal(s.Taken)).To(BeTrue())
+		loaded.Taken = s.Taken // get rid of the monotonic clock reading
+		Expect(loaded).To(Equal(s))
+	})
+
+	It("fills in structured locations of older snapshots", func() {
+		loaded, err := LoadSnapshot(strings.NewReader(`{"Goroutines":[{"ID":42,"CreatorFunction":"main.foo","BornAt":"/tmp/main.go:5","Frames":[{"Function":"main.bar","Location":"/tmp/main.go:6"}]}]}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Goroutines).To(HaveLen(1))
+		Expect(loaded.Goroutines[0].CreatorFrame).To(Equal(Frame{
+			Function: "main.foo", Location: "/tmp/main.go:5", File: "/tmp/main.go", Line: 5}))
+		Expect(loaded.Goroutines[0].Frames).To(ConsistOf(And(
+			HaveField("File", "/tmp/main.go"), HaveField("Line", 6))))
+	})
+
+	It("reports save and load errors", func() {
+		Expect(TakeSnapshot().Save(errWriter{})).To(MatchError(MatchRegexp(`^cannot save snapshot: .*`)))
+		Expect(LoadSnapshot(strings.NewReader("{"))).Error().To(
+			MatchError(MatchRegexp(`^cannot load snapshot: .*`)))
+		Expect(LoadSnapshot(iotest.ErrReader(errors.New("foo failure")))).Error().To(
+			MatchError("cannot load snapshot: foo failure"))
+	})
+
+})
+
+// errWriter always fails writing.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("foo failure") }
@@ -0,0 +1,164 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// State is the basic state of a goroutine, without any additional flags, such
+// as "(scan)", the number of minutes blocked, or "locked to thread". For
+// waiting goroutines, the State is the reason for waiting, such as "chan
+// receive".
+type State string
+
+// The goroutine states and waiting reasons most often encountered in goroutine
+// dumps. Please note that some waiting reasons have changed over time between
+// Go versions; for instance, goroutines blocked on a sync.Mutex in Go 1.18 and
+// later report StateSyncMutexLock, while they reported StateSemacquire before.
+const (
+	StateIdle      State = "idle"
+	StateRunnable  State = "runnable"
+	StateRunning   State = "running"
+	StateSyscall   State = "syscall"
+	StateCopystack State = "copystack"
+	StatePreempted State = "preempted"
+
+	StateChanReceive        State = "chan receive"
+	StateChanReceiveNilChan State = "chan receive (nil chan)"
+	StateChanSend           State = "chan send"
+	StateChanSendNilChan    State = "chan send (nil chan)"
+	StateSelect             State = "select"
+	StateSelectNoCases      State = "select (no cases)"
+	StateSleep              State = "sleep"
+	StateIOWait             State = "IO wait"
+	StateFinalizerWait      State = "finalizer wait"
+	StateSemacquire         State = "semacquire"
+	StateSyncCondWait       State = "sync.Cond.Wait"
+	StateSyncMutexLock      State = "sync.Mutex.Lock"
+	StateSyncRWMutexRLock   State = "sync.RWMutex.RLock"
+	StateSyncRWMutexLock    State = "sync.RWMutex.Lock"
+	StateSyncWaitGroupWait  State = "sync.WaitGroup.Wait"
+)
+
+// Flags appended by Go's runtime to the basic goroutine state and that are not
+// considered to be part of the State.
+var stateFlags = []string{" (scan)", " (leaked)", " (durable)"}
+
+// ParseState splits the specified textual goroutine state, as found in the
+// State field of a Goroutine, into its basic State, the number of minutes the
+// goroutine has been blocked (if any), and whether the goroutine is locked to
+// its OS thread.
+func ParseState(s string) (state State, minutes int, locked bool) {
+	details := strings.Split(s, ", ")
+	base := details[0]
+	for _, flag := range stateFlags {
+		base = strings.TrimSuffix(base, flag)
+	}
+	state = State(base)
+	for _, detail := range details[1:] {
+		switch {
+		case detail == "locked to thread":
+			locked = true
+		case strings.HasSuffix(detail, " minutes"):
+			if m, err := strconv.Atoi(strings.TrimSuffix(detail, " minutes")); err == nil {
+				minutes = m
+			}
+		}
+	}
+	return
+}
+
+// IsBlocked returns true if the state indicates a goroutine waiting for some
+// event, such as a channel operation, sleep, lock, et cetera.
+func (s State) IsBlocked() bool {
+	switch s {
+	case StateIdle, StateRunnable, StateRunning, StateSyscall, StateCopystack,
+		StatePreempted, "dead", "":
+		return false
+	}
+	return true
+}
+
+// IsSyscall returns true if the state indicates a goroutine executing a
+// system call.
+func (s State) IsSyscall() bool {
+	return s == StateSyscall
+}
+
+// IsActive returns true if the state indicates a goroutine either running or
+// ready to run, as opposed to a goroutine blocked or executing a system call.
+func (s State) IsActive() bool {
+	return s == StateRunning || s == StateRunnable
+}
+
+// IsChannelOp returns true if the state indicates a goroutine blocked in a
+// channel send, receive, or select operation.
+func (s State) IsChannelOp() bool {
+	switch s {
+	case StateChanReceive, StateChanReceiveNilChan, StateChanSend, StateChanSendNilChan,
+		StateSelect, StateSelectNoCases:
+		return true
+	}
+	return false
+}
+
+// IsLockWait returns true if the state indicates a goroutine waiting to
+// acquire a lock or semaphore, or waiting for a condition or wait group.
+func (s State) IsLockWait() bool {
+	switch s {
+	case StateSemacquire, StateSyncCondWait, StateSyncMutexLock,
+		StateSyncRWMutexRLock, StateSyncRWMutexLock, StateSyncWaitGroupWait:
+		return true
+	}
+	return false
+}
+
+// BaseState returns the basic State of this goroutine, without any additional
+// flags and details.
+func (g Goroutine) BaseState() State {
+	state, _, _ := ParseState(g.State)
+	return state
+}
+
+// IsBlocked returns true if this goroutine is waiting for some event.
+func (g Goroutine) IsBlocked() bool {
+	return g.BaseState().IsBlocked()
+}
+
+// IsSyscall returns true if this goroutine is executing a system call.
+func (g Goroutine) IsSyscall() bool {
+	return g.BaseState().IsSyscall()
+}
+
+// IsActive returns true if this goroutine is running or ready to run.
+func (g Goroutine) IsActive() bool {
+	return g.BaseState().IsActive()
+}
+
+// BlockedMinutes returns the number of minutes this goroutine has been blocked
+// for, as reported by Go's runtime. As the runtime reports only blocking times
+// of at least one minute, BlockedMinutes returns zero for shorter periods.
+func (g Goroutine) BlockedMinutes() int {
+	_, minutes, _ := ParseState(g.State)
+	return minutes
+}
+
+// IsLockedToThread returns true if this goroutine is locked to its OS thread.
+func (g Goroutine) IsLockedToThread() bool {
+	_, _, locked := ParseState(g.State)
+	return locked
+}
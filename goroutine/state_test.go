@@ -0,0 +1,74 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("goroutine states", func() {
+
+	DescribeTable("parsing states",
+		func(s string, expectedState State, expectedMinutes int, expectedLocked bool) {
+			state, minutes, locked := ParseState(s)
+			Expect(state).To(Equal(expectedState))
+			Expect(minutes).To(Equal(expectedMinutes))
+			Expect(locked).To(Equal(expectedLocked))
+		},
+		Entry(nil, "running", StateRunning, 0, false),
+		Entry(nil, "running (scan)", StateRunning, 0, false),
+		Entry(nil, "chan receive (nil chan)", StateChanReceiveNilChan, 0, false),
+		Entry(nil, "chan receive (durable)", StateChanReceive, 0, false),
+		Entry(nil, "chan receive (durable), synctest bubble 1", StateChanReceive, 0, false),
+		Entry(nil, "select (durable), 2 minutes, synctest bubble 3", StateSelect, 2, false),
+		Entry(nil, "select, 42 minutes", StateSelect, 42, false),
+		Entry(nil, "syscall, locked to thread", StateSyscall, 0, true),
+		Entry(nil, "sleep, 1 minutes, locked to thread", StateSleep, 1, true),
+		Entry(nil, "sleep, x minutes", StateSleep, 0, false),
+	)
+
+	It("classifies states", func() {
+		Expect(StateRunning.IsBlocked()).To(BeFalse())
+		Expect(StateSyscall.IsBlocked()).To(BeFalse())
+		Expect(StateSyscall.IsSyscall()).To(BeTrue())
+		Expect(StateChanSend.IsBlocked()).To(BeTrue())
+		Expect(StateChanSend.IsChannelOp()).To(BeTrue())
+		Expect(StateSleep.IsChannelOp()).To(BeFalse())
+		Expect(StateSyncMutexLock.IsLockWait()).To(BeTrue())
+		Expect(StateSemacquire.IsLockWait()).To(BeTrue())
+		Expect(StateSelect.IsLockWait()).To(BeFalse())
+		Expect(StateRunning.IsActive()).To(BeTrue())
+		Expect(StateRunnable.IsActive()).To(BeTrue())
+		Expect(StateSyscall.IsActive()).To(BeFalse())
+	})
+
+	It("classifies goroutines", func() {
+		g := Goroutine{State: "chan receive, 5 minutes, locked to thread"}
+		Expect(g.BaseState()).To(Equal(StateChanReceive))
+		Expect(g.IsBlocked()).To(BeTrue())
+		Expect(g.IsSyscall()).To(BeFalse())
+		Expect(g.BlockedMinutes()).To(Equal(5))
+		Expect(g.IsLockedToThread()).To(BeTrue())
+
+		g = Goroutine{State: "syscall"}
+		Expect(g.IsBlocked()).To(BeFalse())
+		Expect(g.IsSyscall()).To(BeTrue())
+		Expect(g.IsActive()).To(BeFalse())
+		Expect(g.BlockedMinutes()).To(BeZero())
+		Expect(g.IsLockedToThread()).To(BeFalse())
+	})
+
+})
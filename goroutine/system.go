@@ -0,0 +1,97 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"strings"
+)
+
+// Name of the runtime metric counting the live goroutines.
+const liveGoroutinesMetric = "/sched/goroutines:goroutines"
+
+// CountSystemGoroutines returns the number of the Go runtime's own system
+// goroutines, such as the garbage collector's background workers, the
+// sweeper, and the scavenger. Goroutines, runtime.Stack, and
+// runtime.NumGoroutine all leave out system goroutines, yet observing them
+// helps when debugging runaway garbage collector or scavenger behavior, such
+// as memory usage rising without any leaked user goroutines.
+//
+// CountSystemGoroutines derives the number of system goroutines from the
+// runtime metric "/sched/goroutines:goroutines", which recent Go versions
+// report including the system goroutines. CountSystemGoroutines returns false
+// if the running Go version doesn't support counting system goroutines.
+func CountSystemGoroutines() (int, bool) {
+	sample := []metrics.Sample{{Name: liveGoroutinesMetric}}
+	// Goroutines might start or end in between reading the runtime metric and
+	// the number of user goroutines, so try to get a consistent reading.
+	for attempt := 0; attempt < 3; attempt++ {
+		before := runtime.NumGoroutine()
+		metrics.Read(sample)
+		if sample[0].Value.Kind() != metrics.KindUint64 {
+			return 0, false
+		}
+		if runtime.NumGoroutine() != before {
+			continue
+		}
+		// The runtime always starts some system goroutines, such as the
+		// forced GC helper, so seeing none means that the metric doesn't
+		// include them.
+		system := int(sample[0].Value.Uint64()) - before
+		return system, system > 0
+	}
+	return 0, false
+}
+
+// IsSystem returns true if this goroutine is one of the Go runtime's system
+// goroutines, judging from the function this goroutine started with. While
+// Goroutines never returns system goroutines, dumps taken with
+// GOTRACEBACK=system, such as crash dumps, list them. Similar to the runtime,
+// the finalizer and cleanup goroutines only count as system goroutines while
+// not running user finalizers or cleanups. IsSystem returns false if the
+// function this goroutine started with is unknown, such as when frames have
+// been elided from the end of its backtrace.
+func (g Goroutine) IsSystem() bool {
+	entry := g.entryFunction()
+	if !strings.HasPrefix(entry, "runtime.") {
+		return false
+	}
+	switch entry {
+	case "runtime.main", "runtime.handleAsyncEvent":
+		return false
+	case "runtime.runfinq", "runtime.runFinalizers", "runtime.runCleanups":
+		for _, frame := range g.Frames {
+			if !strings.HasPrefix(frame.Function, "runtime.") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// entryFunction returns the name of the function this goroutine started with,
+// or an empty name if unknown.
+func (g Goroutine) entryFunction() string {
+	if g.ElidedFrames < 0 {
+		return ""
+	}
+	for idx := len(g.Frames) - 1; idx >= 0; idx-- {
+		if fn := g.Frames[idx].Function; fn != "runtime.goexit" {
+			return fn
+		}
+	}
+	return ""
+}
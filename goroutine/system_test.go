@@ -0,0 +1,59 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package goroutine
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("system goroutines", func() {
+
+	It("tells system goroutines", func() {
+		dump, err := os.ReadFile("testdata/corpus/go1.27-system.dump")
+		Expect(err).NotTo(HaveOccurred())
+		gs, err := Parse(dump)
+		Expect(err).NotTo(HaveOccurred())
+		system := map[uint64]bool{}
+		for _, g := range gs {
+			system[g.ID] = g.IsSystem()
+		}
+		Expect(system).To(HaveKeyWithValue(uint64(1), false)) // runtime.main
+		Expect(system).To(HaveKeyWithValue(uint64(2), true))  // runtime.forcegchelper
+		Expect(system).To(HaveKeyWithValue(uint64(3), true))  // runtime.gcenable.gowrap1
+		Expect(system).To(HaveKeyWithValue(uint64(6), true))  // runtime.runFinalizers
+		Expect(system).To(HaveKeyWithValue(uint64(7), false)) // main.main.gowrap1
+
+		Expect(Goroutine{
+			Frames: []Frame{{Function: "main.finalize"}, {Function: "runtime.runfinq"}},
+		}.IsSystem()).To(BeFalse())
+		Expect(Goroutine{
+			Frames:       []Frame{{Function: "runtime.bgsweep"}},
+			ElidedFrames: -1,
+		}.IsSystem()).To(BeFalse())
+		Expect(Goroutine{}.IsSystem()).To(BeFalse())
+	})
+
+	It("counts system goroutines", func() {
+		system, ok := CountSystemGoroutines()
+		if !ok {
+			Skip("Go runtime doesn't support counting system goroutines")
+		}
+		Expect(system).To(BeNumerically(">", 0))
+	})
+
+})
@@ -14,11 +14,111 @@
 
 package noleak
 
-import "github.com/thediveo/noleak/goroutine"
+import (
+	"runtime"
+	"time"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// PreCaptureGrace controls whether Goroutines, LightweightGoroutines,
+// PooledGoroutines, and HaveLeaked with LazyGoroutines give goroutines a last
+// chance to end before capturing them: they first run the garbage collector,
+// yield the processor, and then sleep for PreCaptureSleep. Finalizer-driven
+// and just-cancelled goroutines often need such an additional scheduling round
+// to finally end. PreCaptureGrace is off by default, as it slows down every
+// capture, including those of a production Monitor.
+var PreCaptureGrace = false
+
+// PreCaptureSleep is the duration to sleep before capturing goroutines when
+// PreCaptureGrace is enabled.
+var PreCaptureSleep = time.Millisecond
+
+// preCaptureGrace gives goroutines a last chance to end before capturing
+// them, if enabled by PreCaptureGrace.
+func preCaptureGrace() {
+	if !PreCaptureGrace {
+		return
+	}
+	runtime.GC()
+	runtime.Gosched()
+	if PreCaptureSleep > 0 {
+		time.Sleep(PreCaptureSleep)
+	}
+}
 
 // Goroutines returns information about all goroutines: their goroutine IDs, the
 // names of the topmost functions in the backtraces, and finally the goroutine
-// backtraces.
+// backtraces. See also PreCaptureGrace.
 func Goroutines() []goroutine.Goroutine {
-	return goroutine.Goroutines()
+	preCaptureGrace()
+	gs := goroutine.Goroutines()
+	births.observe(gs)
+	return gs
 }
+
+// LightweightGoroutines returns information about all goroutines, but without
+// their backtraces, in order to cut down on memory when repeatedly polling for
+// leaks. HaveLeaked automatically recaptures the full backtraces of any leaked
+// goroutines for its failure message, so it can be used with Eventually:
+//
+//   Eventually(LightweightGoroutines).ShouldNot(HaveLeaked(snapshot))
+func LightweightGoroutines() []goroutine.Goroutine {
+	preCaptureGrace()
+	gs := goroutine.LightweightGoroutines()
+	births.observe(gs)
+	return gs
+}
+
+// PooledGoroutines returns information about all goroutines, reusing slices
+// previously handed back using ReleaseGoroutines in order to reduce pressure
+// on the garbage collector in tight polling loops. For instance:
+//
+//   Eventually(func(g Gomega) {
+//       gs := PooledGoroutines()
+//       defer ReleaseGoroutines(gs)
+//       g.Expect(gs).NotTo(HaveLeaked(snapshot))
+//   }).Should(Succeed())
+func PooledGoroutines() []goroutine.Goroutine {
+	preCaptureGrace()
+	gs := goroutine.PooledGoroutines()
+	births.observe(gs)
+	return gs
+}
+
+// ReleaseGoroutines hands the specified slice of goroutines, as returned by
+// PooledGoroutines, back for reuse. The slice must not be used anymore after
+// releasing it.
+func ReleaseGoroutines(gs []goroutine.Goroutine) {
+	goroutine.ReleaseGoroutines(gs)
+}
+
+// TakeSnapshot returns a snapshot of all goroutines, together with metadata
+// about when the snapshot was taken. Snapshots can be passed to HaveLeaked
+// instead of plain lists of goroutines, allowing HaveLeaked to report the age
+// of a baseline snapshot when detecting leaks.
+func TakeSnapshot() goroutine.Snapshot {
+	snapshot := goroutine.TakeSnapshot()
+	births.observe(snapshot.Goroutines)
+	return snapshot
+}
+
+// TakeTaggedSnapshot returns a snapshot of all goroutines, tagged with the
+// specified name of the current test phase. Besides being used as a baseline,
+// tagged snapshots can be passed to HaveLeaked wrapped in Phases, in order to
+// attribute leaked goroutines to the test phases in which they appeared.
+func TakeTaggedSnapshot(tag string) goroutine.Snapshot {
+	snapshot := TakeSnapshot()
+	snapshot.Tag = tag
+	return snapshot
+}
+
+// LazyGoroutines can be passed to HaveLeaked instead of a list of goroutines,
+// in order to let HaveLeaked capture the current goroutines only when
+// necessary. In particular, HaveLeaked with a baseline list or snapshot of
+// goroutines skips capturing the goroutines if their number hasn't grown since
+// the baseline, see also ForceFullCapture. As Eventually repeatedly passes
+// non-function values to matchers, LazyGoroutines works with Eventually too:
+//
+//   Eventually(LazyGoroutines{}).ShouldNot(HaveLeaked(snapshot))
+type LazyGoroutines struct{}
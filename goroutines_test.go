@@ -15,6 +15,9 @@
 package noleak
 
 import (
+	"runtime"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -28,4 +31,33 @@ var _ = Describe("goroutines", func() {
 				Equal("testing.RunTests")))))
 	})
 
+	It("gives finalizer-driven goroutines a grace period", func() {
+		defer func(grace bool, sleep time.Duration) {
+			PreCaptureGrace, PreCaptureSleep = grace, sleep
+		}(PreCaptureGrace, PreCaptureSleep)
+		PreCaptureSleep = 50 * time.Millisecond
+
+		snapshot := Goroutines()
+		startFinalizedWorker := func() {
+			done := make(chan struct{})
+			resource := new([16]byte)
+			runtime.SetFinalizer(resource, func(*[16]byte) { close(done) })
+			go worker(done)
+		}
+		startFinalizedWorker()
+		PreCaptureGrace = false
+		Expect(Goroutines()).To(HaveLeaked(snapshot))
+		PreCaptureGrace = true
+		Expect(Goroutines()).NotTo(HaveLeaked(snapshot))
+	})
+
+	It("returns pooled goroutines", func() {
+		snapshot := Goroutines()
+		Eventually(func(g Gomega) {
+			gs := PooledGoroutines()
+			defer ReleaseGoroutines(gs)
+			g.Expect(gs).NotTo(HaveLeaked(snapshot))
+		}).Should(Succeed())
+	})
+
 })
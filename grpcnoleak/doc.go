@@ -0,0 +1,37 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package grpcnoleak helps checking tests using grpc-go for leaked goroutines.
+Filters returns a preset of filter matchers for grpc-go's known long-lived
+goroutines that might linger for a short time after closing client
+connections and gracefully stopping servers. Teardown closes client
+connections and stops servers, and then checks for leaks.
+
+    snapshot := noleak.Goroutines()
+    srv := grpc.NewServer()
+    conn, err := grpc.Dial(...)
+    ...
+    grpcnoleak.Teardown(snapshot, conn, srv)
+
+Teardown doesn't ignore the goroutines of HTTP/2 transports, as these are
+usually the leftovers of client connections and servers that were never closed.
+Tests deliberately keeping transports open can ignore them using
+TransportFilters.
+
+Package grpcnoleak doesn't depend on grpc-go itself.
+
+*/
+package grpcnoleak
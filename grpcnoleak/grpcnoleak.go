@@ -0,0 +1,91 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package grpcnoleak
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// SettleTimeout is the maximum duration Teardown waits for the goroutines of
+// closed client connections and stopped servers to finally end.
+var SettleTimeout = 5 * time.Second
+
+// Filters returns the filter matchers for grpc-go's known long-lived
+// goroutines, such as resolver watchers. These goroutines aren't considered to
+// be leaked.
+func Filters() []interface{} {
+	return []interface{}{
+		noleak.IgnoringInBacktrace("google.golang.org/grpc/internal/resolver/dns.(*dnsResolver).watcher"),
+		noleak.IgnoringInBacktrace("google.golang.org/grpc/internal/grpcsync.(*CallbackSerializer).run"),
+	}
+}
+
+// TransportFilters returns the filter matchers for the goroutines of grpc-go's
+// HTTP/2 transports, that is, the "loopy" writers and keepalive goroutines.
+// Please note that these goroutines are the tell-tale signs of client
+// connections and servers that never get closed, so TransportFilters isn't
+// part of Filters: only use it in tests that deliberately keep transports
+// open.
+func TransportFilters() []interface{} {
+	return []interface{}{
+		noleak.IgnoringInBacktrace("google.golang.org/grpc/internal/transport.(*loopyWriter).run"),
+		noleak.IgnoringInBacktrace("google.golang.org/grpc/internal/transport.(*http2Server).keepalive"),
+		noleak.IgnoringInBacktrace("google.golang.org/grpc/internal/transport.(*http2Client).keepalive"),
+	}
+}
+
+// Closer is implemented by grpc-go's client connections.
+type Closer interface {
+	Close() error
+}
+
+// GracefulStopper is implemented by grpc-go's servers.
+type GracefulStopper interface {
+	GracefulStop()
+}
+
+// Teardown closes the specified client connections and gracefully stops the
+// specified servers in the order specified, and then asserts that eventually
+// no goroutines have leaked since the specified snapshot (anything accepted by
+// HaveLeaked as its baseline, such as a list of goroutines or a snapshot),
+// ignoring the goroutines matched by Filters. Failing assertions are reported
+// using gomega.Default.
+//
+// Teardown panics if any of the specified closers is neither a Closer nor a
+// GracefulStopper.
+func Teardown(snapshot interface{}, closers ...interface{}) {
+	TeardownWithGomega(gomega.Default, snapshot, closers...)
+}
+
+// TeardownWithGomega works the same as Teardown, but reports failing
+// assertions using the specified Gomega.
+func TeardownWithGomega(g gomega.Gomega, snapshot interface{}, closers ...interface{}) {
+	for _, closer := range closers {
+		switch closer := closer.(type) {
+		case GracefulStopper:
+			closer.GracefulStop()
+		case Closer:
+			g.Expect(closer.Close()).WithOffset(1).To(gomega.Succeed(), "cannot close gRPC client connection")
+		default:
+			panic(fmt.Sprintf("Teardown expected a Closer or GracefulStopper, but got: %T", closer))
+		}
+	}
+	g.Eventually(noleak.Goroutines).WithOffset(1).WithTimeout(SettleTimeout).
+		ShouldNot(noleak.HaveLeaked(append(Filters(), snapshot)...))
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package grpcnoleak
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+)
+
+// fakeServer serves in a goroutine until gracefully stopped.
+type fakeServer struct {
+	done chan struct{}
+}
+
+func newFakeServer() *fakeServer {
+	s := &fakeServer{done: make(chan struct{})}
+	go func() { <-s.done }()
+	return s
+}
+
+func (s *fakeServer) GracefulStop() { close(s.done) }
+
+// fakeConn runs a goroutine until closed, optionally failing to close.
+type fakeConn struct {
+	done chan struct{}
+	err  error
+}
+
+func newFakeConn(err error) *fakeConn {
+	c := &fakeConn{done: make(chan struct{}), err: err}
+	go func() { <-c.done }()
+	return c
+}
+
+func (c *fakeConn) Close() error {
+	close(c.done)
+	return c.err
+}
+
+var _ = Describe("gRPC teardown", func() {
+
+	BeforeEach(func() {
+		oldTimeout := SettleTimeout
+		SettleTimeout = 500 * time.Millisecond
+		DeferCleanup(func() { SettleTimeout = oldTimeout })
+	})
+
+	It("returns filters", func() {
+		Expect(Filters()).NotTo(BeEmpty())
+		Expect(TransportFilters()).NotTo(BeEmpty())
+	})
+
+	It("tears down without leaks", func() {
+		snapshot := noleak.Goroutines()
+		Teardown(snapshot, newFakeConn(nil), newFakeServer())
+	})
+
+	It("reports failures and leaks", func() {
+		snapshot := noleak.Goroutines()
+		var failures []string
+		g := NewGomega(func(message string, callerSkip ...int) {
+			failures = append(failures, message)
+		})
+		srv := newFakeServer()
+		defer func() {
+			srv.GracefulStop()
+			Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked(snapshot))
+		}()
+		TeardownWithGomega(g, snapshot, newFakeConn(errors.New("D'oh!")))
+		Expect(failures).To(ConsistOf(
+			ContainSubstring("cannot close gRPC client connection"),
+			ContainSubstring("Expected not to leak 1 goroutines")))
+	})
+
+	It("panics on invalid closers", func() {
+		Expect(func() { Teardown(nil, 42) }).To(PanicWith(
+			"Teardown expected a Closer or GracefulStopper, but got: int"))
+	})
+
+})
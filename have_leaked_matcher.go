@@ -15,12 +15,15 @@
 package noleak
 
 import (
+	"context"
 	"fmt"
 	"path"
-	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/onsi/gomega/format"
 	"github.com/onsi/gomega/types"
@@ -41,42 +44,23 @@ import (
 //      /home/goworld/coolprojects/mymodule/foo/bar.go:123
 var ReportFilenameWithPath = false
 
-// standardFilters specifies the always automatically included no-leak goroutine
-// filter matchers.
-//
-// Note: it's okay to instantiate the Gomega Matchers here, as all goroutine
-// filtering-related noleak matchers are stateless with respect to any actual
-// value they try to match. This allows us to simply prepend them to any
-// user-supplied optional matchers when HaveLeaked returns a new goroutine
-// leakage detecting matcher.
-//
-// Note: cgo's goroutines with status "[syscall, locked to thread]" do not
-// appear any longer (since mid-2017), as these cgo goroutines are put into the
-// "dead" state when not in use. See: https://github.com/golang/go/issues/16714
-// and https://go-review.googlesource.com/c/go/+/45030/.
-var standardFilters = []types.GomegaMatcher{
-	// Ginkgo testing framework
-	IgnoringTopFunction("github.com/onsi/ginkgo/v2/internal.(*Suite).runNode"),
-	IgnoringTopFunction("github.com/onsi/ginkgo/v2/internal.(*Suite).runNode..."),
-	IgnoringTopFunction("github.com/onsi/ginkgo/v2/internal/interrupt_handler.(*InterruptHandler).registerForInterrupts..."),
-	IgnoringTopFunction("github.com/onsi/ginkgo/internal/specrunner.(*SpecRunner).registerForInterrupts"),
-
-	// goroutines of Go's own testing package for its own workings...
-	IgnoringTopFunction("testing.RunTests [chan receive]"),
-	IgnoringTopFunction("testing.(*T).Run [chan receive]"),
-	IgnoringTopFunction("testing.(*T).Parallel [chan receive]"),
-
-	// os/signal starts its own runtime goroutine, where loop calls signal_recv
-	// in a loop, so we need to expect them both...
-	IgnoringTopFunction("os/signal.signal_recv"),
-	IgnoringTopFunction("os/signal.loop"),
-
-	// signal.Notify starts a runtime goroutine...
-	IgnoringInBacktrace("runtime.ensureSigM"),
-
-	// reading a trace...
-	IgnoringInBacktrace("runtime.ReadTrace"),
-}
+// ForceFullCapture disables the fast path of HaveLeaked when being passed
+// LazyGoroutines as its actual value. When ForceFullCapture is true, HaveLeaked
+// always captures and checks the full list of goroutines, even if the number
+// of goroutines hasn't grown since the baseline was taken.
+var ForceFullCapture = false
+
+// SuggestFilters controls whether HaveLeaked's failure messages suggest
+// ready-to-paste filter matchers for ignoring the leaked goroutines.
+var SuggestFilters = true
+
+// LockWaitThreshold is the minimum duration leaked goroutines need to wait for
+// a lock or semaphore in order to be listed in the "possibly deadlocked on
+// locks" section of HaveLeaked's failure message. As Go's runtime reports
+// waiting durations only in whole minutes, LockWaitThreshold effectively has
+// a granularity of minutes, too. A zero or negative LockWaitThreshold
+// disables this section.
+var LockWaitThreshold = time.Minute
 
 // HaveLeaked succeeds (or rather, "suckceeds" considering it appears in failing
 // tests) if after filtering out ("ignoring") the expected goroutines from the
@@ -89,6 +73,10 @@ var standardFilters = []types.GomegaMatcher{
 // non-leaky goroutine filter matchers. These filtering matchers can be
 // specified in different formats, as described below.
 //
+// The catalog of built-in standard filters, together with the rationale for
+// each filter, is available from StandardFilters. Individual standard filters
+// can be disabled using DisableStandardFilters.
+//
 // Since there might be "pending" goroutines at the end of tests that eventually
 // will properly wind down so they aren't leaking, HaveLeaked is best paired
 // with Eventually instead of Expect. In its shortest form this will use
@@ -122,6 +110,41 @@ var standardFilters = []types.GomegaMatcher{
 //   DoSomething()
 //   Eventually(Goroutines).ShouldNot(HaveLeaked(IgnoringGoroutines(snapshot)))
 //
+// Instead of a slice of Goroutine objects, HaveLeaked accepts a Snapshot (or a
+// pointer to it) as returned by TakeSnapshot. In this case, failure messages
+// additionally report the age of the (most recently passed) snapshot, in order
+// to help diagnosing stale baselines.
+//
+//   snapshot := TakeSnapshot()
+//   DoSomething()
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+//
+// When passing LazyGoroutines instead of Goroutines as the actual value,
+// HaveLeaked first compares the current number of goroutines with the number of
+// goroutines in the baseline, and skips the expensive capture and filtering of
+// goroutines if their number hasn't grown. Please note that this fast path
+// cannot detect a leaked goroutine replacing another goroutine that has
+// terminated in the meantime; set ForceFullCapture to true in order to always
+// do a full capture in correctness-sensitive situations.
+//
+//   snapshot := TakeSnapshot()
+//   DoSomething()
+//   Eventually(LazyGoroutines{}).ShouldNot(HaveLeaked(snapshot))
+//
+// When passed LazyGoroutines and SettleTimeout is set, HaveLeaked additionally
+// gives leaked goroutines up to SettleTimeout to end, counting from its first
+// match, and only then reports them as leaked. This way, the "-noleak.settle"
+// go test flag also applies to plain assertions:
+//
+//   Expect(LazyGoroutines{}).NotTo(HaveLeaked(snapshot))
+//
+// HaveLeaked also accepts a context.Context, such as one with a spec's deadline,
+// that interrupts the settle period when done: HaveLeaked then tells Eventually to
+// stop polling (please see MatchMayChangeInTheFuture for when Eventually
+// consults HaveLeaked) and its failure message notes the interruption.
+//
+//   Eventually(LazyGoroutines{}).ShouldNot(HaveLeaked(ctx, snapshot))
+//
 // Finally, HaveLeaked accepts any GomegaMatcher and will repeatedly pass it a
 // Goroutine object: if the matcher succeeds, the Goroutine object in question
 // is considered to be non-leaked and thus filtered out. While the following
@@ -133,18 +156,87 @@ var standardFilters = []types.GomegaMatcher{
 //   IgnoringTopFunction("foo.bar [chan receive]")
 //   IgnoringGoroutines(expectedGoroutines)
 //   IgnoringInBacktrace("foo.bar.baz")
+//
+// HaveLeaked takes the package-level settings at the time it is called (see
+// also Config). In order to use a configuration independent of the
+// package-level settings, create the matcher using Detector.HaveLeaked
+// instead.
 func HaveLeaked(ignoring ...interface{}) types.GomegaMatcher {
-	m := &HaveLeakedMatcher{filters: standardFilters}
+	return newHaveLeakedMatcher(nil, ignoring)
+}
+
+// HaveLeakedWithin works like HaveLeaked, but when passed LazyGoroutines gives
+// leaked goroutines up to the specified timeout to end, instead of
+// SettleTimeout, polling in intervals determined by the specified Backoff
+// policy. A nil backoff polls every DefaultCheckInterval.
+//
+//   backoff := noleak.ExponentialBackoff{Initial: 10 * time.Millisecond, Max: time.Second, Multiplier: 2}
+//   Expect(LazyGoroutines{}).NotTo(HaveLeakedWithin(time.Minute, backoff, snapshot))
+func HaveLeakedWithin(timeout time.Duration, backoff Backoff, ignoring ...interface{}) types.GomegaMatcher {
+	m := newHaveLeakedMatcher(nil, ignoring)
+	m.settleTimeout = timeout
+	m.backoff = backoff
+	return m
+}
+
+// newHaveLeakedMatcher returns a new HaveLeakedMatcher using the configuration
+// of the specified detector, or the package-level settings if nil.
+func newHaveLeakedMatcher(detector *Detector, ignoring []interface{}) *HaveLeakedMatcher {
+	m := &HaveLeakedMatcher{filters: enabledStandardFilters(), baselineCount: -1, detector: detector}
+	if detector != nil {
+		m.cfg = detector.config
+	} else {
+		m.cfg = DefaultConfig()
+	}
+	if extra := m.config().Ignoring; len(extra) > 0 {
+		m.filters = append(append([]types.GomegaMatcher{}, m.filters...), extra...)
+	}
+	includingInit := false
 	for _, ign := range ignoring {
 		switch ign := ign.(type) {
 		case string:
 			m.filters = append(m.filters, IgnoringTopFunction(ign))
 		case []goroutine.Goroutine:
 			m.filters = append(m.filters, IgnoringGoroutines(ign))
+			m.baselineCount = len(ign)
+			m.baselineGoroutines = ign
+		case goroutine.Snapshot:
+			m.filters = append(m.filters, IgnoringGoroutines(ign.Goroutines))
+			m.baseline = &ign
+			m.baselineCount = ign.Count()
+			m.baselineGoroutines = ign.Goroutines
+		case *goroutine.Snapshot:
+			if ign == nil {
+				panic("HaveLeaked expected a Snapshot, but got a nil *Snapshot")
+			}
+			m.filters = append(m.filters, IgnoringGoroutines(ign.Goroutines))
+			m.baseline = ign
+			m.baselineCount = ign.Count()
+			m.baselineGoroutines = ign.Goroutines
 		case types.GomegaMatcher:
 			m.filters = append(m.filters, ign)
+		case context.Context:
+			m.ctx = ign
+		case Survivors:
+			m.survivors = append(m.survivors, ign.filters...)
+		case Persistence:
+			m.persistence = &persistenceTracker{Persistence: ign}
+		case includingInitGoroutines:
+			includingInit = true
+		case onlyPersistentLeaks:
+			m.onlyPersistent = true
+		case phaseSnapshots:
+			m.phases = append(m.phases, ign...)
+		case countingSystemGoroutines:
+			m.countSystem = true
+			m.systemBaseline = systemGoroutines()
 		default:
-			panic(fmt.Sprintf("HaveLeaked expected a string, []Goroutine, or GomegaMatcher, but got:\n%s", format.Object(ign, 1)))
+			panic(fmt.Sprintf("HaveLeaked expected a string, []Goroutine, Snapshot, GomegaMatcher, Context, Survivors, Persistence, IncludingInitGoroutines, OnlyPersistentLeaks, Phases, or CountingSystemGoroutines, but got:\n%s", format.Object(ign, 1)))
+		}
+	}
+	if !includingInit {
+		if filter := initBaselineFilter(); filter != nil {
+			m.filters = append(m.filters, filter)
 		}
 	}
 	return m
@@ -154,16 +246,136 @@ func HaveLeaked(ignoring ...interface{}) types.GomegaMatcher {
 // the actual list of goroutines is non-empty after filtering out the expected
 // goroutines.
 type HaveLeakedMatcher struct {
-	filters []types.GomegaMatcher // expected goroutines that aren't leaks.
-	leaked  []goroutine.Goroutine // surplus goroutines which we consider to be leaks.
+	filters       []types.GomegaMatcher // expected goroutines that aren't leaks.
+	baseline      *goroutine.Snapshot   // optional baseline snapshot, for reporting its age.
+	baselineCount int                   // number of goroutines in the (last) baseline; -1 if none.
+	leaked        []goroutine.Goroutine // surplus goroutines which we consider to be leaks.
+	stuck         stuckTracker          // tracks stuck goroutines across polls.
+	ctx           context.Context       // optional context interrupting the settle period.
+	test          string                // optional name of the test to attribute leaks to.
+	detector      *Detector             // optional detector with its own configuration.
+	cfg           Config                // configuration when created.
+
+	baselineGoroutines []goroutine.Goroutine // goroutines of the (last) baseline.
+	survivors          []types.GomegaMatcher // baseline goroutines that must not vanish.
+	vanished           []goroutine.Goroutine // baseline goroutines that must not have vanished, but did.
+	persistence        *persistenceTracker   // optional tracker of persistently leaked goroutines.
+	classifier         persistenceClassifier // classifies leaked goroutines as persistent or transient.
+	onlyPersistent     bool                  // only consider persistent goroutines to be leaked.
+	transient          []goroutine.Goroutine // transient goroutines not considered leaked.
+	phases             []goroutine.Snapshot  // optional snapshots delimiting test phases.
+	countSystem        bool                  // report the number of runtime system goroutines.
+	systemBaseline     int                   // number of runtime system goroutines when created; -1 if unknown.
+	finalized          bool                  // the result of the latest Match has already been finally reported.
+	reportErr          error                 // error writing the leak report of the latest Match, if any.
+	fresh              []goroutine.Goroutine // leaked goroutines of the latest Match not reported before.
+	previous           []goroutine.Goroutine // leaked goroutines of the latest Match reported before.
+	settleDeadline     time.Time             // end of the settle period when passed LazyGoroutines.
+	settleTimeout      time.Duration         // optional settle period overriding SettleTimeout.
+	backoff            Backoff               // optional polling policy during the settle period.
 }
 
 var gsT = reflect.TypeOf([]goroutine.Goroutine{})
 
+// config returns the configuration of the Detector this matcher was created
+// by, or otherwise the package-level settings at the time this matcher was
+// created.
+func (matcher *HaveLeakedMatcher) config() Config {
+	return matcher.cfg
+}
+
+// reportedLeaks returns the record of already reported leaks of the Detector
+// this matcher was created by, or otherwise the package-level record.
+func (matcher *HaveLeakedMatcher) reportedLeaks() *reportedLeaks {
+	if matcher.detector != nil {
+		return &matcher.detector.reported
+	}
+	return &reported
+}
+
 // Match succeeds if actual is an array or slice of goroutine.Goroutine
-// information and still contains goroutines after filtering out all expected
-// goroutines that were specified when creating the matcher.
+// information (or a goroutine.Snapshot) and still contains goroutines after
+// filtering out all expected goroutines that were specified when creating the
+// matcher. If actual is LazyGoroutines, then Match captures the current
+// goroutines itself, but only if necessary, and polls until the leaked
+// goroutines have ended or the settle period is over.
 func (matcher *HaveLeakedMatcher) Match(actual interface{}) (success bool, err error) {
+	success, err = matcher.match(actual)
+	if _, lazy := actual.(LazyGoroutines); !lazy || !success || err != nil {
+		return success, err
+	}
+	return matcher.settle(actual)
+}
+
+// settle repeatedly matches the specified actual value until there aren't any
+// leaked goroutines anymore or the settle period has passed, counting from the
+// first time settle was called. The settle period is either the timeout passed
+// to HaveLeakedWithin, or otherwise SettleTimeout.
+func (matcher *HaveLeakedMatcher) settle(actual interface{}) (success bool, err error) {
+	timeout := matcher.settleTimeout
+	if timeout <= 0 {
+		timeout = matcher.config().SettleTimeout
+	}
+	if timeout <= 0 {
+		return true, nil
+	}
+	backoff := matcher.backoff
+	if backoff == nil {
+		backoff = ConstantBackoff(DefaultCheckInterval)
+	}
+	if matcher.settleDeadline.IsZero() {
+		matcher.settleDeadline = time.Now().Add(timeout)
+	}
+	var done <-chan struct{}
+	if matcher.ctx != nil {
+		done = matcher.ctx.Done()
+	}
+	for poll := 0; ; poll++ {
+		interval := nextInterval(backoff, poll)
+		if !time.Now().Add(interval).Before(matcher.settleDeadline) || matcher.interrupted() != nil {
+			return true, nil
+		}
+		select {
+		case <-time.After(interval):
+		case <-done:
+		}
+		if success, err = matcher.match(actual); !success || err != nil {
+			return success, err
+		}
+	}
+}
+
+// match implements a single Match without any settle period.
+func (matcher *HaveLeakedMatcher) match(actual interface{}) (success bool, err error) {
+	matcher.vanished = nil
+	matcher.finalized = false
+	config := matcher.config()
+	if !config.Enabled {
+		matcher.leaked = nil
+		return false, nil
+	}
+	switch snapshot := actual.(type) {
+	case LazyGoroutines:
+		if !config.ForceFullCapture && len(matcher.survivors) == 0 && matcher.baselineCount >= 0 &&
+			runtime.NumGoroutine() <= matcher.baselineCount {
+			matcher.leaked = nil
+			return false, nil
+		}
+		preCaptureGrace()
+		gs, err := captureGoroutines(config.CaptureTimeout)
+		if err != nil {
+			matcher.leaked = nil
+			return false, err
+		}
+		births.observe(gs)
+		actual = gs
+	case goroutine.Snapshot:
+		actual = snapshot.Goroutines
+	case *goroutine.Snapshot:
+		if snapshot != nil {
+			actual = snapshot.Goroutines
+		}
+	}
 	val := reflect.ValueOf(actual)
 	switch val.Kind() {
 	case reflect.Array, reflect.Slice:
@@ -182,7 +394,22 @@ func (matcher *HaveLeakedMatcher) Match(actual interface{}) (success bool, err e
 	if err != nil {
 		return false, err
 	}
-	if len(matcher.leaked) == 0 {
+	matcher.classifier.update(matcher.leaked)
+	matcher.transient = nil
+	if matcher.onlyPersistent {
+		matcher.leaked, matcher.transient = matcher.classifier.split(matcher.leaked)
+	}
+	if matcher.persistence != nil {
+		matcher.leaked = matcher.persistence.persistent(matcher.leaked)
+	}
+	sortGoroutines(matcher.leaked)
+	sortGoroutines(matcher.transient)
+	matcher.stuck.update(matcher.leaked)
+	matcher.vanished, err = matcher.vanishedSurvivors(goroutines)
+	if err != nil {
+		return false, err
+	}
+	if len(matcher.leaked) == 0 && len(matcher.vanished) == 0 {
 		return false, nil
 	}
 	return true, nil // we have leak(ed)
@@ -190,19 +417,180 @@ func (matcher *HaveLeakedMatcher) Match(actual interface{}) (success bool, err e
 
 // FailureMessage returns a failure message if there are leaked goroutines.
 func (matcher *HaveLeakedMatcher) FailureMessage(actual interface{}) (message string) {
-	return fmt.Sprintf("Expected to leak %d goroutines:\n%s", len(matcher.leaked), matcher.listGoroutines(matcher.leaked, 1))
+	matcher.recaptureLeaked()
+	return matcher.render(false, fmt.Sprintf("Expected to leak %d goroutines%s:\n%s",
+		len(matcher.leaked), matcher.baselineAge(), matcher.listGoroutines(matcher.leaked, 1)))
 }
 
-// NegatedFailureMessage returns a negated failure message if there aren't any leaked goroutines.
+// NegatedFailureMessage returns a negated failure message if there aren't any
+// leaked goroutines. As Gomega asks for this message only when an assertion
+// finally fails, NegatedFailureMessage also finalizes the failed check.
 func (matcher *HaveLeakedMatcher) NegatedFailureMessage(actual interface{}) (message string) {
-	return fmt.Sprintf("Expected not to leak %d goroutines:\n%s", len(matcher.leaked), matcher.listGoroutines(matcher.leaked, 1))
+	matcher.recaptureLeaked()
+	matcher.finalize()
+	return matcher.render(true, matcher.negatedFailureMessage())
+}
+
+// finalize notifies any registered leak hooks about the leaked goroutines of
+// the latest Match and writes a leak report if a report directory has been
+// configured. When deduplicating reports, finalize also partitions the leaked
+// goroutines into the fresh and the previously reported ones. No matter how
+// often the failure message gets asked for, such as by custom reporters,
+// finalize does so only once per Match.
+func (matcher *HaveLeakedMatcher) finalize() {
+	if matcher.finalized {
+		return
+	}
+	matcher.finalized = true
+	matcher.fresh, matcher.previous = matcher.leaked, nil
+	if matcher.config().DeduplicateReports {
+		matcher.fresh, matcher.previous = matcher.reportedLeaks().partition(matcher.leaked)
+	}
+	notifyLeakHooks(matcher.test, matcher.leaked, false)
+	matcher.reportErr = nil
+	if len(matcher.leaked) > 0 {
+		matcher.reportErr = matcher.config().writeReport(matcher.leaked)
+	}
+}
+
+// negatedFailureMessage returns the default negated failure message, reporting
+// the leaked goroutines, or otherwise the unexpectedly vanished goroutines. It
+// relies on finalize having partitioned the leaked goroutines into the fresh
+// and previously reported ones.
+func (matcher *HaveLeakedMatcher) negatedFailureMessage() (message string) {
+	if len(matcher.leaked) == 0 {
+		return fmt.Sprintf("Expected %d goroutines not to vanish%s:\n%s",
+			len(matcher.vanished), matcher.baselineAge(), matcher.listGoroutines(matcher.vanished, 1))
+	}
+	previous := counted(len(matcher.previous), "previously-reported leak", "previously-reported leaks")
+	ids := "goroutines " + goids(matcher.previous)
+	if len(matcher.previous) == 1 {
+		ids = "goroutine " + goids(matcher.previous)
+	}
+	if len(matcher.fresh) == 0 {
+		return fmt.Sprintf("Expected not to leak %d goroutines%s: %s still present (%s)",
+			len(matcher.leaked), matcher.baselineAge(), previous, ids)
+	}
+	message = fmt.Sprintf("Expected not to leak %d goroutines%s:\n%s",
+		len(matcher.leaked), matcher.baselineAge(), matcher.leakDetails(matcher.fresh))
+	if len(matcher.previous) > 0 {
+		message += fmt.Sprintf("\n(plus %s still present: %s)", previous, ids)
+	}
+	return message
+}
+
+// leakDetails returns the detailed description of the specified leaked goroutines,
+// followed by the diagnostic sections about stuck and possibly deadlocked
+// goroutines. If there are many leaked goroutines, leakDetails starts with a
+// summary of their creator locations.
+func (matcher *HaveLeakedMatcher) leakDetails(leaked []goroutine.Goroutine) (message string) {
+	config := matcher.config()
+	if hotspots := config.creatorHotSpots(leaked); len(hotspots) > 0 {
+		message = "Leaked goroutines by creator location:\n" +
+			format.Indent + strings.Join(hotspots, "\n"+format.Indent) + "\n"
+	}
+	message += matcher.listGoroutines(leaked, 1)
+	if config.SuggestFilters {
+		if suggestions := suggestFilters(leaked); len(suggestions) > 0 {
+			message += "\nIf these goroutines are expected, consider ignoring them using:\n" +
+				format.Indent + strings.Join(suggestions, "\n"+format.Indent)
+		}
+	}
+	message += matcher.persistenceDetails(leaked)
+	message += matcher.phaseDetails(leaked)
+	if stuck := matcher.stuck.stuckOnes(leaked); len(stuck) > 0 {
+		message += fmt.Sprintf("\nStuck goroutines (unchanged while waiting increasingly longer):\n%s",
+			matcher.listGoroutines(stuck, 1))
+	}
+	if hypotheses := deadlockHypotheses(leaked); len(hypotheses) > 0 {
+		message += "\nPossible deadlocks:\n" + format.Indent + "- " +
+			strings.Join(hypotheses, "\n"+format.Indent+"- ")
+	}
+	if deadlocked := config.longLockWaits(leaked); len(deadlocked) > 0 {
+		message += fmt.Sprintf("\nPossibly deadlocked on locks (waiting for at least %s):\n%s",
+			config.LockWaitThreshold, matcher.listGoroutines(deadlocked, 1))
+	}
+	if len(matcher.vanished) > 0 {
+		message += fmt.Sprintf("\nUnexpectedly vanished goroutines:\n%s",
+			matcher.listGoroutines(matcher.vanished, 1))
+	}
+	message += matcher.systemGoroutinesDetails()
+	if err := matcher.interrupted(); err != nil {
+		message += fmt.Sprintf("\n(the settle period was interrupted: %s)", err)
+	}
+	if matcher.reportErr != nil {
+		message += fmt.Sprintf("\n(%s)", matcher.reportErr)
+	}
+	return message
 }
 
+// MatchMayChangeInTheFuture implements Gomega's oracle interface, telling
+// Eventually that it can stop polling early when the actual value is a fixed
+// list or snapshot of goroutines: these cannot change anymore, so the outcome
+// of matching them won't change either. Only when actual is LazyGoroutines
+// might the outcome change, as HaveLeaked then captures the goroutines itself.
+//
+// Please note that Eventually doesn't consult this oracle when passed a
+// function, so the intended usage pattern is (note the missing "()"):
+//
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+//
+// instead of Eventually(Goroutines()), which polls the same fixed list of
+// goroutines over and over again.
+//
+// In addition, once the optional context passed to HaveLeaked is done, the
+// outcome is final. In order to stop polling promptly in this case, pass
+// LazyGoroutines to Eventually instead of the Goroutines function.
+func (matcher *HaveLeakedMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	if matcher.interrupted() != nil {
+		return false
+	}
+	_, lazy := actual.(LazyGoroutines)
+	return lazy
+}
+
+// interrupted returns the reason for the settle period having been
+// interrupted by the optional context passed to HaveLeaked, or nil otherwise.
+func (matcher *HaveLeakedMatcher) interrupted() error {
+	if matcher.ctx == nil {
+		return nil
+	}
+	return matcher.ctx.Err()
+}
+
+// recaptureLeaked retrieves the full backtraces of leaked goroutines that were
+// captured using LightweightGoroutines, as only now the backtraces are needed
+// for the failure message.
+func (matcher *HaveLeakedMatcher) recaptureLeaked() {
+	for _, g := range matcher.leaked {
+		if g.IsLightweight() {
+			matcher.leaked = goroutine.Recapture(matcher.leaked)
+			return
+		}
+	}
+}
+
+// baselineAge returns a textual description of the age of the baseline
+// snapshot, if any, or an empty string otherwise.
+func (matcher *HaveLeakedMatcher) baselineAge() string {
+	if matcher.baseline == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (baseline snapshot taken %s ago)",
+		matcher.baseline.Age().Round(time.Millisecond))
+}
+
+// activeMarker flags leaked goroutines that are running or ready to run in
+// listings of goroutines. As the calling goroutine is always filtered out,
+// such leaked goroutines usually are busy-looping and thus eating CPU.
+const activeMarker = " <-- actively running, possibly busy-looping"
+
 // listGoroutines returns a somewhat compact textual representation of the
 // specified goroutines, by ignoring the often quite lengthy backtrace
 // information.
 func (matcher *HaveLeakedMatcher) listGoroutines(gs []goroutine.Goroutine, indentation uint) string {
 	var buff strings.Builder
+	config := matcher.config()
 	indent := strings.Repeat(format.Indent, int(indentation))
 	backtraceIdent := strings.Repeat(format.Indent, int(indentation+1))
 	for gidx, g := range gs {
@@ -214,53 +602,51 @@ func (matcher *HaveLeakedMatcher) listGoroutines(gs []goroutine.Goroutine, inden
 		buff.WriteString(strconv.FormatUint(g.ID, 10))
 		buff.WriteString(" [")
 		buff.WriteString(g.State)
-		buff.WriteString("]\n")
+		buff.WriteRune(']')
+		if g.IsActive() {
+			buff.WriteString(activeMarker)
+		}
+		buff.WriteRune('\n')
 
-		backtrace := g.Backtrace
-		for backtrace != "" {
-			buff.WriteString(backtraceIdent)
-			// take the next two lines (function name and file name plus line
-			// number) and output them as a single indented line.
-			nlIdx := strings.IndexRune(backtrace, '\n')
-			if nlIdx < 0 {
-				// ...a dodgy single line
-				buff.WriteString(backtrace)
-				break
-			}
-			calledFuncName := backtrace[:nlIdx]
-			// Take care of not mangling the optional "created by " prefix is
-			// present, when formatting the location to use either long or
-			// shortened filenames and paths.
-			location := backtrace[nlIdx+1:]
-			nnlIdx := strings.IndexRune(location, '\n')
-			if nnlIdx >= 0 {
-				backtrace, location = location[nnlIdx+1:], location[:nnlIdx]
-			} else {
-				backtrace = "" // ...the next location line is missing
-			}
-			// Don't accidentally strip off the "created by" prefix when
-			// shortening the call site location filename...
-			location = strings.TrimSpace(location) // strip of indentation
-			lineno := ""
-			if linenoIdx := strings.LastIndex(location, ":"); linenoIdx >= 0 {
-				location, lineno = location[:linenoIdx], location[linenoIdx+1:]
-			}
-			location = formatFilename(location) + ":" + lineno
-			// Add to compact backtrace
-			buff.WriteString(calledFuncName)
-			buff.WriteString(" at ")
-			// Don't output any program counter hex offsets, so strip them out
-			// here, if present; well, they should always be present, but better
-			// safe than sorry.
-			if offsetIdx := strings.LastIndexFunc(location,
-				func(r rune) bool { return r == ' ' }); offsetIdx >= 0 {
-				buff.WriteString(location[:offsetIdx])
-			} else {
-				buff.WriteString(location)
+		// Render each call on a single line, followed by the location of the
+		// call as parsed into the goroutine's frames. For goroutines without
+		// parsed frames, such as goroutines put together by hand, fall back
+		// to the location line following the call in the backtrace.
+		frames := g.Frames
+		first := true
+		for rest := g.Backtrace; rest != ""; {
+			var line string
+			line, rest, _ = strings.Cut(rest, "\n")
+			if line == "" || line[0] == ' ' || line[0] == '\t' {
+				continue
 			}
-			if backtrace != "" {
+			if !first {
 				buff.WriteRune('\n')
 			}
+			first = false
+			buff.WriteString(backtraceIdent)
+			switch {
+			case strings.HasPrefix(line, "..."):
+				// Elision markers in deep backtraces are single lines without
+				// any location, so render them in a more compact form.
+				buff.WriteString("… ")
+				buff.WriteString(strings.Trim(line, "."))
+			case strings.HasPrefix(line, "created by "):
+				buff.WriteString(line)
+				frame := g.CreatorFrame
+				if frame.File == "" {
+					frame = locationFrame(rest)
+				}
+				config.writeFrameLocation(&buff, frame)
+			default:
+				buff.WriteString(line)
+				if len(frames) > 0 && strings.HasPrefix(line, frames[0].Function+"(") {
+					config.writeFrameLocation(&buff, frames[0])
+					frames = frames[1:]
+				} else {
+					config.writeFrameLocation(&buff, locationFrame(rest))
+				}
+			}
 		}
 	}
 	return buff.String()
@@ -296,22 +682,101 @@ nextgoroutine:
 	return gs, nil
 }
 
+// longLockWaits returns those of the specified goroutines that have been
+// waiting for a lock or semaphore for at least the configured lock wait
+// threshold.
+func (c Config) longLockWaits(gs []goroutine.Goroutine) []goroutine.Goroutine {
+	if c.LockWaitThreshold <= 0 {
+		return nil
+	}
+	var waiting []goroutine.Goroutine
+	for _, g := range gs {
+		if g.BaseState().IsLockWait() &&
+			time.Duration(g.BlockedMinutes())*time.Minute >= c.LockWaitThreshold {
+			waiting = append(waiting, g)
+		}
+	}
+	return waiting
+}
+
+// sortGoroutines sorts the specified goroutines in place, with actively
+// running (or runnable) goroutines first as these are the most urgent leaks,
+// then by their creator locations, then top function names, and finally IDs.
+// In contrast to the raw dump order this keeps the order of leaked goroutines
+// in successive failure messages stable, so that failures can be diffed and
+// deduplicated.
+func sortGoroutines(gs []goroutine.Goroutine) {
+	sort.SliceStable(gs, func(i, j int) bool {
+		gi, gj := gs[i], gs[j]
+		if ai, aj := gi.IsActive(), gj.IsActive(); ai != aj {
+			return ai
+		}
+		ci, cj := gi.CreatorFrame, gj.CreatorFrame
+		if ci.File != cj.File {
+			return ci.File < cj.File
+		}
+		if ci.Line != cj.Line {
+			return ci.Line < cj.Line
+		}
+		if gi.TopFunction != gj.TopFunction {
+			return gi.TopFunction < gj.TopFunction
+		}
+		return gi.ID < gj.ID
+	})
+}
+
+// locationFrame returns a frame with only the call location taken from the
+// first line of the specified backtrace rest, if it is an indented location
+// line; otherwise, it returns a zero frame. The optional hex offset of the
+// location is dropped.
+func locationFrame(rest string) goroutine.Frame {
+	if rest == "" || (rest[0] != '\t' && rest[0] != ' ') {
+		return goroutine.Frame{}
+	}
+	line, _, _ := strings.Cut(rest, "\n")
+	line = strings.TrimSpace(line)
+	if offsetIdx := strings.LastIndex(line, " +0x"); offsetIdx >= 0 {
+		line = line[:offsetIdx]
+	}
+	file, lineno := goroutine.SplitLocation(line)
+	return goroutine.Frame{Location: line, File: file, Line: lineno}
+}
+
+// writeFrameLocation writes the location of the specified frame, if known, in
+// the form of " at file-name:line-number", shortening the file name unless
+// ReportFilenameWithPath is set.
+func (c Config) writeFrameLocation(buff *strings.Builder, frame goroutine.Frame) {
+	if frame.File == "" {
+		return
+	}
+	buff.WriteString(" at ")
+	buff.WriteString(c.formatFilename(frame.File))
+	if frame.Line > 0 {
+		buff.WriteRune(':')
+		buff.WriteString(strconv.Itoa(frame.Line))
+	}
+}
+
 // formatFilename takes the ReportFilenameWithPath setting into account to
 // either return the full specified filename with a path or alternatively
 // shortening it to contain only the package name and the filename, but not the
 // full path.
-func formatFilename(filename string) string {
-	if ReportFilenameWithPath {
+func (c Config) formatFilename(filename string) string {
+	if c.ReportFilenameWithPath {
 		return filename
 	}
-	dir := filepath.Dir(filename)
-	pkg := filepath.Base(dir)
-	switch pkg {
-	case ".", "..", "/", "\\":
+	// Go dumps stacks always with file locations containing forward slashes,
+	// even on Windows. However, dumps might have been post-processed, so we
+	// don't rely on this and also accept backslashes, independent of the
+	// platform we're running on. Thus, we do NOT use filepath here, but
+	// instead path in order to keep with using forward slashes.
+	filename = strings.ReplaceAll(filename, "\\", "/")
+	pkg := path.Base(path.Dir(filename))
+	switch {
+	case pkg == "." || pkg == ".." || pkg == "/":
+		pkg = ""
+	case strings.HasSuffix(pkg, ":"): // Windows drive letter, such as "C:"
 		pkg = ""
 	}
-	// Go dumps stacks always with file locations containing forward slashes,
-	// even on Windows. Thus, we do NOT use filepath.Join here, but instead
-	// path.Join in order to keep with using forward slashes.
-	return path.Join(pkg, filepath.ToSlash(filepath.Base(filename)))
+	return path.Join(pkg, path.Base(filename))
 }
@@ -15,8 +15,10 @@
 package noleak
 
 import (
+	"context"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
 )
 
 // Note: Go's stack dumps (backtraces) always contain forward slashes, even on
@@ -90,6 +93,28 @@ created by main.foo`,
         created by main.foo`))
 	})
 
+	It("renders elided frames", func() {
+		gs := []goroutine.Goroutine{
+			{
+				ID:    42,
+				State: "stoned",
+				Backtrace: `main.foo.func1()
+		/home/foo/test.go:6 +0x28
+...42 frames elided...
+main.foo.func1()
+		/home/foo/test.go:6 +0x28
+...additional frames elided...
+`,
+			},
+		}
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		Expect(m.listGoroutines(gs, 1)).To(Equal(`    goroutine 42 [stoned]
+        main.foo.func1() at foo/test.go:6
+        … 42 frames elided
+        main.foo.func1() at foo/test.go:6
+        … additional frames elided`))
+	})
+
 	It("considers testing and runtime goroutines not to be leaks", func() {
 		Expect(Goroutines()).NotTo(HaveLeaked(), "should not find any leaks by default")
 	})
@@ -132,6 +157,243 @@ created by main.foo`,
 		}).Should(BeFalse())
 	})
 
+	It("checks against a baseline snapshot", func() {
+		By("taking a snapshot")
+		snapshot := TakeSnapshot()
+		m := HaveLeaked(snapshot)
+		Expect(m.Match(TakeSnapshot())).To(BeFalse())
+		Expect(HaveLeaked(&snapshot).Match(&snapshot)).To(BeFalse())
+
+		By("starting a goroutine")
+		done := make(chan struct{})
+		var once sync.Once
+		go func() {
+			<-done
+		}()
+		defer once.Do(func() { close(done) })
+
+		By("detecting the goroutine and reporting the snapshot age")
+		Expect(m.Match(TakeSnapshot())).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(MatchRegexp(
+			`^Expected not to leak 1 goroutines \(baseline snapshot taken .+ ago\):\n`))
+		Expect(m.FailureMessage(nil)).To(MatchRegexp(
+			`^Expected to leak 1 goroutines \(baseline snapshot taken .+ ago\):\n`))
+
+		By("terminating the goroutine and ensuring it has terminated")
+		once.Do(func() { close(done) })
+		Eventually(TakeSnapshot).ShouldNot(HaveLeaked(snapshot))
+	})
+
+	Context("lazily capturing goroutines", func() {
+
+		// paddedSnapshot returns a snapshot of the current goroutines, padded
+		// with an additional fake goroutine, as well as a function to start a
+		// goroutine that then gets leaked.
+		paddedSnapshot := func() (goroutine.Snapshot, func()) {
+			snapshot := TakeSnapshot()
+			snapshot.Goroutines = append(snapshot.Goroutines, goroutine.Goroutine{TopFunction: "foo.bar"})
+			done := make(chan struct{})
+			DeferCleanup(func() {
+				close(done)
+				Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+			})
+			return snapshot, func() { go func() { <-done }() }
+		}
+
+		It("skips capturing when the number of goroutines hasn't grown", func() {
+			m := HaveLeaked(TakeSnapshot())
+			Expect(m.Match(LazyGoroutines{})).To(BeFalse())
+
+			// The fast path cannot notice a leaked goroutine replacing another
+			// goroutine, which we simulate using a padded baseline.
+			snapshot, leak := paddedSnapshot()
+			leak()
+			m = HaveLeaked(snapshot)
+			Expect(m.Match(LazyGoroutines{})).To(BeFalse())
+			Expect(m.Match(Goroutines())).To(BeTrue())
+		})
+
+		It("captures goroutines when their number has grown or no baseline is known", func() {
+			snapshot := Goroutines()
+			m := HaveLeaked(snapshot)
+			done := make(chan struct{})
+			go func() { <-done }()
+			Expect(m.Match(LazyGoroutines{})).To(BeTrue())
+			close(done)
+			Eventually(LazyGoroutines{}).ShouldNot(HaveLeaked(snapshot))
+
+			Expect(HaveLeaked().Match(LazyGoroutines{})).To(BeFalse())
+		})
+
+		It("gives leaked goroutines SettleTimeout to end", func() {
+			defer func(old time.Duration) { SettleTimeout = old }(SettleTimeout)
+			SettleTimeout = 2 * time.Second
+			snapshot := Goroutines()
+			done := make(chan struct{})
+			go func() { <-done }()
+			time.AfterFunc(50*time.Millisecond, func() { close(done) })
+			Expect(LazyGoroutines{}).NotTo(HaveLeaked(snapshot))
+
+			SettleTimeout = 100 * time.Millisecond
+			done = make(chan struct{})
+			defer close(done)
+			go func() { <-done }()
+			start := time.Now()
+			Expect(HaveLeaked(snapshot).Match(LazyGoroutines{})).To(BeTrue())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		When("forcing full captures", Ordered, func() {
+
+			BeforeAll(func() {
+				oldState := ForceFullCapture
+				ForceFullCapture = true
+				DeferCleanup(func() {
+					ForceFullCapture = oldState
+				})
+			})
+
+			It("doesn't take the fast path", func() {
+				snapshot, leak := paddedSnapshot()
+				leak()
+				Expect(HaveLeaked(snapshot).Match(LazyGoroutines{})).To(BeTrue())
+			})
+
+		})
+
+	})
+
+	It("annotates possible deadlocks", func() {
+		m := HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{
+			{ID: 1<<62 + 1, State: "chan send", CreatorFunction: "foo.a"},
+			{ID: 1<<62 + 2, State: "running", CreatorFunction: "foo.a"},
+		})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("Possible deadlocks"))
+
+		Expect(m.Match([]goroutine.Goroutine{
+			{ID: 1<<62 + 1, State: "chan send", CreatorFunction: "foo.a"},
+			{ID: 1<<62 + 2, State: "chan receive, 2 minutes", CreatorFunction: "foo.a"},
+			{ID: 1<<62 + 3, State: "chan receive (nil chan)"},
+		})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(HaveSuffix("\nPossible deadlocks:\n" +
+			"    - goroutines 4611686018427387907 are blocked forever on nil channels\n" +
+			"    - goroutines 4611686018427387905 blocked sending and goroutines 4611686018427387906 blocked receiving, all created by foo.a, might be waiting on each other\n" +
+			"    - all 3 leaked goroutines are blocked on channels or locks, without any runnable peer among them to unblock the others"))
+
+		Expect(m.Match([]goroutine.Goroutine{
+			{ID: 1<<62 + 1, State: "sync.Mutex.Lock", CreatorFunction: "foo.a"},
+			{ID: 1<<62 + 2, State: "sleep", CreatorFunction: "foo.b"},
+			{ID: 1<<62 + 3, State: "IO wait", CreatorFunction: "foo.c"},
+		})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("Possible deadlocks"))
+	})
+
+	It("lists goroutines waiting on locks for a long time", func() {
+		gs := []goroutine.Goroutine{
+			{ID: 1<<62 + 1, State: "sync.Mutex.Lock, 5 minutes", TopFunction: "foo.a"},
+			{ID: 1<<62 + 2, State: "semacquire, 1 minutes", TopFunction: "foo.b"},
+			{ID: 1<<62 + 3, State: "sync.Mutex.Lock", TopFunction: "foo.c"},
+			{ID: 1<<62 + 4, State: "chan receive, 10 minutes", TopFunction: "foo.d"},
+		}
+		m := HaveLeaked()
+		Expect(m.Match(gs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(HaveSuffix(
+			"\nPossibly deadlocked on locks (waiting for at least 1m0s):\n" +
+				"    goroutine 4611686018427387905 [sync.Mutex.Lock, 5 minutes]\n\n" +
+				"    goroutine 4611686018427387906 [semacquire, 1 minutes]\n"))
+
+		oldThreshold := LockWaitThreshold
+		defer func() { LockWaitThreshold = oldThreshold }()
+		LockWaitThreshold = 2 * time.Minute
+		m = HaveLeaked()
+		Expect(m.Match(gs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(HaveSuffix(
+			"\nPossibly deadlocked on locks (waiting for at least 2m0s):\n" +
+				"    goroutine 4611686018427387905 [sync.Mutex.Lock, 5 minutes]\n"))
+		LockWaitThreshold = 0
+		m = HaveLeaked()
+		Expect(m.Match(gs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("Possibly deadlocked"))
+	})
+
+	It("sorts leaked goroutines deterministically", func() {
+		gs := []goroutine.Goroutine{
+			{ID: 1<<62 + 5, TopFunction: "foo.b", CreatorFrame: goroutine.Frame{File: "/foo/b.go", Line: 10}},
+			{ID: 1<<62 + 4, TopFunction: "foo.b", CreatorFrame: goroutine.Frame{File: "/foo/b.go", Line: 9}},
+			{ID: 1<<62 + 3, TopFunction: "foo.b", CreatorFrame: goroutine.Frame{File: "/foo/a.go", Line: 42}},
+			{ID: 1<<62 + 2, TopFunction: "foo.a", CreatorFrame: goroutine.Frame{File: "/foo/b.go", Line: 9}},
+			{ID: 1<<62 + 1, TopFunction: "foo.a", CreatorFrame: goroutine.Frame{File: "/foo/b.go", Line: 9}},
+			{ID: 1<<62 + 6, TopFunction: "foo.z"},
+		}
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		Expect(m.Match(gs)).To(BeTrue())
+		ids := []uint64{}
+		for _, g := range m.leaked {
+			ids = append(ids, g.ID-1<<62)
+		}
+		Expect(ids).To(Equal([]uint64{6, 3, 1, 2, 4, 5}))
+	})
+
+	It("sorts and flags actively running leaked goroutines first", func() {
+		gs := []goroutine.Goroutine{
+			{ID: 1<<62 + 1, State: "chan receive", TopFunction: "foo.a", BornAt: "/foo/a.go:1"},
+			{ID: 1<<62 + 2, State: "running", TopFunction: "foo.z", BornAt: "/foo/z.go:1"},
+			{ID: 1<<62 + 3, State: "runnable", TopFunction: "foo.y", BornAt: "/foo/y.go:1"},
+		}
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		Expect(m.Match(gs)).To(BeTrue())
+		ids := []uint64{}
+		for _, g := range m.leaked {
+			ids = append(ids, g.ID-1<<62)
+		}
+		Expect(ids).To(Equal([]uint64{3, 2, 1}))
+		msg := m.listGoroutines(m.leaked, 1)
+		Expect(msg).To(ContainSubstring("[runnable]" + activeMarker + "\n"))
+		Expect(msg).To(ContainSubstring("[running]" + activeMarker + "\n"))
+		Expect(msg).To(ContainSubstring("[chan receive]\n"))
+	})
+
+	It("tells Eventually whether matching might change", func() {
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		Expect(m.MatchMayChangeInTheFuture(LazyGoroutines{})).To(BeTrue())
+		Expect(m.MatchMayChangeInTheFuture(Goroutines())).To(BeFalse())
+		Expect(m.MatchMayChangeInTheFuture(TakeSnapshot())).To(BeFalse())
+
+		for _, filter := range []types.GomegaMatcher{
+			IgnoringTopFunction("foo.bar"),
+			IgnoringCreator("foo.bar"),
+			IgnoringInBacktrace("foo.bar"),
+			IgnoringGoroutines(nil),
+		} {
+			Expect(types.MatchMayChangeInTheFuture(filter, goroutine.Goroutine{})).To(BeFalse())
+		}
+
+		// Eventually gives up immediately on a fixed list of goroutines.
+		start := time.Now()
+		failures := InterceptGomegaFailures(func() {
+			Eventually([]goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.bar"}}).
+				WithTimeout(10 * time.Second).ShouldNot(HaveLeaked())
+		})
+		Expect(failures).To(HaveLen(1))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	It("recaptures backtraces of leaked lightweight goroutines", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go func() { <-done }()
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+
+		m := HaveLeaked(snapshot)
+		Expect(m.Match(LightweightGoroutines())).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(MatchRegexp(
+			`Expected not to leak 1 goroutines:\n    goroutine \d+ \[.*\]\n        github.com/thediveo/noleak\..* at .*have_leaked_matcher_test.go:\d+\n`))
+	})
+
 	Context("failure messages", func() {
 
 		var snapshot []goroutine.Goroutine
@@ -174,7 +436,9 @@ created by main.foo`,
 
 			It("rejects unsupported filter args types", func() {
 				Expect(func() { _ = HaveLeaked(42) }).To(PanicWith(
-					"HaveLeaked expected a string, []Goroutine, or GomegaMatcher, but got:\n    <int>: 42"))
+					"HaveLeaked expected a string, []Goroutine, Snapshot, GomegaMatcher, Context, Survivors, Persistence, IncludingInitGoroutines, OnlyPersistentLeaks, Phases, or CountingSystemGoroutines, but got:\n    <int>: 42"))
+				Expect(func() { _ = HaveLeaked((*goroutine.Snapshot)(nil)) }).To(PanicWith(
+					"HaveLeaked expected a Snapshot, but got a nil *Snapshot"))
 			})
 
 			It("accepts plain strings as filters", func() {
@@ -251,6 +515,17 @@ created by main.foo`,
 
 	Context("handling file names and paths in backtraces", func() {
 
+		It("renders frame locations", func() {
+			location := func(frame goroutine.Frame) string {
+				var buff strings.Builder
+				DefaultConfig().writeFrameLocation(&buff, frame)
+				return buff.String()
+			}
+			Expect(location(goroutine.Frame{File: "/home/foo/test.go", Line: 6})).To(Equal(" at foo/test.go:6"))
+			Expect(location(goroutine.Frame{File: `C:\foo\test.go`})).To(Equal(" at foo/test.go"))
+			Expect(location(goroutine.Frame{})).To(BeEmpty())
+		})
+
 		When("ReportFilenameWithPath is true", Ordered, func() {
 
 			var oldState bool
@@ -264,7 +539,7 @@ created by main.foo`,
 			})
 
 			It("doesn't shorten filenames", func() {
-				Expect(formatFilename("/home/foo/bar/baz.go")).To(Equal("/home/foo/bar/baz.go"))
+				Expect(DefaultConfig().formatFilename("/home/foo/bar/baz.go")).To(Equal("/home/foo/bar/baz.go"))
 			})
 
 		})
@@ -282,14 +557,59 @@ created by main.foo`,
 			})
 
 			It("does return only package and filename, but no path", func() {
-				Expect(formatFilename("/home/foo/bar/baz.go")).To(Equal("bar/baz.go"))
-				Expect(formatFilename("/bar/baz.go")).To(Equal("bar/baz.go"))
-				Expect(formatFilename("/baz.go")).To(Equal("baz.go"))
-				Expect(formatFilename("/")).To(Equal("/"))
+				Expect(DefaultConfig().formatFilename("/home/foo/bar/baz.go")).To(Equal("bar/baz.go"))
+				Expect(DefaultConfig().formatFilename("/bar/baz.go")).To(Equal("bar/baz.go"))
+				Expect(DefaultConfig().formatFilename("/baz.go")).To(Equal("baz.go"))
+				Expect(DefaultConfig().formatFilename("/")).To(Equal("/"))
+			})
+
+			It("handles Windows paths", func() {
+				Expect(DefaultConfig().formatFilename(`C:\Users\foo\bar\baz.go`)).To(Equal("bar/baz.go"))
+				Expect(DefaultConfig().formatFilename("C:/Users/foo/bar/baz.go")).To(Equal("bar/baz.go"))
+				Expect(DefaultConfig().formatFilename(`C:\baz.go`)).To(Equal("baz.go"))
+			})
+
+			It("renders backtraces with Windows paths", func() {
+				m := HaveLeaked().(*HaveLeakedMatcher)
+				Expect(m.listGoroutines([]goroutine.Goroutine{
+					{
+						ID:    42,
+						State: "stoned",
+						Backtrace: `main.foo.func1()
+	C:\Users\foo\test.go:6 +0x28
+created by main.foo in goroutine 1
+	C:\Program Files\foo\test.go:5 +0x64
+`,
+					},
+				}, 1)).To(Equal(`    goroutine 42 [stoned]
+        main.foo.func1() at foo/test.go:6
+        created by main.foo in goroutine 1 at foo/test.go:5`))
 			})
 
 		})
 
 	})
 
+	It("stops polling when the context is done", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		ctx, cancel := context.WithCancel(context.Background())
+		m := HaveLeaked(ctx, snapshot).(*HaveLeakedMatcher)
+		Expect(m.MatchMayChangeInTheFuture(LazyGoroutines{})).To(BeTrue())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		failures := InterceptGomegaFailures(func() {
+			Eventually(LazyGoroutines{}).WithTimeout(10 * time.Second).ShouldNot(m)
+		})
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		Expect(failures).To(ConsistOf(ContainSubstring(
+			"\n(the settle period was interrupted: context canceled)")))
+		Expect(m.MatchMayChangeInTheFuture(LazyGoroutines{})).To(BeFalse())
+	})
+
 })
@@ -0,0 +1,127 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/onsi/gomega/format"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// snapshotRing is a ring buffer keeping only the most recent snapshots.
+type snapshotRing struct {
+	snapshots []goroutine.Snapshot
+	next      int // index of the slot to overwrite next.
+	full      bool
+}
+
+// newSnapshotRing returns a new ring buffer for the specified number of
+// snapshots.
+func newSnapshotRing(size int) *snapshotRing {
+	return &snapshotRing{snapshots: make([]goroutine.Snapshot, size)}
+}
+
+// add adds the specified snapshot, evicting the oldest snapshot if the ring
+// buffer is full.
+func (r *snapshotRing) add(s goroutine.Snapshot) {
+	r.snapshots[r.next] = s
+	r.next = (r.next + 1) % len(r.snapshots)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the snapshots in the ring buffer, oldest first.
+func (r *snapshotRing) list() []goroutine.Snapshot {
+	if !r.full {
+		return append([]goroutine.Snapshot{}, r.snapshots[:r.next]...)
+	}
+	return append(append([]goroutine.Snapshot{}, r.snapshots[r.next:]...), r.snapshots[:r.next]...)
+}
+
+// KeepHistory tells this monitor to keep the leaked goroutines of its last n
+// checks, in order to show how leaks accumulated over time instead of only the
+// leaks of the latest check. If w is non-nil, the monitor writes the history
+// (see HistoryReport) to w whenever it detects new leaks. A zero or negative
+// n disables keeping a history.
+func (m *Monitor) KeepHistory(n int, w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.historyW = w
+	if n <= 0 {
+		m.history = nil
+		return
+	}
+	m.history = newSnapshotRing(n)
+}
+
+// History returns the leaked goroutines of the most recent checks, oldest
+// first, as kept by this monitor when told so using KeepHistory.
+func (m *Monitor) History() []goroutine.Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.history == nil {
+		return nil
+	}
+	return m.history.list()
+}
+
+// HistoryReport returns a textual report of the leaked goroutines of the most
+// recent checks kept by this monitor: for each check it lists the goroutines
+// that started to leak since the preceding check, as well as the goroutines
+// that have ended in the meantime.
+func (m *Monitor) HistoryReport() string {
+	return m.matcher.historyReport(m.History())
+}
+
+// historyReport returns a textual report of the specified history of leaked
+// goroutines, diffing consecutive snapshots.
+func (matcher *HaveLeakedMatcher) historyReport(history []goroutine.Snapshot) string {
+	var buff strings.Builder
+	fmt.Fprintf(&buff, "Leaked goroutines over the last %s:", counted(len(history), "check", "checks"))
+	var previous []goroutine.Goroutine
+	for idx, snapshot := range history {
+		fmt.Fprintf(&buff, "\ncheck at %s: %s",
+			snapshot.Taken.Format(time.RFC3339Nano),
+			counted(snapshot.Count(), "leaked goroutine", "leaked goroutines"))
+		diff := goroutine.Compare(previous, snapshot.Goroutines)
+		previous = snapshot.Goroutines
+		if idx == 0 {
+			// There's nothing to diff the oldest snapshot against, so list
+			// all its leaked goroutines.
+			if snapshot.Count() > 0 {
+				buff.WriteString("\n" + matcher.listGoroutines(snapshot.Goroutines, 1))
+			}
+			continue
+		}
+		fmt.Fprintf(&buff, " (%d new, %d ended)", len(diff.Appeared), len(diff.Vanished))
+		if len(diff.Appeared) > 0 {
+			sortGoroutines(diff.Appeared)
+			buff.WriteString("\n" + format.Indent + "new leaks:\n" + matcher.listGoroutines(diff.Appeared, 2))
+		}
+		if len(diff.Vanished) > 0 {
+			ended := "ended: goroutines "
+			if len(diff.Vanished) == 1 {
+				ended = "ended: goroutine "
+			}
+			buff.WriteString("\n" + format.Indent + ended + goids(diff.Vanished))
+		}
+	}
+	return buff.String()
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("monitor history", func() {
+
+	It("keeps only the most recent snapshots", func() {
+		r := newSnapshotRing(2)
+		Expect(r.list()).To(BeEmpty())
+		for pid := 1; pid <= 3; pid++ {
+			r.add(goroutine.Snapshot{PID: pid})
+		}
+		snapshots := r.list()
+		Expect(snapshots).To(HaveLen(2))
+		Expect(snapshots[0].PID).To(Equal(2))
+		Expect(snapshots[1].PID).To(Equal(3))
+	})
+
+	It("doesn't keep a history unless told so", func() {
+		m := NewMonitor(time.Hour, Goroutines())
+		Expect(m.Check()).To(BeEmpty())
+		Expect(m.History()).To(BeNil())
+	})
+
+	It("dumps the history when detecting new leaks", func() {
+		snapshot := Goroutines()
+		var buff strings.Builder
+		m := NewMonitor(time.Hour, snapshot)
+		m.KeepHistory(2, &buff)
+		Expect(m.Check()).To(BeEmpty())
+		Expect(m.Check()).To(BeEmpty())
+		Expect(buff.String()).To(BeEmpty())
+
+		done := make(chan struct{})
+		go func() { <-done }()
+		Expect(m.Check()).To(HaveLen(1))
+		history := m.History()
+		Expect(history).To(HaveLen(2))
+		Expect(history[0].Goroutines).To(BeEmpty())
+		Expect(history[1].Goroutines).To(HaveLen(1))
+		Expect(buff.String()).To(MatchRegexp(
+			`(?s)^Leaked goroutines over the last 2 checks:\ncheck at .*: 0 leaked goroutines\n` +
+				`check at .*: 1 leaked goroutine \(1 new, 0 ended\)\n    new leaks:\n        goroutine \d+ \[chan receive\]\n.*\n$`))
+
+		buff.Reset()
+		close(done)
+		Eventually(m.Check).Should(BeEmpty())
+		Expect(buff.String()).To(BeEmpty())
+		Expect(m.HistoryReport()).To(MatchRegexp(
+			`\ncheck at .*: 0 leaked goroutines \(0 new, 1 ended\)\n    ended: goroutine \d+$`))
+
+		m.KeepHistory(0, nil)
+		Expect(m.History()).To(BeNil())
+	})
+
+	It("lists the leaks of the oldest snapshot", func() {
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		Expect(m.historyReport([]goroutine.Snapshot{
+			{Goroutines: []goroutine.Goroutine{{ID: 42, State: "sleep"}}},
+		})).To(MatchRegexp(`: 1 leaked goroutine\n    goroutine 42 \[sleep\]\n$`))
+	})
+
+})
@@ -0,0 +1,86 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// HotSpotsThreshold is the minimum number of leaked goroutines for HaveLeaked's
+// failure message to start with a summary of the creator locations, ranked by
+// the number of goroutines leaked from them. A zero or negative
+// HotSpotsThreshold disables this summary.
+var HotSpotsThreshold = 3
+
+// MaxHotSpots is the maximum number of creator locations listed in the hot
+// spot summary; any further locations are only counted.
+var MaxHotSpots = 10
+
+// hotSpot is a creator location together with the number of leaked goroutines
+// started from it.
+type hotSpot struct {
+	creator string
+	bornAt  string
+	count   int
+}
+
+// creatorHotSpots returns the summary lines of the creator locations of the
+// specified leaked goroutines, ranked by the number of goroutines started from
+// each location. It returns nil if there are fewer leaked goroutines than the
+// HotSpotsThreshold.
+func (c Config) creatorHotSpots(leaked []goroutine.Goroutine) []string {
+	if c.HotSpotsThreshold <= 0 || len(leaked) < c.HotSpotsThreshold {
+		return nil
+	}
+	spots := map[hotSpot]int{}
+	for _, g := range leaked {
+		spots[hotSpot{creator: g.CreatorFunction, bornAt: g.BornAt}]++
+	}
+	ranked := make([]hotSpot, 0, len(spots))
+	for spot, count := range spots {
+		spot.count = count
+		ranked = append(ranked, spot)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].count != ranked[j].count {
+			return ranked[i].count > ranked[j].count
+		}
+		if ranked[i].bornAt != ranked[j].bornAt {
+			return ranked[i].bornAt < ranked[j].bornAt
+		}
+		return ranked[i].creator < ranked[j].creator
+	})
+	lines := make([]string, 0, len(ranked))
+	for idx, spot := range ranked {
+		if c.MaxHotSpots > 0 && idx >= c.MaxHotSpots {
+			lines = append(lines, "... and "+counted(len(ranked)-idx, "more creator location", "more creator locations"))
+			break
+		}
+		lines = append(lines, spot.String())
+	}
+	return lines
+}
+
+// String returns the summary line of this hot spot, such as
+// "pkg/pool.go:87 (pkg.NewPool) → 42 goroutines".
+func (s hotSpot) String() string {
+	if s.creator == "" && s.bornAt == "" {
+		return "(unknown creator) → " + counted(s.count, "goroutine", "goroutines")
+	}
+	return fmt.Sprintf("%s (%s) → %s", s.bornAt, s.creator, counted(s.count, "goroutine", "goroutines"))
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("creator hot spots", func() {
+
+	leaks := func(creator, bornAt string, count int) []goroutine.Goroutine {
+		gs := []goroutine.Goroutine{}
+		for idx := 0; idx < count; idx++ {
+			gs = append(gs, goroutine.Goroutine{
+				ID:              1<<62 + uint64(len(bornAt)*100+idx),
+				State:           "chan receive",
+				TopFunction:     "main.worker",
+				CreatorFunction: creator,
+				BornAt:          bornAt,
+			})
+		}
+		return gs
+	}
+
+	It("doesn't summarize only few leaks", func() {
+		Expect(DefaultConfig().creatorHotSpots(leaks("pkg.NewPool", "/pkg/pool.go:87", HotSpotsThreshold-1))).To(BeNil())
+	})
+
+	It("ranks creator locations", func() {
+		leaked := append(leaks("pkg.NewPool", "/pkg/pool.go:87", 2),
+			append(leaks("pkg.Serve", "/pkg/server.go:123", 3), leaks("", "", 1)...)...)
+		Expect(DefaultConfig().creatorHotSpots(leaked)).To(Equal([]string{
+			"/pkg/server.go:123 (pkg.Serve) → 3 goroutines",
+			"/pkg/pool.go:87 (pkg.NewPool) → 2 goroutines",
+			"(unknown creator) → 1 goroutine",
+		}))
+	})
+
+	It("limits the number of creator locations", func() {
+		defer func(old int) { MaxHotSpots = old }(MaxHotSpots)
+		MaxHotSpots = 1
+		leaked := append(leaks("pkg.NewPool", "/pkg/pool.go:87", 2),
+			append(leaks("pkg.Serve", "/pkg/server.go:123", 3), leaks("", "", 1)...)...)
+		Expect(DefaultConfig().creatorHotSpots(leaked)).To(Equal([]string{
+			"/pkg/server.go:123 (pkg.Serve) → 3 goroutines",
+			"... and 2 more creator locations",
+		}))
+	})
+
+	It("can be disabled", func() {
+		defer func(old int) { HotSpotsThreshold = old }(HotSpotsThreshold)
+		HotSpotsThreshold = 0
+		Expect(DefaultConfig().creatorHotSpots(leaks("pkg.NewPool", "/pkg/pool.go:87", 42))).To(BeNil())
+	})
+
+	It("starts failure messages with the summary", func() {
+		m := HaveLeaked()
+		leaked := leaks("pkg.NewPool", "/pkg/pool.go:87", 3)
+		Expect(m.Match(leaked)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leaked)).To(HavePrefix(
+			"Expected not to leak 3 goroutines:\nLeaked goroutines by creator location:\n" +
+				"    /pkg/pool.go:87 (pkg.NewPool) → 3 goroutines\n    goroutine "))
+	})
+
+})
@@ -0,0 +1,26 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package httpnoleak provides httptest servers that check for leaked server
+goroutines when being closed. A Server takes a snapshot of the goroutines
+before starting, and after closing asserts that the goroutines of the server,
+such as accepting and serving connections, are eventually gone.
+
+    srv := httpnoleak.NewServer(handler)
+    defer srv.Close()
+
+*/
+package httpnoleak
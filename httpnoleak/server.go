@@ -0,0 +1,97 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package httpnoleak
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// SettleTimeout is the maximum duration a closed Server waits for its
+// goroutines to finally end.
+var SettleTimeout = 2 * time.Second
+
+// KeepAliveFilters returns the filter matchers for the goroutines of HTTP
+// client connections kept alive by net/http transports, other than the
+// default transport and a Server's own client. These goroutines aren't
+// controlled by a Server and thus aren't considered to be leaked.
+func KeepAliveFilters() []interface{} {
+	return []interface{}{
+		noleak.IgnoringInBacktrace("net/http.(*persistConn).readLoop"),
+		noleak.IgnoringInBacktrace("net/http.(*persistConn).writeLoop"),
+	}
+}
+
+// Server is an httptest.Server that checks for leaked goroutines when being
+// closed.
+type Server struct {
+	*httptest.Server
+	g        gomega.Gomega
+	snapshot goroutine.Snapshot
+}
+
+// NewServer starts and returns a new Server, using gomega.Default for
+// asserting that the server didn't leak any goroutines after being closed.
+func NewServer(handler http.Handler) *Server {
+	return NewServerWithGomega(gomega.Default, handler)
+}
+
+// NewServerWithGomega starts and returns a new Server, using the specified
+// Gomega for asserting that the server didn't leak any goroutines after being
+// closed.
+func NewServerWithGomega(g gomega.Gomega, handler http.Handler) *Server {
+	s := &Server{
+		g:        g,
+		snapshot: goroutine.TakeSnapshot(),
+	}
+	s.Server = httptest.NewServer(handler)
+	return s
+}
+
+// NewTLSServer starts and returns a new Server using TLS, using gomega.Default
+// for asserting that the server didn't leak any goroutines after being closed.
+func NewTLSServer(handler http.Handler) *Server {
+	return NewTLSServerWithGomega(gomega.Default, handler)
+}
+
+// NewTLSServerWithGomega starts and returns a new Server using TLS, using the
+// specified Gomega for asserting that the server didn't leak any goroutines
+// after being closed.
+func NewTLSServerWithGomega(g gomega.Gomega, handler http.Handler) *Server {
+	s := &Server{
+		g:        g,
+		snapshot: goroutine.TakeSnapshot(),
+	}
+	s.Server = httptest.NewTLSServer(handler)
+	return s
+}
+
+// Close shuts down the server, closing the idle connections of the server's
+// client as well as of the default transport, and then asserts that all
+// goroutines started since the server was created eventually end, except for
+// any goroutines of keep-alive client connections (see KeepAliveFilters).
+func (s *Server) Close() {
+	s.Server.Close()
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	s.g.Eventually(noleak.Goroutines).WithOffset(1).WithTimeout(SettleTimeout).
+		ShouldNot(noleak.HaveLeaked(append(KeepAliveFilters(), s.snapshot)...))
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package httpnoleak
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("leak-checking httptest servers", func() {
+
+	BeforeEach(func() {
+		oldTimeout := SettleTimeout
+		SettleTimeout = 500 * time.Millisecond
+		DeferCleanup(func() { SettleTimeout = oldTimeout })
+	})
+
+	serve := func(s *Server) {
+		resp, err := s.Client().Get(s.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(io.ReadAll(resp.Body)).To(Equal([]byte("noleak")))
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("noleak"))
+	})
+
+	It("doesn't complain about servers not leaking", func() {
+		s := NewServer(handler)
+		serve(s)
+		s.Close()
+
+		s = NewTLSServer(handler)
+		serve(s)
+		s.Close()
+	})
+
+	It("ignores keep-alive client connections of other transports", func() {
+		s := NewServer(handler)
+		transport := &http.Transport{}
+		defer transport.CloseIdleConnections()
+		resp, err := (&http.Client{Transport: transport}).Get(s.URL)
+		Expect(err).NotTo(HaveOccurred())
+		_, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		s.Close()
+	})
+
+	It("reports goroutines leaked by handlers", func() {
+		done := make(chan struct{})
+		defer close(done)
+		var failure string
+		g := NewGomega(func(message string, callerSkip ...int) {
+			failure = message
+		})
+		s := NewServerWithGomega(g, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			go func() { <-done }()
+		}))
+		resp, err := s.Client().Get(s.URL)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		s.Close()
+		Expect(failure).To(ContainSubstring("Expected not to leak 1 goroutines"))
+	})
+
+	It("reports goroutines leaked by handlers of TLS servers", func() {
+		done := make(chan struct{})
+		defer close(done)
+		var failure string
+		g := NewGomega(func(message string, callerSkip ...int) {
+			failure = message
+		})
+		s := NewTLSServerWithGomega(g, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			go func() { <-done }()
+		}))
+		resp, err := s.Client().Get(s.URL)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		s.Close()
+		Expect(failure).To(ContainSubstring("Expected not to leak 1 goroutines"))
+	})
+
+})
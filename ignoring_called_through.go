@@ -0,0 +1,131 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// IgnoringCalledThrough succeeds if the actual goroutine passes through the
+// specified function at any depth of its backtrace, or if the goroutine was
+// created by this function. This allows ignoring whole subsystems managed by
+// a single supervisor without having to enumerate all the leaf functions:
+//
+//   IgnoringCalledThrough("example.com/pkg.(*Supervisor).run")
+//
+// Unlike IgnoringInBacktrace, IgnoringCalledThrough matches complete function
+// names only, so "pkg.run" doesn't match "pkg.runner". Methods can be specified
+// with or without their receiver parentheses, so "pkg.Supervisor.run" also
+// matches "pkg.(*Supervisor).run". Similar to IgnoringCreator, a trailing
+// ellipsis "..." matches any function nested at least one level deeper, such as
+// "pkg.Supervisor.run..." matching the function literal
+// "pkg.(*Supervisor).run.func1".
+//
+// For goroutines without parsed call frames, such as those captured by
+// LightweightGoroutines, IgnoringCalledThrough matches only the top and
+// creator functions.
+func IgnoringCalledThrough(fname string) types.GomegaMatcher {
+	if strings.HasSuffix(fname, "...") {
+		return &ignoringCalledThroughMatcher{
+			fname:       stripReceiverParens(fname[:len(fname)-3+1]), // ...one trailing dot still expected
+			matchPrefix: true,
+		}
+	}
+	return &ignoringCalledThroughMatcher{fname: stripReceiverParens(fname)}
+}
+
+type ignoringCalledThroughMatcher struct {
+	fname       string // function name without receiver parentheses
+	matchPrefix bool
+}
+
+// Match succeeds if actual's backtrace passes through the specified function
+// or the actual goroutine was created by this function.
+func (matcher *ignoringCalledThroughMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := G(actual, "IgnoringCalledThrough")
+	if err != nil {
+		return false, err
+	}
+	if matcher.matches(g.CreatorFunction) {
+		return true, nil
+	}
+	if len(g.Frames) == 0 {
+		return matcher.matches(g.TopFunction), nil
+	}
+	for _, frame := range g.Frames {
+		if matcher.matches(frame.Function) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matches returns true if the specified function name matches the expected
+// function name or prefix.
+func (matcher *ignoringCalledThroughMatcher) matches(fname string) bool {
+	if fname == "" {
+		return false
+	}
+	fname = stripReceiverParens(fname)
+	if matcher.matchPrefix {
+		return strings.HasPrefix(fname, matcher.fname)
+	}
+	return fname == matcher.fname
+}
+
+// FailureMessage returns a failure message if the actual goroutine doesn't
+// pass through the specified function.
+func (matcher *ignoringCalledThroughMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("to pass through %q", matcher.expected()))
+}
+
+// NegatedFailureMessage returns a failure message if the actual goroutine
+// passes through the specified function.
+func (matcher *ignoringCalledThroughMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("not to pass through %q", matcher.expected()))
+}
+
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringCalledThroughMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
+// String returns the filter in source form, such as
+// IgnoringCalledThrough("pkg.Supervisor.run").
+func (matcher *ignoringCalledThroughMatcher) String() string {
+	return fmt.Sprintf("IgnoringCalledThrough(%q)", matcher.expected())
+}
+
+// expected returns the expected function name, in prefix form if necessary.
+func (matcher *ignoringCalledThroughMatcher) expected() string {
+	if matcher.matchPrefix {
+		return matcher.fname + ".."
+	}
+	return matcher.fname
+}
+
+// stripReceiverParens returns the specified function name with any method
+// receiver parentheses and type parameters removed, such as "pkg.T.run" for
+// "pkg.(*T[...]).run".
+func stripReceiverParens(fname string) string {
+	fname = strings.ReplaceAll(fname, "[...]", "")
+	fname = strings.Replace(fname, ".(*", ".", 1)
+	return strings.Replace(fname, ").", ".", 1)
+}
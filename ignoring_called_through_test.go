@@ -0,0 +1,70 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("IgnoringCalledThrough matcher", func() {
+
+	g := goroutine.Goroutine{
+		TopFunction:     "example.com/pkg.leaf",
+		CreatorFunction: "example.com/app.main",
+		Frames: []goroutine.Frame{
+			{Function: "example.com/pkg.leaf"},
+			{Function: "example.com/pkg.(*Supervisor).run.func1"},
+			{Function: "example.com/pkg.(*Supervisor).run"},
+		},
+	}
+
+	It("returns an error for an invalid actual", func() {
+		m := IgnoringCalledThrough("foo.bar")
+		Expect(m.Match(nil)).Error().To(MatchError(
+			"IgnoringCalledThrough matcher expects a goroutine.Goroutine or *goroutine.Goroutine.  Got:\n    <nil>: nil"))
+	})
+
+	It("matches functions at any depth", func() {
+		Expect(g).To(IgnoringCalledThrough("example.com/pkg.(*Supervisor).run"))
+		Expect(g).To(IgnoringCalledThrough("example.com/pkg.Supervisor.run"))
+		Expect(g).To(IgnoringCalledThrough("example.com/pkg.leaf"))
+		Expect(g).NotTo(IgnoringCalledThrough("example.com/pkg.Supervisor.ru"))
+		Expect(g).NotTo(IgnoringCalledThrough("example.com/pkg.Supervisor"))
+	})
+
+	It("matches prefixes", func() {
+		Expect(g).To(IgnoringCalledThrough("example.com/pkg.Supervisor.run..."))
+		Expect(g).NotTo(IgnoringCalledThrough("example.com/pkg.leaf..."))
+	})
+
+	It("matches creators and top functions without frames", func() {
+		Expect(g).To(IgnoringCalledThrough("example.com/app.main"))
+		lightweight := goroutine.Goroutine{TopFunction: "example.com/pkg.(*Supervisor).run"}
+		Expect(lightweight).To(IgnoringCalledThrough("example.com/pkg.Supervisor.run"))
+		Expect(lightweight).NotTo(IgnoringCalledThrough("example.com/app.main"))
+	})
+
+	It("returns failure messages", func() {
+		m := IgnoringCalledThrough("pkg.Supervisor.run...")
+		Expect(m.FailureMessage(goroutine.Goroutine{})).To(MatchRegexp(
+			`Expected\n    <goroutine.Goroutine>: {ID: 0, State: "", TopFunction: "", CreatorFunction: "", BornAt: ""}\nto pass through "pkg.Supervisor.run..."`))
+		Expect(m.NegatedFailureMessage(goroutine.Goroutine{})).To(MatchRegexp(
+			`Expected\n    <goroutine.Goroutine>: {ID: 0, State: "", TopFunction: "", CreatorFunction: "", BornAt: ""}\nnot to pass through "pkg.Supervisor.run..."`))
+		Expect(describeFilter(m)).To(Equal(`IgnoringCalledThrough("pkg.Supervisor.run...")`))
+	})
+
+})
@@ -75,6 +75,12 @@ func (matcher *ignoringCreator) NegatedFailureMessage(actual interface{}) (messa
 	return format.Message(actual, "not "+matcher.message())
 }
 
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringCreator) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
 func (matcher *ignoringCreator) message() string {
 	if matcher.matchPrefix {
 		return fmt.Sprintf("to be created by a function with prefix %q", matcher.expectedCreatorFunction)
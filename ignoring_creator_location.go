@@ -0,0 +1,98 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// IgnoringCreatorLocation succeeds if the goroutine was created by a "go"
+// statement in the specified file, with the line number of the "go" statement
+// in the range from the first to the last line, inclusive. This allows
+// ignoring goroutines by where they are started instead of by function names,
+// such as when a single large file starts both legitimate daemons and suspect
+// goroutines, and the function names are unstable, as with function literals.
+//
+// The file is matched against the end of the creator location's file path, on
+// path element boundaries; for instance, "pkg/server.go" matches
+// "/home/foo/src/pkg/server.go", but not "/home/foo/src/mypkg/server.go".
+// Backslashes in Windows file paths are considered to be path separators, too.
+//
+//   IgnoringCreatorLocation("pkg/server.go", 100, 250)
+//
+// IgnoringCreatorLocation panics if the last line is before the first line.
+func IgnoringCreatorLocation(file string, first, last int) types.GomegaMatcher {
+	if last < first {
+		panic(fmt.Sprintf("IgnoringCreatorLocation expects first <= last, but got %d and %d", first, last))
+	}
+	return &ignoringCreatorLocationMatcher{
+		file:  strings.ReplaceAll(file, "\\", "/"),
+		first: first,
+		last:  last,
+	}
+}
+
+type ignoringCreatorLocationMatcher struct {
+	file  string
+	first int
+	last  int
+}
+
+// Match succeeds if an actual goroutine has been created in the specified file
+// and line range.
+func (matcher *ignoringCreatorLocationMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := G(actual, "IgnoringCreatorLocation")
+	if err != nil {
+		return false, err
+	}
+	creator := g.CreatorFrame
+	if creator.Line < matcher.first || creator.Line > matcher.last {
+		return false, nil
+	}
+	file := strings.ReplaceAll(creator.File, "\\", "/")
+	return file == matcher.file || strings.HasSuffix(file, "/"+matcher.file), nil
+}
+
+// FailureMessage returns a failure message if the actual goroutine hasn't been
+// created in the specified file and line range.
+func (matcher *ignoringCreatorLocationMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, matcher.message())
+}
+
+// NegatedFailureMessage returns a failure message if the actual goroutine has
+// been created in the specified file and line range.
+func (matcher *ignoringCreatorLocationMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, "not "+matcher.message())
+}
+
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringCreatorLocationMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
+func (matcher *ignoringCreatorLocationMatcher) message() string {
+	return fmt.Sprintf("to be created in %q at lines %d to %d", matcher.file, matcher.first, matcher.last)
+}
+
+// String returns the filter in source form, such as
+// IgnoringCreatorLocation("pkg/server.go", 100, 250).
+func (matcher *ignoringCreatorLocationMatcher) String() string {
+	return fmt.Sprintf("IgnoringCreatorLocation(%q, %d, %d)", matcher.file, matcher.first, matcher.last)
+}
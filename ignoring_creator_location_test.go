@@ -0,0 +1,71 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("IgnoringCreatorLocation matcher", func() {
+
+	bornAt := func(file string, line int) goroutine.Goroutine {
+		return goroutine.Goroutine{ID: 42, CreatorFrame: goroutine.Frame{
+			Function: "pkg.foo", File: file, Line: line}}
+	}
+
+	It("returns an error for an invalid actual", func() {
+		m := IgnoringCreatorLocation("pkg/server.go", 1, 2)
+		Expect(m.Match(nil)).Error().To(MatchError(HavePrefix(
+			"IgnoringCreatorLocation matcher expects a goroutine.Goroutine or *goroutine.Goroutine.")))
+	})
+
+	It("rejects invalid line ranges", func() {
+		Expect(func() { IgnoringCreatorLocation("pkg/server.go", 2, 1) }).To(
+			PanicWith("IgnoringCreatorLocation expects first <= last, but got 2 and 1"))
+	})
+
+	It("matches creator files and line ranges", func() {
+		m := IgnoringCreatorLocation("pkg/server.go", 100, 250)
+		Expect(m.Match(bornAt("/home/foo/src/pkg/server.go", 100))).To(BeTrue())
+		Expect(m.Match(bornAt("/home/foo/src/pkg/server.go", 250))).To(BeTrue())
+		Expect(m.Match(bornAt("pkg/server.go", 123))).To(BeTrue())
+		Expect(m.Match(bornAt(`C:\src\pkg\server.go`, 123))).To(BeTrue())
+
+		Expect(m.Match(bornAt("/home/foo/src/pkg/server.go", 99))).To(BeFalse())
+		Expect(m.Match(bornAt("/home/foo/src/pkg/server.go", 251))).To(BeFalse())
+		Expect(m.Match(bornAt("/home/foo/src/mypkg/server.go", 123))).To(BeFalse())
+		Expect(m.Match(goroutine.Goroutine{ID: 1})).To(BeFalse())
+	})
+
+	It("matches real creator locations", func() {
+		g := creator()
+		m := IgnoringCreatorLocation("ignoring_creator_test.go", g.CreatorFrame.Line, g.CreatorFrame.Line)
+		Expect(m.Match(g)).To(BeTrue(), "creator %v", g.String())
+		Expect(m.Match(goroutine.Current())).To(BeFalse())
+	})
+
+	It("returns failure messages", func() {
+		m := IgnoringCreatorLocation("pkg/server.go", 100, 250)
+		Expect(m.FailureMessage(goroutine.Goroutine{ID: 42})).To(HaveSuffix(
+			"\nto be created in \"pkg/server.go\" at lines 100 to 250"))
+		Expect(m.NegatedFailureMessage(goroutine.Goroutine{ID: 42})).To(HaveSuffix(
+			"\nnot to be created in \"pkg/server.go\" at lines 100 to 250"))
+		Expect(m).To(BeAssignableToTypeOf(&ignoringCreatorLocationMatcher{}))
+		Expect(describeFilter(m)).To(Equal(`IgnoringCreatorLocation("pkg/server.go", 100, 250)`))
+	})
+
+})
@@ -27,29 +27,39 @@ import (
 // matcher is to take a snapshot of the current goroutines just right before a
 // test and then at the end of a test filtering out these "good" and known
 // goroutines.
+//
+// As a safeguard against goroutine IDs getting recycled, IgnoringGoroutines
+// additionally checks that the creator function and location of an actual
+// goroutine match those of the expected goroutine with the same ID. Expected
+// goroutines lacking any creator information, such as the main goroutine or
+// goroutines only specified by their IDs, are matched by their IDs alone.
 func IgnoringGoroutines(goroutines []goroutine.Goroutine) types.GomegaMatcher {
 	m := &ignoringGoroutinesMatcher{
-		ignoreGoids: map[uint64]struct{}{},
+		ignoreGoids: map[uint64]goroutine.Identity{},
 	}
 	for _, g := range goroutines {
-		m.ignoreGoids[g.ID] = struct{}{}
+		m.ignoreGoids[g.ID] = g.Identity()
 	}
 	return m
 }
 
 type ignoringGoroutinesMatcher struct {
-	ignoreGoids map[uint64]struct{}
+	ignoreGoids map[uint64]goroutine.Identity
 }
 
 // Match succeeds if actual is a goroutine.Goroutine and its ID is in the set of
-// goroutine IDs to expect and thus to ignore in leak checks.
+// goroutine IDs to expect and thus to ignore in leak checks, and additionally
+// its creator matches the creator of the expected goroutine, if known.
 func (matcher *ignoringGoroutinesMatcher) Match(actual interface{}) (success bool, err error) {
 	g, err := G(actual, "IgnoringGoroutines")
 	if err != nil {
 		return false, err
 	}
-	_, ok := matcher.ignoreGoids[g.ID]
-	return ok, nil
+	expected, ok := matcher.ignoreGoids[g.ID]
+	if !ok {
+		return false, nil
+	}
+	return expected.IsAnonymous() || expected == g.Identity(), nil
 }
 
 // FailureMessage returns a failure message if the actual goroutine isn't in the
@@ -64,6 +74,12 @@ func (matcher *ignoringGoroutinesMatcher) NegatedFailureMessage(actual interface
 	return format.Message(actual, "not to be contained in the list of expected goroutine IDs", matcher.expectedGoids())
 }
 
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringGoroutinesMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
 // expectedGoids returns the sorted list of expected goroutine IDs.
 func (matcher *ignoringGoroutinesMatcher) expectedGoids() []uint64 {
 	ids := make([]uint64, 0, len(matcher.ignoreGoids))
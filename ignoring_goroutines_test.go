@@ -37,6 +37,15 @@ var _ = Describe("IgnoringGoroutines matcher", func() {
 		Expect(m.Match(goroutine.Goroutine{})).To(BeFalse())
 	})
 
+	It("doesn't match goroutines with recycled IDs", func() {
+		expected := goroutine.Goroutine{ID: 1 << 62, CreatorFunction: "main.foo", BornAt: "/foo.go:42"}
+		m := IgnoringGoroutines([]goroutine.Goroutine{expected, {ID: 1<<62 + 1}})
+		Expect(m.Match(expected)).To(BeTrue())
+		Expect(m.Match(goroutine.Goroutine{ID: 1 << 62, CreatorFunction: "main.bar", BornAt: "/bar.go:666"})).To(BeFalse())
+		Expect(m.Match(goroutine.Goroutine{ID: 1 << 62, CreatorFunction: "main.foo", BornAt: "/foo.go:666"})).To(BeFalse())
+		Expect(m.Match(goroutine.Goroutine{ID: 1<<62 + 1, CreatorFunction: "main.bar", BornAt: "/bar.go:666"})).To(BeTrue())
+	})
+
 	It("returns failure messages", func() {
 		m := IgnoringGoroutines(Goroutines())
 		Expect(m.FailureMessage(goroutine.Goroutine{})).To(MatchRegexp(
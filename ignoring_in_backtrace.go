@@ -52,3 +52,9 @@ func (matcher *ignoringInBacktraceMatcher) FailureMessage(actual interface{}) (m
 func (matcher *ignoringInBacktraceMatcher) NegatedFailureMessage(actual interface{}) (message string) {
 	return format.Message(actual, fmt.Sprintf("not to contain %q in the goroutine's backtrace", matcher.fname))
 }
+
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringInBacktraceMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// IgnoringMethodOf succeeds if the backtrace of the actual goroutine contains a
+// call to a method of the specified receiver type, including any function
+// literals inside such methods. The receiver type is specified either with
+// the last element of its package path only, such as "pool.Worker", or with
+// the full package path, such as "example.com/pool.Worker". A leading "*"
+// restricts matching to methods with pointer receivers, as in
+// IgnoringMethodOf("*pool.Worker"); otherwise, methods with both value and
+// pointer receivers match. Matching on receiver types often is more stable
+// across refactorings than matching on full function names.
+//
+// For goroutines without parsed call frames, such as those captured by
+// LightweightGoroutines, IgnoringMethodOf matches only the top function.
+func IgnoringMethodOf(typ string) types.GomegaMatcher {
+	return &ignoringMethodOfMatcher{
+		typ:     strings.TrimPrefix(typ, "*"),
+		pointer: strings.HasPrefix(typ, "*"),
+	}
+}
+
+type ignoringMethodOfMatcher struct {
+	typ     string // receiver type without any leading "*"
+	pointer bool   // only match pointer receivers
+}
+
+// Match succeeds if actual's backtrace contains a call to a method of the
+// specified receiver type.
+func (matcher *ignoringMethodOfMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := G(actual, "IgnoringMethodOf")
+	if err != nil {
+		return false, err
+	}
+	if len(g.Frames) == 0 {
+		return matcher.isMethod(g.TopFunction), nil
+	}
+	for _, frame := range g.Frames {
+		if matcher.isMethod(frame.Function) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isMethod returns true if the specified fully qualified function name is a
+// method (or a function literal inside a method) of the receiver type.
+func (matcher *ignoringMethodOfMatcher) isMethod(fname string) bool {
+	typ, pointer := goroutine.Receiver(fname)
+	if typ == "" || (matcher.pointer && !pointer) {
+		return false
+	}
+	return typ == matcher.typ || strings.HasSuffix(typ, "/"+matcher.typ)
+}
+
+// FailureMessage returns a failure message if the actual's backtrace does not
+// contain a method of the specified receiver type.
+func (matcher *ignoringMethodOfMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("to contain a method of %q in the goroutine's backtrace", matcher.receiver()))
+}
+
+// NegatedFailureMessage returns a failure message if the actual's backtrace
+// does contain a method of the specified receiver type.
+func (matcher *ignoringMethodOfMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("not to contain a method of %q in the goroutine's backtrace", matcher.receiver()))
+}
+
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringMethodOfMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
+// String returns the filter in source form, such as
+// IgnoringMethodOf("*pool.Worker").
+func (matcher *ignoringMethodOfMatcher) String() string {
+	return fmt.Sprintf("IgnoringMethodOf(%q)", matcher.receiver())
+}
+
+// receiver returns the receiver type as originally specified.
+func (matcher *ignoringMethodOfMatcher) receiver() string {
+	if matcher.pointer {
+		return "*" + matcher.typ
+	}
+	return matcher.typ
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+type methodOfTestWorker struct{}
+
+func (w *methodOfTestWorker) current() goroutine.Goroutine {
+	return goroutine.Current()
+}
+
+var _ = Describe("IgnoringMethodOf matcher", func() {
+
+	It("returns an error for an invalid actual", func() {
+		m := IgnoringMethodOf("*pool.Worker")
+		Expect(m.Match(nil)).Error().To(MatchError(
+			"IgnoringMethodOf matcher expects a goroutine.Goroutine or *goroutine.Goroutine.  Got:\n    <nil>: nil"))
+	})
+
+	It("matches methods in backtraces", func() {
+		g := (&methodOfTestWorker{}).current()
+		Expect(g).To(IgnoringMethodOf("*noleak.methodOfTestWorker"))
+		Expect(g).To(IgnoringMethodOf("noleak.methodOfTestWorker"))
+		Expect(g).To(IgnoringMethodOf("github.com/thediveo/noleak.methodOfTestWorker"))
+		Expect(g).NotTo(IgnoringMethodOf("thediveo/noleak.methodOfTestWorker.foo"))
+		Expect(g).NotTo(IgnoringMethodOf("xnoleak.methodOfTestWorker"))
+		Expect(somefunction()).NotTo(IgnoringMethodOf("noleak.methodOfTestWorker"))
+	})
+
+	It("distinguishes pointer and value receivers", func() {
+		ptr := goroutine.Goroutine{TopFunction: "example.com/pool.(*Worker).run.func1"}
+		val := goroutine.Goroutine{TopFunction: "example.com/pool.Worker.run"}
+		Expect(ptr).To(IgnoringMethodOf("*pool.Worker"))
+		Expect(ptr).To(IgnoringMethodOf("pool.Worker"))
+		Expect(val).To(IgnoringMethodOf("pool.Worker"))
+		Expect(val).NotTo(IgnoringMethodOf("*pool.Worker"))
+		Expect(goroutine.Goroutine{TopFunction: "example.com/pool.run"}).NotTo(IgnoringMethodOf("pool.Worker"))
+	})
+
+	It("returns failure messages", func() {
+		m := IgnoringMethodOf("*pool.Worker")
+		Expect(m.FailureMessage(goroutine.Goroutine{})).To(MatchRegexp(
+			`Expected\n    <goroutine.Goroutine>: {ID: 0, State: "", TopFunction: "", CreatorFunction: "", BornAt: ""}\nto contain a method of "\*pool.Worker" in the goroutine's backtrace`))
+		Expect(m.NegatedFailureMessage(goroutine.Goroutine{})).To(MatchRegexp(
+			`Expected\n    <goroutine.Goroutine>: {ID: 0, State: "", TopFunction: "", CreatorFunction: "", BornAt: ""}\nnot to contain a method of "\*pool.Worker" in the goroutine's backtrace`))
+		Expect(describeFilter(m)).To(Equal(`IgnoringMethodOf("*pool.Worker")`))
+	})
+
+})
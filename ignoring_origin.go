@@ -0,0 +1,119 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// IgnoringOrigin succeeds if the actual goroutine originates purely in source
+// code classified by the specified classifier function, based on the source
+// file locations of its call frames and creator, in the form of
+// "file-path:line-number". Frames and creators of standard library functions
+// are skipped, so a goroutine started by vendored code and blocking in the
+// runtime still counts as originating purely in vendored code. The origin
+// description, such as "vendored", is used in failure messages. Goroutines
+// without any locations to classify never match.
+//
+// Please see also IgnoringVendored and IgnoringGenerated for ignoring leaks
+// from vendored and generated code, in order to triage them separately from
+// first-party leaks.
+func IgnoringOrigin(origin string, classifier func(location string) bool) types.GomegaMatcher {
+	return &ignoringOriginMatcher{
+		origin:     origin,
+		classifier: classifier,
+		source:     fmt.Sprintf("IgnoringOrigin(%q, ...)", origin),
+	}
+}
+
+// IgnoringVendored succeeds if the actual goroutine originates purely in code
+// inside "vendor" or "third_party" directories, see also goroutine.IsVendored.
+func IgnoringVendored() types.GomegaMatcher {
+	return &ignoringOriginMatcher{
+		origin:     "vendored",
+		classifier: goroutine.IsVendored,
+		source:     "IgnoringVendored()",
+	}
+}
+
+// IgnoringGenerated succeeds if the actual goroutine originates purely in
+// generated code, such as in "*.pb.go" and "*_gen.go" source files, see also
+// goroutine.IsGenerated.
+func IgnoringGenerated() types.GomegaMatcher {
+	return &ignoringOriginMatcher{
+		origin:     "generated",
+		classifier: goroutine.IsGenerated,
+		source:     "IgnoringGenerated()",
+	}
+}
+
+type ignoringOriginMatcher struct {
+	origin     string
+	classifier func(location string) bool
+	source     string // filter in source form for describeFilter
+}
+
+// Match succeeds if all non-standard library frames and the creator of the
+// actual goroutine are classified to be of the specified origin.
+func (matcher *ignoringOriginMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := G(actual, "IgnoringOrigin")
+	if err != nil {
+		return false, err
+	}
+	classified := false
+	for _, frame := range g.Frames {
+		if frame.Location == "" || goroutine.IsStdFunction(frame.Function) {
+			continue
+		}
+		if !matcher.classifier(frame.Location) {
+			return false, nil
+		}
+		classified = true
+	}
+	if g.BornAt != "" && !goroutine.IsStdFunction(g.CreatorFunction) {
+		if !matcher.classifier(g.BornAt) {
+			return false, nil
+		}
+		classified = true
+	}
+	return classified, nil
+}
+
+// FailureMessage returns a failure message if the actual goroutine doesn't
+// originate purely in code of the specified origin.
+func (matcher *ignoringOriginMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("to originate purely in %s code", matcher.origin))
+}
+
+// NegatedFailureMessage returns a failure message if the actual goroutine
+// originates purely in code of the specified origin.
+func (matcher *ignoringOriginMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, fmt.Sprintf("not to originate purely in %s code", matcher.origin))
+}
+
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringOriginMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
+// String returns the filter in source form, such as IgnoringVendored().
+func (matcher *ignoringOriginMatcher) String() string {
+	return matcher.source
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("IgnoringOrigin matchers", func() {
+
+	vendored := goroutine.Goroutine{
+		CreatorFunction: "example.com/dep.Start",
+		BornAt:          "/src/app/vendor/example.com/dep/dep.go:42",
+		Frames: []goroutine.Frame{
+			{Function: "runtime.gopark", Location: "/usr/local/go/src/runtime/proc.go:1"},
+			{Function: "example.com/dep.loop", Location: "/src/app/vendor/example.com/dep/dep.go:66"},
+		},
+	}
+
+	firstparty := goroutine.Goroutine{
+		CreatorFunction: "example.com/app.main",
+		BornAt:          "/src/app/main.go:12",
+		Frames: []goroutine.Frame{
+			{Function: "example.com/dep.loop", Location: "/src/app/vendor/example.com/dep/dep.go:66"},
+		},
+	}
+
+	It("returns an error for an invalid actual", func() {
+		m := IgnoringVendored()
+		Expect(m.Match(nil)).Error().To(MatchError(
+			"IgnoringOrigin matcher expects a goroutine.Goroutine or *goroutine.Goroutine.  Got:\n    <nil>: nil"))
+	})
+
+	It("ignores vendored goroutines", func() {
+		Expect(vendored).To(IgnoringVendored())
+		Expect(firstparty).NotTo(IgnoringVendored())
+		Expect(goroutine.Goroutine{}).NotTo(IgnoringVendored())
+		Expect(goroutine.Goroutine{
+			CreatorFunction: "example.com/dep.Start",
+			BornAt:          "/src/app/vendor/example.com/dep/dep.go:42",
+		}).To(IgnoringVendored())
+	})
+
+	It("ignores generated goroutines", func() {
+		Expect(goroutine.Goroutine{
+			CreatorFunction: "example.com/api.RegisterServer",
+			BornAt:          "/src/app/api/service.pb.go:42",
+			Frames: []goroutine.Frame{
+				{Function: "example.com/api.serve", Location: "/src/app/api/service_gen.go:66"},
+			},
+		}).To(IgnoringGenerated())
+		Expect(vendored).NotTo(IgnoringGenerated())
+	})
+
+	It("classifies using custom classifiers", func() {
+		m := IgnoringOrigin("dep", func(location string) bool {
+			return strings.Contains(location, "/dep/")
+		})
+		Expect(vendored).To(m)
+		Expect(firstparty).NotTo(m)
+		Expect(describeFilter(m)).To(Equal(`IgnoringOrigin("dep", ...)`))
+	})
+
+	It("returns failure messages", func() {
+		m := IgnoringVendored()
+		Expect(m.FailureMessage(goroutine.Goroutine{})).To(MatchRegexp(
+			`Expected\n    <goroutine.Goroutine>: {ID: 0, State: "", TopFunction: "", CreatorFunction: "", BornAt: ""}\nto originate purely in vendored code`))
+		Expect(m.NegatedFailureMessage(goroutine.Goroutine{})).To(MatchRegexp(
+			`Expected\n    <goroutine.Goroutine>: {ID: 0, State: "", TopFunction: "", CreatorFunction: "", BornAt: ""}\nnot to originate purely in vendored code`))
+		Expect(describeFilter(m)).To(Equal("IgnoringVendored()"))
+		Expect(describeFilter(IgnoringGenerated())).To(Equal("IgnoringGenerated()"))
+	})
+
+})
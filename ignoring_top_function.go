@@ -59,6 +59,54 @@ func IgnoringTopFunction(topfname string) types.GomegaMatcher {
 	}
 }
 
+// TopOption configures the top function matcher returned by IgnoringTop.
+type TopOption func(*ignoringTopFunctionMatcher)
+
+// TopFunctionMatch specifies the (fully qualified) name of the topmost
+// function to be matched by IgnoringTop.
+func TopFunctionMatch(name string) TopOption {
+	return func(m *ignoringTopFunctionMatcher) { m.expectedTopFunction = name }
+}
+
+// TopPrefixMatch makes IgnoringTop match any topmost function that is at least
+// one level deeper than the TopFunctionMatch name. For instance,
+// TopFunctionMatch("foo.bar") together with TopPrefixMatch() matches
+// "foo.bar.baz", but doesn't match "foo.bar".
+func TopPrefixMatch() TopOption {
+	return func(m *ignoringTopFunctionMatcher) { m.matchPrefix = true }
+}
+
+// TopStateMatch makes IgnoringTop additionally require a goroutine's state to
+// start with the specified state text, such as "chan receive".
+func TopStateMatch(state string) TopOption {
+	return func(m *ignoringTopFunctionMatcher) { m.expectedState = state }
+}
+
+// IgnoringTop succeeds if the topmost function in the backtrace of an actual
+// goroutine matches the specified options. It is the typed equivalent of
+// IgnoringTopFunction, avoiding its string syntax; for instance:
+//
+//   IgnoringTop(TopFunctionMatch("foo.bar"))                   // "foo.bar"
+//   IgnoringTop(TopFunctionMatch("foo.bar"), TopPrefixMatch()) // "foo.bar..."
+//   IgnoringTop(TopFunctionMatch("foo.bar"),
+//       TopStateMatch("chan receive"))                         // "foo.bar [chan receive]"
+//
+// In contrast to the string syntax, TopPrefixMatch and TopStateMatch can also
+// be combined. IgnoringTop panics if no TopFunctionMatch has been specified.
+func IgnoringTop(opts ...TopOption) types.GomegaMatcher {
+	m := &ignoringTopFunctionMatcher{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.expectedTopFunction == "" {
+		panic("IgnoringTop expected a TopFunctionMatch option")
+	}
+	if m.matchPrefix {
+		m.expectedTopFunction += "."
+	}
+	return m
+}
+
 type ignoringTopFunctionMatcher struct {
 	expectedTopFunction string
 	expectedState       string
@@ -74,9 +122,10 @@ func (matcher *ignoringTopFunctionMatcher) Match(actual interface{}) (success bo
 		return false, err
 	}
 	if matcher.matchPrefix {
-		return strings.HasPrefix(g.TopFunction, matcher.expectedTopFunction), nil
-	}
-	if g.TopFunction != matcher.expectedTopFunction {
+		if !strings.HasPrefix(g.TopFunction, matcher.expectedTopFunction) {
+			return false, nil
+		}
+	} else if g.TopFunction != matcher.expectedTopFunction {
 		return false, nil
 	}
 	if matcher.expectedState == "" {
@@ -99,8 +148,18 @@ func (matcher *ignoringTopFunctionMatcher) NegatedFailureMessage(actual interfac
 	return format.Message(actual, "not "+matcher.message())
 }
 
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringTopFunctionMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
 func (matcher *ignoringTopFunctionMatcher) message() string {
 	if matcher.matchPrefix {
+		if matcher.expectedState != "" {
+			return fmt.Sprintf("to have the prefix %q for its topmost function and the state %q",
+				matcher.expectedTopFunction, matcher.expectedState)
+		}
 		return fmt.Sprintf("to have the prefix %q for its topmost function", matcher.expectedTopFunction)
 	}
 	if matcher.expectedState != "" {
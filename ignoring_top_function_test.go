@@ -17,6 +17,7 @@ package noleak
 import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
 	"github.com/thediveo/noleak/goroutine"
 )
 
@@ -79,3 +80,42 @@ var _ = Describe("IgnoringTopFunction matcher", func() {
 	})
 
 })
+
+var _ = Describe("IgnoringTop matcher", func() {
+
+	It("panics without a function name", func() {
+		Expect(func() { IgnoringTop(TopPrefixMatch()) }).To(PanicWith("IgnoringTop expected a TopFunctionMatch option"))
+	})
+
+	It("matches like the string form", func() {
+		for _, tc := range []struct {
+			m types.GomegaMatcher
+			s string
+		}{
+			{IgnoringTop(TopFunctionMatch("foo.bar")), "foo.bar"},
+			{IgnoringTop(TopPrefixMatch(), TopFunctionMatch("foo")), "foo..."},
+			{IgnoringTop(TopFunctionMatch("foo.bar"), TopStateMatch("worried")), "foo.bar [worried]"},
+		} {
+			Expect(tc.m).To(Equal(IgnoringTopFunction(tc.s)), "%s", tc.s)
+		}
+	})
+
+	It("matches a toplevel function by prefix and state", func() {
+		m := IgnoringTop(TopFunctionMatch("foo"), TopPrefixMatch(), TopStateMatch("worried"))
+		Expect(m.Match(goroutine.Goroutine{
+			TopFunction: "foo.bar",
+			State:       "worried, stalled",
+		})).To(BeTrue())
+		Expect(m.Match(goroutine.Goroutine{
+			TopFunction: "foo.bar",
+			State:       "uneasy",
+		})).To(BeFalse())
+		Expect(m.Match(goroutine.Goroutine{
+			TopFunction: "foo",
+			State:       "worried",
+		})).To(BeFalse())
+		Expect(m.FailureMessage(goroutine.Goroutine{ID: 42, TopFunction: "foo"})).To(HaveSuffix(
+			"to have the prefix \"foo.\" for its topmost function and the state \"worried\""))
+	})
+
+})
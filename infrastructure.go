@@ -0,0 +1,38 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+// GoInfrastructure runs the specified function f in a new goroutine that is
+// marked as "infrastructure", so HaveLeaked and friends never consider it to
+// be leaked. noleak uses GoInfrastructure for its own helper goroutines, such
+// as the background goroutine of a Monitor. Embedders can use it, too, for
+// their own long-lived infrastructure goroutines that are expected to outlive
+// individual tests:
+//
+//   noleak.GoInfrastructure(func() {
+//       metricsServer.Serve(listener)
+//   })
+//
+// The mark is GoInfrastructure being the creator of the goroutine; the mark
+// is thus inherited neither by goroutines started from f nor by goroutines
+// started using plain go statements. As the mark doesn't depend on backtraces,
+// it works with LightweightGoroutines too.
+func GoInfrastructure(f func()) {
+	go f()
+}
+
+// infrastructureCreator is the fully qualified name of the function creating
+// infrastructure goroutines.
+const infrastructureCreator = "github.com/thediveo/noleak.GoInfrastructure"
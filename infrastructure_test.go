@@ -0,0 +1,57 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("infrastructure goroutines", func() {
+
+	It("never considers infrastructure goroutines to be leaks", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		GoInfrastructure(func() { worker(done) })
+		Eventually(Goroutines).Should(ContainElement(
+			HaveField("TopFunction", "github.com/thediveo/noleak.worker")))
+		Expect(Goroutines()).NotTo(HaveLeaked(snapshot))
+
+		go worker(done)
+		Eventually(Goroutines).Should(HaveLeaked(snapshot))
+		close(done)
+		Eventually(Goroutines).ShouldNot(ContainElement(
+			HaveField("TopFunction", "github.com/thediveo/noleak.worker")))
+	})
+
+	It("doesn't report monitor goroutines", func() {
+		snapshot := Goroutines()
+		m := NewMonitor(time.Hour)
+		m.Start()
+		defer m.Stop()
+		Consistently(Goroutines, 50*time.Millisecond).ShouldNot(HaveLeaked(snapshot))
+	})
+
+	It("doesn't report monitor goroutines in lightweight captures", func() {
+		snapshot := LightweightGoroutines()
+		m := NewMonitor(time.Hour)
+		m.Start()
+		defer m.Stop()
+		Consistently(LightweightGoroutines, 50*time.Millisecond).ShouldNot(HaveLeaked(snapshot))
+	})
+
+})
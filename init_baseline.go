@@ -0,0 +1,70 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"sync"
+
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// initBaseline is the baseline recorded by BaselineInit, if any.
+var initBaseline struct {
+	sync.Mutex
+	goroutines []goroutine.Goroutine
+}
+
+// BaselineInit records the current goroutines as the init-time baseline and
+// returns it. BaselineInit is intended to be called from TestMain before
+// running the tests, in order to record the goroutines started by package init
+// functions, such as loggers and metrics exporters:
+//
+//   func TestMain(m *testing.M) {
+//       noleak.BaselineInit()
+//       os.Exit(m.Run())
+//   }
+//
+// Afterwards, HaveLeaked (and thus also Check, testingnoleak.VerifyTest,
+// Monitor, et cetera) implicitly ignore the goroutines of the init-time
+// baseline, unless passed IncludingInitGoroutines.
+func BaselineInit() []goroutine.Goroutine {
+	gs := Goroutines()
+	initBaseline.Lock()
+	defer initBaseline.Unlock()
+	initBaseline.goroutines = gs
+	return gs
+}
+
+// IncludingInitGoroutines returns an option to be passed to HaveLeaked in
+// order to not implicitly ignore the goroutines of the init-time baseline
+// recorded by BaselineInit.
+func IncludingInitGoroutines() interface{} {
+	return includingInitGoroutines{}
+}
+
+// includingInitGoroutines is the type of the IncludingInitGoroutines option.
+type includingInitGoroutines struct{}
+
+// initBaselineFilter returns the filter matcher for ignoring the goroutines of
+// the init-time baseline, or nil if there is no init-time baseline.
+func initBaselineFilter() types.GomegaMatcher {
+	initBaseline.Lock()
+	defer initBaseline.Unlock()
+	if initBaseline.goroutines == nil {
+		return nil
+	}
+	return IgnoringGoroutines(initBaseline.goroutines)
+}
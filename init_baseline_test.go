@@ -0,0 +1,44 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("init-time baseline", func() {
+
+	It("implicitly ignores init-time goroutines", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		Eventually(Goroutines).Should(HaveLeaked(snapshot))
+
+		defer func(old []goroutine.Goroutine) {
+			initBaseline.goroutines = old
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}(initBaseline.goroutines)
+		Expect(BaselineInit()).To(ContainElement(
+			HaveField("TopFunction", "github.com/thediveo/noleak.worker")))
+
+		Expect(Goroutines()).NotTo(HaveLeaked())
+		Expect(Goroutines()).To(HaveLeaked(IncludingInitGoroutines()))
+		Expect(Goroutines()).NotTo(HaveLeaked(IncludingInitGoroutines(), snapshot, "github.com/thediveo/noleak.worker"))
+	})
+
+})
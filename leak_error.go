@@ -0,0 +1,41 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import "github.com/thediveo/noleak/goroutine"
+
+// LeakError is the error returned by Check and reported by
+// testingnoleak.VerifyNone when detecting leaked goroutines. It carries the
+// leaked goroutines so that callers can use errors.As to implement their own
+// handling instead of parsing the formatted error message:
+//
+//   var leakErr *noleak.LeakError
+//   if errors.As(err, &leakErr) {
+//       for _, g := range leakErr.Leaked {
+//           ...
+//       }
+//   }
+//
+// When built with Go 1.21 or later, LeakError additionally implements
+// slog.LogValuer, logging the leaked goroutines as structured attributes.
+type LeakError struct {
+	Leaked  []goroutine.Goroutine // leaked goroutines.
+	Message string                // human-readable description of the leaked goroutines.
+}
+
+// Error returns the human-readable description of the leaked goroutines.
+func (e *LeakError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build go1.21
+
+package noleak
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// LogValue implements slog.LogValuer, returning the number of leaked
+// goroutines together with the leaked goroutines' IDs, states, top functions,
+// and creators.
+func (e *LeakError) LogValue() slog.Value {
+	gs := make([]slog.Attr, 0, len(e.Leaked))
+	for _, g := range e.Leaked {
+		attrs := []slog.Attr{
+			slog.String("state", g.State),
+			slog.String("topFunction", g.TopFunction),
+		}
+		if g.CreatorFunction != "" {
+			attrs = append(attrs,
+				slog.String("creator", g.CreatorFunction),
+				slog.String("bornAt", g.BornAt))
+		}
+		gs = append(gs, slog.Attr{
+			Key:   strconv.FormatUint(g.ID, 10),
+			Value: slog.GroupValue(attrs...),
+		})
+	}
+	return slog.GroupValue(
+		slog.Int("leaked", len(e.Leaked)),
+		slog.Attr{Key: "goroutines", Value: slog.GroupValue(gs...)})
+}
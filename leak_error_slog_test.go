@@ -0,0 +1,59 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build go1.21
+
+package noleak
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("LeakError structured logging", func() {
+
+	It("logs the leaked goroutines", func() {
+		var out strings.Builder
+		logger := slog.New(slog.NewJSONHandler(&out, nil))
+		logger.Error("leak", "err", &LeakError{
+			Leaked: []goroutine.Goroutine{
+				{ID: 42, State: "chan receive", TopFunction: "foo.bar", CreatorFunction: "foo.Start", BornAt: "foo.go:1"},
+				{ID: 666, State: "select", TopFunction: "main.main"},
+			},
+		})
+		var entry map[string]interface{}
+		Expect(json.Unmarshal([]byte(out.String()), &entry)).To(Succeed())
+		Expect(entry).To(HaveKeyWithValue("err", map[string]interface{}{
+			"leaked": float64(2),
+			"goroutines": map[string]interface{}{
+				"42": map[string]interface{}{
+					"state":       "chan receive",
+					"topFunction": "foo.bar",
+					"creator":     "foo.Start",
+					"bornAt":      "foo.go:1",
+				},
+				"666": map[string]interface{}{
+					"state":       "select",
+					"topFunction": "main.main",
+				},
+			},
+		}))
+	})
+
+})
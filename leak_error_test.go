@@ -0,0 +1,43 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LeakError", func() {
+
+	It("carries the leaked goroutines", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		_, err := Check(snapshot, CheckOptions{Timeout: 50 * time.Millisecond})
+		var leakErr *LeakError
+		Expect(errors.As(err, &leakErr)).To(BeTrue())
+		Expect(leakErr.Leaked).To(ConsistOf(
+			HaveField("TopFunction", "github.com/thediveo/noleak.worker")))
+		Expect(leakErr.Error()).To(HavePrefix("found 1 leaked goroutine:\n"))
+	})
+
+})
@@ -0,0 +1,149 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/thediveo/noleak/goroutine"
+	"github.com/thediveo/noleak/report"
+)
+
+// LeakEvent describes the leaked goroutines detected when a leak check finally
+// failed.
+type LeakEvent struct {
+	Test         string                // name of the test the leaks are attributed to, if known.
+	Leaked       []goroutine.Goroutine // leaked goroutines.
+	Fingerprints []string              // unique fingerprints of the leaked goroutines, sorted.
+	Monitor      bool                  // detected by a Monitor in the background instead of a test.
+}
+
+// LeakHook gets called with the details about leaked goroutines whenever a
+// leak check finally fails.
+type LeakHook func(LeakEvent)
+
+var (
+	leakHooksMu sync.Mutex
+	leakHooks   = map[int]LeakHook{}
+	leakHookID  int
+)
+
+// AddLeakHook registers the specified hook to be called whenever a leak check
+// finally fails, that is, when HaveLeaked detects leaked goroutines in an
+// assertion such as:
+//
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(...))
+//
+// Please note that hooks are only called for the final outcome of an
+// assertion, but not for any intermediate leaks detected while Eventually is
+// still polling. AddLeakHook returns a function that removes the hook again.
+// For instance:
+//
+//   remove := AddLeakHook(func(e LeakEvent) {
+//       log.Printf("%d goroutines leaked", len(e.Leaked))
+//   })
+//   DeferCleanup(remove)
+func AddLeakHook(hook LeakHook) (remove func()) {
+	leakHooksMu.Lock()
+	defer leakHooksMu.Unlock()
+	leakHookID++
+	id := leakHookID
+	leakHooks[id] = hook
+	return func() {
+		leakHooksMu.Lock()
+		defer leakHooksMu.Unlock()
+		delete(leakHooks, id)
+	}
+}
+
+// NewLeakEntryHook returns a leak hook that writes machine-readable leak
+// entries to the specified writer (see report.WriteLeakEntry). When writing to
+// os.Stdout, "go test -json" picks up the leak entries as test output, which
+// then can be summarized per test and package using report.ParseTestJSON or
+// the "noleak testjson" command:
+//
+//   DeferCleanup(AddLeakHook(NewLeakEntryHook(os.Stdout)))
+func NewLeakEntryHook(w io.Writer) LeakHook {
+	return func(e LeakEvent) {
+		_ = report.WriteLeakEntry(w, e.Leaked, e.Fingerprints)
+	}
+}
+
+// NewHeatmapHook returns a leak hook that adds leaked goroutines to the
+// specified heatmap, aggregating leaks by creator package across an entire
+// suite run. For instance:
+//
+//   var heatmap report.Heatmap
+//
+//   var _ = BeforeSuite(func() {
+//       DeferCleanup(AddLeakHook(NewHeatmapHook(&heatmap)))
+//   })
+//
+//   var _ = AfterSuite(func() {
+//       f, _ := os.Create("leak-heatmap.csv")
+//       defer f.Close()
+//       _ = heatmap.WriteCSV(f)
+//   })
+func NewHeatmapHook(h *report.Heatmap) LeakHook {
+	return func(e LeakEvent) {
+		h.Add(e.Leaked)
+	}
+}
+
+// notifyLeakHooks calls all registered leak hooks with the specified leaked
+// goroutines attributed to the specified test, or detected by a Monitor, in
+// the order the hooks were registered. In addition, it adds the leaked
+// goroutines to the exit summary, but only if there's a SummaryFile to write
+// it to.
+func notifyLeakHooks(test string, leaked []goroutine.Goroutine, monitor bool) {
+	if SummaryFile != "" {
+		exitSummary.Add(test, leaked)
+	}
+	leakHooksMu.Lock()
+	ids := make([]int, 0, len(leakHooks))
+	for id := range leakHooks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	hooks := make([]LeakHook, len(ids))
+	for idx, id := range ids {
+		hooks[idx] = leakHooks[id]
+	}
+	leakHooksMu.Unlock()
+	if len(hooks) == 0 {
+		return
+	}
+	event := LeakEvent{Test: test, Leaked: leaked, Fingerprints: fingerprints(leaked), Monitor: monitor}
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
+
+// fingerprints returns the sorted list of unique fingerprints of the specified
+// goroutines.
+func fingerprints(gs []goroutine.Goroutine) []string {
+	unique := map[string]struct{}{}
+	for _, g := range gs {
+		unique[g.Fingerprint()] = struct{}{}
+	}
+	fps := make([]string, 0, len(unique))
+	for fp := range unique {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+	return fps
+}
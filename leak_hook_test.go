@@ -0,0 +1,81 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+	"github.com/thediveo/noleak/report"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("leak hooks", func() {
+
+	It("notifies hooks about finally failed leak checks", func() {
+		var events []LeakEvent
+		remove := AddLeakHook(func(e LeakEvent) { events = append(events, e) })
+		defer remove()
+		var order []int
+		defer AddLeakHook(func(LeakEvent) { order = append(order, 1) })()
+		defer AddLeakHook(func(LeakEvent) { order = append(order, 2) })()
+
+		gs := []goroutine.Goroutine{
+			{ID: 1 << 62, TopFunction: "foo.bar"},
+			{ID: 1<<62 + 1, TopFunction: "foo.bar"},
+		}
+		m := HaveLeaked()
+		Expect(m.Match(gs)).To(BeTrue())
+		Expect(events).To(BeEmpty())
+		_ = m.NegatedFailureMessage(gs)
+		Expect(events).To(ConsistOf(And(
+			HaveField("Leaked", HaveLen(2)),
+			HaveField("Fingerprints", ConsistOf(gs[0].Fingerprint())), // sic!
+			HaveField("Monitor", BeFalse()))))
+		Expect(order).To(Equal([]int{1, 2}))
+
+		_ = m.NegatedFailureMessage(gs)
+		Expect(events).To(HaveLen(1), "notified twice about the same failed check")
+
+		remove()
+		Expect(m.Match(gs)).To(BeTrue())
+		_ = m.NegatedFailureMessage(gs)
+		Expect(events).To(HaveLen(1))
+	})
+
+	It("writes machine-readable leak entries", func() {
+		var buff strings.Builder
+		NewLeakEntryHook(&buff)(LeakEvent{
+			Leaked:       []goroutine.Goroutine{{ID: 42, State: "chan receive", TopFunction: "foo.bar"}},
+			Fingerprints: []string{"0123456789abcdef"},
+		})
+		Expect(buff.String()).To(Equal(report.LeakEntryPrefix +
+			`{"leaked":1,"fingerprints":["0123456789abcdef"],"goroutines":[{"id":42,"state":"chan receive","topFunction":"foo.bar"}]}` + "\n"))
+	})
+
+	It("aggregates leaks into heatmaps", func() {
+		var heatmap report.Heatmap
+		NewHeatmapHook(&heatmap)(LeakEvent{
+			Leaked: []goroutine.Goroutine{
+				{ID: 42, CreatorFunction: "github.com/foo/bar.Baz"},
+				{ID: 666, CreatorFunction: "github.com/foo/bar.Baz"},
+			},
+		})
+		Expect(heatmap.Cells()).To(ConsistOf(report.HeatmapCell{Package: "github.com/foo/bar", Leaks: 2}))
+	})
+
+})
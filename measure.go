@@ -0,0 +1,90 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/onsi/gomega/gmeasure"
+	"github.com/onsi/gomega/types"
+)
+
+// Names of the measurements recorded by Measuring and Check into gmeasure
+// experiments, one measurement per capture+match cycle.
+const (
+	CycleDurationMeasurement       = "noleak: capture+match duration"
+	CycleAllocatedBytesMeasurement = "noleak: capture+match allocated bytes"
+	CycleAllocationsMeasurement    = "noleak: capture+match allocations"
+)
+
+// Measuring wraps the specified leak matcher, such as HaveLeaked, so that it
+// records the duration and allocation cost of each match into the specified
+// gmeasure experiment. This allows suite owners to quantify the overhead noleak
+// adds to their suites and to tune the settle and polling settings based on
+// data. In order to include capturing the goroutines in the measured cycles,
+// pass LazyGoroutines to Eventually:
+//
+//   experiment := gmeasure.NewExperiment("leak checks")
+//   AddReportEntry(experiment.Name, experiment)
+//   ...
+//   Eventually(LazyGoroutines{}).ShouldNot(Measuring(experiment, HaveLeaked(snapshot)))
+//
+// The allocation cost is determined from the process-wide memory allocator
+// statistics, so other goroutines allocating concurrently skew the results.
+// Additionally, reading the statistics briefly stops the world, so Measuring
+// itself adds some overhead on top of the measured cycles.
+func Measuring(e *gmeasure.Experiment, m types.GomegaMatcher) types.GomegaMatcher {
+	return &measuringMatcher{GomegaMatcher: m, experiment: e}
+}
+
+type measuringMatcher struct {
+	types.GomegaMatcher
+	experiment *gmeasure.Experiment
+}
+
+// Match passes the actual value on to the wrapped matcher, measuring the
+// duration and allocation cost.
+func (matcher *measuringMatcher) Match(actual interface{}) (success bool, err error) {
+	measureCycle(matcher.experiment, func() {
+		success, err = matcher.GomegaMatcher.Match(actual)
+	})
+	return
+}
+
+// MatchMayChangeInTheFuture asks the wrapped matcher, if it implements
+// Gomega's oracle interface; otherwise, it returns true.
+func (matcher *measuringMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	if oracle, ok := matcher.GomegaMatcher.(interface {
+		MatchMayChangeInTheFuture(actual interface{}) bool
+	}); ok {
+		return oracle.MatchMayChangeInTheFuture(actual)
+	}
+	return true
+}
+
+// measureCycle runs the specified capture+match cycle, recording its duration
+// and allocation cost into the specified experiment.
+func measureCycle(e *gmeasure.Experiment, cycle func()) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	cycle()
+	duration := time.Since(start)
+	runtime.ReadMemStats(&after)
+	e.RecordDuration(CycleDurationMeasurement, duration)
+	e.RecordValue(CycleAllocatedBytesMeasurement, float64(after.TotalAlloc-before.TotalAlloc), gmeasure.Units("B"))
+	e.RecordValue(CycleAllocationsMeasurement, float64(after.Mallocs-before.Mallocs))
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gmeasure"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("measuring leak checks", func() {
+
+	It("records capture+match cycles", func() {
+		e := gmeasure.NewExperiment("leak checks")
+		snapshot := TakeSnapshot()
+		Eventually(LazyGoroutines{}).ShouldNot(Measuring(e, HaveLeaked(snapshot)))
+		Expect(e.Get(CycleDurationMeasurement).Durations).NotTo(BeEmpty())
+		Expect(e.Get(CycleAllocatedBytesMeasurement).Units).To(Equal("B"))
+		Expect(e.Get(CycleAllocationsMeasurement).Values).To(HaveLen(
+			len(e.Get(CycleDurationMeasurement).Durations)))
+	})
+
+	It("passes on match results and the oracle", func() {
+		e := gmeasure.NewExperiment("leak checks")
+		m := Measuring(e, HaveLeaked())
+		Expect(m.Match([]goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.bar"}})).To(BeTrue())
+		Expect(m.Match(nil)).Error().To(HaveOccurred())
+		Expect(e.Get(CycleDurationMeasurement).Durations).To(HaveLen(2))
+		Expect(m.NegatedFailureMessage(nil)).To(HavePrefix("Expected not to leak 1 goroutines"))
+
+		oracle := m.(*measuringMatcher)
+		Expect(oracle.MatchMayChangeInTheFuture(LazyGoroutines{})).To(BeTrue())
+		Expect(oracle.MatchMayChangeInTheFuture([]goroutine.Goroutine{})).To(BeFalse())
+		Expect(Measuring(e, BeTrue()).(*measuringMatcher).MatchMayChangeInTheFuture(nil)).To(BeTrue())
+	})
+
+	It("measures Check", func() {
+		e := gmeasure.NewExperiment("leak checks")
+		done := make(chan struct{})
+		snapshot := Goroutines()
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		_, err := Check(snapshot, CheckOptions{
+			Timeout:    50 * time.Millisecond,
+			Interval:   10 * time.Millisecond,
+			Experiment: e,
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(len(e.Get(CycleDurationMeasurement).Durations)).To(BeNumerically(">=", 2))
+	})
+
+})
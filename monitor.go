@@ -0,0 +1,200 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Monitor continuously checks for leaked goroutines in the background, such as
+// in long-running soak tests where goroutines should not creep up over time. A
+// Monitor periodically takes the current goroutines and filters out all
+// expected goroutines, in the same way as HaveLeaked does. The remaining
+// goroutines are considered to be leaks.
+//
+// Whenever a Monitor detects goroutines it hasn't seen as leaks before, it
+// notifies any registered leak hooks (see AddLeakHook) about these new leaks.
+// Optionally, a Monitor keeps the leaked goroutines of its most recent checks
+// (see KeepHistory) in order to show how leaks accumulated over time.
+type Monitor struct {
+	backoff Backoff
+	matcher *HaveLeakedMatcher
+
+	mu    sync.Mutex
+	stats MonitorStats
+	seen  map[uint64]struct{} // IDs of leaked goroutines seen in the last check.
+	stuck stuckTracker
+	stop  chan struct{}
+	done  chan struct{}
+
+	history  *snapshotRing // optional history of leaked goroutines.
+	historyW io.Writer     // optional writer to dump the history to on new leaks.
+}
+
+// MonitorStats are the statistics of a Monitor.
+type MonitorStats struct {
+	Checks            int64         // number of checks done.
+	Goroutines        int           // number of leaked (non-ignored) goroutines found in the last check.
+	LeaksDetected     int64         // total number of leaked goroutines detected over all checks.
+	Stuck             int           // number of leaked goroutines found stuck in the last check.
+	SystemGoroutines  int           // number of the runtime's system goroutines at the last check; -1 if unknown.
+	LastCheck         time.Time     // when the last check was done.
+	LastCheckDuration time.Duration // duration of the last check.
+	LastError         error         // error of the last check, if any.
+}
+
+// NewMonitor returns a new Monitor that checks for leaked goroutines in the
+// specified interval, after it has been started. The goroutines to be ignored
+// are specified in the same way as with HaveLeaked; for instance, passing a
+// goroutine snapshot ignores all goroutines in this snapshot.
+func NewMonitor(interval time.Duration, ignoring ...interface{}) *Monitor {
+	return &Monitor{
+		backoff: ConstantBackoff(interval),
+		matcher: HaveLeaked(ignoring...).(*HaveLeakedMatcher),
+		seen:    map[uint64]struct{}{},
+	}
+}
+
+// SetBackoff sets the Backoff policy determining the intervals between the
+// checks of this monitor, instead of the fixed interval specified when
+// creating the monitor. The backoff policy takes effect when the monitor is
+// started next.
+func (m *Monitor) SetBackoff(b Backoff) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backoff = b
+}
+
+// Start starts monitoring in the background, if not already started.
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	stop, done, backoff := m.stop, m.done, m.backoff
+	GoInfrastructure(func() { m.run(backoff, stop, done) })
+}
+
+// Stop stops monitoring and waits for the background monitoring to terminate.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// run periodically checks for leaked goroutines until told to stop, waiting
+// between checks as determined by the specified backoff policy.
+func (m *Monitor) run(backoff Backoff, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	timer := time.NewTimer(nextInterval(backoff, 0))
+	defer timer.Stop()
+	for poll := 1; ; poll++ {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+			_, _ = m.Check()
+			timer.Reset(nextInterval(backoff, poll))
+		}
+	}
+}
+
+// Check immediately checks for leaked goroutines, independent of whether the
+// monitor has been started, returning the leaked goroutines found.
+func (m *Monitor) Check() ([]goroutine.Goroutine, error) {
+	start := time.Now()
+	leaked, err := m.matcher.filter(Goroutines(), m.matcher.filters)
+	duration := time.Since(start)
+	system, ok := goroutine.CountSystemGoroutines()
+	if !ok {
+		system = -1
+	}
+
+	m.mu.Lock()
+	m.stats.Checks++
+	m.stats.SystemGoroutines = system
+	m.stats.LastCheck = start
+	m.stats.LastCheckDuration = duration
+	m.stats.LastError = err
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	m.stats.Goroutines = len(leaked)
+	seen := make(map[uint64]struct{}, len(leaked))
+	newlyLeaked := []goroutine.Goroutine{}
+	for _, g := range leaked {
+		seen[g.ID] = struct{}{}
+		if _, ok := m.seen[g.ID]; !ok {
+			newlyLeaked = append(newlyLeaked, g)
+		}
+	}
+	m.seen = seen
+	m.stuck.update(leaked)
+	m.stats.Stuck = len(m.stuck.stuckOnes(leaked))
+	m.stats.LeaksDetected += int64(len(newlyLeaked))
+	if m.history != nil {
+		m.history.add(goroutine.Snapshot{Goroutines: leaked, Taken: start})
+		// write while still holding the lock, so that concurrent checks don't
+		// interleave their history dumps.
+		if m.historyW != nil && len(newlyLeaked) > 0 {
+			_, _ = io.WriteString(m.historyW, m.matcher.historyReport(m.history.list())+"\n")
+		}
+	}
+	m.mu.Unlock()
+
+	if len(newlyLeaked) > 0 {
+		notifyLeakHooks("", newlyLeaked, true)
+	}
+	return leaked, nil
+}
+
+// Stuck returns those leaked goroutines of the last check that are stuck: that
+// is, their top functions and wait reasons haven't changed over successive
+// checks, while they have been waiting increasingly longer. In contrast to
+// goroutines that are just slow to exit, stuck goroutines are most probably
+// wedged for good.
+func (m *Monitor) Stuck() []goroutine.Goroutine {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stuck := make([]goroutine.Goroutine, 0, len(m.stuck.stuck))
+	for _, g := range m.stuck.last {
+		if _, ok := m.stuck.stuck[g.ID]; ok {
+			stuck = append(stuck, g)
+		}
+	}
+	sortGoroutines(stuck)
+	return stuck
+}
+
+// Stats returns the current statistics of this monitor.
+func (m *Monitor) Stats() MonitorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
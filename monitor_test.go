@@ -0,0 +1,104 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"errors"
+	"time"
+
+	"github.com/onsi/gomega/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Monitor", func() {
+
+	It("detects new leaks", func() {
+		var events []LeakEvent
+		defer AddLeakHook(func(e LeakEvent) { events = append(events, e) })()
+
+		snapshot := Goroutines()
+		m := NewMonitor(time.Hour, snapshot)
+		Expect(m.Check()).To(BeEmpty())
+		Expect(m.Stats()).To(And(
+			HaveField("Checks", int64(1)),
+			HaveField("Goroutines", 0),
+			HaveField("LeaksDetected", int64(0))))
+		Expect(events).To(BeEmpty())
+
+		done := make(chan struct{})
+		go func() { <-done }()
+		Expect(m.Check()).To(HaveLen(1))
+		Expect(m.Check()).To(HaveLen(1))
+		Expect(m.Stats()).To(And(
+			HaveField("Checks", int64(3)),
+			HaveField("Goroutines", 1),
+			HaveField("LeaksDetected", int64(1)),
+			HaveField("Stuck", 0),
+			HaveField("LastCheckDuration", BeNumerically(">", 0))))
+		Expect(events).To(ConsistOf(And(
+			HaveField("Leaked", HaveLen(1)),
+			HaveField("Monitor", BeTrue()))))
+		Expect(m.Stuck()).To(BeEmpty())
+
+		close(done)
+		Eventually(m.Check).Should(BeEmpty())
+		Expect(m.Stats().Goroutines).To(BeZero())
+		Expect(m.Stats().LeaksDetected).To(Equal(int64(1)))
+	})
+
+	It("monitors in the background", func() {
+		snapshot := Goroutines()
+		m := NewMonitor(10*time.Millisecond, snapshot)
+		m.Start()
+		m.Start()
+		Eventually(func() int64 { return m.Stats().Checks }).Should(BeNumerically(">=", 2))
+		m.Stop()
+		m.Stop()
+		Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+	})
+
+	It("monitors using a backoff policy", func() {
+		snapshot := Goroutines()
+		m := NewMonitor(time.Hour, snapshot)
+		m.SetBackoff(ExponentialBackoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2})
+		m.Start()
+		Eventually(func() int64 { return m.Stats().Checks }).Should(BeNumerically(">=", 5))
+		m.Stop()
+		Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+	})
+
+	It("reports filter errors", func() {
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		go func() { <-done }()
+		m := NewMonitor(time.Hour, failingMatcher{})
+		Expect(m.Check()).Error().To(MatchError("foo failure"))
+		Expect(m.Stats().LastError).To(MatchError("foo failure"))
+		close(done)
+		Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+	})
+
+})
+
+// failingMatcher always fails matching with an error.
+type failingMatcher struct{}
+
+var _ types.GomegaMatcher = failingMatcher{}
+
+func (failingMatcher) Match(interface{}) (bool, error)          { return false, errors.New("foo failure") }
+func (failingMatcher) FailureMessage(interface{}) string        { return "" }
+func (failingMatcher) NegatedFailureMessage(interface{}) string { return "" }
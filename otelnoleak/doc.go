@@ -0,0 +1,14 @@
+/*
+
+Package otelnoleak records leaked goroutines detected by noleak as
+OpenTelemetry span events, so that test observability pipelines can track leak
+trends, such as across branches.
+
+    remove := noleak.AddLeakHook(otelnoleak.NewSpanEventHook(span))
+    defer remove()
+
+Package otelnoleak is a separate Go module, so that only users of this package
+depend on OpenTelemetry.
+
+*/
+package otelnoleak
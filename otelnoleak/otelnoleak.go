@@ -0,0 +1,42 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package otelnoleak
+
+import (
+	"github.com/thediveo/noleak"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EventName is the name of the span events recorded for leaked goroutines.
+const EventName = "noleak.leak"
+
+// Attribute keys of the span events recorded for leaked goroutines.
+const (
+	LeakCountKey        = attribute.Key("noleak.leak.count")
+	LeakFingerprintsKey = attribute.Key("noleak.leak.fingerprints")
+)
+
+// NewSpanEventHook returns a leak hook that records leaked goroutines as an
+// event on the specified span, with the number of leaked goroutines and the
+// unique fingerprints of the leaked goroutines as event attributes.
+func NewSpanEventHook(span trace.Span) noleak.LeakHook {
+	return func(e noleak.LeakEvent) {
+		span.AddEvent(EventName, trace.WithAttributes(
+			LeakCountKey.Int(len(e.Leaked)),
+			LeakFingerprintsKey.StringSlice(e.Fingerprints),
+		))
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package otelnoleak
+
+import (
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// recordingSpan records the events added to it, while otherwise behaving as a
+// non-recording span.
+type recordingSpan struct {
+	trace.Span
+	events []recordedEvent
+}
+
+type recordedEvent struct {
+	name  string
+	attrs []attribute.KeyValue
+}
+
+func (s *recordingSpan) AddEvent(name string, options ...trace.EventOption) {
+	config := trace.NewEventConfig(options...)
+	s.events = append(s.events, recordedEvent{
+		name:  name,
+		attrs: config.Attributes(),
+	})
+}
+
+var _ = Describe("OpenTelemetry span events", func() {
+
+	It("records leaks as span events", func() {
+		span := &recordingSpan{Span: trace.SpanFromContext(nil)} //nolint:staticcheck // SA1012 nil ctx is fine here
+		defer noleak.AddLeakHook(NewSpanEventHook(span))()
+
+		gs := []goroutine.Goroutine{{ID: 42, TopFunction: "foo.bar"}}
+		m := noleak.HaveLeaked()
+		Expect(m.Match(gs)).To(BeTrue())
+		_ = m.NegatedFailureMessage(gs)
+
+		Expect(span.events).To(ConsistOf(recordedEvent{
+			name: EventName,
+			attrs: []attribute.KeyValue{
+				LeakCountKey.Int(1),
+				LeakFingerprintsKey.StringSlice([]string{gs[0].Fingerprint()}),
+			},
+		}))
+	})
+
+})
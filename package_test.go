@@ -12,16 +12,18 @@
 // License for the specific language governing permissions and limitations
 // under the License.
 
-package noleak
+package noleak_test
 
 import (
 	"testing"
 
-	. "github.com/onsi/ginkgo/v2"
-	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/ginkgonoleak"
 )
 
 func TestPackage(t *testing.T) {
-	RegisterFailHandler(Fail)
-	RunSpecs(t, "noleak package")
+	// Many specs start goroutines and then immediately capture them, so give
+	// these goroutines a scheduling round to reach their blocking points.
+	noleak.PreCaptureGrace = true
+	ginkgonoleak.RunLeakCheckedSpecs(t, "noleak package")
 }
@@ -0,0 +1,104 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// HaveParkedGoroutines succeeds if any goroutine has been observed in a
+// blocking state, such as "chan receive", "select", or "IO wait", in all the
+// successive goroutine lists it has been matched against for at least the
+// specified duration. Goroutines are ignored in the same way as with
+// HaveLeaked, so typically a baseline snapshot is passed in order to only
+// consider goroutines started afterwards.
+//
+// HaveParkedGoroutines is meant to be used with Consistently, in order to
+// detect goroutines that are technically alive, but permanently parked, and
+// thus might not be caught by checks for leaked goroutines at the end of
+// tests:
+//
+//   Consistently(Goroutines).WithTimeout(2 * time.Second).
+//       ShouldNot(HaveParkedGoroutines(time.Second, snapshot))
+//
+// Please note that as goroutine lists are only samples, a goroutine that gets
+// woken up and then blocks again between two samples still counts as parked
+// all the time. Thus, the sampling window should span multiple polling
+// intervals of goroutines expected to make progress.
+func HaveParkedGoroutines(d time.Duration, ignoring ...interface{}) types.GomegaMatcher {
+	return &haveParkedGoroutinesMatcher{
+		duration: d,
+		leaked:   HaveLeaked(ignoring...).(*HaveLeakedMatcher),
+		since:    map[goroutine.Identity]time.Time{},
+	}
+}
+
+type haveParkedGoroutinesMatcher struct {
+	duration time.Duration
+	leaked   *HaveLeakedMatcher               // filters the goroutines to consider.
+	since    map[goroutine.Identity]time.Time // start of uninterrupted blocking per goroutine.
+	parked   []goroutine.Goroutine            // goroutines parked for at least the duration.
+}
+
+// Match succeeds if any non-ignored goroutine has been observed blocking in
+// all samples over at least the specified duration.
+func (matcher *haveParkedGoroutinesMatcher) Match(actual interface{}) (success bool, err error) {
+	if _, err := matcher.leaked.Match(actual); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	since := make(map[goroutine.Identity]time.Time, len(matcher.leaked.leaked))
+	matcher.parked = nil
+	for _, g := range matcher.leaked.leaked {
+		if !g.IsBlocked() {
+			continue
+		}
+		id := g.Identity()
+		start, ok := matcher.since[id]
+		if !ok {
+			start = now
+		}
+		since[id] = start
+		if now.Sub(start) >= matcher.duration {
+			matcher.parked = append(matcher.parked, g)
+		}
+	}
+	matcher.since = since
+	sortGoroutines(matcher.parked)
+	return len(matcher.parked) > 0, nil
+}
+
+// FailureMessage returns a failure message if no goroutines have been parked
+// for at least the specified duration.
+func (matcher *haveParkedGoroutinesMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected goroutines to be parked for at least %s, but found none", matcher.duration)
+}
+
+// NegatedFailureMessage returns a failure message if goroutines have been
+// parked for at least the specified duration.
+func (matcher *haveParkedGoroutinesMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected no goroutines to be parked for %s or longer, but found %d:\n%s",
+		matcher.duration, len(matcher.parked), matcher.leaked.listGoroutines(matcher.parked, 1))
+}
+
+// MatchMayChangeInTheFuture always returns true, as goroutines might get
+// parked only later.
+func (matcher *haveParkedGoroutinesMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return true
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("parked goroutines", func() {
+
+	It("detects permanently parked goroutines", func() {
+		snapshot := TakeSnapshot()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+
+		Consistently(Goroutines).WithTimeout(100 * time.Millisecond).
+			ShouldNot(HaveParkedGoroutines(time.Minute, snapshot))
+
+		m := HaveParkedGoroutines(50*time.Millisecond, snapshot)
+		Expect(m.Match(Goroutines())).To(BeFalse())
+		Eventually(Goroutines).Should(m)
+		Expect(m.NegatedFailureMessage(nil)).To(MatchRegexp(
+			`^Expected no goroutines to be parked for 50ms or longer, but found 1:\n    goroutine \d+ \[chan receive\]\n        github.com/thediveo/noleak.worker\(`))
+		Expect(m.FailureMessage(nil)).To(Equal(
+			"Expected goroutines to be parked for at least 50ms, but found none"))
+	})
+
+	It("restarts the parking time when goroutines make progress", func() {
+		g := goroutine.Goroutine{ID: 1 << 62, State: "chan receive", TopFunction: "main.foo", CreatorFunction: "main.main"}
+		running := g
+		running.State = "running"
+
+		m := HaveParkedGoroutines(50 * time.Millisecond)
+		Expect(m.Match([]goroutine.Goroutine{g})).To(BeFalse())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match([]goroutine.Goroutine{running})).To(BeFalse())
+		Expect(m.Match([]goroutine.Goroutine{g})).To(BeFalse())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match([]goroutine.Goroutine{g})).To(BeTrue())
+		Expect(m.Match([]goroutine.Goroutine{})).To(BeFalse())
+	})
+
+	It("passes on errors", func() {
+		Expect(HaveParkedGoroutines(time.Second).Match(nil)).Error().To(HaveOccurred())
+	})
+
+})
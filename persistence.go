@@ -0,0 +1,87 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Persistence specifies how persistently goroutines need to show up in
+// successive captures in order to be considered leaked, see
+// RequiringPersistence.
+type Persistence struct {
+	k, n int
+}
+
+// RequiringPersistence returns a specification to be passed to HaveLeaked that
+// reports goroutines as leaked only if they appear (by their fingerprints) in
+// at least k of the last n captures HaveLeaked has been matched against. This
+// sharply reduces false positives from goroutines that come and go, such as
+// the short-lived goroutines of worker pools, without the need for long fixed
+// sleeps.
+//
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot, RequiringPersistence(3, 5)))
+//
+// As long as HaveLeaked has seen fewer than n captures, it reports all
+// non-ignored goroutines as leaked, as it can't tell yet which goroutines are
+// persistent. RequiringPersistence panics unless 1 <= k <= n.
+func RequiringPersistence(k, n int) Persistence {
+	if k < 1 || n < k {
+		panic(fmt.Sprintf("RequiringPersistence expected 1 <= k <= n, but got k=%d, n=%d", k, n))
+	}
+	return Persistence{k: k, n: n}
+}
+
+// persistenceTracker tracks the fingerprints of the leaked goroutines of the
+// most recent captures.
+type persistenceTracker struct {
+	Persistence
+	captures []map[string]struct{} // fingerprints of the last n captures, oldest first.
+}
+
+// persistent records the specified leaked goroutines of the latest capture
+// and returns only those goroutines that have been persistent in at least k
+// of the last n captures. As long as fewer than n captures have been recorded,
+// all goroutines are returned.
+func (t *persistenceTracker) persistent(leaked []goroutine.Goroutine) []goroutine.Goroutine {
+	fingerprints := make([]string, len(leaked))
+	capture := make(map[string]struct{}, len(leaked))
+	for idx, g := range leaked {
+		fingerprints[idx] = g.Fingerprint()
+		capture[fingerprints[idx]] = struct{}{}
+	}
+	t.captures = append(t.captures, capture)
+	if len(t.captures) > t.n {
+		t.captures = t.captures[1:]
+	}
+	if len(t.captures) < t.n {
+		return leaked
+	}
+	persistent := make([]goroutine.Goroutine, 0, len(leaked))
+	for idx, g := range leaked {
+		seen := 0
+		for _, capture := range t.captures {
+			if _, ok := capture[fingerprints[idx]]; ok {
+				seen++
+			}
+		}
+		if seen >= t.k {
+			persistent = append(persistent, g)
+		}
+	}
+	return persistent
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("persistent leaks", func() {
+
+	persistentG := goroutine.Goroutine{ID: 1<<62 + 1, TopFunction: "foo.persistent", CreatorFunction: "foo.main", BornAt: "/foo/main.go:1"}
+	transientG := goroutine.Goroutine{ID: 1<<62 + 2, TopFunction: "foo.transient", CreatorFunction: "foo.main", BornAt: "/foo/main.go:2"}
+
+	It("rejects invalid specifications", func() {
+		Expect(func() { _ = RequiringPersistence(0, 1) }).To(PanicWith(
+			"RequiringPersistence expected 1 <= k <= n, but got k=0, n=1"))
+		Expect(func() { _ = RequiringPersistence(3, 2) }).To(Panic())
+	})
+
+	It("reports only goroutines seen in at least k of the last n captures", func() {
+		m := HaveLeaked(RequiringPersistence(2, 3)).(*HaveLeakedMatcher)
+		both := []goroutine.Goroutine{persistentG, transientG}
+		only := []goroutine.Goroutine{persistentG}
+
+		Expect(m.Match(both)).To(BeTrue())
+		Expect(m.leaked).To(HaveLen(2), "undecided until n captures")
+		Expect(m.Match(only)).To(BeTrue())
+		Expect(m.leaked).To(HaveLen(1))
+
+		// transient goroutine now seen in exactly 2 of the last 3 captures.
+		Expect(m.Match(both)).To(BeTrue())
+		Expect(m.leaked).To(ConsistOf(HaveField("TopFunction", "foo.persistent"), HaveField("TopFunction", "foo.transient")))
+
+		// identifies goroutines by their fingerprints, not IDs.
+		Expect(m.Match(only)).To(BeTrue())
+		transientG2 := transientG
+		transientG2.ID++
+		Expect(m.Match([]goroutine.Goroutine{persistentG, transientG2})).To(BeTrue())
+		Expect(m.leaked).To(ConsistOf(HaveField("TopFunction", "foo.persistent"), HaveField("ID", transientG2.ID)))
+
+		// transient goroutine seen only in 1 of the last 3 captures.
+		Expect(m.Match(only)).To(BeTrue())
+		Expect(m.Match(only)).To(BeTrue())
+		Expect(m.Match([]goroutine.Goroutine{transientG})).To(BeFalse())
+	})
+
+	It("lets Eventually succeed with goroutines that come and go", func() {
+		otherG := transientG
+		otherG.ID++
+		otherG.BornAt = "/foo/main.go:3"
+		// there's always some goroutine around, but never the same one long
+		// enough.
+		captures := [][]goroutine.Goroutine{{transientG}, {otherG}, {transientG}, {otherG}}
+		poll := 0
+		Eventually(func() []goroutine.Goroutine {
+			gs := captures[poll%len(captures)]
+			poll++
+			return gs
+		}).ShouldNot(HaveLeaked(RequiringPersistence(3, 4)))
+		Expect(poll).To(Equal(4), "undecided until n captures")
+	})
+
+})
@@ -0,0 +1,116 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Phases returns an option to be passed to HaveLeaked in order to attribute
+// leaked goroutines to the phases of multi-stage tests in which they appeared.
+// The phases are delimited by the specified snapshots, which usually have been
+// tagged with the names of the phases they end using TakeTaggedSnapshot. In
+// contrast to baseline snapshots, the goroutines in these snapshots are not
+// ignored. For instance:
+//
+//   baseline := TakeTaggedSnapshot("start")
+//   SetUpSuite()
+//   afterSetup := TakeTaggedSnapshot("after-suite-setup")
+//   SetUpFixture()
+//   afterFixture := TakeTaggedSnapshot("after-fixture-X")
+//   ...
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(baseline, Phases(afterSetup, afterFixture)))
+//
+// Failure messages then attribute leaked goroutines to the phases, such as "2
+// goroutines appeared between 'after-fixture-X' and now". A tagged baseline
+// snapshot delimits the start of the first phase.
+func Phases(snapshots ...goroutine.Snapshot) interface{} {
+	return phaseSnapshots(snapshots)
+}
+
+// phaseSnapshots is the type of the Phases option.
+type phaseSnapshots []goroutine.Snapshot
+
+// phaseName returns the name of the phase ended by the specified snapshot,
+// falling back to the time the snapshot was taken for untagged snapshots.
+func phaseName(s *goroutine.Snapshot) string {
+	if s.Tag != "" {
+		return s.Tag
+	}
+	return s.Taken.Format("15:04:05.000")
+}
+
+// phaseDetails returns a description of the phases the specified leaked
+// goroutines appeared in, or an empty string if no phases have been specified.
+func (matcher *HaveLeakedMatcher) phaseDetails(leaked []goroutine.Goroutine) string {
+	if len(matcher.phases) == 0 || len(leaked) == 0 {
+		return ""
+	}
+	phases := append(phaseSnapshots{}, matcher.phases...)
+	sort.SliceStable(phases, func(a, b int) bool { return phases[a].Taken.Before(phases[b].Taken) })
+	seen := make([]map[goroutine.Identity]struct{}, len(phases))
+	for idx, s := range phases {
+		seen[idx] = make(map[goroutine.Identity]struct{}, len(s.Goroutines))
+		for _, g := range s.Goroutines {
+			seen[idx][g.Identity()] = struct{}{}
+		}
+	}
+	// Leaked goroutines appeared in the phase ended by the first snapshot
+	// they're in, or otherwise in the current phase.
+	appeared := make([][]goroutine.Goroutine, len(phases)+1)
+	for _, g := range leaked {
+		idx := len(phases)
+		for pidx := range phases {
+			if _, ok := seen[pidx][g.Identity()]; ok {
+				idx = pidx
+				break
+			}
+		}
+		appeared[idx] = append(appeared[idx], g)
+	}
+	var lines []string
+	for idx, gs := range appeared {
+		if len(gs) == 0 {
+			continue
+		}
+		to := "now"
+		if idx < len(phases) {
+			to = "'" + phaseName(&phases[idx]) + "'"
+		}
+		var from string
+		switch {
+		case idx > 0:
+			from = phaseName(&phases[idx-1])
+		case matcher.baseline != nil && matcher.baseline.Tag != "":
+			from = matcher.baseline.Tag
+		}
+		count := counted(len(gs), "goroutine", "goroutines")
+		ids := "goroutines " + goids(gs)
+		if len(gs) == 1 {
+			ids = "goroutine " + goids(gs)
+		}
+		if from == "" {
+			lines = append(lines, fmt.Sprintf("%s appeared before %s: %s", count, to, ids))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s appeared between '%s' and %s: %s", count, from, to, ids))
+	}
+	return "\nLeaked goroutines by phase:\n" + format.Indent + strings.Join(lines, "\n"+format.Indent)
+}
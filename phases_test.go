@@ -0,0 +1,76 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("test phases", func() {
+
+	g := func(id uint64) goroutine.Goroutine {
+		return goroutine.Goroutine{ID: 1<<62 + id, State: "sleep", TopFunction: "foo.bar"}
+	}
+	now := time.Now()
+	snapshot := func(tag string, age time.Duration, gs ...goroutine.Goroutine) goroutine.Snapshot {
+		return goroutine.Snapshot{Goroutines: gs, Taken: now.Add(-age), Tag: tag}
+	}
+
+	It("attributes leaks to the phases they appeared in", func() {
+		baseline := snapshot("start", 3*time.Minute)
+		afterSetup := snapshot("after-suite-setup", 2*time.Minute, g(1))
+		afterFixture := snapshot("after-fixture-X", time.Minute, g(1), g(2))
+
+		m := HaveLeaked(baseline, Phases(afterFixture, afterSetup)).(*HaveLeakedMatcher)
+		Expect(m.Match([]goroutine.Goroutine{g(1), g(2), g(3), g(4)})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring(`
+Leaked goroutines by phase:
+    1 goroutine appeared between 'start' and 'after-suite-setup': goroutine 4611686018427387905
+    1 goroutine appeared between 'after-suite-setup' and 'after-fixture-X': goroutine 4611686018427387906
+    2 goroutines appeared between 'after-fixture-X' and now: goroutines 4611686018427387907, 4611686018427387908`))
+	})
+
+	It("doesn't ignore the goroutines of phase snapshots", func() {
+		m := HaveLeaked(Phases(snapshot("after-suite-setup", time.Minute, g(1))))
+		Expect(m.Match([]goroutine.Goroutine{g(1)})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring(
+			"\n    1 goroutine appeared before 'after-suite-setup': goroutine 4611686018427387905"))
+	})
+
+	It("names untagged phases by time", func() {
+		untagged := snapshot("", 0)
+		m := HaveLeaked(Phases(untagged))
+		Expect(m.Match([]goroutine.Goroutine{g(1)})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring(
+			"appeared between '" + untagged.Taken.Format("15:04:05.000") + "' and now"))
+	})
+
+	It("doesn't report phases if there are none", func() {
+		m := HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{g(1)})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("by phase"))
+	})
+
+	It("takes tagged snapshots", func() {
+		s := TakeTaggedSnapshot("after-fixture-X")
+		Expect(s.Tag).To(Equal("after-fixture-X"))
+		Expect(s.Goroutines).NotTo(BeEmpty())
+	})
+
+})
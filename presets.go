@@ -0,0 +1,135 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// Names of the test framework filter presets, see IgnoringPreset.
+const (
+	PresetGinkgo  = "ginkgo"  // Ginkgo's spec runner, progress reporters, and further internals.
+	PresetTestify = "testify" // testify's suite, assert, and require packages.
+	PresetGocheck = "gocheck" // gopkg.in/check.v1.
+)
+
+// presets maps preset names to their filter matchers.
+var presets = map[string][]types.GomegaMatcher{
+	PresetGinkgo: {
+		IgnoringCreator("github.com/onsi/ginkgo/v2/internal..."),
+		IgnoringCreator("github.com/onsi/ginkgo/v2/reporters..."),
+		IgnoringTopFunction("github.com/onsi/ginkgo/v2/internal..."),
+		IgnoringCreator("github.com/onsi/ginkgo/internal..."),
+		IgnoringTopFunction("github.com/onsi/ginkgo/internal..."),
+	},
+	PresetTestify: {
+		IgnoringCreator("github.com/stretchr/testify/suite..."),
+		IgnoringCreator("github.com/stretchr/testify/assert..."),
+		IgnoringCreator("github.com/stretchr/testify/require..."),
+	},
+	PresetGocheck: {
+		// function names escape the dots in the last element of package
+		// paths.
+		IgnoringCreator("gopkg.in/check%2ev1..."),
+		IgnoringTopFunction("gopkg.in/check%2ev1..."),
+	},
+}
+
+// Presets returns the sorted names of the available test framework filter
+// presets.
+func Presets() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IgnoringPreset succeeds if an actual goroutine belongs to any of the test
+// frameworks specified by their preset names, such as PresetTestify. Beyond
+// the standard filters covering Go's testing package, the presets cover the
+// goroutines spawned by testify, gocheck, as well as Ginkgo's internals:
+//
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(IgnoringPreset(PresetTestify)))
+//
+// IgnoringPreset panics if a preset name is unknown.
+func IgnoringPreset(names ...string) types.GomegaMatcher {
+	m := &ignoringPresetMatcher{names: names}
+	for _, name := range names {
+		filters, ok := presets[name]
+		if !ok {
+			panic(fmt.Sprintf("IgnoringPreset expected one of %s, but got: %q",
+				strings.Join(Presets(), ", "), name))
+		}
+		m.filters = append(m.filters, filters...)
+	}
+	return m
+}
+
+type ignoringPresetMatcher struct {
+	names   []string
+	filters []types.GomegaMatcher
+}
+
+// Match succeeds if any of the preset filters matches the actual goroutine.
+func (matcher *ignoringPresetMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := G(actual, "IgnoringPreset")
+	if err != nil {
+		return false, err
+	}
+	for _, filter := range matcher.filters {
+		matches, err := filter.Match(g)
+		if err != nil {
+			return false, err
+		}
+		if matches {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FailureMessage returns a failure message if the actual goroutine doesn't
+// belong to any of the presets.
+func (matcher *ignoringPresetMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, "to belong to the presets", matcher.names)
+}
+
+// NegatedFailureMessage returns a failure message if the actual goroutine
+// belongs to any of the presets.
+func (matcher *ignoringPresetMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, "not to belong to the presets", matcher.names)
+}
+
+// MatchMayChangeInTheFuture always returns false, as a goroutine
+// description never changes.
+func (matcher *ignoringPresetMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
+
+// String returns the Go expression creating this matcher.
+func (matcher *ignoringPresetMatcher) String() string {
+	names := make([]string, len(matcher.names))
+	for idx, name := range matcher.names {
+		names[idx] = fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("IgnoringPreset(%s)", strings.Join(names, ", "))
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("test framework presets", func() {
+
+	It("lists the presets", func() {
+		Expect(Presets()).To(Equal([]string{PresetGinkgo, PresetGocheck, PresetTestify}))
+	})
+
+	It("rejects unknown presets", func() {
+		Expect(func() { IgnoringPreset("foo") }).To(PanicWith(
+			`IgnoringPreset expected one of ginkgo, gocheck, testify, but got: "foo"`))
+	})
+
+	DescribeTable("matching framework goroutines",
+		func(preset string, g goroutine.Goroutine, expected bool) {
+			Expect(IgnoringPreset(preset).Match(g)).To(Equal(expected))
+		},
+		Entry(nil, PresetGinkgo, goroutine.Goroutine{
+			TopFunction: "github.com/onsi/ginkgo/v2/internal.(*ProgressReporterManager).Watch",
+		}, true),
+		Entry(nil, PresetGinkgo, goroutine.Goroutine{
+			TopFunction: "main.worker", CreatorFunction: "github.com/onsi/ginkgo/v2/internal.(*Suite).runNode",
+		}, true),
+		Entry(nil, PresetGinkgo, goroutine.Goroutine{
+			TopFunction: "main.worker", CreatorFunction: "main.foo",
+		}, false),
+		Entry(nil, PresetTestify, goroutine.Goroutine{
+			TopFunction: "time.Sleep", CreatorFunction: "github.com/stretchr/testify/assert.Eventually",
+		}, true),
+		Entry(nil, PresetTestify, goroutine.Goroutine{
+			TopFunction: "github.com/onsi/ginkgo/v2/internal.foo",
+		}, false),
+		Entry(nil, PresetGocheck, goroutine.Goroutine{
+			TopFunction: "gopkg.in/check%2ev1.(*resultTracker)._loopRoutine",
+		}, true),
+	)
+
+	It("propagates filter errors", func() {
+		m := &ignoringPresetMatcher{filters: []types.GomegaMatcher{HaveLen(1)}}
+		Expect(m.Match(goroutine.Goroutine{})).Error().To(HaveOccurred())
+	})
+
+	It("describes itself", func() {
+		m := IgnoringPreset(PresetTestify, PresetGocheck)
+		Expect(describeFilter(m)).To(Equal(`IgnoringPreset("testify", "gocheck")`))
+		Expect(m.Match(nil)).Error().To(HaveOccurred())
+		Expect(m.FailureMessage(goroutine.Goroutine{})).To(ContainSubstring("to belong to the presets"))
+		Expect(m.NegatedFailureMessage(goroutine.Goroutine{})).To(ContainSubstring("not to belong to the presets"))
+	})
+
+})
@@ -0,0 +1,63 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// ProgressReporter returns a function producing a report of the goroutines
+// that are not attributable to the testing framework, Go's runtime, or any of
+// the specified goroutines to ignore. The goroutines to ignore are specified in
+// the same way as with HaveLeaked. The returned function is meant to be called
+// when a spec times out or otherwise seems to hang, as the goroutines it
+// reports often include the wedged goroutine that caused the timeout.
+//
+// For instance, add the report as a report entry to failed specs:
+//
+//   var reporter func() string
+//   BeforeEach(func() { reporter = ProgressReporter(TakeSnapshot()) })
+//   AfterEach(func() {
+//       if CurrentSpecReport().Failed() {
+//           AddReportEntry("unattributed goroutines", reporter())
+//       }
+//   })
+//
+// In contrast to Goroutines, the returned function neither runs the garbage
+// collector nor sleeps before capturing the goroutines, as they are expected
+// to be stuck anyway.
+func ProgressReporter(ignoring ...interface{}) func() string {
+	m := HaveLeaked(ignoring...).(*HaveLeakedMatcher)
+	return func() string {
+		return m.progressReport(goroutine.Goroutines())
+	}
+}
+
+// progressReport returns a report of the specified goroutines not matched by
+// any of the filters of this matcher.
+func (matcher *HaveLeakedMatcher) progressReport(gs []goroutine.Goroutine) string {
+	unattributed, err := matcher.filter(gs, matcher.filters)
+	if err != nil {
+		return fmt.Sprintf("noleak: cannot filter goroutines: %s", err)
+	}
+	if len(unattributed) == 0 {
+		return "noleak: all goroutines are attributable to the testing framework or expected"
+	}
+	sortGoroutines(unattributed)
+	return fmt.Sprintf("noleak: %s not attributable to the testing framework or expected:\n%s",
+		counted(len(unattributed), "goroutine", "goroutines"), matcher.listGoroutines(unattributed, 1))
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("progress reports", func() {
+
+	It("reports unattributable goroutines", func() {
+		snapshot := TakeSnapshot()
+		reporter := ProgressReporter(snapshot)
+		Expect(reporter()).To(Equal(
+			"noleak: all goroutines are attributable to the testing framework or expected"))
+
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+		Eventually(reporter).Should(MatchRegexp(
+			`^noleak: 1 goroutine not attributable to the testing framework or expected:\n    goroutine \d+ \[chan receive\]\n        github.com/thediveo/noleak.worker\(`))
+	})
+
+	It("reports filter errors", func() {
+		m := HaveLeaked(HaveLen(1)).(*HaveLeakedMatcher)
+		Expect(m.progressReport([]goroutine.Goroutine{{ID: 1 << 62}})).To(
+			HavePrefix("noleak: cannot filter goroutines: "))
+	})
+
+})
@@ -0,0 +1,83 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package promnoleak
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thediveo/noleak"
+)
+
+var (
+	goroutinesDesc = prometheus.NewDesc(
+		"noleak_goroutines",
+		"Number of leaked (non-ignored) goroutines found in the last check.",
+		nil, nil)
+	leaksDetectedDesc = prometheus.NewDesc(
+		"noleak_leaks_detected_total",
+		"Total number of leaked goroutines detected.",
+		nil, nil)
+	checksDesc = prometheus.NewDesc(
+		"noleak_checks_total",
+		"Total number of leak checks done.",
+		nil, nil)
+	lastCheckDurationDesc = prometheus.NewDesc(
+		"noleak_last_check_duration_seconds",
+		"Duration of the last leak check in seconds.",
+		nil, nil)
+	systemGoroutinesDesc = prometheus.NewDesc(
+		"noleak_system_goroutines",
+		"Number of the Go runtime's system goroutines at the last check.",
+		nil, nil)
+)
+
+// Collector is a prometheus.Collector exposing the statistics of a noleak
+// Monitor.
+type Collector struct {
+	monitor *noleak.Monitor
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a new Collector for the specified monitor.
+func NewCollector(monitor *noleak.Monitor) *Collector {
+	return &Collector{monitor: monitor}
+}
+
+// Describe sends the descriptors of the monitor metrics to the specified
+// channel.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- goroutinesDesc
+	ch <- leaksDetectedDesc
+	ch <- checksDesc
+	ch <- lastCheckDurationDesc
+	ch <- systemGoroutinesDesc
+}
+
+// Collect sends the current monitor metrics to the specified channel.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.monitor.Stats()
+	ch <- prometheus.MustNewConstMetric(goroutinesDesc,
+		prometheus.GaugeValue, float64(stats.Goroutines))
+	ch <- prometheus.MustNewConstMetric(leaksDetectedDesc,
+		prometheus.CounterValue, float64(stats.LeaksDetected))
+	ch <- prometheus.MustNewConstMetric(checksDesc,
+		prometheus.CounterValue, float64(stats.Checks))
+	ch <- prometheus.MustNewConstMetric(lastCheckDurationDesc,
+		prometheus.GaugeValue, stats.LastCheckDuration.Seconds())
+	if stats.SystemGoroutines >= 0 {
+		ch <- prometheus.MustNewConstMetric(systemGoroutinesDesc,
+			prometheus.GaugeValue, float64(stats.SystemGoroutines))
+	}
+}
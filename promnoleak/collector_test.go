@@ -0,0 +1,57 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package promnoleak
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/thediveo/noleak"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prometheus collector", func() {
+
+	It("exposes monitor statistics", func() {
+		m := noleak.NewMonitor(time.Hour, noleak.Goroutines())
+		done := make(chan struct{})
+		defer close(done)
+		go func() { <-done }()
+		Expect(m.Check()).To(HaveLen(1))
+
+		c := NewCollector(m)
+		metrics := 4
+		if m.Stats().SystemGoroutines >= 0 {
+			metrics++ // ...as this Go runtime supports counting system goroutines.
+		}
+		Expect(testutil.CollectAndCount(c)).To(Equal(metrics))
+		Expect(testutil.CollectAndCount(c, "noleak_system_goroutines")).To(Equal(metrics - 4))
+		Expect(testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP noleak_checks_total Total number of leak checks done.
+# TYPE noleak_checks_total counter
+noleak_checks_total 1
+# HELP noleak_goroutines Number of leaked (non-ignored) goroutines found in the last check.
+# TYPE noleak_goroutines gauge
+noleak_goroutines 1
+# HELP noleak_leaks_detected_total Total number of leaked goroutines detected.
+# TYPE noleak_leaks_detected_total counter
+noleak_leaks_detected_total 1
+`), "noleak_checks_total", "noleak_goroutines", "noleak_leaks_detected_total")).To(Succeed())
+	})
+
+})
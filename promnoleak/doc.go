@@ -0,0 +1,15 @@
+/*
+
+Package promnoleak exposes the statistics of a noleak Monitor as Prometheus
+metrics, so that soak test dashboards can alert on goroutine creep.
+
+    m := noleak.NewMonitor(10*time.Second, noleak.Goroutines())
+    prometheus.MustRegister(promnoleak.NewCollector(m))
+    m.Start()
+    defer m.Stop()
+
+Package promnoleak is a separate Go module, so that only users of this package
+depend on the Prometheus client library.
+
+*/
+package promnoleak
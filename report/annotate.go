@@ -0,0 +1,211 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Severity is the severity of an Annotation.
+type Severity string
+
+// The severities of annotations.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNotice  Severity = "notice"
+)
+
+// Annotation points a CI system at a source code location, such as the "go"
+// statement that started leaked goroutines.
+type Annotation struct {
+	File     string   `json:"file,omitempty"` // file name; empty if unknown.
+	Line     int      `json:"line,omitempty"` // line number; zero if unknown.
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Annotator writes annotations in the format of a particular CI system, so
+// that the CI system surfaces them natively, such as in pull request diffs.
+// GitHubAnnotator, TeamCityAnnotator, and JSONAnnotator cover GitHub Actions,
+// TeamCity, and other CI systems, respectively.
+type Annotator interface {
+	Annotate(w io.Writer, annotations []Annotation) error
+}
+
+// Annotations returns an annotation with the specified severity for each
+// creator location of the specified leaked goroutines, pointing at the "go"
+// statement that started the leaked goroutines. If basedir isn't empty, then
+// file locations inside basedir are made relative to it, as CI systems usually
+// expect file locations relative to the repository root.
+func Annotations(leaks []goroutine.Goroutine, severity Severity, basedir string) []Annotation {
+	annotations := []Annotation{}
+	for _, group := range GroupByCreator(leaks) {
+		annotation := Annotation{Severity: severity, Message: groupMessage(group)}
+		if creator := group.Goroutines[0].CreatorFrame; creator.File != "" && creator.Line > 0 {
+			annotation.File = relativeURI(creator.File, basedir)
+			annotation.Line = creator.Line
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations
+}
+
+// Annotate writes error annotations for the creator locations of the
+// specified leaked goroutines to w, using the specified annotator. See also
+// Annotations.
+func Annotate(w io.Writer, annotator Annotator, leaks []goroutine.Goroutine, basedir string) error {
+	return annotator.Annotate(w, Annotations(leaks, SeverityError, basedir))
+}
+
+// AnnotatorFor returns the annotator with the specified name, that is,
+// "github", "teamcity", or "json".
+func AnnotatorFor(name string) (Annotator, error) {
+	switch name {
+	case "github":
+		return GitHubAnnotator{}, nil
+	case "teamcity":
+		return TeamCityAnnotator{}, nil
+	case "json":
+		return JSONAnnotator{}, nil
+	}
+	return nil, fmt.Errorf("unknown annotator %q", name)
+}
+
+// DetectAnnotator returns the annotator for the CI system the current process
+// runs in, based on the environment variables set by the CI system, or nil if
+// the CI system isn't known.
+func DetectAnnotator() Annotator {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return GitHubAnnotator{}
+	case os.Getenv("TEAMCITY_VERSION") != "":
+		return TeamCityAnnotator{}
+	}
+	return nil
+}
+
+// GitHubAnnotator writes annotations as GitHub Actions workflow commands.
+type GitHubAnnotator struct{}
+
+// Annotate writes the specified annotations as GitHub Actions workflow
+// commands, one per line.
+func (GitHubAnnotator) Annotate(w io.Writer, annotations []Annotation) error {
+	var b strings.Builder
+	for _, a := range annotations {
+		b.WriteString("::")
+		b.WriteString(string(a.Severity))
+		var props []string
+		if a.File != "" {
+			props = append(props, "file="+githubProperty.Replace(a.File))
+			if a.Line > 0 {
+				props = append(props, "line="+strconv.Itoa(a.Line))
+			}
+		}
+		if len(props) > 0 {
+			b.WriteString(" " + strings.Join(props, ","))
+		}
+		b.WriteString("::")
+		b.WriteString(githubData.Replace(a.Message))
+		b.WriteString("\n")
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("cannot write GitHub annotations: %w", err)
+	}
+	return nil
+}
+
+// githubData and githubProperty escape workflow command data and property
+// values respectively.
+var (
+	githubData     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// TeamCityInspectionTypeID is the ID of the inspection type TeamCityAnnotator
+// reports annotations as.
+const TeamCityInspectionTypeID = "noleak-goroutine-leak"
+
+// TeamCityAnnotator writes annotations as TeamCity service messages reporting
+// code inspections.
+type TeamCityAnnotator struct{}
+
+// Annotate writes the specified annotations as TeamCity inspection service
+// messages, preceded by the definition of the inspection type.
+func (TeamCityAnnotator) Annotate(w io.Writer, annotations []Annotation) error {
+	var b strings.Builder
+	if len(annotations) > 0 {
+		fmt.Fprintf(&b, "##teamcity[inspectionType id='%s' name='Leaked goroutine' description='%s' category='noleak']\n",
+			TeamCityInspectionTypeID,
+			teamcityValue.Replace("Goroutines started here did not terminate by the end of the test."))
+	}
+	for _, a := range annotations {
+		fmt.Fprintf(&b, "##teamcity[inspection typeId='%s' message='%s'",
+			TeamCityInspectionTypeID, teamcityValue.Replace(a.Message))
+		if a.File != "" {
+			fmt.Fprintf(&b, " file='%s'", teamcityValue.Replace(a.File))
+			if a.Line > 0 {
+				fmt.Fprintf(&b, " line='%d'", a.Line)
+			}
+		}
+		fmt.Fprintf(&b, " SEVERITY='%s']\n", teamcitySeverity(a.Severity))
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("cannot write TeamCity annotations: %w", err)
+	}
+	return nil
+}
+
+// teamcityValue escapes service message attribute values.
+var teamcityValue = strings.NewReplacer(
+	"|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]",
+	"\u0085", "|x", "\u2028", "|l", "\u2029", "|p")
+
+// teamcitySeverity returns the TeamCity inspection severity for the specified
+// annotation severity.
+func teamcitySeverity(severity Severity) string {
+	switch severity {
+	case SeverityWarning:
+		return "WARNING"
+	case SeverityNotice:
+		return "INFO"
+	}
+	return "ERROR"
+}
+
+// JSONAnnotator writes annotations as a JSON array, for CI systems without
+// native annotation support that can import annotations from files.
+type JSONAnnotator struct{}
+
+// Annotate writes the specified annotations as a JSON array of objects with
+// "file", "line", "severity", and "message" fields.
+func (JSONAnnotator) Annotate(w io.Writer, annotations []Annotation) error {
+	if annotations == nil {
+		annotations = []Annotation{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(annotations); err != nil {
+		return fmt.Errorf("cannot write JSON annotations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("annotations", func() {
+
+	var annotations []Annotation
+
+	BeforeEach(func() {
+		annotations = Annotations(append(leaks, goroutine.Goroutine{ID: 1, TopFunction: "main.main"}),
+			SeverityError, "/home/foo/")
+	})
+
+	It("annotates creator locations", func() {
+		Expect(annotations).To(Equal([]Annotation{
+			{File: "test.go", Line: 5, Severity: SeverityError,
+				Message: "2 leaked goroutines created by main.foo (goroutine IDs: 42, 666), with topmost function main.foo.func1"},
+			{Severity: SeverityError,
+				Message: "1 leaked goroutine (goroutine ID: 1), with topmost function main.main"},
+			{File: "test.go", Line: 15, Severity: SeverityError,
+				Message: "1 leaked goroutine created by main.bar (goroutine ID: 7), with topmost function main.bar.func1"},
+		}))
+	})
+
+	It("returns annotators by name", func() {
+		Expect(AnnotatorFor("github")).To(Equal(GitHubAnnotator{}))
+		Expect(AnnotatorFor("teamcity")).To(Equal(TeamCityAnnotator{}))
+		Expect(AnnotatorFor("json")).To(Equal(JSONAnnotator{}))
+		Expect(AnnotatorFor("foo")).Error().To(MatchError(`unknown annotator "foo"`))
+	})
+
+	It("detects CI systems", func() {
+		for _, name := range []string{"GITHUB_ACTIONS", "TEAMCITY_VERSION"} {
+			if old, ok := os.LookupEnv(name); ok {
+				DeferCleanup(os.Setenv, name, old)
+			} else {
+				DeferCleanup(os.Unsetenv, name)
+			}
+			Expect(os.Unsetenv(name)).To(Succeed())
+		}
+		Expect(DetectAnnotator()).To(BeNil())
+		Expect(os.Setenv("TEAMCITY_VERSION", "2022.04")).To(Succeed())
+		Expect(DetectAnnotator()).To(Equal(TeamCityAnnotator{}))
+		Expect(os.Setenv("GITHUB_ACTIONS", "true")).To(Succeed())
+		Expect(DetectAnnotator()).To(Equal(GitHubAnnotator{}))
+	})
+
+	It("writes GitHub workflow commands", func() {
+		var buff strings.Builder
+		Expect(Annotate(&buff, GitHubAnnotator{}, leaks, "/home/foo")).To(Succeed())
+		Expect(buff.String()).To(Equal(
+			"::error file=test.go,line=5::2 leaked goroutines created by main.foo (goroutine IDs: 42, 666), with topmost function main.foo.func1\n" +
+				"::error file=test.go,line=15::1 leaked goroutine created by main.bar (goroutine ID: 7), with topmost function main.bar.func1\n"))
+
+		buff.Reset()
+		Expect(GitHubAnnotator{}.Annotate(&buff, []Annotation{
+			{File: "a,b:c.go", Severity: SeverityWarning, Message: "100%\nsure"},
+			{Severity: SeverityNotice, Message: "foo"},
+		})).To(Succeed())
+		Expect(buff.String()).To(Equal(
+			"::warning file=a%2Cb%3Ac.go::100%25%0Asure\n" +
+				"::notice::foo\n"))
+		Expect(GitHubAnnotator{}.Annotate(errWriter{}, annotations)).To(
+			MatchError("cannot write GitHub annotations: foo failure"))
+	})
+
+	It("writes TeamCity service messages", func() {
+		var buff strings.Builder
+		Expect(TeamCityAnnotator{}.Annotate(&buff, nil)).To(Succeed())
+		Expect(buff.String()).To(BeEmpty())
+
+		Expect(TeamCityAnnotator{}.Annotate(&buff, []Annotation{
+			{File: "test.go", Line: 5, Severity: SeverityError, Message: "it's [leaking]|\n"},
+			{Severity: SeverityWarning, Message: "foo"},
+			{File: "test.go", Severity: SeverityNotice, Message: "bar"},
+		})).To(Succeed())
+		Expect(buff.String()).To(Equal(
+			"##teamcity[inspectionType id='noleak-goroutine-leak' name='Leaked goroutine' description='Goroutines started here did not terminate by the end of the test.' category='noleak']\n" +
+				"##teamcity[inspection typeId='noleak-goroutine-leak' message='it|'s |[leaking|]|||n' file='test.go' line='5' SEVERITY='ERROR']\n" +
+				"##teamcity[inspection typeId='noleak-goroutine-leak' message='foo' SEVERITY='WARNING']\n" +
+				"##teamcity[inspection typeId='noleak-goroutine-leak' message='bar' file='test.go' SEVERITY='INFO']\n"))
+		Expect(TeamCityAnnotator{}.Annotate(errWriter{}, annotations)).To(
+			MatchError("cannot write TeamCity annotations: foo failure"))
+	})
+
+	It("writes JSON", func() {
+		var buff strings.Builder
+		Expect(JSONAnnotator{}.Annotate(&buff, nil)).To(Succeed())
+		Expect(buff.String()).To(Equal("[]\n"))
+
+		buff.Reset()
+		Expect(JSONAnnotator{}.Annotate(&buff, annotations)).To(Succeed())
+		var decoded []map[string]interface{}
+		Expect(json.Unmarshal([]byte(buff.String()), &decoded)).To(Succeed())
+		Expect(decoded).To(HaveLen(3))
+		Expect(decoded[0]).To(And(
+			HaveKeyWithValue("file", "test.go"),
+			HaveKeyWithValue("line", BeNumerically("==", 5)),
+			HaveKeyWithValue("severity", "error"),
+			HaveKeyWithValue("message", HavePrefix("2 leaked goroutines"))))
+		Expect(decoded[1]).NotTo(HaveKey("file"))
+		Expect(decoded[1]).NotTo(HaveKey("line"))
+		Expect(JSONAnnotator{}.Annotate(errWriter{}, annotations)).To(
+			MatchError(HavePrefix("cannot write JSON annotations: ")))
+	})
+
+})
@@ -0,0 +1,159 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Pseudo module names used by BlameModules for goroutines that cannot be
+// attributed to a specific Go module.
+const (
+	StdModule     = "std"       // Go's standard library and runtime.
+	UnknownModule = "(unknown)" // goroutines without creator, or unknown modules.
+)
+
+// ModuleLeaks are the leaked goroutines created from code of a particular Go
+// module.
+type ModuleLeaks struct {
+	Module     string                // path of the module owning the creator location.
+	Goroutines []goroutine.Goroutine // goroutines created from this module, sorted by increasing ID.
+}
+
+// BlameModules groups the specified leaked goroutines by the Go modules owning
+// their creator locations, helping to route leak bugs to the owners of the
+// modules. Creator locations inside the module cache are resolved from the
+// module cache paths; otherwise, the package of the creator function is
+// resolved using the modules listed in the build information of the running
+// binary. The groups are sorted by decreasing number of goroutines, and then
+// by module path.
+func BlameModules(gs []goroutine.Goroutine) []ModuleLeaks {
+	var modules []string
+	mainModule := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		mainModule = info.Main.Path
+		modules = append(modules, info.Main.Path)
+		for _, dep := range info.Deps {
+			modules = append(modules, dep.Path)
+		}
+	}
+	return blameModules(gs, mainModule, modules)
+}
+
+// blameModules groups the specified goroutines by the modules owning their
+// creator locations, given the path of the main module and the paths of all
+// known modules.
+func blameModules(gs []goroutine.Goroutine, mainModule string, modules []string) []ModuleLeaks {
+	index := map[string]int{}
+	blame := []ModuleLeaks{}
+	for _, g := range gs {
+		module := moduleOf(g, mainModule, modules)
+		idx, ok := index[module]
+		if !ok {
+			idx = len(blame)
+			index[module] = idx
+			blame = append(blame, ModuleLeaks{Module: module})
+		}
+		blame[idx].Goroutines = append(blame[idx].Goroutines, g)
+	}
+	for _, ml := range blame {
+		gs := ml.Goroutines
+		sort.Slice(gs, func(a, b int) bool { return gs[a].ID < gs[b].ID })
+	}
+	sort.SliceStable(blame, func(a, b int) bool {
+		if len(blame[a].Goroutines) != len(blame[b].Goroutines) {
+			return len(blame[a].Goroutines) > len(blame[b].Goroutines)
+		}
+		return blame[a].Module < blame[b].Module
+	})
+	return blame
+}
+
+// moduleOf returns the path of the module owning the creator location of the
+// specified goroutine.
+func moduleOf(g goroutine.Goroutine, mainModule string, modules []string) string {
+	if g.CreatorFunction == "" {
+		return UnknownModule
+	}
+	if module := moduleCachePath(g.BornAt); module != "" {
+		return module
+	}
+	pkg := packagePath(g.CreatorFunction)
+	if pkg == "main" {
+		if mainModule != "" {
+			return mainModule
+		}
+		return UnknownModule
+	}
+	longest := ""
+	for _, module := range modules {
+		if (pkg == module || strings.HasPrefix(pkg, module+"/")) && len(module) > len(longest) {
+			longest = module
+		}
+	}
+	if longest != "" {
+		return longest
+	}
+	// Standard library packages don't have a dot in their first path element.
+	if first := strings.SplitN(pkg, "/", 2)[0]; !strings.Contains(first, ".") {
+		return StdModule
+	}
+	return UnknownModule
+}
+
+// moduleCachePath returns the module path for a location inside the module
+// cache, such as "/home/foo/go/pkg/mod/github.com/!foo/bar@v1.2.3/baz.go:42",
+// undoing the module cache's case-encoding. Otherwise, it returns "".
+func moduleCachePath(location string) string {
+	location = strings.ReplaceAll(location, "\\", "/")
+	idx := strings.LastIndex(location, "/pkg/mod/")
+	if idx < 0 {
+		return ""
+	}
+	location = location[idx+len("/pkg/mod/"):]
+	at := strings.Index(location, "@")
+	if at <= 0 {
+		return ""
+	}
+	var module strings.Builder
+	escaped := false
+	for _, r := range location[:at] {
+		switch {
+		case r == '!':
+			escaped = true
+			continue
+		case escaped && r >= 'a' && r <= 'z':
+			r -= 'a' - 'A'
+		}
+		escaped = false
+		module.WriteRune(r)
+	}
+	return module.String()
+}
+
+// packagePath returns the package import path of the specified fully qualified
+// function name, such as "github.com/foo/bar" for
+// "github.com/foo/bar.(*Baz).Run.func1".
+func packagePath(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("module blame", func() {
+
+	It("determines package paths", func() {
+		Expect(packagePath("main.foo")).To(Equal("main"))
+		Expect(packagePath("github.com/foo/bar.(*Baz).Run.func1")).To(Equal("github.com/foo/bar"))
+		Expect(packagePath("net/http.(*Server).Serve")).To(Equal("net/http"))
+		Expect(packagePath("foo")).To(Equal("foo"))
+	})
+
+	It("resolves module cache paths", func() {
+		Expect(moduleCachePath("/home/foo/go/pkg/mod/github.com/!foo/bar@v1.2.3/baz/baz.go:42")).
+			To(Equal("github.com/Foo/bar"))
+		Expect(moduleCachePath(`C:\Users\foo\go\pkg\mod\example.org\x@v0.0.1\x.go:1`)).
+			To(Equal("example.org/x"))
+		Expect(moduleCachePath("/home/foo/test.go:5")).To(BeEmpty())
+		Expect(moduleCachePath("/home/foo/pkg/mod/@v1/x.go:5")).To(BeEmpty())
+	})
+
+	It("blames modules", func() {
+		modules := []string{"example.org/main", "github.com/foo/bar", "github.com/foo/bar/v2"}
+		blame := blameModules([]goroutine.Goroutine{
+			{ID: 5, CreatorFunction: "github.com/foo/bar/v2/baz.New"},
+			{ID: 4, CreatorFunction: "github.com/foo/bar/baz.New"},
+			{ID: 3, CreatorFunction: "github.com/foo/bar.New", BornAt: "/go/pkg/mod/github.com/foo/bar@v1.0.0/bar.go:1"},
+			{ID: 2, CreatorFunction: "main.main"},
+			{ID: 1, CreatorFunction: "net/http.(*Server).Serve"},
+			{ID: 6, CreatorFunction: "example.com/unknown.New"},
+			{ID: 7},
+		}, "example.org/main", modules)
+		Expect(blame).To(HaveLen(5))
+		Expect(blame[0].Module).To(Equal(UnknownModule))
+		Expect(blame[0].Goroutines).To(HaveLen(2))
+		Expect(blame[0].Goroutines[0].ID).To(Equal(uint64(6)))
+		Expect(blame[1].Module).To(Equal("github.com/foo/bar"))
+		Expect(blame[1].Goroutines).To(HaveLen(2))
+		Expect(blame[1].Goroutines[0].ID).To(Equal(uint64(3)))
+		Expect(blame[2:]).To(HaveEach(HaveField("Goroutines", HaveLen(1))))
+		Expect(blame[2].Module).To(Equal("example.org/main"))
+		Expect(blame[3].Module).To(Equal("github.com/foo/bar/v2"))
+		Expect(blame[4].Module).To(Equal(StdModule))
+
+		Expect(blameModules([]goroutine.Goroutine{{ID: 1, CreatorFunction: "main.main"}}, "", nil)).
+			To(ConsistOf(HaveField("Module", UnknownModule)))
+	})
+
+	It("uses the build information", func() {
+		Expect(BlameModules(leaks)).To(ConsistOf(HaveField("Goroutines", HaveLen(3))))
+	})
+
+})
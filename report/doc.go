@@ -0,0 +1,42 @@
+/*
+
+Package report renders leaked goroutines into different report formats,
+suitable for consumption by humans as well as tools. For instance, Markdown
+renders a report suitable for pull request comments or CI job summaries, while
+HTML renders a self-contained interactive report that can be stored next to
+other test artifacts. For visualizing where leaked goroutines are parked,
+Folded exports their stacks in the "folded stacks" format of flamegraph
+tooling, while DOT exports a Graphviz graph of which functions spawned them.
+Finally, SARIF emits leaks as code scanning results pointing at the "go"
+statements that started the leaked goroutines.
+
+For CI systems surfacing source code annotations natively, Annotations turns
+leaks into annotations of their creator locations, which an Annotator then
+writes in the format of the particular CI system: GitHubAnnotator writes GitHub
+Actions workflow commands, TeamCityAnnotator writes TeamCity service messages,
+and JSONAnnotator writes generic JSON for other CI systems. DetectAnnotator
+picks the annotator for the CI system the tests currently run in.
+
+Reports group the leaked goroutines by their creators, that is, by the creator
+function and the location of the "go" statement, as leaked goroutines from the
+same creator location most probably share the same root cause.
+
+In addition, BlameModules groups leaked goroutines by the Go modules owning
+their creator locations, helping to route leak bugs to the module owners.
+MarkdownWithModules includes such a module blame in its report. As
+BlameModules relies on the build information of the running binary, it only
+applies to goroutines of the running process, but not to goroutine dumps from
+other processes.
+
+For visualizing which parts of a (mono) repository leak most, a Heatmap
+aggregates leaked goroutines by creator package, such as across an entire suite
+run, and exports the package × leak count cells in CSV or JSON format.
+
+A Summary accumulates the leaks of an entire test binary run into a
+machine-readable LeakReport with totals, leaks per fingerprint, and leaks per
+test, such as for CI steps gating merges on leak counts. Merge combines the
+leak reports of many test binaries into a single report deduplicated by
+fingerprint.
+
+*/
+package report
@@ -0,0 +1,122 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// DOT writes a Graphviz DOT graph to w showing which functions spawned the
+// specified leaked goroutines, in order to visualize leak "hot spots". The
+// nodes of the graph are functions: creator functions as well as the entry
+// functions of the leaked goroutines, that is, the outermost functions in
+// their backtraces. Solid edges lead from creator functions to the entry
+// functions of the goroutines they created and are labelled with the number of
+// leaked goroutines. Where the creator of a leaked goroutine is known to have
+// run in another leaked goroutine (see goroutine.Goroutine.CreatorID), dashed
+// edges additionally lead from the entry function of the creating goroutine
+// to the creator function, thus showing chains of leaked goroutines.
+//
+// The output can be rendered using, for instance:
+//
+//   dot -Tsvg leaks.dot > leaks.svg
+func DOT(w io.Writer, leaks []goroutine.Goroutine) error {
+	type edge struct{ from, to string }
+	leakedEntries := map[string]int{}
+	spawns := map[edge]int{}
+	runsIn := map[edge]struct{}{}
+	byID := map[uint64]goroutine.Goroutine{}
+	for _, g := range leaks {
+		byID[g.ID] = g
+	}
+	for _, g := range leaks {
+		entry := entryFunction(g)
+		leakedEntries[entry]++
+		if g.CreatorFunction == "" {
+			continue
+		}
+		spawns[edge{from: g.CreatorFunction, to: entry}]++
+		if parent, ok := byID[g.CreatorID]; ok && g.CreatorID != 0 {
+			runsIn[edge{from: entryFunction(parent), to: g.CreatorFunction}] = struct{}{}
+		}
+	}
+
+	var buff strings.Builder
+	buff.WriteString("digraph leaks {\n")
+	buff.WriteString("\trankdir=LR;\n")
+	buff.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	for _, entry := range sortedKeys(leakedEntries) {
+		fmt.Fprintf(&buff, "\t%s [label=%s, style=filled, fillcolor=\"#ffcccc\"];\n",
+			strconv.Quote(entry), strconv.Quote(fmt.Sprintf("%s\n%d leaked", entry, leakedEntries[entry])))
+	}
+	spawnEdges := make([]edge, 0, len(spawns))
+	for e := range spawns {
+		spawnEdges = append(spawnEdges, e)
+	}
+	sortEdges := func(edges []edge) {
+		sort.Slice(edges, func(a, b int) bool {
+			if edges[a].from != edges[b].from {
+				return edges[a].from < edges[b].from
+			}
+			return edges[a].to < edges[b].to
+		})
+	}
+	sortEdges(spawnEdges)
+	for _, e := range spawnEdges {
+		fmt.Fprintf(&buff, "\t%s -> %s [label=\"%d\"];\n",
+			strconv.Quote(e.from), strconv.Quote(e.to), spawns[e])
+	}
+	runsInEdges := make([]edge, 0, len(runsIn))
+	for e := range runsIn {
+		runsInEdges = append(runsInEdges, e)
+	}
+	sortEdges(runsInEdges)
+	for _, e := range runsInEdges {
+		fmt.Fprintf(&buff, "\t%s -> %s [style=dashed];\n",
+			strconv.Quote(e.from), strconv.Quote(e.to))
+	}
+	buff.WriteString("}\n")
+	if _, err := io.WriteString(w, buff.String()); err != nil {
+		return fmt.Errorf("cannot write DOT graph: %w", err)
+	}
+	return nil
+}
+
+// entryFunction returns the outermost function in the backtrace of the
+// specified goroutine, that is, the function the goroutine was started with.
+// If the goroutine lacks parsed frames, its topmost function is returned
+// instead.
+func entryFunction(g goroutine.Goroutine) string {
+	if len(g.Frames) == 0 {
+		return g.TopFunction
+	}
+	return g.Frames[len(g.Frames)-1].Function
+}
+
+// sortedKeys returns the keys of the specified map in increasing order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
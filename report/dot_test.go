@@ -0,0 +1,72 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DOT graph", func() {
+
+	It("renders creator relationships", func() {
+		var buff strings.Builder
+		Expect(DOT(&buff, []goroutine.Goroutine{
+			{
+				ID:              42,
+				CreatorFunction: "main.foo",
+				CreatorID:       1,
+				Frames:          []goroutine.Frame{{Function: "time.Sleep"}, {Function: "main.foo.func1"}},
+			},
+			{
+				ID:              666,
+				CreatorFunction: "main.foo",
+				CreatorID:       1,
+				Frames:          []goroutine.Frame{{Function: "main.foo.func1"}},
+			},
+			{
+				ID:              7,
+				CreatorFunction: "main.foo.func1",
+				CreatorID:       42,
+				TopFunction:     "main.bar",
+			},
+			{
+				ID:          1,
+				TopFunction: "main.main",
+			},
+		})).To(Succeed())
+		Expect(buff.String()).To(Equal(`digraph leaks {
+	rankdir=LR;
+	node [shape=box, fontname="monospace"];
+	"main.bar" [label="main.bar\n1 leaked", style=filled, fillcolor="#ffcccc"];
+	"main.foo.func1" [label="main.foo.func1\n2 leaked", style=filled, fillcolor="#ffcccc"];
+	"main.main" [label="main.main\n1 leaked", style=filled, fillcolor="#ffcccc"];
+	"main.foo" -> "main.foo.func1" [label="2"];
+	"main.foo.func1" -> "main.bar" [label="1"];
+	"main.foo.func1" -> "main.foo.func1" [style=dashed];
+	"main.main" -> "main.foo" [style=dashed];
+}
+`))
+	})
+
+	It("reports write errors", func() {
+		Expect(DOT(errWriter{}, leaks)).To(MatchError("cannot write DOT graph: foo failure"))
+	})
+
+})
@@ -0,0 +1,71 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Folded writes the backtraces of the specified leaked goroutines to w in
+// Brendan Gregg's "folded stacks" format, as used by flamegraph tooling. Each
+// line represents a unique stack, with its function names separated by
+// semicolons, starting with the outermost function, and followed by a blank
+// and the number of leaked goroutines with this stack. Where known, the
+// creator function of a goroutine becomes the root of its stack, so leaked
+// goroutines get attributed to where they were started from.
+//
+// For instance:
+//
+//   main.foo;main.foo.func1 2
+//   main.bar;main.bar.func1;time.Sleep 1
+func Folded(w io.Writer, leaks []goroutine.Goroutine) error {
+	counts := map[string]int{}
+	for _, g := range leaks {
+		counts[foldedStack(g)]++
+	}
+	stacks := make([]string, 0, len(counts))
+	for stack := range counts {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, counts[stack]); err != nil {
+			return fmt.Errorf("cannot write folded stacks: %w", err)
+		}
+	}
+	return nil
+}
+
+// foldedStack returns the folded stack of the specified goroutine, with the
+// outermost function first. If the goroutine lacks parsed frames then only its
+// topmost function is used.
+func foldedStack(g goroutine.Goroutine) string {
+	fns := make([]string, 0, len(g.Frames)+1)
+	if g.CreatorFunction != "" {
+		fns = append(fns, g.CreatorFunction)
+	}
+	if len(g.Frames) == 0 {
+		fns = append(fns, g.TopFunction)
+	}
+	for idx := len(g.Frames) - 1; idx >= 0; idx-- {
+		fns = append(fns, g.Frames[idx].Function)
+	}
+	return strings.Join(fns, ";")
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("folded stacks", func() {
+
+	It("folds stacks", func() {
+		var buff strings.Builder
+		Expect(Folded(&buff, []goroutine.Goroutine{
+			{
+				ID:              42,
+				CreatorFunction: "main.foo",
+				Frames: []goroutine.Frame{
+					{Function: "time.Sleep"},
+					{Function: "main.foo.func1"},
+				},
+			},
+			{
+				ID:          1,
+				TopFunction: "main.main",
+			},
+			{
+				ID:              666,
+				CreatorFunction: "main.foo",
+				Frames: []goroutine.Frame{
+					{Function: "time.Sleep"},
+					{Function: "main.foo.func1"},
+				},
+			},
+		})).To(Succeed())
+		Expect(buff.String()).To(Equal("main.foo;main.foo.func1;time.Sleep 2\nmain.main 1\n"))
+	})
+
+	It("folds live stacks", func() {
+		var buff strings.Builder
+		Expect(Folded(&buff, goroutine.Goroutines())).To(Succeed())
+		Expect(buff.String()).To(MatchRegexp(`(?m)^.*;github\.com/thediveo/noleak/goroutine\.stacks 1$`))
+	})
+
+	It("reports write errors", func() {
+		Expect(Folded(errWriter{}, leaks)).To(MatchError("cannot write folded stacks: foo failure"))
+	})
+
+})
@@ -0,0 +1,83 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Group is a set of goroutines sharing the same creator function and creator
+// location.
+type Group struct {
+	CreatorFunction string                // name of the creator function; empty for the main goroutine.
+	BornAt          string                // location where the goroutines were started from, if any.
+	Goroutines      []goroutine.Goroutine // goroutines in this group, sorted by increasing ID.
+}
+
+// Title returns a short textual description of this group's creator.
+func (g Group) Title() string {
+	if g.CreatorFunction == "" {
+		return "(no creator)"
+	}
+	return g.CreatorFunction + " at " + g.BornAt
+}
+
+// GroupByCreator groups the specified goroutines by their creator functions and
+// creator locations. The groups are sorted by decreasing number of goroutines,
+// and then by creator function and location.
+func GroupByCreator(gs []goroutine.Goroutine) []Group {
+	type key struct{ creator, bornat string }
+	index := map[key]int{}
+	groups := []Group{}
+	for _, g := range gs {
+		k := key{creator: g.CreatorFunction, bornat: g.BornAt}
+		idx, ok := index[k]
+		if !ok {
+			idx = len(groups)
+			index[k] = idx
+			groups = append(groups, Group{
+				CreatorFunction: g.CreatorFunction,
+				BornAt:          g.BornAt,
+			})
+		}
+		groups[idx].Goroutines = append(groups[idx].Goroutines, g)
+	}
+	for _, group := range groups {
+		gs := group.Goroutines
+		sort.Slice(gs, func(a, b int) bool { return gs[a].ID < gs[b].ID })
+	}
+	sort.SliceStable(groups, func(a, b int) bool {
+		if len(groups[a].Goroutines) != len(groups[b].Goroutines) {
+			return len(groups[a].Goroutines) > len(groups[b].Goroutines)
+		}
+		if groups[a].CreatorFunction != groups[b].CreatorFunction {
+			return groups[a].CreatorFunction < groups[b].CreatorFunction
+		}
+		return groups[a].BornAt < groups[b].BornAt
+	})
+	return groups
+}
+
+// counted returns the specified count followed by either the singular or
+// plural noun, such as "1 goroutine" or "2 goroutines".
+func counted(n int, singular string, plural string) string {
+	if n == 1 {
+		return strconv.Itoa(n) + " " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// leaks is a set of leaked goroutines shared by the report tests.
+var leaks = []goroutine.Goroutine{
+	{
+		ID:              666,
+		State:           "chan receive",
+		TopFunction:     "main.foo.func1",
+		CreatorFunction: "main.foo",
+		BornAt:          "/home/foo/test.go:5",
+		CreatorFrame:    goroutine.Frame{Function: "main.foo", Location: "/home/foo/test.go:5", File: "/home/foo/test.go", Line: 5},
+		Backtrace:       "main.foo.func1()\n\t/home/foo/test.go:6 +0x28\ncreated by main.foo in goroutine 1\n\t/home/foo/test.go:5 +0x64\n",
+	},
+	{
+		ID:              42,
+		State:           "chan receive",
+		TopFunction:     "main.foo.func1",
+		CreatorFunction: "main.foo",
+		BornAt:          "/home/foo/test.go:5",
+		CreatorFrame:    goroutine.Frame{Function: "main.foo", Location: "/home/foo/test.go:5", File: "/home/foo/test.go", Line: 5},
+		Backtrace:       "main.foo.func1()\n\t/home/foo/test.go:6 +0x28\ncreated by main.foo in goroutine 1\n\t/home/foo/test.go:5 +0x64\n",
+	},
+	{
+		ID:              7,
+		State:           "select",
+		TopFunction:     "main.bar.func1",
+		CreatorFunction: "main.bar",
+		BornAt:          "/home/foo/test.go:15",
+		CreatorFrame:    goroutine.Frame{Function: "main.bar", Location: "/home/foo/test.go:15", File: "/home/foo/test.go", Line: 15},
+		Backtrace:       "main.bar.func1()\n\t/home/foo/test.go:16 +0x28\ncreated by main.bar in goroutine 1\n\t/home/foo/test.go:15 +0x64\n",
+	},
+}
+
+var _ = Describe("grouping", func() {
+
+	It("groups by creator", func() {
+		groups := GroupByCreator(leaks)
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[0].Title()).To(Equal("main.foo at /home/foo/test.go:5"))
+		Expect(groups[0].Goroutines).To(HaveLen(2))
+		Expect(groups[0].Goroutines[0].ID).To(Equal(uint64(42)))
+		Expect(groups[1].Title()).To(Equal("main.bar at /home/foo/test.go:15"))
+
+		Expect(GroupByCreator(nil)).To(BeEmpty())
+		Expect(Group{}.Title()).To(Equal("(no creator)"))
+	})
+
+	It("sorts groups with same sizes by creator", func() {
+		groups := GroupByCreator([]goroutine.Goroutine{
+			{ID: 1, CreatorFunction: "b", BornAt: "x:1"},
+			{ID: 2, CreatorFunction: "a", BornAt: "y:1"},
+			{ID: 3, CreatorFunction: "a", BornAt: "x:1"},
+		})
+		Expect(groups).To(HaveEach(HaveField("Goroutines", HaveLen(1))))
+		Expect(groups[0].Title()).To(Equal("a at x:1"))
+		Expect(groups[1].Title()).To(Equal("a at y:1"))
+		Expect(groups[2].Title()).To(Equal("b at x:1"))
+	})
+
+})
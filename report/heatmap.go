@@ -0,0 +1,107 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// UnknownPackage is the pseudo package name used by Heatmap for goroutines
+// without creator.
+const UnknownPackage = "(unknown)"
+
+// Heatmap aggregates leaked goroutines by the packages of their creator
+// functions, such as across an entire suite run, in order to visualize which
+// parts of a (mono) repository leak most. A Heatmap is safe for concurrent use
+// by multiple goroutines; its zero value is an empty heatmap ready to use.
+type Heatmap struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// HeatmapCell is the number of goroutines leaked from a particular package.
+type HeatmapCell struct {
+	Package string `json:"package"` // import path of the creator function's package.
+	Leaks   int    `json:"leaks"`   // number of leaked goroutines.
+}
+
+// Add adds the specified leaked goroutines to the heatmap.
+func (h *Heatmap) Add(leaks []goroutine.Goroutine) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil {
+		h.counts = map[string]int{}
+	}
+	for _, g := range leaks {
+		pkg := UnknownPackage
+		if g.CreatorFunction != "" {
+			pkg = packagePath(g.CreatorFunction)
+		}
+		h.counts[pkg]++
+	}
+}
+
+// Cells returns the packages with their numbers of leaked goroutines, sorted
+// by decreasing number of leaks, and then by package path.
+func (h *Heatmap) Cells() []HeatmapCell {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cells := make([]HeatmapCell, 0, len(h.counts))
+	for pkg, count := range h.counts {
+		cells = append(cells, HeatmapCell{Package: pkg, Leaks: count})
+	}
+	sort.Slice(cells, func(a, b int) bool {
+		if cells[a].Leaks != cells[b].Leaks {
+			return cells[a].Leaks > cells[b].Leaks
+		}
+		return cells[a].Package < cells[b].Package
+	})
+	return cells
+}
+
+// WriteCSV writes the heatmap cells to w in CSV format, with a header line
+// followed by one line per package:
+//
+//   package,leaks
+//   github.com/foo/bar,42
+func (h *Heatmap) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"package", "leaks"})
+	for _, cell := range h.Cells() {
+		_ = cw.Write([]string{cell.Package, strconv.Itoa(cell.Leaks)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("cannot write CSV heatmap: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON writes the heatmap cells to w as a JSON array of objects with the
+// fields "package" and "leaks".
+func (h *Heatmap) WriteJSON(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(h.Cells()); err != nil {
+		return fmt.Errorf("cannot write JSON heatmap: %w", err)
+	}
+	return nil
+}
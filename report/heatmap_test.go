@@ -0,0 +1,75 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("leak heatmaps", func() {
+
+	leaks := []goroutine.Goroutine{
+		{ID: 1, CreatorFunction: "github.com/foo/bar.(*Pool).Start.func1"},
+		{ID: 2, CreatorFunction: "github.com/foo/bar.New"},
+		{ID: 3, CreatorFunction: "github.com/foo/baz.Serve"},
+		{ID: 4},
+	}
+
+	It("aggregates leaks by package", func() {
+		var h Heatmap
+		Expect(h.Cells()).To(BeEmpty())
+		var wg sync.WaitGroup
+		for idx := 0; idx < 2; idx++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.Add(leaks)
+			}()
+		}
+		wg.Wait()
+		Expect(h.Cells()).To(Equal([]HeatmapCell{
+			{Package: "github.com/foo/bar", Leaks: 4},
+			{Package: UnknownPackage, Leaks: 2},
+			{Package: "github.com/foo/baz", Leaks: 2},
+		}))
+	})
+
+	It("writes CSV and JSON", func() {
+		var h Heatmap
+		h.Add(leaks)
+		var buff strings.Builder
+		Expect(h.WriteCSV(&buff)).To(Succeed())
+		Expect(buff.String()).To(Equal(
+			"package,leaks\ngithub.com/foo/bar,2\n(unknown),1\ngithub.com/foo/baz,1\n"))
+
+		buff.Reset()
+		Expect(h.WriteJSON(&buff)).To(Succeed())
+		Expect(buff.String()).To(MatchJSON(
+			`[{"package":"github.com/foo/bar","leaks":2},{"package":"(unknown)","leaks":1},{"package":"github.com/foo/baz","leaks":1}]`))
+	})
+
+	It("reports write errors", func() {
+		var h Heatmap
+		h.Add(leaks)
+		Expect(h.WriteCSV(errWriter{})).To(MatchError(HavePrefix("cannot write CSV heatmap: ")))
+		Expect(h.WriteJSON(errWriter{})).To(MatchError(HavePrefix("cannot write JSON heatmap: ")))
+	})
+
+})
@@ -0,0 +1,113 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// HTML writes a self-contained HTML report about the specified leaked
+// goroutines to w. The report groups the leaked goroutines by their creators,
+// with collapsible groups and backtraces, and a filter input to interactively
+// show only those goroutines with matching states or backtraces. The report
+// doesn't reference any external resources, so it can be stored as a test
+// artifact and viewed offline.
+func HTML(w io.Writer, title string, leaks []goroutine.Goroutine) error {
+	groups := GroupByCreator(leaks)
+	summary := counted(len(leaks), "leaked goroutine", "leaked goroutines") +
+		" from " + counted(len(groups), "creator location", "creator locations")
+	if err := htmlTemplate.Execute(w, struct {
+		Title   string
+		Summary string
+		Groups  []Group
+	}{
+		Title:   title,
+		Summary: summary,
+		Groups:  groups,
+	}); err != nil {
+		return fmt.Errorf("cannot render HTML report: %w", err)
+	}
+	return nil
+}
+
+// WriteHTMLFile writes a self-contained HTML report about the specified leaked
+// goroutines into the named file, creating or truncating it as necessary.
+func WriteHTMLFile(filename string, title string, leaks []goroutine.Goroutine) (err error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("cannot create HTML report: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("cannot write HTML report: %w", cerr)
+		}
+	}()
+	return HTML(f, title, leaks)
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+details { margin: 0.5em 0; }
+summary { cursor: pointer; }
+summary code { font-weight: bold; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; margin: 0.25em 0 0.25em 1.5em; }
+.count { display: inline-block; min-width: 3em; text-align: right; margin-right: 0.5em; }
+.hidden { display: none; }
+#filter { width: 40em; max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Summary}}.</p>
+<p><label>Filter: <input id="filter" type="search" placeholder="function name, state, file, …" autofocus></label></p>
+{{range .Groups}}<details class="group">
+<summary><span class="count">{{len .Goroutines}} ×</span> {{if .CreatorFunction}}<code>{{.CreatorFunction}}</code> at <code>{{.BornAt}}</code>{{else}}(no creator){{end}}</summary>
+{{range .Goroutines}}<details class="goroutine">
+<summary>goroutine {{.ID}} [{{.State}}]: <code>{{.TopFunction}}</code></summary>
+<pre>goroutine {{.ID}} [{{.State}}]:
+{{.Backtrace}}</pre>
+</details>
+{{end}}</details>
+{{end}}<script>
+(function () {
+    var filter = document.getElementById("filter");
+    filter.addEventListener("input", function () {
+        var text = filter.value.toLowerCase();
+        document.querySelectorAll("details.group").forEach(function (group) {
+            var visible = 0;
+            group.querySelectorAll("details.goroutine").forEach(function (g) {
+                var match = text === "" || g.textContent.toLowerCase().indexOf(text) >= 0;
+                g.classList.toggle("hidden", !match);
+                if (match) { visible++; }
+            });
+            group.classList.toggle("hidden", visible === 0);
+            group.open = text !== "" && visible > 0;
+        });
+    });
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,74 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTML report", func() {
+
+	It("renders a self-contained report", func() {
+		var buff strings.Builder
+		Expect(HTML(&buff, "Leaks <galore>", leaks)).To(Succeed())
+		html := buff.String()
+		Expect(html).To(ContainSubstring("<title>Leaks &lt;galore&gt;</title>"))
+		Expect(html).To(ContainSubstring("<p>3 leaked goroutines from 2 creator locations.</p>"))
+		Expect(html).To(ContainSubstring(
+			`<summary><span class="count">2 ×</span> <code>main.foo</code> at <code>/home/foo/test.go:5</code></summary>`))
+		Expect(html).To(ContainSubstring("<pre>goroutine 42 [chan receive]:\nmain.foo.func1()\n"))
+		Expect(html).NotTo(MatchRegexp(`(src|href)=`))
+	})
+
+	It("reports a single leak in singular", func() {
+		var buff strings.Builder
+		Expect(HTML(&buff, "", leaks[2:])).To(Succeed())
+		Expect(buff.String()).To(ContainSubstring("<p>1 leaked goroutine from 1 creator location.</p>"))
+	})
+
+	It("escapes", func() {
+		var buff strings.Builder
+		Expect(HTML(&buff, "", []goroutine.Goroutine{{ID: 1, Backtrace: "<script>"}})).To(Succeed())
+		Expect(buff.String()).To(ContainSubstring("(no creator)"))
+		Expect(buff.String()).To(ContainSubstring("&lt;script&gt;</pre>"))
+	})
+
+	It("writes a report file", func() {
+		name := filepath.Join(GinkgoT().TempDir(), "leaks.html")
+		Expect(WriteHTMLFile(name, "Leaks", leaks)).To(Succeed())
+		Expect(os.ReadFile(name)).To(ContainSubstring("<title>Leaks</title>"))
+
+		Expect(WriteHTMLFile(filepath.Join(name, "foo"), "Leaks", leaks)).To(
+			MatchError(MatchRegexp(`^cannot create HTML report: .*`)))
+	})
+
+	It("reports rendering errors", func() {
+		Expect(HTML(errWriter{}, "", leaks)).To(MatchError(MatchRegexp(`^cannot render HTML report: .*`)))
+	})
+
+})
+
+// errWriter always fails writing.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("foo failure") }
@@ -0,0 +1,103 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Markdown returns a Markdown report about the specified leaked goroutines,
+// suitable for pull request comments or CI job summaries. The leaked
+// goroutines are grouped by their creators, with each group being collapsible
+// and showing the number of leaked goroutines of this group. The backtraces
+// of the goroutines are rendered as fenced code blocks.
+func Markdown(leaks []goroutine.Goroutine) string {
+	return MarkdownWithModules(leaks, nil)
+}
+
+// MarkdownWithModules works like Markdown, but additionally lists the number of
+// leaked goroutines per Go module owning their creator locations. As only the
+// process that leaked the goroutines knows its modules, the caller passes in
+// the module blame, such as returned by BlameModules for the running binary.
+// If modules is empty, the report doesn't contain a module summary.
+func MarkdownWithModules(leaks []goroutine.Goroutine, modules []ModuleLeaks) string {
+	var buff strings.Builder
+	buff.WriteString("## Leaked Goroutines\n\n")
+	if len(leaks) == 0 {
+		buff.WriteString("No leaked goroutines.\n")
+		return buff.String()
+	}
+	groups := GroupByCreator(leaks)
+	fmt.Fprintf(&buff, "%s from %s.\n\n",
+		counted(len(leaks), "leaked goroutine", "leaked goroutines"),
+		counted(len(groups), "creator location", "creator locations"))
+	buff.WriteString("| Goroutines | Creator | Location |\n")
+	buff.WriteString("| ---: | --- | --- |\n")
+	for _, group := range groups {
+		fmt.Fprintf(&buff, "| %d | %s | %s |\n",
+			len(group.Goroutines), markdownCode(group.CreatorFunction), markdownCode(group.BornAt))
+	}
+	if len(modules) > 0 {
+		buff.WriteString("\n| Goroutines | Module |\n")
+		buff.WriteString("| ---: | --- |\n")
+		for _, ml := range modules {
+			fmt.Fprintf(&buff, "| %d | %s |\n", len(ml.Goroutines), markdownCode(ml.Module))
+		}
+	}
+	for _, group := range groups {
+		buff.WriteString("\n<details>\n")
+		fmt.Fprintf(&buff, "<summary>%d × %s</summary>\n\n",
+			len(group.Goroutines), htmlEscaper.Replace(group.Title()))
+		for _, g := range group.Goroutines {
+			fence := markdownFence(g.Backtrace)
+			buff.WriteString(fence)
+			buff.WriteString("text\n")
+			fmt.Fprintf(&buff, "goroutine %d [%s]:\n", g.ID, g.State)
+			buff.WriteString(g.Backtrace)
+			if !strings.HasSuffix(g.Backtrace, "\n") {
+				buff.WriteRune('\n')
+			}
+			buff.WriteString(fence)
+			buff.WriteString("\n\n")
+		}
+		buff.WriteString("</details>\n")
+	}
+	return buff.String()
+}
+
+// markdownCode returns the specified text as inline code, or "–" if the text
+// is empty.
+func markdownCode(s string) string {
+	if s == "" {
+		return "–"
+	}
+	return "`" + strings.ReplaceAll(s, "|", "\\|") + "`"
+}
+
+// markdownFence returns a code fence that is longer than any backtick
+// sequence inside the specified text.
+func markdownFence(s string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// htmlEscaper escapes text for use inside HTML elements, such as <summary>.
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
@@ -0,0 +1,59 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Markdown report", func() {
+
+	It("reports no leaks", func() {
+		Expect(Markdown(nil)).To(Equal("## Leaked Goroutines\n\nNo leaked goroutines.\n"))
+	})
+
+	It("reports grouped leaks", func() {
+		md := Markdown(leaks)
+		Expect(md).To(HavePrefix("## Leaked Goroutines\n\n3 leaked goroutines from 2 creator locations.\n\n"))
+		Expect(md).To(ContainSubstring("| 2 | `main.foo` | `/home/foo/test.go:5` |\n"))
+		Expect(md).NotTo(ContainSubstring("| Module |"))
+		Expect(md).To(ContainSubstring("<summary>2 × main.foo at /home/foo/test.go:5</summary>\n\n"))
+		Expect(md).To(ContainSubstring("```text\ngoroutine 42 [chan receive]:\nmain.foo.func1()\n"))
+		Expect(md).To(HaveSuffix("```\n\n</details>\n"))
+	})
+
+	It("reports leaks per module only when passed the modules", func() {
+		md := MarkdownWithModules(leaks, []ModuleLeaks{{Module: "example.org/foo", Goroutines: leaks}})
+		Expect(md).To(ContainSubstring("\n| Goroutines | Module |\n| ---: | --- |\n| 3 | `example.org/foo` |\n"))
+		Expect(MarkdownWithModules(leaks, nil)).To(Equal(Markdown(leaks)))
+	})
+
+	It("reports a single leak in singular", func() {
+		Expect(Markdown(leaks[2:])).To(HavePrefix(
+			"## Leaked Goroutines\n\n1 leaked goroutine from 1 creator location.\n\n"))
+	})
+
+	It("escapes", func() {
+		md := Markdown([]goroutine.Goroutine{{ID: 1, Backtrace: "```"}})
+		Expect(md).To(ContainSubstring("| 1 | – | – |\n"))
+		Expect(md).To(ContainSubstring("<summary>1 × (no creator)</summary>"))
+		Expect(md).To(ContainSubstring("````text\ngoroutine 1 []:\n```\n````\n"))
+		Expect(markdownCode("a|b")).To(Equal("`a\\|b`"))
+	})
+
+})
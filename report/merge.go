@@ -0,0 +1,116 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ReadLeakReport reads a leak report in JSON format from r, such as a summary
+// file written by noleak.VerifyTestMain.
+func ReadLeakReport(r io.Reader) (LeakReport, error) {
+	var report LeakReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return LeakReport{}, fmt.Errorf("cannot read leak report: %w", err)
+	}
+	return report, nil
+}
+
+// Merge combines the specified leak reports, such as those produced by the
+// many test binaries of a mono repository build, into a single summary.
+// Leaks with the same fingerprint are deduplicated into a single entry, adding
+// up their numbers of leaked goroutines and joining the package-qualified tests
+// they have been attributed to. Similarly, the entries of the same test in the same package
+// are merged.
+func Merge(reports []LeakReport) LeakReport {
+	type testKey struct{ pkg, test string }
+	fingerprints := map[string]*fingerprintSummary{}
+	tests := map[testKey]*testSummary{}
+	merged := LeakReport{}
+	for _, r := range reports {
+		merged.Checks += r.Checks
+		merged.Leaked += r.Leaked
+		for _, fl := range r.Fingerprints {
+			f := fingerprints[fl.Fingerprint]
+			if f == nil {
+				f = &fingerprintSummary{
+					FingerprintLeaks: FingerprintLeaks{
+						Fingerprint: fl.Fingerprint,
+						TopFunction: fl.TopFunction,
+						Creator:     fl.Creator,
+						BornAt:      fl.BornAt,
+					},
+					tests: map[string]struct{}{},
+				}
+				fingerprints[fl.Fingerprint] = f
+			}
+			f.Leaked += fl.Leaked
+			for _, test := range fl.Tests {
+				f.tests[test] = struct{}{}
+			}
+		}
+		for _, tl := range r.Tests {
+			k := testKey{pkg: tl.Package, test: tl.Test}
+			t := tests[k]
+			if t == nil {
+				t = &testSummary{
+					TestLeaks:    TestLeaks{Package: tl.Package, Test: tl.Test},
+					fingerprints: map[string]struct{}{},
+				}
+				tests[k] = t
+			}
+			t.Checks += tl.Checks
+			t.Leaked += tl.Leaked
+			for _, fp := range tl.Fingerprints {
+				t.fingerprints[fp] = struct{}{}
+			}
+		}
+	}
+	merged.Fingerprints = make([]FingerprintLeaks, 0, len(fingerprints))
+	for _, f := range fingerprints {
+		fl := f.FingerprintLeaks
+		fl.Tests = sortedMembers(f.tests)
+		merged.Fingerprints = append(merged.Fingerprints, fl)
+	}
+	sort.Slice(merged.Fingerprints, func(a, b int) bool {
+		return merged.Fingerprints[a].Fingerprint < merged.Fingerprints[b].Fingerprint
+	})
+	merged.Tests = make([]TestLeaks, 0, len(tests))
+	for _, t := range tests {
+		tl := t.TestLeaks
+		tl.Fingerprints = sortedMembers(t.fingerprints)
+		merged.Tests = append(merged.Tests, tl)
+	}
+	sort.Slice(merged.Tests, func(a, b int) bool {
+		if merged.Tests[a].Package != merged.Tests[b].Package {
+			return merged.Tests[a].Package < merged.Tests[b].Package
+		}
+		return merged.Tests[a].Test < merged.Tests[b].Test
+	})
+	return merged
+}
+
+// WriteJSON writes the leak report to w in JSON format.
+func (r LeakReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("cannot write leak summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("merging leak reports", func() {
+
+	foo := goroutine.Goroutine{ID: 1, State: "chan receive", TopFunction: "foo.bar", CreatorFunction: "foo.New"}
+	baz := goroutine.Goroutine{ID: 2, State: "select", TopFunction: "foo.baz"}
+
+	It("deduplicates by fingerprint", func() {
+		a := Summary{Package: "example.org/a"}
+		b := Summary{Package: "example.org/b"}
+		a.Add("TestMain", []goroutine.Goroutine{foo, baz})
+		b.Add("TestMain", []goroutine.Goroutine{foo})
+		b.Add("TestB", []goroutine.Goroutine{foo})
+
+		merged := Merge([]LeakReport{a.Report(), b.Report(), {}})
+		Expect(merged.Checks).To(Equal(3))
+		Expect(merged.Leaked).To(Equal(4))
+		Expect(merged.Fingerprints).To(ConsistOf(
+			FingerprintLeaks{Fingerprint: foo.Fingerprint(), Leaked: 3,
+				TopFunction: "foo.bar", Creator: "foo.New", Tests: []string{
+					"example.org/a.TestMain", "example.org/b.TestB", "example.org/b.TestMain"}},
+			FingerprintLeaks{Fingerprint: baz.Fingerprint(), Leaked: 1,
+				TopFunction: "foo.baz", Tests: []string{"example.org/a.TestMain"}},
+		))
+		Expect(merged.Tests).To(HaveLen(3), "same-named tests of different packages")
+		Expect(merged.Tests[0]).To(And(
+			HaveField("Package", "example.org/a"), HaveField("Test", "TestMain"),
+			HaveField("Checks", 1), HaveField("Leaked", 2), HaveField("Fingerprints", HaveLen(2))))
+		Expect(merged.Tests[1]).To(Equal(TestLeaks{Package: "example.org/b", Test: "TestB",
+			Checks: 1, Leaked: 1, Fingerprints: []string{foo.Fingerprint()}}))
+		Expect(merged.Tests[2]).To(Equal(TestLeaks{Package: "example.org/b", Test: "TestMain",
+			Checks: 1, Leaked: 1, Fingerprints: []string{foo.Fingerprint()}}))
+
+		c := Summary{Package: "example.org/a"}
+		c.Add("TestMain", []goroutine.Goroutine{foo})
+		Expect(Merge([]LeakReport{a.Report(), c.Report()}).Tests).To(ConsistOf(
+			HaveField("Checks", 2)), "same tests of the same package")
+
+		Expect(Merge(nil)).To(Equal(LeakReport{Fingerprints: []FingerprintLeaks{}, Tests: []TestLeaks{}}))
+	})
+
+	It("reads leak reports", func() {
+		var s Summary
+		s.Add("TestA", []goroutine.Goroutine{foo})
+		var buff strings.Builder
+		Expect(s.WriteJSON(&buff)).To(Succeed())
+		r, err := ReadLeakReport(strings.NewReader(buff.String()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r).To(Equal(s.Report()))
+
+		Expect(ReadLeakReport(strings.NewReader("{"))).Error().To(
+			MatchError(HavePrefix("cannot read leak report: ")))
+	})
+
+})
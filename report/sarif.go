@@ -0,0 +1,163 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// SARIFRuleID is the ID of the rule reported in SARIF results for leaked
+// goroutines.
+const SARIFRuleID = "goroutine-leak"
+
+// SARIF writes the specified leaked goroutines to w as a SARIF 2.1.0 log,
+// suitable for code scanning tools, such as GitHub code scanning. There is one
+// result per creator location, pointing at the "go" statement that started
+// the leaked goroutines. If basedir isn't empty, then file locations inside
+// basedir are made relative to it, as code scanning tools usually expect file
+// locations relative to the repository root.
+func SARIF(w io.Writer, leaks []goroutine.Goroutine, basedir string) error {
+	results := []sarifResult{}
+	for _, group := range GroupByCreator(leaks) {
+		result := sarifResult{
+			RuleID:  SARIFRuleID,
+			Level:   "error",
+			Message: sarifMessage{Text: groupMessage(group)},
+		}
+		if creator := group.Goroutines[0].CreatorFrame; creator.File != "" && creator.Line > 0 {
+			result.Locations = []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: relativeURI(creator.File, basedir)},
+					Region:           sarifRegion{StartLine: creator.Line},
+				},
+			}}
+		}
+		results = append(results, result)
+	}
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "noleak",
+				InformationURI: "https://github.com/thediveo/noleak",
+				Rules: []sarifRule{{
+					ID:               SARIFRuleID,
+					ShortDescription: sarifMessage{Text: "Leaked goroutine"},
+					FullDescription: sarifMessage{
+						Text: "Goroutines started here did not terminate by the end of the test."},
+				}},
+			}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(log); err != nil {
+		return fmt.Errorf("cannot write SARIF log: %w", err)
+	}
+	return nil
+}
+
+// groupMessage returns a single-line description of the leaked goroutines in
+// the specified creator group.
+func groupMessage(group Group) string {
+	msg := counted(len(group.Goroutines), "leaked goroutine", "leaked goroutines")
+	if group.CreatorFunction != "" {
+		msg += fmt.Sprintf(" created by %s", group.CreatorFunction)
+	}
+	ids := make([]string, len(group.Goroutines))
+	for idx, g := range group.Goroutines {
+		ids[idx] = strconv.FormatUint(g.ID, 10)
+	}
+	label := "goroutine IDs"
+	if len(ids) == 1 {
+		label = "goroutine ID"
+	}
+	return msg + fmt.Sprintf(" (%s: %s), with topmost function %s",
+		label, strings.Join(ids, ", "), group.Goroutines[0].TopFunction)
+}
+
+// relativeURI returns the specified file path as a URI reference with forward
+// slashes, relative to basedir if the file is located inside basedir.
+func relativeURI(filename string, basedir string) string {
+	filename = strings.ReplaceAll(filename, "\\", "/")
+	if basedir == "" {
+		return filename
+	}
+	basedir = strings.TrimSuffix(strings.ReplaceAll(basedir, "\\", "/"), "/") + "/"
+	return strings.TrimPrefix(filename, basedir)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	FullDescription  sarifMessage `json:"fullDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
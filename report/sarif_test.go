@@ -0,0 +1,64 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SARIF", func() {
+
+	It("reports leaks at their creator locations", func() {
+		var buff strings.Builder
+		Expect(SARIF(&buff, append(leaks, goroutine.Goroutine{ID: 1, TopFunction: "main.main"}),
+			"/home/foo/")).To(Succeed())
+		var log map[string]interface{}
+		Expect(json.Unmarshal([]byte(buff.String()), &log)).To(Succeed())
+		Expect(log).To(HaveKeyWithValue("version", "2.1.0"))
+		Expect(log["runs"]).To(ConsistOf(HaveKeyWithValue("results", ConsistOf(
+			And(
+				HaveKeyWithValue("ruleId", SARIFRuleID),
+				HaveKeyWithValue("message", HaveKeyWithValue("text",
+					"2 leaked goroutines created by main.foo (goroutine IDs: 42, 666), with topmost function main.foo.func1")),
+				HaveKeyWithValue("locations", ConsistOf(HaveKeyWithValue("physicalLocation", And(
+					HaveKeyWithValue("artifactLocation", HaveKeyWithValue("uri", "test.go")),
+					HaveKeyWithValue("region", HaveKeyWithValue("startLine", BeNumerically("==", 5)))))))),
+			HaveKeyWithValue("locations", ConsistOf(HaveKeyWithValue("physicalLocation",
+				HaveKeyWithValue("region", HaveKeyWithValue("startLine", BeNumerically("==", 15)))))),
+			And(
+				HaveKeyWithValue("message", HaveKeyWithValue("text",
+					"1 leaked goroutine (goroutine ID: 1), with topmost function main.main")),
+				Not(HaveKey("locations"))),
+		))))
+	})
+
+	It("makes locations relative", func() {
+		Expect(relativeURI("/home/foo/bar/baz.go", "")).To(Equal("/home/foo/bar/baz.go"))
+		Expect(relativeURI("/home/foo/bar/baz.go", "/home/foo")).To(Equal("bar/baz.go"))
+		Expect(relativeURI("/home/bar/baz.go", "/home/foo")).To(Equal("/home/bar/baz.go"))
+		Expect(relativeURI(`C:\foo\bar\baz.go`, `C:\foo`)).To(Equal("bar/baz.go"))
+	})
+
+	It("reports write errors", func() {
+		Expect(SARIF(errWriter{}, leaks, "")).To(MatchError("cannot write SARIF log: foo failure"))
+	})
+
+})
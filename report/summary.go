@@ -0,0 +1,168 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// LeakReport is the machine-readable summary of the goroutines leaked during
+// a test binary run, such as for CI steps gating merges on leak counts.
+type LeakReport struct {
+	Checks       int                `json:"checks"`       // number of failed leak checks.
+	Leaked       int                `json:"leaked"`       // total number of leaked goroutines over all failed checks.
+	Fingerprints []FingerprintLeaks `json:"fingerprints"` // leaks per unique fingerprint, sorted by fingerprint.
+	Tests        []TestLeaks        `json:"tests"`        // leaks per test, sorted by test name.
+}
+
+// FingerprintLeaks summarizes the leaked goroutines sharing the same
+// fingerprint, together with the tests they have been attributed to.
+type FingerprintLeaks struct {
+	Fingerprint string   `json:"fingerprint"`
+	Leaked      int      `json:"leaked"` // number of leaked goroutines with this fingerprint.
+	TopFunction string   `json:"topFunction"`
+	Creator     string   `json:"creator,omitempty"`
+	BornAt      string   `json:"bornAt,omitempty"`
+	Tests       []string `json:"tests,omitempty"` // names of the tests leaking, qualified by their packages if known, sorted.
+}
+
+// Summary accumulates the goroutines leaked in failed leak checks into a
+// LeakReport. A Summary is safe for concurrent use by multiple goroutines; its
+// zero value is an empty summary ready to use.
+type Summary struct {
+	// Package optionally names the package of the tests, so that Merge keeps
+	// same-named tests from different test binaries apart. The test names of
+	// the per-fingerprint summaries are then qualified by this package.
+	// Package must not be changed after adding leaks.
+	Package string
+
+	mu           sync.Mutex
+	checks       int
+	leaked       int
+	fingerprints map[string]*fingerprintSummary
+	tests        map[string]*testSummary
+}
+
+type fingerprintSummary struct {
+	FingerprintLeaks
+	tests map[string]struct{}
+}
+
+type testSummary struct {
+	TestLeaks
+	fingerprints map[string]struct{}
+}
+
+// Add adds the goroutines leaked in a single failed leak check, attributing
+// them to the specified test. The test name might be empty if a leak cannot be
+// attributed to a particular test.
+func (s *Summary) Add(test string, leaks []goroutine.Goroutine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fingerprints == nil {
+		s.fingerprints = map[string]*fingerprintSummary{}
+		s.tests = map[string]*testSummary{}
+	}
+	s.checks++
+	s.leaked += len(leaks)
+	t := s.tests[test]
+	if t == nil {
+		t = &testSummary{
+			TestLeaks:    TestLeaks{Package: s.Package, Test: test},
+			fingerprints: map[string]struct{}{},
+		}
+		s.tests[test] = t
+	}
+	t.Checks++
+	t.Leaked += len(leaks)
+	for _, g := range leaks {
+		fp := g.Fingerprint()
+		t.fingerprints[fp] = struct{}{}
+		f := s.fingerprints[fp]
+		if f == nil {
+			f = &fingerprintSummary{
+				FingerprintLeaks: FingerprintLeaks{
+					Fingerprint: fp,
+					TopFunction: g.TopFunction,
+					Creator:     g.CreatorFunction,
+					BornAt:      g.BornAt,
+				},
+				tests: map[string]struct{}{},
+			}
+			s.fingerprints[fp] = f
+		}
+		f.Leaked++
+		if test != "" {
+			f.tests[qualifiedTest(s.Package, test)] = struct{}{}
+		}
+	}
+}
+
+// Report returns the leak report summarizing all leaks added so far.
+func (s *Summary) Report() LeakReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := LeakReport{
+		Checks:       s.checks,
+		Leaked:       s.leaked,
+		Fingerprints: make([]FingerprintLeaks, 0, len(s.fingerprints)),
+		Tests:        make([]TestLeaks, 0, len(s.tests)),
+	}
+	for _, f := range s.fingerprints {
+		fl := f.FingerprintLeaks
+		fl.Tests = sortedMembers(f.tests)
+		r.Fingerprints = append(r.Fingerprints, fl)
+	}
+	sort.Slice(r.Fingerprints, func(a, b int) bool {
+		return r.Fingerprints[a].Fingerprint < r.Fingerprints[b].Fingerprint
+	})
+	for _, t := range s.tests {
+		tl := t.TestLeaks
+		tl.Fingerprints = sortedMembers(t.fingerprints)
+		r.Tests = append(r.Tests, tl)
+	}
+	sort.Slice(r.Tests, func(a, b int) bool { return r.Tests[a].Test < r.Tests[b].Test })
+	return r
+}
+
+// WriteJSON writes the leak report summarizing all leaks added so far to w in
+// JSON format.
+func (s *Summary) WriteJSON(w io.Writer) error {
+	return s.Report().WriteJSON(w)
+}
+
+// qualifiedTest returns the name of the specified test qualified by the
+// import path of its package, such as "example.org/foo.TestBar". If the
+// package is unknown, the test name is returned unqualified.
+func qualifiedTest(pkg, test string) string {
+	if pkg == "" {
+		return test
+	}
+	return pkg + "." + test
+}
+
+// sortedMembers returns the members of the specified set in sorted order.
+func sortedMembers(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("leak summaries", func() {
+
+	foo := goroutine.Goroutine{ID: 1, State: "chan receive", TopFunction: "foo.bar", CreatorFunction: "foo.New", BornAt: "/foo/foo.go:42"}
+	baz := goroutine.Goroutine{ID: 2, State: "select", TopFunction: "foo.baz"}
+
+	It("summarizes leaks per fingerprint and test", func() {
+		var s Summary
+		Expect(s.Report()).To(Equal(LeakReport{
+			Fingerprints: []FingerprintLeaks{},
+			Tests:        []TestLeaks{},
+		}))
+		s.Add("TestB", []goroutine.Goroutine{foo, baz})
+		s.Add("TestA", []goroutine.Goroutine{foo})
+		s.Add("", []goroutine.Goroutine{baz})
+		r := s.Report()
+		Expect(r.Checks).To(Equal(3))
+		Expect(r.Leaked).To(Equal(4))
+		Expect(r.Fingerprints).To(ConsistOf(
+			FingerprintLeaks{Fingerprint: foo.Fingerprint(), Leaked: 2,
+				TopFunction: "foo.bar", Creator: "foo.New", BornAt: "/foo/foo.go:42",
+				Tests: []string{"TestA", "TestB"}},
+			FingerprintLeaks{Fingerprint: baz.Fingerprint(), Leaked: 2,
+				TopFunction: "foo.baz", Tests: []string{"TestB"}},
+		))
+		Expect(r.Tests).To(HaveLen(3))
+		Expect(r.Tests[0]).To(Equal(TestLeaks{Test: "", Checks: 1, Leaked: 1,
+			Fingerprints: []string{baz.Fingerprint()}}))
+		Expect(r.Tests[1]).To(Equal(TestLeaks{Test: "TestA", Checks: 1, Leaked: 1,
+			Fingerprints: []string{foo.Fingerprint()}}))
+		Expect(r.Tests[2].Test).To(Equal("TestB"))
+		Expect(r.Tests[2].Fingerprints).To(HaveLen(2))
+	})
+
+	It("writes JSON", func() {
+		var s Summary
+		s.Add("TestA", []goroutine.Goroutine{baz})
+		var buff strings.Builder
+		Expect(s.WriteJSON(&buff)).To(Succeed())
+		Expect(buff.String()).To(MatchJSON(`{
+			"checks": 1,
+			"leaked": 1,
+			"fingerprints": [{"fingerprint": "` + baz.Fingerprint() + `", "leaked": 1, "topFunction": "foo.baz", "tests": ["TestA"]}],
+			"tests": [{"test": "TestA", "checks": 1, "leaked": 1, "fingerprints": ["` + baz.Fingerprint() + `"]}]
+		}`))
+		Expect(s.WriteJSON(errWriter{})).To(MatchError(HavePrefix("cannot write leak summary: ")))
+	})
+
+})
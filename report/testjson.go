@@ -0,0 +1,258 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// LeakEntryPrefix marks the machine-readable leak entries in test output, as
+// written by WriteLeakEntry.
+const LeakEntryPrefix = "noleak:leak "
+
+// LeakEntry is the machine-readable description of the goroutines leaked in a
+// single failed leak check.
+type LeakEntry struct {
+	Leaked       int               `json:"leaked"`       // number of leaked goroutines.
+	Fingerprints []string          `json:"fingerprints"` // unique fingerprints of the leaked goroutines.
+	Goroutines   []LeakedGoroutine `json:"goroutines"`   // short descriptions of the leaked goroutines.
+}
+
+// LeakedGoroutine is the short description of a leaked goroutine in a
+// LeakEntry, without its backtrace.
+type LeakedGoroutine struct {
+	ID          uint64 `json:"id"`
+	State       string `json:"state"`
+	TopFunction string `json:"topFunction"`
+	Creator     string `json:"creator,omitempty"`
+	BornAt      string `json:"bornAt,omitempty"`
+}
+
+// WriteLeakEntry writes a single line with a machine-readable leak entry for
+// the specified leaked goroutines to w, prefixed by LeakEntryPrefix. When
+// written to a test's output, such as os.Stdout, "go test -json" picks up these
+// lines as test output, so that ParseTestJSON later can find them.
+func WriteLeakEntry(w io.Writer, leaks []goroutine.Goroutine, fingerprints []string) error {
+	entry := LeakEntry{
+		Leaked:       len(leaks),
+		Fingerprints: fingerprints,
+		Goroutines:   make([]LeakedGoroutine, len(leaks)),
+	}
+	for idx, g := range leaks {
+		entry.Goroutines[idx] = LeakedGoroutine{
+			ID:          g.ID,
+			State:       g.State,
+			TopFunction: g.TopFunction,
+			Creator:     g.CreatorFunction,
+			BornAt:      g.BornAt,
+		}
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("cannot write leak entry: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s%s\n", LeakEntryPrefix, line); err != nil {
+		return fmt.Errorf("cannot write leak entry: %w", err)
+	}
+	return nil
+}
+
+// TestLeaks summarizes the leak entries of a single test.
+type TestLeaks struct {
+	Package      string   `json:"package,omitempty"` // import path of the test's package.
+	Test         string   `json:"test"`              // name of the test; empty for package-level output.
+	Checks       int      `json:"checks"`            // number of failed leak checks.
+	Leaked       int      `json:"leaked"`            // total number of leaked goroutines over all failed checks.
+	Fingerprints []string `json:"fingerprints"`      // unique fingerprints of the leaked goroutines, sorted.
+}
+
+// testEvent is the subset of the "go test -json" (test2json) event fields we
+// need.
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// ParseTestJSON reads "go test -json" output and returns the leak summaries of
+// all tests with leak entries (see WriteLeakEntry) in their output, sorted by
+// package and test name. Lines that aren't test events are ignored, so that
+// ParseTestJSON also works on output interspersed with build messages. As
+// test2json splits long output lines into multiple output events,
+// ParseTestJSON reassembles the output lines of each test before looking for
+// leak entries. ParseTestJSON returns an error for leak entries that cannot be
+// parsed, instead of silently undercounting leaks.
+func ParseTestJSON(r io.Reader) ([]TestLeaks, error) {
+	type key struct{ pkg, test string }
+	type summary struct {
+		TestLeaks
+		fingerprints map[string]struct{}
+	}
+	summaries := map[key]*summary{}
+	add := func(k key, line string) error {
+		entry, ok, err := parseLeakEntry(line)
+		if err != nil {
+			return fmt.Errorf("cannot parse leak entry of test %q in package %q: %w", k.test, k.pkg, err)
+		}
+		if !ok {
+			return nil
+		}
+		s := summaries[k]
+		if s == nil {
+			s = &summary{
+				TestLeaks:    TestLeaks{Package: k.pkg, Test: k.test},
+				fingerprints: map[string]struct{}{},
+			}
+			summaries[k] = s
+		}
+		s.Checks++
+		s.Leaked += entry.Leaked
+		for _, fp := range entry.Fingerprints {
+			s.fingerprints[fp] = struct{}{}
+		}
+		return nil
+	}
+	// partial output lines per test, waiting for their continuation events.
+	partial := map[key]string{}
+	// Test events with leak entries can get arbitrarily long, such as when
+	// leaked goroutines have very long function names or source paths, so read
+	// whole lines of whatever length instead of scanning with a maximum token
+	// size.
+	br := bufio.NewReader(r)
+	for done := false; !done; {
+		line, err := br.ReadBytes('\n')
+		switch {
+		case err == io.EOF:
+			done = true
+		case err != nil:
+			return nil, fmt.Errorf("cannot read test output: %w", err)
+		}
+		var event testEvent
+		if err := json.Unmarshal(line, &event); err != nil || event.Action == "" {
+			continue
+		}
+		k := key{pkg: event.Package, test: event.Test}
+		if event.Action != "output" {
+			// The test has finished or paused, so any partial output line
+			// won't get continued.
+			if out, ok := partial[k]; ok {
+				delete(partial, k)
+				if err := add(k, out); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		out := partial[k] + event.Output
+		for {
+			eol := strings.IndexByte(out, '\n')
+			if eol < 0 {
+				break
+			}
+			if err := add(k, out[:eol]); err != nil {
+				return nil, err
+			}
+			out = out[eol+1:]
+		}
+		if out == "" {
+			delete(partial, k)
+		} else {
+			partial[k] = out
+		}
+	}
+	for k, out := range partial {
+		if err := add(k, out); err != nil {
+			return nil, err
+		}
+	}
+	tests := make([]TestLeaks, 0, len(summaries))
+	for _, s := range summaries {
+		s.Fingerprints = make([]string, 0, len(s.fingerprints))
+		for fp := range s.fingerprints {
+			s.Fingerprints = append(s.Fingerprints, fp)
+		}
+		sort.Strings(s.Fingerprints)
+		tests = append(tests, s.TestLeaks)
+	}
+	sort.Slice(tests, func(a, b int) bool {
+		if tests[a].Package != tests[b].Package {
+			return tests[a].Package < tests[b].Package
+		}
+		return tests[a].Test < tests[b].Test
+	})
+	return tests, nil
+}
+
+// TestJSONSummary writes Markdown tables summarizing the specified per-test
+// leaks, first per test and then per package, suitable for CI dashboards and
+// job summaries.
+func TestJSONSummary(w io.Writer, tests []TestLeaks) error {
+	var buff strings.Builder
+	buff.WriteString("## Leaks per Test\n\n")
+	if len(tests) == 0 {
+		buff.WriteString("No leaked goroutines.\n")
+		_, err := io.WriteString(w, buff.String())
+		return err
+	}
+	buff.WriteString("| Package | Test | Failed Checks | Leaked | Unique |\n")
+	buff.WriteString("| --- | --- | ---: | ---: | ---: |\n")
+	type pkgSummary struct{ tests, checks, leaked int }
+	pkgs := map[string]*pkgSummary{}
+	pkgnames := []string{}
+	for _, t := range tests {
+		fmt.Fprintf(&buff, "| %s | %s | %d | %d | %d |\n",
+			markdownCode(t.Package), markdownCode(t.Test), t.Checks, t.Leaked, len(t.Fingerprints))
+		p := pkgs[t.Package]
+		if p == nil {
+			p = &pkgSummary{}
+			pkgs[t.Package] = p
+			pkgnames = append(pkgnames, t.Package)
+		}
+		p.tests++
+		p.checks += t.Checks
+		p.leaked += t.Leaked
+	}
+	buff.WriteString("\n## Leaks per Package\n\n")
+	buff.WriteString("| Package | Tests | Failed Checks | Leaked |\n")
+	buff.WriteString("| --- | ---: | ---: | ---: |\n")
+	for _, name := range pkgnames {
+		p := pkgs[name]
+		fmt.Fprintf(&buff, "| %s | %d | %d | %d |\n", markdownCode(name), p.tests, p.checks, p.leaked)
+	}
+	_, err := io.WriteString(w, buff.String())
+	return err
+}
+
+// parseLeakEntry parses a single line of test output, returning the leak entry
+// in it, if any. parseLeakEntry returns false for lines without leak entries,
+// and an error for lines with malformed leak entries.
+func parseLeakEntry(line string) (entry LeakEntry, ok bool, err error) {
+	idx := strings.Index(line, LeakEntryPrefix)
+	if idx < 0 {
+		return entry, false, nil
+	}
+	if err := json.Unmarshal([]byte(line[idx+len(LeakEntryPrefix):]), &entry); err != nil {
+		return entry, false, err
+	}
+	return entry, true, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package report
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// testOutput is "go test -json" output with leak entries of two tests in two
+// packages, interspersed with non-JSON lines and other output.
+const testOutput = `# github.com/foo/bar
+{"Action":"run","Package":"example.org/a","Test":"TestA"}
+{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"    noleak:leak {\"leaked\":2,\"fingerprints\":[\"aa\",\"bb\"],\"goroutines\":[]}\n"}
+{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"noleak:leak {\"leaked\":1,\"fingerprints\":[\"aa\"],\"goroutines\":[]}\n"}
+{"Action":"output","Package":"example.org/a","Test":"TestB","Output":"--- PASS: TestB\n"}
+{"Action":"output","Package":"example.org/b","Test":"TestC","Output":"noleak:leak {\"leaked\":3,\"fingerprints\":[\"cc\"],\"goroutines\":[]}\n"}
+{"Action":"fail","Package":"example.org/b","Test":"TestC"}
+`
+
+var _ = Describe("go test -json analysis", func() {
+
+	It("writes leak entries", func() {
+		var buff strings.Builder
+		Expect(WriteLeakEntry(&buff, leaks[:1], []string{"aa"})).To(Succeed())
+		Expect(buff.String()).To(HavePrefix(LeakEntryPrefix + `{"leaked":1,"fingerprints":["aa"],"goroutines":[{"id":666,"state":"chan receive","topFunction":"main.foo.func1","creator":"main.foo","bornAt":"/home/foo/test.go:5"}]}`))
+		Expect(WriteLeakEntry(&errWriter{}, leaks, nil)).To(MatchError(ContainSubstring("cannot write leak entry")))
+	})
+
+	It("parses leak entries", func() {
+		tests, err := ParseTestJSON(strings.NewReader(testOutput))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tests).To(Equal([]TestLeaks{
+			{Package: "example.org/a", Test: "TestA", Checks: 2, Leaked: 3, Fingerprints: []string{"aa", "bb"}},
+			{Package: "example.org/b", Test: "TestC", Checks: 1, Leaked: 3, Fingerprints: []string{"cc"}},
+		}))
+	})
+
+	It("parses arbitrarily long leak entries", func() {
+		fp := strings.Repeat("f", 17*1024*1024)
+		output := `{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"noleak:leak {\"leaked\":1,\"fingerprints\":[\"` +
+			fp + `\"],\"goroutines\":[]}\n"}`
+		tests, err := ParseTestJSON(strings.NewReader(output))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tests).To(ConsistOf(HaveField("Fingerprints", ConsistOf(HaveLen(len(fp))))))
+	})
+
+	It("reassembles leak entries split over multiple output events", func() {
+		output := `{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"noleak:leak {\"leaked\":2,"}
+{"Action":"output","Package":"example.org/a","Test":"TestB","Output":"noleak:leak {\"leaked\":1,\"fingerprints\":[\"bb\"]}\n"}
+{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"\"fingerprints\":[\"aa\"],"}
+{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"\"goroutines\":[]}\n=== RUN"}
+{"Action":"output","Package":"example.org/a","Test":"TestC","Output":"noleak:leak {\"leaked\":3,"}
+{"Action":"output","Package":"example.org/a","Test":"TestC","Output":"\"fingerprints\":[\"cc\"]}"}
+{"Action":"pass","Package":"example.org/a","Test":"TestC"}
+`
+		tests, err := ParseTestJSON(strings.NewReader(output))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tests).To(Equal([]TestLeaks{
+			{Package: "example.org/a", Test: "TestA", Checks: 1, Leaked: 2, Fingerprints: []string{"aa"}},
+			{Package: "example.org/a", Test: "TestB", Checks: 1, Leaked: 1, Fingerprints: []string{"bb"}},
+			{Package: "example.org/a", Test: "TestC", Checks: 1, Leaked: 3, Fingerprints: []string{"cc"}},
+		}))
+	})
+
+	It("rejects malformed leak entries", func() {
+		_, err := ParseTestJSON(strings.NewReader(
+			`{"Action":"output","Package":"example.org/a","Test":"TestA","Output":"noleak:leak {garbage\n"}`))
+		Expect(err).To(MatchError(ContainSubstring(`cannot parse leak entry of test "TestA" in package "example.org/a"`)))
+	})
+
+	It("reports read errors", func() {
+		_, err := ParseTestJSON(&errReader{})
+		Expect(err).To(MatchError(ContainSubstring("cannot read test output")))
+	})
+
+	It("summarizes", func() {
+		tests, _ := ParseTestJSON(strings.NewReader(testOutput))
+		var buff strings.Builder
+		Expect(TestJSONSummary(&buff, tests)).To(Succeed())
+		Expect(buff.String()).To(Equal("## Leaks per Test\n\n" +
+			"| Package | Test | Failed Checks | Leaked | Unique |\n" +
+			"| --- | --- | ---: | ---: | ---: |\n" +
+			"| `example.org/a` | `TestA` | 2 | 3 | 2 |\n" +
+			"| `example.org/b` | `TestC` | 1 | 3 | 1 |\n" +
+			"\n## Leaks per Package\n\n" +
+			"| Package | Tests | Failed Checks | Leaked |\n" +
+			"| --- | ---: | ---: | ---: |\n" +
+			"| `example.org/a` | 1 | 2 | 3 |\n" +
+			"| `example.org/b` | 1 | 1 | 3 |\n"))
+
+		buff.Reset()
+		Expect(TestJSONSummary(&buff, nil)).To(Succeed())
+		Expect(buff.String()).To(Equal("## Leaks per Test\n\nNo leaked goroutines.\n"))
+	})
+
+})
+
+// errReader always fails reading.
+type errReader struct{}
+
+func (r *errReader) Read(p []byte) (int, error) { return 0, errors.New("D'oh!") }
@@ -0,0 +1,43 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/thediveo/noleak/goroutine"
+	"github.com/thediveo/noleak/report"
+)
+
+// writeReport writes a Markdown report about the specified leaked goroutines
+// into a new file inside the configured report directory, unless it is empty.
+func (c Config) writeReport(leaked []goroutine.Goroutine) error {
+	if c.ReportDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.ReportDir, 0o755); err != nil {
+		return fmt.Errorf("cannot write leak report: %w", err)
+	}
+	f, err := os.CreateTemp(c.ReportDir, "noleak-*.md")
+	if err != nil {
+		return fmt.Errorf("cannot write leak report: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(report.MarkdownWithModules(leaked, report.BlameModules(leaked))); err != nil {
+		return fmt.Errorf("cannot write leak report: %w", err)
+	}
+	return nil
+}
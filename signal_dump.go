@@ -0,0 +1,84 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// DumpOnSignal installs a signal handler that upon receiving any of the
+// DumpSignals (SIGUSR1 on Unix systems) captures the current goroutines,
+// filters out the expected goroutines, and writes a report about the remaining
+// goroutines to w; if w is nil, the report is written to os.Stderr. The
+// goroutines to be ignored are specified in the same way as with HaveLeaked;
+// the standard filters as well as the filters registered using command line
+// flags are always applied. DumpOnSignal is handy for inspecting long-running
+// integration harnesses without having to kill them.
+//
+//   stop := noleak.DumpOnSignal(nil, snapshot)
+//   defer stop()
+//
+// Then send SIGUSR1 to the process, such as with "kill -USR1 <pid>". The
+// returned function removes the signal handler again. On systems without
+// DumpSignals, DumpOnSignal does nothing.
+func DumpOnSignal(w io.Writer, ignoring ...interface{}) (stop func()) {
+	if len(DumpSignals) == 0 {
+		return func() {}
+	}
+	if w == nil {
+		w = os.Stderr
+	}
+	m := HaveLeaked(ignoring...).(*HaveLeakedMatcher)
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, DumpSignals...)
+	GoInfrastructure(func() {
+		defer close(done)
+		for sig := range sigs {
+			_, _ = io.WriteString(w, m.signalReport(sig, goroutine.Goroutines()))
+		}
+	})
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(sigs)
+			<-done
+		})
+	}
+}
+
+// signalReport returns a report of the specified goroutines not matched by any
+// of the filters of this matcher, as triggered by the specified signal.
+func (matcher *HaveLeakedMatcher) signalReport(sig os.Signal, gs []goroutine.Goroutine) string {
+	header := fmt.Sprintf("noleak: received %s at %s", sig, time.Now().Format(time.RFC3339))
+	leaked, err := matcher.filter(gs, matcher.filters)
+	if err != nil {
+		return fmt.Sprintf("%s, but cannot filter goroutines: %s\n", header, err)
+	}
+	if len(leaked) == 0 {
+		return header + ": no leaked goroutines\n"
+	}
+	sortGoroutines(leaked)
+	return fmt.Sprintf("%s: %s:\n%s\n",
+		header, counted(len(leaked), "leaked goroutine", "leaked goroutines"), matcher.listGoroutines(leaked, 1))
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package noleak
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/thediveo/noleak/goroutine"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("dumping leaks on signal", func() {
+
+	It("dumps leaked goroutines on SIGUSR1", func() {
+		snapshot := Goroutines()
+		buff := gbytes.NewBuffer()
+		stop := DumpOnSignal(buff, snapshot)
+		done := make(chan struct{})
+		go func() { <-done }()
+		defer func() {
+			close(done)
+			stop()
+			stop()
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+
+		Expect(syscall.Kill(os.Getpid(), syscall.SIGUSR1)).To(Succeed())
+		Eventually(buff).Should(gbytes.Say(`noleak: received user defined signal 1 at .*: 1 leaked goroutine:\n    goroutine \d+ \[chan receive\]\n`))
+	})
+
+	It("reports when there's nothing to report", func() {
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		Expect(m.signalReport(syscall.SIGUSR1, nil)).To(HaveSuffix(": no leaked goroutines\n"))
+		m = HaveLeaked(failingMatcher{}).(*HaveLeakedMatcher)
+		Expect(m.signalReport(syscall.SIGUSR1, []goroutine.Goroutine{{ID: 42}})).To(
+			HaveSuffix(", but cannot filter goroutines: foo failure\n"))
+	})
+
+})
@@ -0,0 +1,27 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package noleak
+
+import (
+	"os"
+	"syscall"
+)
+
+// DumpSignals are the signals triggering leak reports after calling
+// DumpOnSignal. Please note that adding SIGQUIT replaces Go's default action
+// of dumping all goroutines and then exiting.
+var DumpSignals = []os.Signal{syscall.SIGUSR1}
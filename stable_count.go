@@ -0,0 +1,150 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// HaveStableGoroutineCount succeeds unless the number of goroutines sampled
+// over (at least) the specified window has been monotonically increasing by
+// more than the specified tolerance. In contrast to HaveLeaked, it only needs
+// the number of goroutines, not their backtraces, so it is a cheap early
+// warning for soak-style tests that cannot afford full stack diffs every
+// second.
+//
+// HaveStableGoroutineCount is meant to be used with Consistently, passing it
+// either runtime.NumGoroutine, or LazyGoroutines, or lists of goroutines:
+//
+//   Consistently(runtime.NumGoroutine).
+//       WithTimeout(time.Minute).WithPolling(time.Second).
+//       Should(HaveStableGoroutineCount(10*time.Second, 2))
+//
+// As long as the samples don't yet span the window, HaveStableGoroutineCount
+// succeeds, as there isn't enough evidence for a trend. Only samples within
+// the window (plus the latest sample older than the window) are considered.
+func HaveStableGoroutineCount(window time.Duration, tolerance int) types.GomegaMatcher {
+	return &haveStableGoroutineCountMatcher{
+		window:    window,
+		tolerance: tolerance,
+	}
+}
+
+type haveStableGoroutineCountMatcher struct {
+	window    time.Duration
+	tolerance int
+	samples   []countSample // samples spanning the window, oldest first.
+}
+
+// countSample is a goroutine count sampled at a particular time.
+type countSample struct {
+	at    time.Time
+	count int
+}
+
+// Match succeeds unless the goroutine counts sampled over the window have been
+// monotonically increasing beyond the tolerance.
+func (matcher *haveStableGoroutineCountMatcher) Match(actual interface{}) (success bool, err error) {
+	count, err := goroutineCount(actual)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	matcher.samples = append(matcher.samples, countSample{at: now, count: count})
+	// Drop the samples that are no longer needed to span the window.
+	for len(matcher.samples) > 1 && now.Sub(matcher.samples[1].at) >= matcher.window {
+		matcher.samples = matcher.samples[1:]
+	}
+	return !matcher.growing(), nil
+}
+
+// growing returns true if the samples span the window and the goroutine
+// counts have been monotonically increasing beyond the tolerance.
+func (matcher *haveStableGoroutineCountMatcher) growing() bool {
+	if len(matcher.samples) < 2 {
+		return false
+	}
+	first, last := matcher.samples[0], matcher.samples[len(matcher.samples)-1]
+	if last.at.Sub(first.at) < matcher.window || last.count-first.count <= matcher.tolerance {
+		return false
+	}
+	for idx := 1; idx < len(matcher.samples); idx++ {
+		if matcher.samples[idx].count < matcher.samples[idx-1].count {
+			return false
+		}
+	}
+	return true
+}
+
+// counts returns the sampled goroutine counts, oldest first.
+func (matcher *haveStableGoroutineCountMatcher) counts() []int {
+	counts := make([]int, len(matcher.samples))
+	for idx, sample := range matcher.samples {
+		counts[idx] = sample.count
+	}
+	return counts
+}
+
+// FailureMessage returns a failure message if the goroutine count has been
+// growing.
+func (matcher *haveStableGoroutineCountMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected goroutine count to be stable within a tolerance of %d over %s, but it grew monotonically: %v",
+		matcher.tolerance, matcher.window, matcher.counts())
+}
+
+// NegatedFailureMessage returns a failure message if the goroutine count has
+// been stable.
+func (matcher *haveStableGoroutineCountMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected goroutine count to grow monotonically by more than %d over %s, but it didn't: %v",
+		matcher.tolerance, matcher.window, matcher.counts())
+}
+
+// MatchMayChangeInTheFuture always returns true, as the goroutine count
+// might start growing only later.
+func (matcher *haveStableGoroutineCountMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return true
+}
+
+// goroutineCount returns the number of goroutines represented by actual,
+// which is either a number of goroutines, LazyGoroutines, a list of goroutines,
+// or a goroutine.Snapshot.
+func goroutineCount(actual interface{}) (int, error) {
+	switch actual := actual.(type) {
+	case int:
+		return actual, nil
+	case LazyGoroutines:
+		return runtime.NumGoroutine(), nil
+	case goroutine.Snapshot:
+		return actual.Count(), nil
+	case *goroutine.Snapshot:
+		if actual != nil {
+			return actual.Count(), nil
+		}
+	}
+	val := reflect.ValueOf(actual)
+	if (val.Kind() == reflect.Array || val.Kind() == reflect.Slice) && val.Type().AssignableTo(gsT) {
+		return val.Len(), nil
+	}
+	return 0, fmt.Errorf(
+		"HaveStableGoroutineCount matcher expects a number or list of goroutines.  Got:\n%s",
+		format.Object(actual, 1))
+}
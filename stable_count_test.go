@@ -0,0 +1,85 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"runtime"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("stable goroutine count", func() {
+
+	It("accepts stable goroutine counts", func() {
+		Consistently(runtime.NumGoroutine).WithTimeout(100 * time.Millisecond).
+			Should(HaveStableGoroutineCount(20*time.Millisecond, 0))
+		Consistently(LazyGoroutines{}).WithTimeout(50 * time.Millisecond).
+			Should(HaveStableGoroutineCount(20*time.Millisecond, 0))
+	})
+
+	It("detects monotonically growing goroutine counts", func() {
+		m := HaveStableGoroutineCount(50*time.Millisecond, 2)
+		Expect(m.Match(10)).To(BeTrue())
+		Expect(m.Match(20)).To(BeTrue(), "window not yet spanned")
+		m = HaveStableGoroutineCount(50*time.Millisecond, 2)
+		Expect(m.Match(10)).To(BeTrue())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match(15)).To(BeFalse())
+		Expect(m.Match(20)).To(BeFalse())
+		Expect(m.FailureMessage(nil)).To(Equal(
+			"Expected goroutine count to be stable within a tolerance of 2 over 50ms, but it grew monotonically: [10 15 20]"))
+		Expect(m.NegatedFailureMessage(nil)).To(HavePrefix(
+			"Expected goroutine count to grow monotonically by more than 2 over 50ms"))
+		Expect(m.(*haveStableGoroutineCountMatcher).MatchMayChangeInTheFuture(nil)).To(BeTrue())
+	})
+
+	It("tolerates small or non-monotonic growth", func() {
+		m := HaveStableGoroutineCount(50*time.Millisecond, 2)
+		Expect(m.Match(10)).To(BeTrue())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match(12)).To(BeTrue(), "within tolerance")
+
+		m = HaveStableGoroutineCount(50*time.Millisecond, 2)
+		Expect(m.Match(10)).To(BeTrue())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match(5)).To(BeTrue())
+		Expect(m.Match(20)).To(BeTrue(), "not monotonic")
+	})
+
+	It("only considers samples within the window", func() {
+		m := HaveStableGoroutineCount(50*time.Millisecond, 2)
+		Expect(m.Match(100)).To(BeTrue())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match(10)).To(BeTrue())
+		time.Sleep(60 * time.Millisecond)
+		Expect(m.Match(20)).To(BeFalse())
+		Expect(m.(*haveStableGoroutineCountMatcher).counts()).To(Equal([]int{10, 20}))
+	})
+
+	It("counts goroutines in different forms", func() {
+		gs := []goroutine.Goroutine{{ID: 1}, {ID: 2}}
+		Expect(goroutineCount(gs)).To(Equal(2))
+		Expect(goroutineCount(goroutine.Snapshot{Goroutines: gs})).To(Equal(2))
+		Expect(goroutineCount(&goroutine.Snapshot{Goroutines: gs})).To(Equal(2))
+		Expect(goroutineCount(LazyGoroutines{})).To(BeNumerically(">", 0))
+		Expect(goroutineCount((*goroutine.Snapshot)(nil))).Error().To(HaveOccurred())
+		Expect(goroutineCount("foo")).Error().To(MatchError(ContainSubstring(
+			"HaveStableGoroutineCount matcher expects a number or list of goroutines")))
+	})
+
+})
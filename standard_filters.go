@@ -0,0 +1,209 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/onsi/gomega/types"
+)
+
+// NamedFilter is an entry in the catalog of built-in standard filters that
+// HaveLeaked always applies, unless disabled using DisableStandardFilters.
+type NamedFilter struct {
+	Name      string              // unique name of this standard filter.
+	Rationale string              // why the goroutines matched are not leaks.
+	Filter    types.GomegaMatcher // the underlying goroutine filter matcher.
+	Disabled  bool                // true if disabled using DisableStandardFilters.
+}
+
+// standardNamedFilters specifies the always automatically included no-leak
+// goroutine filter matchers.
+//
+// Note: it's okay to instantiate the Gomega Matchers here, as all goroutine
+// filtering-related noleak matchers are stateless with respect to any actual
+// value they try to match. This allows us to simply prepend them to any
+// user-supplied optional matchers when HaveLeaked returns a new goroutine
+// leakage detecting matcher.
+//
+// Note: cgo's goroutines with status "[syscall, locked to thread]" do not
+// appear any longer (since mid-2017), as these cgo goroutines are put into the
+// "dead" state when not in use. See: https://github.com/golang/go/issues/16714
+// and https://go-review.googlesource.com/c/go/+/45030/.
+var standardNamedFilters = []NamedFilter{
+	{
+		Name:      "noleak-infrastructure",
+		Rationale: "noleak's own infrastructure goroutines, as well as the ones of embedders; see GoInfrastructure",
+		Filter:    IgnoringCreator(infrastructureCreator),
+	},
+	{
+		Name:      "ginkgo-run-node",
+		Rationale: "Ginkgo runs each spec node in its own goroutine",
+		Filter:    IgnoringTopFunction("github.com/onsi/ginkgo/v2/internal.(*Suite).runNode"),
+	},
+	{
+		Name:      "ginkgo-run-node-closures",
+		Rationale: "goroutines of closures inside Ginkgo's spec node runner",
+		Filter:    IgnoringTopFunction("github.com/onsi/ginkgo/v2/internal.(*Suite).runNode..."),
+	},
+	{
+		Name:      "ginkgo-interrupt-handler",
+		Rationale: "Ginkgo v2 watches for interrupt signals during the whole suite",
+		Filter:    IgnoringTopFunction("github.com/onsi/ginkgo/v2/internal/interrupt_handler.(*InterruptHandler).registerForInterrupts..."),
+	},
+	{
+		Name:      "ginkgo-v1-interrupt-handler",
+		Rationale: "Ginkgo v1 watches for interrupt signals during the whole suite",
+		Filter:    IgnoringTopFunction("github.com/onsi/ginkgo/internal/specrunner.(*SpecRunner).registerForInterrupts"),
+	},
+	{
+		Name:      "testing-run-tests",
+		Rationale: "Go's testing package waits for the tests to finish",
+		Filter:    IgnoringTopFunction("testing.RunTests [chan receive]"),
+	},
+	{
+		Name:      "testing-run",
+		Rationale: "Go's testing package waits for (sub)tests to finish",
+		Filter:    IgnoringTopFunction("testing.(*T).Run [chan receive]"),
+	},
+	{
+		Name:      "testing-parallel",
+		Rationale: "parallel tests wait for their turn to run",
+		Filter:    IgnoringTopFunction("testing.(*T).Parallel [chan receive]"),
+	},
+	{
+		Name:      "testing-wait-parallel",
+		Rationale: "parallel tests wait for a free slot to run",
+		Filter:    IgnoringTopFunction("testing.(*testState).waitParallel [chan receive]"),
+	},
+	{
+		Name:      "signal-recv",
+		Rationale: "os/signal starts its own runtime goroutine receiving signals",
+		Filter:    IgnoringTopFunction("os/signal.signal_recv"),
+	},
+	{
+		Name:      "signal-loop",
+		Rationale: "os/signal loops calling signal_recv",
+		Filter:    IgnoringTopFunction("os/signal.loop"),
+	},
+	{
+		Name:      "signal-mask",
+		Rationale: "signal.Notify starts a runtime goroutine maintaining the signal mask",
+		Filter:    IgnoringInBacktrace("runtime.ensureSigM"),
+	},
+	{
+		Name:      "read-trace",
+		Rationale: "the runtime's execution tracer reads traces in its own goroutine",
+		Filter:    IgnoringInBacktrace("runtime.ReadTrace"),
+	},
+}
+
+// standardFilters contains the enabled standard filter matchers, as a
+// contiguous slice suitable for HaveLeaked; it is updated whenever standard
+// filters get disabled or reset.
+var standardFilters = namedFilterMatchers(nil)
+
+// disabledStandardFilters contains the names of the disabled standard filters.
+var disabledStandardFilters = struct {
+	sync.Mutex
+	names map[string]struct{}
+}{}
+
+// StandardFilters returns the catalog of built-in standard filters in the order
+// HaveLeaked applies them. Suites can, for instance, log the catalog in order
+// to document exactly which goroutines are silently ignored.
+func StandardFilters() []NamedFilter {
+	disabledStandardFilters.Lock()
+	defer disabledStandardFilters.Unlock()
+	filters := make([]NamedFilter, len(standardNamedFilters))
+	for idx, filter := range standardNamedFilters {
+		_, filter.Disabled = disabledStandardFilters.names[filter.Name]
+		filters[idx] = filter
+	}
+	return filters
+}
+
+// DisableStandardFilters disables the standard filters with the specified
+// names for all HaveLeaked matchers created afterwards. Disabling an already
+// disabled standard filter is a no-op. DisableStandardFilters panics if any
+// name doesn't belong to a standard filter, as listed by StandardFilters.
+func DisableStandardFilters(names ...string) {
+	disabledStandardFilters.Lock()
+	defer disabledStandardFilters.Unlock()
+	disabled := map[string]struct{}{}
+	for name := range disabledStandardFilters.names {
+		disabled[name] = struct{}{}
+	}
+	for _, name := range names {
+		if !isStandardFilter(name) {
+			panic(fmt.Sprintf("DisableStandardFilters expected one of %s, but got: %q",
+				strings.Join(standardFilterNames(), ", "), name))
+		}
+		disabled[name] = struct{}{}
+	}
+	disabledStandardFilters.names = disabled
+	standardFilters = namedFilterMatchers(disabled)
+}
+
+// ResetStandardFilters enables all standard filters again.
+func ResetStandardFilters() {
+	disabledStandardFilters.Lock()
+	defer disabledStandardFilters.Unlock()
+	disabledStandardFilters.names = nil
+	standardFilters = namedFilterMatchers(nil)
+}
+
+// enabledStandardFilters returns the currently enabled standard filter
+// matchers. Callers must not modify the returned slice.
+func enabledStandardFilters() []types.GomegaMatcher {
+	disabledStandardFilters.Lock()
+	defer disabledStandardFilters.Unlock()
+	return standardFilters
+}
+
+// namedFilterMatchers returns the matchers of the standard filters not in the
+// specified set of disabled filter names.
+func namedFilterMatchers(disabled map[string]struct{}) []types.GomegaMatcher {
+	matchers := make([]types.GomegaMatcher, 0, len(standardNamedFilters))
+	for _, filter := range standardNamedFilters {
+		if _, ok := disabled[filter.Name]; ok {
+			continue
+		}
+		matchers = append(matchers, filter.Filter)
+	}
+	return matchers[:len(matchers):len(matchers)]
+}
+
+// isStandardFilter returns true if there is a standard filter with the
+// specified name.
+func isStandardFilter(name string) bool {
+	for _, filter := range standardNamedFilters {
+		if filter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// standardFilterNames returns the names of all standard filters.
+func standardFilterNames() []string {
+	names := make([]string, len(standardNamedFilters))
+	for idx, filter := range standardNamedFilters {
+		names[idx] = filter.Name
+	}
+	return names
+}
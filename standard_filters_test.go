@@ -0,0 +1,65 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("standard filters catalog", func() {
+
+	AfterEach(func() {
+		ResetStandardFilters()
+	})
+
+	It("lists the standard filters", func() {
+		filters := StandardFilters()
+		Expect(filters).To(HaveLen(len(standardNamedFilters)))
+		names := map[string]struct{}{}
+		for _, filter := range filters {
+			Expect(filter.Name).NotTo(BeEmpty())
+			Expect(filter.Rationale).NotTo(BeEmpty())
+			Expect(filter.Filter).NotTo(BeNil())
+			Expect(filter.Disabled).To(BeFalse())
+			Expect(names).NotTo(HaveKey(filter.Name))
+			names[filter.Name] = struct{}{}
+		}
+		Expect(HaveLeaked().(*HaveLeakedMatcher).filters).To(HaveLen(len(filters)))
+	})
+
+	It("rejects unknown standard filters", func() {
+		Expect(func() { DisableStandardFilters("foo") }).To(PanicWith(
+			MatchRegexp(`^DisableStandardFilters expected one of noleak-infrastructure, .*, but got: "foo"$`)))
+	})
+
+	It("disables and resets standard filters", func() {
+		g := goroutine.Goroutine{ID: 1 << 62, TopFunction: "os/signal.loop", State: "select"}
+		Expect([]goroutine.Goroutine{g}).NotTo(HaveLeaked())
+
+		DisableStandardFilters("signal-loop")
+		DisableStandardFilters("signal-loop")
+		Expect(StandardFilters()).To(ContainElement(And(
+			HaveField("Name", "signal-loop"), HaveField("Disabled", true))))
+		Expect(HaveLeaked().(*HaveLeakedMatcher).filters).To(HaveLen(len(standardNamedFilters) - 1))
+		Expect([]goroutine.Goroutine{g}).To(HaveLeaked())
+
+		ResetStandardFilters()
+		Expect(StandardFilters()).To(HaveEach(HaveField("Disabled", false)))
+		Expect([]goroutine.Goroutine{g}).NotTo(HaveLeaked())
+	})
+
+})
@@ -0,0 +1,56 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import "github.com/thediveo/noleak/goroutine"
+
+// stuckTracker tracks leaked goroutines across successive polls in order to
+// tell genuinely wedged goroutines from goroutines that are just slow to exit.
+// A goroutine is considered to be "stuck" when its top function and wait
+// reason stay the same across polls, while the number of minutes it has been
+// waiting increases. It stays stuck as long as its top function and wait
+// reason don't change.
+type stuckTracker struct {
+	last  map[uint64]goroutine.Goroutine // leaked goroutines of the last poll.
+	stuck map[uint64]struct{}            // IDs of stuck goroutines.
+}
+
+// update updates the tracker with the leaked goroutines of the latest poll.
+func (t *stuckTracker) update(leaked []goroutine.Goroutine) {
+	last := make(map[uint64]goroutine.Goroutine, len(leaked))
+	stuck := map[uint64]struct{}{}
+	for _, g := range leaked {
+		last[g.ID] = g
+		prev, ok := t.last[g.ID]
+		if !ok || prev.TopFunction != g.TopFunction || prev.BaseState() != g.BaseState() {
+			continue
+		}
+		if _, wasStuck := t.stuck[g.ID]; wasStuck || g.BlockedMinutes() > prev.BlockedMinutes() {
+			stuck[g.ID] = struct{}{}
+		}
+	}
+	t.last, t.stuck = last, stuck
+}
+
+// stuckOnes returns those of the specified goroutines that are stuck.
+func (t *stuckTracker) stuckOnes(gs []goroutine.Goroutine) []goroutine.Goroutine {
+	var stuck []goroutine.Goroutine
+	for _, g := range gs {
+		if _, ok := t.stuck[g.ID]; ok {
+			stuck = append(stuck, g)
+		}
+	}
+	return stuck
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("stuck goroutines", func() {
+
+	const id = 1 << 62
+
+	It("tracks stuck goroutines across polls", func() {
+		t := stuckTracker{}
+		t.update([]goroutine.Goroutine{
+			{ID: id + 1, TopFunction: "foo.a", State: "chan receive, 1 minutes"},
+			{ID: id + 2, TopFunction: "foo.b", State: "chan receive, 1 minutes"},
+			{ID: id + 3, TopFunction: "foo.c", State: "chan receive, 1 minutes"},
+		})
+		Expect(t.stuck).To(BeEmpty())
+
+		gs := []goroutine.Goroutine{
+			{ID: id + 1, TopFunction: "foo.a", State: "chan receive, 2 minutes"},
+			{ID: id + 2, TopFunction: "foo.b", State: "chan receive, 1 minutes"},
+			{ID: id + 3, TopFunction: "foo.c", State: "select, 2 minutes"},
+		}
+		t.update(gs)
+		Expect(t.stuckOnes(gs)).To(ConsistOf(HaveField("ID", uint64(id+1))))
+
+		// stays stuck as long as it doesn't change.
+		t.update(gs)
+		Expect(t.stuckOnes(gs)).To(ConsistOf(HaveField("ID", uint64(id+1))))
+
+		gs[0].TopFunction = "foo.aa"
+		t.update(gs)
+		Expect(t.stuckOnes(gs)).To(BeEmpty())
+	})
+
+	It("reports stuck goroutines of a monitor", func() {
+		m := NewMonitor(0)
+		m.stuck.update([]goroutine.Goroutine{{ID: id + 1, TopFunction: "foo.a", State: "sleep, 1 minutes"}})
+		m.stuck.update([]goroutine.Goroutine{{ID: id + 1, TopFunction: "foo.a", State: "sleep, 2 minutes"}})
+		Expect(m.Stuck()).To(ConsistOf(HaveField("ID", uint64(id+1))))
+	})
+
+	It("reports stuck goroutines in failure messages", func() {
+		m := HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{
+			{ID: id + 1, TopFunction: "foo.a", State: "sync.Cond.Wait, 1 minutes"}})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("Stuck goroutines"))
+		Expect(m.Match([]goroutine.Goroutine{
+			{ID: id + 1, TopFunction: "foo.a", State: "sync.Cond.Wait, 2 minutes"}})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring(
+			"\nStuck goroutines (unchanged while waiting increasingly longer):\n" +
+				"    goroutine 4611686018427387905 [sync.Cond.Wait, 2 minutes]\n"))
+	})
+
+})
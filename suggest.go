@@ -0,0 +1,62 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"strings"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// suggestFilters returns descriptions of filter matchers that would ignore the
+// specified leaked goroutines, without any duplicates. For each leaked
+// goroutine, suggestFilters suggests ignoring its top function and its creator
+// function. Top functions that are function literals ("closures"), such as
+// "foo.bar.func1", get suggested in the more stable prefix form "foo.bar...",
+// as the numbering of function literals changes easily.
+func suggestFilters(leaked []goroutine.Goroutine) []string {
+	suggestions := []string{}
+	seen := map[string]struct{}{}
+	suggest := func(suggestion string) {
+		if _, ok := seen[suggestion]; ok {
+			return
+		}
+		seen[suggestion] = struct{}{}
+		suggestions = append(suggestions, suggestion)
+	}
+	for _, g := range leaked {
+		if g.TopFunction != "" {
+			suggest(describeFilter(IgnoringTopFunction(closureOwner(g.TopFunction))))
+		}
+		if g.CreatorFunction != "" {
+			suggest(describeFilter(IgnoringCreator(g.CreatorFunction)))
+		}
+	}
+	return suggestions
+}
+
+// closureOwner returns the name of the function owning the specified function
+// literal in prefix form, such as "foo.bar..." for "foo.bar.func1.2". Other
+// function names are returned unchanged.
+func closureOwner(fname string) string {
+	pkgIdx := strings.LastIndex(fname, "/") + 1
+	if funcIdx := strings.Index(fname[pkgIdx:], ".func"); funcIdx > 0 {
+		owner := fname[:pkgIdx+funcIdx]
+		if strings.Contains(owner[pkgIdx:], ".") {
+			return owner + "..."
+		}
+	}
+	return fname
+}
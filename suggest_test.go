@@ -0,0 +1,64 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("suggesting filters", func() {
+
+	DescribeTable("closure owners",
+		func(fname, expected string) {
+			Expect(closureOwner(fname)).To(Equal(expected))
+		},
+		Entry(nil, "foo.bar", "foo.bar"),
+		Entry(nil, "foo.bar.func1", "foo.bar..."),
+		Entry(nil, "foo.(*Bar).run.func2.1", "foo.(*Bar).run..."),
+		Entry(nil, "example.org/function.bar", "example.org/function.bar"),
+		Entry(nil, "main.func1", "main.func1"),
+	)
+
+	It("suggests filters without duplicates", func() {
+		Expect(suggestFilters([]goroutine.Goroutine{
+			{ID: 1, TopFunction: "foo.bar.func1", CreatorFunction: "foo.Start"},
+			{ID: 2, TopFunction: "foo.bar.func1", CreatorFunction: "foo.Start"},
+			{ID: 3, TopFunction: "foo.baz"},
+		})).To(Equal([]string{
+			`IgnoringTopFunction("foo.bar...")`,
+			`IgnoringCreator("foo.Start")`,
+			`IgnoringTopFunction("foo.baz")`,
+		}))
+	})
+
+	It("appends suggestions to failure messages", func() {
+		defer func(old bool) { SuggestFilters = old }(SuggestFilters)
+		gs := []goroutine.Goroutine{{ID: 1<<62 + 1, State: "select", TopFunction: "foo.bar", CreatorFunction: "foo.Start"}}
+		m := HaveLeaked()
+		Expect(m.Match(gs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(gs)).To(ContainSubstring(
+			"\nIf these goroutines are expected, consider ignoring them using:\n" +
+				"    IgnoringTopFunction(\"foo.bar\")\n    IgnoringCreator(\"foo.Start\")"))
+		SuggestFilters = false
+		Expect(m.NegatedFailureMessage(gs)).To(ContainSubstring("consider ignoring"),
+			"settings taken when created")
+		m = HaveLeaked()
+		Expect(m.Match(gs)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(gs)).NotTo(ContainSubstring("consider ignoring"))
+	})
+
+})
@@ -0,0 +1,124 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/thediveo/noleak/report"
+)
+
+// SummaryFile specifies an optional file VerifyTestMain writes a
+// machine-readable JSON leak summary to when the test binary exits, with the
+// total number of failed leak checks and leaked goroutines, as well as the
+// leaks per fingerprint and per test (see report.LeakReport). CI steps then
+// can gate merges on the leak counts. The summary file is written even if no
+// goroutines leaked. SummaryFile can also be set using the "-noleak.summary"
+// go test flag. The tests in the summary carry the import path of the package
+// under test, so that report.Merge keeps same-named tests, such as TestMain,
+// from different test binaries apart.
+var SummaryFile = ""
+
+// exitSummary accumulates all leaks reported to the leak hooks for writing the
+// summary file at exit, attributing them to the package under test.
+var exitSummary = report.Summary{Package: binaryPackage()}
+
+// binaryPackage returns the import path of the package under test when running
+// a test binary, otherwise of the main package; or "" if unknown.
+func binaryPackage() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(info.Path, ".test")
+}
+
+// testingM is the subset of *testing.M used by VerifyTestMain.
+type testingM interface {
+	Run() int
+}
+
+// VerifyTestMain runs the tests of a test binary, then checks for goroutines
+// leaked by the tests as a whole, and finally exits the test binary.
+// VerifyTestMain records the init-time baseline before running the tests (see
+// BaselineInit), so goroutines started by package init functions don't count
+// as leaks. When the tests passed but leaked goroutines, VerifyTestMain prints
+// the leaked goroutines and exits with exit code 1.
+//
+//   func TestMain(m *testing.M) {
+//       noleak.VerifyTestMain(m, noleak.CheckOptions{})
+//   }
+//
+// VerifyTestMain registers noleak's go test flags (see RegisterFlags) before
+// running the tests.
+//
+// If SummaryFile is set, VerifyTestMain writes the summary of all leaks
+// reported during the test binary run to this file just before exiting,
+// including the leaks reported by HaveLeaked, Check, testingnoleak.VerifyTest,
+// and ginkgonoleak.Guard.
+func VerifyTestMain(m testingM, opts CheckOptions) {
+	RegisterFlags(nil)
+	os.Exit(verifyTestMain(m, opts, os.Stderr))
+}
+
+// verifyTestMain implements VerifyTestMain, returning the exit code instead of
+// exiting and reporting to the specified writer.
+func verifyTestMain(m testingM, opts CheckOptions, w io.Writer) int {
+	BaselineInit()
+	code := m.Run()
+	if code == 0 {
+		if opts.Test == "" {
+			opts.Test = "TestMain"
+		}
+		if _, err := Check(nil, opts); err != nil {
+			fmt.Fprintf(w, "noleak: %s\n", err)
+			code = 1
+		}
+	}
+	if err := WriteSummary(); err != nil {
+		fmt.Fprintf(w, "noleak: %s\n", err)
+	}
+	return code
+}
+
+// WriteSummary writes the summary of all leaks reported so far to SummaryFile
+// in JSON format, unless SummaryFile is empty. VerifyTestMain and
+// ginkgonoleak.RunLeakCheckedSpecs call WriteSummary when done, so it usually
+// doesn't need to be called explicitly.
+func WriteSummary() error {
+	if SummaryFile == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(SummaryFile), 0o755); err != nil {
+		return fmt.Errorf("cannot write leak summary: %w", err)
+	}
+	f, err := os.Create(SummaryFile)
+	if err != nil {
+		return fmt.Errorf("cannot write leak summary: %w", err)
+	}
+	if err := exitSummary.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot write leak summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+	"github.com/thediveo/noleak/report"
+)
+
+type fakeM func() int
+
+func (m fakeM) Run() int { return m() }
+
+var _ = Describe("exit summary", func() {
+
+	BeforeEach(func() {
+		oldSummaryFile, oldInit := SummaryFile, initBaseline.goroutines
+		exitSummary = report.Summary{Package: binaryPackage()}
+		DeferCleanup(func() {
+			SummaryFile, initBaseline.goroutines = oldSummaryFile, oldInit
+			exitSummary = report.Summary{Package: binaryPackage()}
+		})
+	})
+
+	It("attributes leaks to tests", func() {
+		var events []LeakEvent
+		DeferCleanup(AddLeakHook(func(e LeakEvent) { events = append(events, e) }))
+		leaked := []goroutine.Goroutine{{ID: 1 << 62, TopFunction: "foo.bar"}}
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		m.test = "TestFoo"
+		Expect(m.Match(leaked)).To(BeTrue())
+		_ = m.NegatedFailureMessage(nil)
+		Expect(events).To(ConsistOf(HaveField("Test", "TestFoo")))
+		Expect(exitSummary.Report().Tests).To(BeEmpty(), "no summary file")
+
+		SummaryFile = filepath.Join(GinkgoT().TempDir(), "leaks.json")
+		Expect(m.Match(leaked)).To(BeTrue())
+		_ = m.NegatedFailureMessage(nil)
+		Expect(exitSummary.Report().Tests).To(ConsistOf(HaveField("Test", "TestFoo")))
+	})
+
+	It("writes the summary at exit of VerifyTestMain", func() {
+		SummaryFile = filepath.Join(GinkgoT().TempDir(), "ci", "leaks.json")
+		snapshot := Goroutines()
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+		}()
+
+		var out strings.Builder
+		Expect(verifyTestMain(fakeM(func() int {
+			go worker(done)
+			return 0
+		}), CheckOptions{Timeout: 100 * time.Millisecond}, &out)).To(Equal(1))
+		Expect(out.String()).To(MatchRegexp(`^noleak: found 1 leaked goroutine`))
+
+		data, err := os.ReadFile(SummaryFile)
+		Expect(err).NotTo(HaveOccurred())
+		var r report.LeakReport
+		Expect(json.Unmarshal(data, &r)).To(Succeed())
+		Expect(r.Checks).To(Equal(1))
+		Expect(r.Leaked).To(Equal(1))
+		Expect(r.Fingerprints).To(ConsistOf(And(
+			HaveField("TopFunction", "github.com/thediveo/noleak.worker"),
+			HaveField("Tests", ConsistOf("github.com/thediveo/noleak.TestMain")))))
+		Expect(r.Tests).To(ConsistOf(And(
+			HaveField("Package", "github.com/thediveo/noleak"),
+			HaveField("Test", "TestMain"))))
+	})
+
+	It("passes on failed tests and reports summary write errors", func() {
+		SummaryFile = "/dev/null/leaks.json"
+		var out strings.Builder
+		Expect(verifyTestMain(fakeM(func() int { return 42 }), CheckOptions{}, &out)).To(Equal(42))
+		Expect(out.String()).To(HavePrefix("noleak: cannot write leak summary: "))
+	})
+
+})
@@ -0,0 +1,64 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"github.com/onsi/gomega/types"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Survivors specifies baseline goroutines that must not vanish, see
+// MustSurvive.
+type Survivors struct {
+	filters []types.GomegaMatcher
+}
+
+// MustSurvive returns a specification to be passed to HaveLeaked that flags
+// the unexpected disappearance of long-lived goroutines, such as a metrics
+// pump that crashed mid-test. All goroutines of HaveLeaked's baseline that
+// match any of the specified filter matchers must still be present, otherwise
+// HaveLeaked succeeds (and thus ShouldNot fails) even if no goroutines have
+// leaked.
+//
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot,
+//       MustSurvive(IgnoringTopFunction("metrics.(*Pump).run"))))
+//
+// Without a baseline, MustSurvive has no effect.
+func MustSurvive(filters ...types.GomegaMatcher) Survivors {
+	return Survivors{filters: filters}
+}
+
+// vanishedSurvivors returns those baseline goroutines that must survive but
+// are missing from the specified current goroutines.
+func (matcher *HaveLeakedMatcher) vanishedSurvivors(current []goroutine.Goroutine) ([]goroutine.Goroutine, error) {
+	if len(matcher.survivors) == 0 {
+		return nil, nil
+	}
+	var vanished []goroutine.Goroutine
+	for _, g := range goroutine.Compare(matcher.baselineGoroutines, current).Vanished {
+		for _, survivor := range matcher.survivors {
+			matches, err := survivor.Match(g)
+			if err != nil {
+				return nil, err
+			}
+			if matches {
+				vanished = append(vanished, g)
+				break
+			}
+		}
+	}
+	sortGoroutines(vanished)
+	return vanished, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("surviving goroutines", func() {
+
+	pump := goroutine.Goroutine{ID: 1<<62 + 1, State: "select", TopFunction: "metrics.pump", CreatorFunction: "metrics.Start"}
+	other := goroutine.Goroutine{ID: 1<<62 + 2, State: "select", TopFunction: "foo.bar", CreatorFunction: "foo.Start"}
+	baseline := []goroutine.Goroutine{pump, other}
+
+	It("flags vanished goroutines that must survive", func() {
+		m := HaveLeaked(baseline, MustSurvive(IgnoringTopFunction("metrics.pump")))
+		Expect(m.Match(baseline)).To(BeFalse())
+		Expect(m.Match([]goroutine.Goroutine{pump})).To(BeFalse())
+		Expect(m.Match([]goroutine.Goroutine{other})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(MatchRegexp(
+			`^Expected 1 goroutines not to vanish:\n    goroutine 4611686018427387905 \[select\]`))
+
+		leaked := goroutine.Goroutine{ID: 1<<62 + 3, State: "select", TopFunction: "foo.leaky"}
+		Expect(m.Match([]goroutine.Goroutine{other, leaked})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(And(
+			HavePrefix("Expected not to leak 1 goroutines:\n"),
+			MatchRegexp(`\nUnexpectedly vanished goroutines:\n    goroutine 4611686018427387905 \[select\]`)))
+	})
+
+	It("ignores survivors without a baseline", func() {
+		m := HaveLeaked(MustSurvive(IgnoringTopFunction("metrics.pump")))
+		Expect(m.Match([]goroutine.Goroutine{})).To(BeFalse())
+	})
+
+	It("returns filter errors", func() {
+		m := HaveLeaked(baseline, MustSurvive(HaveLen(1)))
+		Expect(m.Match([]goroutine.Goroutine{})).Error().To(HaveOccurred())
+	})
+
+	It("doesn't take the fast path", func() {
+		snapshot := TakeSnapshot()
+		snapshot.Goroutines = append(snapshot.Goroutines, pump)
+		Expect(LazyGoroutines{}).To(HaveLeaked(snapshot, MustSurvive(IgnoringTopFunction("metrics.pump"))))
+	})
+
+})
@@ -0,0 +1,61 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// CountingSystemGoroutines returns an option to be passed to HaveLeaked in
+// order to additionally report the number of the Go runtime's system
+// goroutines in failure messages, together with their number when the
+// matcher was created. System goroutines, such as the garbage collector's
+// background workers and the scavenger, are never considered leaked, as
+// Goroutines doesn't capture them. However, their number helps debugging
+// runaway garbage collector or scavenger behavior. Please see also
+// goroutine.CountSystemGoroutines.
+func CountingSystemGoroutines() interface{} {
+	return countingSystemGoroutines{}
+}
+
+// countingSystemGoroutines is the type of the CountingSystemGoroutines option.
+type countingSystemGoroutines struct{}
+
+// systemGoroutines counts the runtime's system goroutines, returning -1 if the
+// Go runtime doesn't support counting them.
+func systemGoroutines() int {
+	system, ok := goroutine.CountSystemGoroutines()
+	if !ok {
+		return -1
+	}
+	return system
+}
+
+// systemGoroutinesDetails returns the failure message section reporting the
+// number of runtime system goroutines, if requested using
+// CountingSystemGoroutines.
+func (matcher *HaveLeakedMatcher) systemGoroutinesDetails() string {
+	if !matcher.countSystem {
+		return ""
+	}
+	system := systemGoroutines()
+	if system < 0 || matcher.systemBaseline < 0 {
+		return "\nRuntime system goroutines: unknown, as this Go version cannot count them"
+	}
+	return fmt.Sprintf("\nRuntime system goroutines: %d (%d when creating the matcher)",
+		system, matcher.systemBaseline)
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("counting system goroutines", func() {
+
+	leak := goroutine.Goroutine{ID: 1<<62 + 1, State: "select", TopFunction: "foo.leaking"}
+
+	It("reports system goroutines only when asked to", func() {
+		m := HaveLeaked()
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("Runtime system goroutines"))
+
+		m = HaveLeaked(CountingSystemGoroutines())
+		Expect(m.Match([]goroutine.Goroutine{leak})).To(BeTrue())
+		message := m.NegatedFailureMessage(nil)
+		if _, ok := goroutine.CountSystemGoroutines(); !ok {
+			Expect(message).To(ContainSubstring("\nRuntime system goroutines: unknown"))
+			return
+		}
+		Expect(message).To(MatchRegexp(`\nRuntime system goroutines: \d+ \(\d+ when creating the matcher\)`))
+	})
+
+	It("doesn't consider system goroutines to be leaked", func() {
+		Expect(Goroutines()).NotTo(ContainElement(HaveField("IsSystem()", BeTrue())))
+	})
+
+})
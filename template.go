@@ -0,0 +1,108 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// MessageTemplate optionally renders the failure messages of HaveLeaked, such
+// as for matching an organization's triage format with links to runbooks or
+// owner mentions. The template gets passed a MessageData value. When nil, the
+// default, HaveLeaked returns its default failure messages. MessageTemplate
+// can also be loaded from a file using the "-noleak.template" go test flag.
+//
+// Please create message templates using NewMessageTemplate, in order to make
+// noleak's template functions available.
+var MessageTemplate *template.Template
+
+// MessageData is passed to the MessageTemplate when rendering a failure
+// message.
+type MessageData struct {
+	Negated     bool                  // true for unexpected leaks, false for missing leaks.
+	Count       int                   // number of leaked goroutines.
+	Leaked      []goroutine.Goroutine // leaked goroutines.
+	Vanished    []goroutine.Goroutine // unexpectedly vanished goroutines, see MustSurvive.
+	Baseline    *goroutine.Snapshot   // baseline snapshot, if any.
+	BaselineAge time.Duration         // age of the baseline snapshot, if any.
+	Message     string                // default failure message.
+}
+
+// NewMessageTemplate returns a new failure message template, parsed from the
+// specified text. In addition to the standard template functions, the
+// following functions are available:
+//
+//   list         renders goroutines in the same way as the default failure messages
+//   fingerprint  returns the fingerprint of a goroutine
+//
+// For instance:
+//
+//   {{ .Count }} leaked goroutines, see https://runbooks.example.com/leaks
+//   {{ range .Leaked }}- {{ fingerprint . }} started at {{ .BornAt }}
+//   {{ end }}
+func NewMessageTemplate(text string) (*template.Template, error) {
+	return template.New("noleak").Funcs(template.FuncMap{
+		"list": func(gs []goroutine.Goroutine) string {
+			return (&HaveLeakedMatcher{cfg: DefaultConfig()}).listGoroutines(gs, 1)
+		},
+		"fingerprint": goroutine.Goroutine.Fingerprint,
+	}).Parse(text)
+}
+
+// loadMessageTemplate reads and parses a failure message template from the
+// specified file.
+func loadMessageTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read message template: %w", err)
+	}
+	tmpl, err := NewMessageTemplate(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// render returns the failure message rendered using the configured message
+// template, if set, otherwise the specified default message. If rendering
+// fails, render returns the default message together with the rendering error.
+func (matcher *HaveLeakedMatcher) render(negated bool, message string) string {
+	tmpl := matcher.config().MessageTemplate
+	if tmpl == nil {
+		return message
+	}
+	data := MessageData{
+		Negated:  negated,
+		Count:    len(matcher.leaked),
+		Leaked:   matcher.leaked,
+		Vanished: matcher.vanished,
+		Baseline: matcher.baseline,
+		Message:  message,
+	}
+	if matcher.baseline != nil {
+		data.BaselineAge = matcher.baseline.Age()
+	}
+	var buff strings.Builder
+	if err := tmpl.Execute(&buff, data); err != nil {
+		return fmt.Sprintf("%s\n(cannot render message template: %s)", message, err)
+	}
+	return buff.String()
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"text/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("failure message templates", func() {
+
+	leaked := []goroutine.Goroutine{{
+		ID:              1 << 62,
+		State:           "chan receive",
+		TopFunction:     "foo.bar",
+		CreatorFunction: "foo.baz",
+		BornAt:          "/foo.go:42",
+	}}
+
+	BeforeEach(func() {
+		old := MessageTemplate
+		DeferCleanup(func() { MessageTemplate = old })
+	})
+
+	It("renders failure messages", func() {
+		MessageTemplate = template.Must(NewMessageTemplate(
+			`{{ if .Negated }}{{ .Count }} leaks{{ else }}no leaks{{ end }}{{ if .Baseline }} since {{ .Baseline.Count }}{{ end }}
+{{ range .Leaked }}- {{ fingerprint . }} @ {{ .BornAt }}
+{{ end }}{{ list .Leaked }}`))
+		snapshot := goroutine.Snapshot{}
+		m := HaveLeaked(snapshot)
+		Expect(m.Match(leaked)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leaked)).To(Equal(
+			"1 leaks since 0\n- " + leaked[0].Fingerprint() + " @ /foo.go:42\n    goroutine 4611686018427387904 [chan receive]\n"))
+		Expect(m.FailureMessage(leaked)).To(HavePrefix("no leaks since 0\n"))
+	})
+
+	It("passes the default message", func() {
+		MessageTemplate = template.Must(NewMessageTemplate("runbook: https://example.com\n{{ .Message }}"))
+		m := HaveLeaked()
+		Expect(m.Match(leaked)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leaked)).To(HavePrefix(
+			"runbook: https://example.com\nExpected not to leak 1 goroutines:\n"))
+	})
+
+	It("falls back to the default message on rendering errors", func() {
+		MessageTemplate = template.Must(NewMessageTemplate("{{ .Foo }}"))
+		m := HaveLeaked()
+		Expect(m.Match(leaked)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(leaked)).To(MatchRegexp(
+			`(?s)^Expected not to leak 1 goroutines:\n.*\n\(cannot render message template: .*\)$`))
+	})
+
+})
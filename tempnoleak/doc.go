@@ -0,0 +1,23 @@
+/*
+
+Package tempnoleak checks for leaked temporary files and directories, in the
+same way as package noleak checks for leaked goroutines: take a snapshot of the
+temporary directory before a test, and afterwards check that the test removed
+all the files and directories it created in the meantime.
+
+    snapshot, err := tempnoleak.TakeSnapshot()
+    Expect(err).NotTo(HaveOccurred())
+    DoSomething()
+    Eventually(snapshot.Rescan).ShouldNot(tempnoleak.HaveLeakedTempFiles(snapshot))
+
+Without any roots, TakeSnapshot lists only the top-level entries of
+os.TempDir, which also holds the directories created by testing.T.TempDir.
+Please note that os.TempDir is often shared with other processes, which might
+create temporary files of their own while a test runs, so these will show up as
+leaks too. Setting TMPDIR to a dedicated directory for the test run avoids such
+false positives. Other roots, such as the directory returned by
+testing.T.TempDir, can be passed to TakeSnapshot explicitly; TakeSnapshot then
+lists all files and directories below these roots.
+
+*/
+package tempnoleak
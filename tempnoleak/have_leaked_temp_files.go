@@ -0,0 +1,81 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package tempnoleak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// HaveLeakedTempFiles succeeds (or rather, "suckceeds") if the actual
+// Snapshot contains files or directories that are not in the specified
+// baseline snapshot. It is best paired with Eventually and the baseline's
+// Rescan method, giving files removed in the background some time:
+//
+//   Eventually(snapshot.Rescan).ShouldNot(HaveLeakedTempFiles(snapshot))
+//
+// The failure message lists the leaked files and directories, but not the
+// contents of leaked directories.
+func HaveLeakedTempFiles(baseline Snapshot) types.GomegaMatcher {
+	return &haveLeakedTempFilesMatcher{baseline: baseline}
+}
+
+type haveLeakedTempFilesMatcher struct {
+	baseline Snapshot
+	leaked   []string
+}
+
+// Match succeeds if actual is a Snapshot with files or directories not in the
+// baseline.
+func (matcher *haveLeakedTempFilesMatcher) Match(actual interface{}) (bool, error) {
+	var snapshot Snapshot
+	switch s := actual.(type) {
+	case Snapshot:
+		snapshot = s
+	case *Snapshot:
+		if s == nil {
+			return false, fmt.Errorf("HaveLeakedTempFiles matcher expects a Snapshot, but got nil")
+		}
+		snapshot = *s
+	default:
+		return false, fmt.Errorf("HaveLeakedTempFiles matcher expects a Snapshot.  Got:\n%s",
+			format.Object(actual, 1))
+	}
+	matcher.leaked = snapshot.appeared(matcher.baseline)
+	return len(matcher.leaked) > 0, nil
+}
+
+// FailureMessage returns a failure message if no temporary files or
+// directories leaked.
+func (matcher *haveLeakedTempFilesMatcher) FailureMessage(actual interface{}) string {
+	return "Expected to leak temporary files or directories below " +
+		strings.Join(matcher.baseline.Roots, ", ")
+}
+
+// NegatedFailureMessage returns a failure message if temporary files or
+// directories leaked.
+func (matcher *haveLeakedTempFilesMatcher) NegatedFailureMessage(actual interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Expected not to leak %d temporary files or directories:",
+		len(matcher.leaked))
+	for _, path := range matcher.leaked {
+		b.WriteString("\n    ")
+		b.WriteString(path)
+	}
+	return b.String()
+}
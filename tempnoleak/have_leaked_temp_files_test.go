@@ -0,0 +1,102 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package tempnoleak
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HaveLeakedTempFiles matcher", func() {
+
+	var root string
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp("", "tempnoleak-*")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() { _ = os.RemoveAll(root) })
+		Expect(os.WriteFile(filepath.Join(root, "old"), nil, 0o600)).To(Succeed())
+	})
+
+	It("rejects invalid actual values", func() {
+		m := HaveLeakedTempFiles(Snapshot{})
+		Expect(m.Match(nil)).Error().To(MatchError(ContainSubstring("expects a Snapshot")))
+		Expect(m.Match((*Snapshot)(nil))).Error().To(MatchError(ContainSubstring("got nil")))
+		Expect(m.Match(42)).Error().To(MatchError(ContainSubstring("expects a Snapshot")))
+	})
+
+	It("defaults to the temporary directory", func() {
+		snapshot, err := TakeSnapshot()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.Roots).To(HaveLen(1))
+		Expect(snapshot.Shallow).To(BeTrue())
+		Expect(snapshot.Paths).To(ContainElement(root + string(filepath.Separator)))
+		Expect(snapshot.Paths).NotTo(ContainElement(filepath.Join(root, "old")), "lists only top-level entries")
+		Expect(snapshot.Taken).NotTo(BeZero())
+
+		rescan, err := snapshot.Rescan()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rescan.Shallow).To(BeTrue())
+		Expect(rescan.Paths).NotTo(ContainElement(filepath.Join(root, "old")))
+	})
+
+	It("fails for non-existing roots", func() {
+		Expect(TakeSnapshot(filepath.Join(root, "nada"))).Error().To(HaveOccurred())
+	})
+
+	It("doesn't report files existing in the baseline or removed", func() {
+		Expect(os.WriteFile(filepath.Join(root, "gone"), nil, 0o600)).To(Succeed())
+		snapshot, err := TakeSnapshot(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.Paths).To(Equal([]string{
+			filepath.Join(root, "gone"),
+			filepath.Join(root, "old"),
+		}))
+		Expect(os.Remove(filepath.Join(root, "gone"))).To(Succeed())
+		Expect(snapshot.Rescan()).NotTo(HaveLeakedTempFiles(snapshot))
+	})
+
+	It("reports leaked files and directories", func() {
+		snapshot, err := TakeSnapshot(root)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(root, "new"), nil, 0o600)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(root, "dir", "sub"), 0o700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "dir", "sub", "file"), nil, 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "dir-file"), nil, 0o600)).To(Succeed())
+
+		actual, err := snapshot.Rescan()
+		Expect(err).NotTo(HaveOccurred())
+		m := HaveLeakedTempFiles(snapshot)
+		Expect(m.Match(&actual)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(actual)).To(Equal(
+			"Expected not to leak 3 temporary files or directories:" +
+				"\n    " + filepath.Join(root, "dir-file") +
+				"\n    " + filepath.Join(root, "dir") + string(filepath.Separator) +
+				"\n    " + filepath.Join(root, "new")))
+		Expect(m.FailureMessage(actual)).To(Equal(
+			"Expected to leak temporary files or directories below " + root))
+
+		Expect(os.RemoveAll(filepath.Join(root, "dir"))).To(Succeed())
+		Expect(os.Remove(filepath.Join(root, "dir-file"))).To(Succeed())
+		Expect(os.Remove(filepath.Join(root, "new"))).To(Succeed())
+		Eventually(snapshot.Rescan).ShouldNot(HaveLeakedTempFiles(snapshot))
+	})
+
+})
@@ -0,0 +1,149 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package tempnoleak
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Snapshot lists the files and directories below a set of root directories at
+// a particular moment.
+type Snapshot struct {
+	Roots   []string  // absolute paths of the root directories.
+	Paths   []string  // sorted paths below the roots, directories end in a path separator.
+	Shallow bool      // only the top-level entries of the roots are listed.
+	Taken   time.Time // when the snapshot was taken.
+}
+
+// TakeSnapshot returns a snapshot of the files and directories below the
+// specified root directories. Directories that cannot be read are skipped, but
+// TakeSnapshot returns an error if a root cannot be read.
+//
+// If no roots are specified, TakeSnapshot only lists the top-level entries of
+// os.TempDir, where leaked temporary files and directories appear, as walking
+// the often huge temporary directory trees of CI systems, such as build
+// caches, would make each snapshot very slow.
+func TakeSnapshot(roots ...string) (Snapshot, error) {
+	if len(roots) == 0 {
+		return takeSnapshot(true, os.TempDir())
+	}
+	return takeSnapshot(false, roots...)
+}
+
+// takeSnapshot returns a snapshot of either only the top-level entries of the
+// specified roots, or all files and directories below them.
+func takeSnapshot(shallow bool, roots ...string) (Snapshot, error) {
+	snapshot := Snapshot{Roots: make([]string, 0, len(roots)), Shallow: shallow}
+	for _, root := range roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		if shallow {
+			err = snapshot.list(root)
+		} else {
+			err = snapshot.walk(root)
+		}
+		if err != nil {
+			return Snapshot{}, err
+		}
+		snapshot.Roots = append(snapshot.Roots, root)
+	}
+	sort.Strings(snapshot.Paths)
+	snapshot.Taken = time.Now()
+	return snapshot, nil
+}
+
+// Rescan returns a new snapshot of the same roots as this snapshot, and as
+// shallow as this snapshot, so that it can be used with Eventually:
+//
+//   Eventually(snapshot.Rescan).ShouldNot(HaveLeakedTempFiles(snapshot))
+func (s Snapshot) Rescan() (Snapshot, error) {
+	return takeSnapshot(s.Shallow, s.Roots...)
+}
+
+// list adds the paths of the top-level files and directories of the specified
+// root to the snapshot.
+func (s *Snapshot) list(root string) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if entry.IsDir() {
+			path += string(filepath.Separator)
+		}
+		s.Paths = append(s.Paths, path)
+	}
+	return nil
+}
+
+// walk adds the paths of all files and directories below the specified root
+// to the snapshot.
+func (s *Snapshot) walk(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Files and directories might vanish while walking, or might not
+			// be readable, so skip them.
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if entry.IsDir() {
+			path += string(filepath.Separator)
+		}
+		s.Paths = append(s.Paths, path)
+		return nil
+	})
+}
+
+// appeared returns the paths in this snapshot that are not in the baseline.
+// For new directories, only the directories themselves are returned, but not
+// their contents.
+func (s Snapshot) appeared(baseline Snapshot) []string {
+	known := make(map[string]struct{}, len(baseline.Paths))
+	for _, path := range baseline.Paths {
+		known[path] = struct{}{}
+	}
+	var fresh []string
+	newDir := ""
+	for _, path := range s.Paths {
+		if _, ok := known[path]; ok {
+			continue
+		}
+		// As the paths are sorted, the contents of a new directory
+		// immediately follow the directory itself.
+		if newDir != "" && len(path) > len(newDir) && path[:len(newDir)] == newDir {
+			continue
+		}
+		fresh = append(fresh, path)
+		if os.IsPathSeparator(path[len(path)-1]) {
+			newDir = path
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,43 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package testingnoleak checks tests using just Go's testing package, or
+testify, for leaked goroutines. It builds on noleak's Check API, so projects
+don't need Gomega assertions in their tests. VerifyNone fails a test if it
+leaked goroutines:
+
+    func TestFoo(t *testing.T) {
+        snapshot := noleak.Goroutines()
+        defer testingnoleak.VerifyNone(t, snapshot, noleak.CheckOptions{})
+        ...
+    }
+
+VerifyTest checks a (sub)test for leaked goroutines when it has finished,
+while Run replaces t.Run in order to check whole hierarchies of subtests,
+attributing leaks to the deepest subtest in whose window they appeared.
+
+CheckFuzz checks the iterations of fuzz targets for leaked goroutines in
+batches of FuzzCheckInterval iterations.
+
+ReportOnTimeout reports stuck goroutines shortly before a test times out, as
+these are most probably the culprits of hung tests.
+
+The testing.TB helpers live in their own package, so that importing noleak
+doesn't import Go's testing package (and thus register its test flags) into
+non-test binaries, such as when using noleak's Monitor in production.
+
+*/
+package testingnoleak
@@ -0,0 +1,106 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// FuzzCheckInterval is the number of fuzz iterations after which CheckFuzz
+// checks for leaked goroutines. Checking only every so many iterations keeps
+// the corpus execution time in check.
+var FuzzCheckInterval = 100
+
+// CheckFuzz returns a function to be called at the beginning of each iteration
+// of a fuzz target in order to check that the iterations don't leak
+// goroutines. Instead of checking after each and every iteration, CheckFuzz
+// takes a baseline snapshot at the beginning of a batch of FuzzCheckInterval
+// iterations and then checks for leaked goroutines at the end of the batch
+// (or when fuzzing ends), so leaks are detected with the batch granularity.
+// Leaked goroutines are given noleak.SettleTimeout to end, if set.
+//
+//   func FuzzFoo(f *testing.F) {
+//       leakcheck := testingnoleak.CheckFuzz(f)
+//       f.Fuzz(func(t *testing.T, data []byte) {
+//           leakcheck(t)
+//           ...
+//       })
+//   }
+func CheckFuzz(f *testing.F) func(t *testing.T) {
+	check := newFuzzChecker(f, FuzzCheckInterval)
+	return func(t *testing.T) {
+		t.Helper()
+		check(t)
+	}
+}
+
+// fuzzT is the subset of testing.F and testing.T functionality needed for
+// checking fuzz iterations.
+type fuzzT interface {
+	Cleanup(func())
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// newFuzzChecker returns a function to be called at the beginning of each
+// fuzz iteration, batching leak checks every interval iterations. Any pending
+// batch gets checked when the fuzz test f finally cleans up.
+func newFuzzChecker(f fuzzT, interval int) func(t fuzzT) {
+	if interval < 1 {
+		interval = 1
+	}
+	var mu sync.Mutex
+	iterations := 0
+	var baseline *goroutine.Snapshot
+	check := func(t fuzzT) {
+		t.Helper()
+		mu.Lock()
+		snapshot := baseline
+		baseline = nil
+		mu.Unlock()
+		if snapshot == nil {
+			return
+		}
+		eventually := gomega.NewWithT(t).Eventually(noleak.Goroutines)
+		if noleak.SettleTimeout > 0 {
+			eventually = eventually.WithTimeout(noleak.SettleTimeout)
+		}
+		eventually.ShouldNot(noleak.HaveLeaked(snapshot), "fuzz iterations leaked goroutines")
+	}
+	f.Cleanup(func() { check(f) })
+	return func(t fuzzT) {
+		t.Helper()
+		mu.Lock()
+		if baseline == nil {
+			snapshot := goroutine.TakeSnapshot()
+			baseline = &snapshot
+		}
+		mu.Unlock()
+		t.Cleanup(func() {
+			mu.Lock()
+			iterations++
+			due := iterations%interval == 0
+			mu.Unlock()
+			if due {
+				check(t)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/thediveo/noleak"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeFuzzT records failures and runs its cleanups on request.
+type fakeFuzzT struct {
+	cleanups []func()
+	failures []string
+}
+
+func (t *fakeFuzzT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeFuzzT) Helper()          {}
+
+func (t *fakeFuzzT) Fatalf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeFuzzT) cleanup() {
+	for idx := len(t.cleanups) - 1; idx >= 0; idx-- {
+		t.cleanups[idx]()
+	}
+	t.cleanups = nil
+}
+
+// FuzzCheckFuzz exercises CheckFuzz using the seed corpus.
+func FuzzCheckFuzz(f *testing.F) {
+	for i := 0; i < 5; i++ {
+		f.Add(i)
+	}
+	leakcheck := CheckFuzz(f)
+	f.Fuzz(func(t *testing.T, i int) {
+		leakcheck(t)
+		done := make(chan struct{})
+		go func() { <-done }()
+		close(done)
+	})
+}
+
+var _ = Describe("fuzz checks", func() {
+
+	It("checks in batches and at the end", func() {
+		snapshot := noleak.Goroutines()
+		defer Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked(snapshot))
+		f := &fakeFuzzT{}
+		iteration := newFuzzChecker(f, 2)
+
+		t := &fakeFuzzT{}
+		iteration(t)
+		t.cleanup()
+		Expect(t.failures).To(BeEmpty())
+
+		done := make(chan struct{})
+		go func() { <-done }()
+		t = &fakeFuzzT{}
+		iteration(t)
+		t.cleanup()
+		Expect(t.failures).To(ConsistOf(ContainSubstring("fuzz iterations leaked goroutines")))
+
+		// The leaked goroutine is part of the next batch's baseline.
+		t = &fakeFuzzT{}
+		iteration(t)
+		t.cleanup()
+		close(done)
+		f.cleanup()
+		Expect(f.failures).To(BeEmpty())
+	})
+
+	It("reports leaks of a pending batch when fuzzing ends", func() {
+		f := &fakeFuzzT{}
+		iteration := newFuzzChecker(f, 0)
+		Expect(f.cleanups).To(HaveLen(1))
+
+		snapshot := noleak.Goroutines()
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked(snapshot))
+		}()
+		t := &fakeFuzzT{}
+		iteration(t)
+		go func() { <-done }()
+		// Simulate fuzzing ending without the iteration cleaning up.
+		f.cleanup()
+		Expect(f.failures).To(ConsistOf(ContainSubstring("fuzz iterations leaked goroutines")))
+	})
+
+})
@@ -0,0 +1,172 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/onsi/gomega/format"
+	"github.com/thediveo/noleak"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// VerifyTest takes a baseline of the current goroutines for the (sub)test t
+// and checks for leaked goroutines when t and all its subtests have finished,
+// failing t if there are leaks. VerifyTest must be called from t's own
+// goroutine, typically as the first statement in a (sub)test. For whole
+// hierarchies of subtests, use Run instead of t.Run.
+//
+// Leaks get attributed to the deepest (sub)test in whose window they
+// appeared: as subtests finish before their parents, leaks reported by a
+// subtest aren't reported again by its parent tests. In addition, goroutines
+// known to have been started (directly or indirectly) from the goroutine of a
+// different verified test are left to that test to report; this helps with
+// attributing leaks correctly when parallel subtests have overlapping windows.
+// Go 1.21 or later is required for this, as older Go versions don't report
+// creator goroutine IDs.
+func VerifyTest(t testing.TB, opts noleak.CheckOptions) {
+	t.Helper()
+	name := t.Name()
+	verifiedTests.register(goroutine.Current().ID, name)
+	snapshot := noleak.Goroutines()
+	t.Cleanup(func() {
+		t.Helper()
+		opts := opts
+		opts.Test = name
+		opts.Ignoring = append(append([]interface{}{}, opts.Ignoring...),
+			&foreignLeaksMatcher{test: name, goroutines: goroutinesByID(noleak.Goroutines())})
+		report, err := noleak.Check(snapshot, opts)
+		verifiedTests.attribute(report.Leaked)
+		if err != nil {
+			t.Errorf("test %s: %s", name, err)
+		}
+	})
+}
+
+// Run runs f as a subtest of t named name, like t.Run, but additionally
+// verifies that the subtest doesn't leak goroutines using VerifyTest. Calling
+// Run again inside f instead of t.Run covers whole hierarchies of subtests,
+// including parallel subtests.
+//
+//   func TestFoo(t *testing.T) {
+//       testingnoleak.Run(t, "bar", func(t *testing.T) {
+//           t.Parallel()
+//           testingnoleak.Run(t, "baz", func(t *testing.T) { ... }, noleak.CheckOptions{})
+//       }, noleak.CheckOptions{})
+//   }
+func Run(t *testing.T, name string, f func(t *testing.T), opts noleak.CheckOptions) bool {
+	t.Helper()
+	return t.Run(name, func(t *testing.T) {
+		VerifyTest(t, opts)
+		f(t)
+	})
+}
+
+// verifiedTests keeps track of the goroutines of verified tests and of the
+// leaked goroutines that have already been attributed to tests.
+var verifiedTests = testRegistry{
+	tests:      map[uint64]string{},
+	attributed: map[uint64]struct{}{},
+}
+
+type testRegistry struct {
+	mu         sync.Mutex
+	tests      map[uint64]string   // goroutine IDs of verified tests to test names.
+	attributed map[uint64]struct{} // IDs of leaked goroutines already attributed to tests.
+}
+
+// register registers the goroutine with the specified ID to run the named
+// test.
+func (r *testRegistry) register(goid uint64, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tests[goid] = name
+}
+
+// attribute records the specified leaked goroutines as having been attributed
+// to a test.
+func (r *testRegistry) attribute(leaked []goroutine.Goroutine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range leaked {
+		r.attributed[g.ID] = struct{}{}
+	}
+}
+
+// foreign returns true if the specified goroutine has already been attributed
+// to a test, or if it is or has been started from the goroutine of a verified
+// test other than the specified test or one of its subtests. The specified
+// goroutines are used to follow the chain of creators.
+func (r *testRegistry) foreign(g goroutine.Goroutine, test string, goroutines map[uint64]goroutine.Goroutine) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.attributed[g.ID]; ok {
+		return true
+	}
+	seen := map[uint64]struct{}{}
+	for creatorID := g.ID; creatorID != 0; {
+		if _, ok := seen[creatorID]; ok {
+			break
+		}
+		seen[creatorID] = struct{}{}
+		if owner, ok := r.tests[creatorID]; ok {
+			return owner != test && !strings.HasPrefix(owner, test+"/")
+		}
+		creator, ok := goroutines[creatorID]
+		if !ok {
+			break
+		}
+		creatorID = creator.CreatorID
+	}
+	return false
+}
+
+// goroutinesByID returns the specified goroutines indexed by their IDs.
+func goroutinesByID(gs []goroutine.Goroutine) map[uint64]goroutine.Goroutine {
+	m := make(map[uint64]goroutine.Goroutine, len(gs))
+	for _, g := range gs {
+		m[g.ID] = g
+	}
+	return m
+}
+
+// foreignLeaksMatcher succeeds for goroutines that are not to be reported by
+// the verified test, see testRegistry.foreign.
+type foreignLeaksMatcher struct {
+	test       string
+	goroutines map[uint64]goroutine.Goroutine
+}
+
+func (matcher *foreignLeaksMatcher) Match(actual interface{}) (success bool, err error) {
+	g, err := noleak.G(actual, "foreignLeaks")
+	if err != nil {
+		return false, err
+	}
+	return verifiedTests.foreign(g, matcher.test, matcher.goroutines), nil
+}
+
+func (matcher *foreignLeaksMatcher) FailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, "to be left to other tests than", matcher.test)
+}
+
+func (matcher *foreignLeaksMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return format.Message(actual, "not to be left to other tests than", matcher.test)
+}
+
+func (matcher *foreignLeaksMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return false
+}
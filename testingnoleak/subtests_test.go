@@ -0,0 +1,99 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thediveo/noleak"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("verifying subtests", func() {
+
+	opts := noleak.CheckOptions{Timeout: 50 * time.Millisecond}
+
+	// inTestGoroutine runs the specified function in a separate goroutine,
+	// acting as the goroutine of a (sub)test, and waits for it to return.
+	inTestGoroutine := func(f func()) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f()
+		}()
+		<-done
+	}
+
+	var snapshot []interface{}
+	var stop chan struct{}
+
+	BeforeEach(func() {
+		snapshot = []interface{}{noleak.Goroutines()}
+		stop = make(chan struct{})
+		DeferCleanup(func() {
+			close(stop)
+			Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked(snapshot...))
+		})
+	})
+
+	It("attributes leaks to the deepest subtest", func() {
+		parent := &fakeTB{name: "TestFoo"}
+		child := &fakeTB{name: "TestFoo/bar"}
+		inTestGoroutine(func() {
+			VerifyTest(parent, opts)
+			inTestGoroutine(func() {
+				VerifyTest(child, opts)
+				go worker(stop)
+			})
+		})
+		child.cleanup()
+		parent.cleanup()
+		Expect(child.errors).To(ConsistOf(HavePrefix("test TestFoo/bar: found 1 leaked goroutine")))
+		Expect(parent.errors).To(BeEmpty())
+	})
+
+	It("leaves leaks of parallel siblings to them", func() {
+		x := &fakeTB{name: "TestFoo/x"}
+		y := &fakeTB{name: "TestFoo/y"}
+		inTestGoroutine(func() { VerifyTest(y, opts) })
+		inTestGoroutine(func() {
+			VerifyTest(x, opts)
+			go worker(stop)
+		})
+		y.cleanup()
+		x.cleanup()
+		Expect(y.errors).To(BeEmpty())
+		Expect(x.errors).To(ConsistOf(HavePrefix("test TestFoo/x: found 1 leaked goroutine")))
+	})
+
+})
+
+// TestRun checks Run using a real subtest hierarchy, including parallel
+// subtests, that must not leak.
+func TestRun(t *testing.T) {
+	for _, name := range []string{"foo", "bar"} {
+		Run(t, name, func(t *testing.T) {
+			t.Parallel()
+			Run(t, "baz", func(t *testing.T) {
+				done := make(chan struct{})
+				go worker(done)
+				close(done)
+			}, noleak.CheckOptions{Timeout: time.Second})
+		}, noleak.CheckOptions{})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/thediveo/noleak"
+)
+
+// TimeoutMargin is how long before the deadline of a test ReportOnTimeout
+// reports the goroutines, as there is no way to intercept Go's testing package
+// when it finally panics on a timed out test.
+var TimeoutMargin = time.Second
+
+// timeoutOutput is where ReportOnTimeout writes its reports to.
+var timeoutOutput io.Writer = os.Stderr
+
+// deadliner is implemented by *testing.T, telling the deadline of a test
+// binary as set using "go test -timeout".
+type deadliner interface {
+	Deadline() (time.Time, bool)
+}
+
+// ReportOnTimeout arranges for a report of the goroutines not attributable to
+// the testing framework, Go's runtime, or any of the specified goroutines to
+// ignore, to be written to os.Stderr shortly (TimeoutMargin) before the test t
+// times out, as set using "go test -timeout". As a hung test is almost always
+// caused by a stuck goroutine, the report often points directly to the culprit
+// that otherwise would be drowned in the full goroutine dump of Go's testing
+// package. The goroutines to ignore are specified in the same way as with
+// noleak.HaveLeaked.
+//
+//   func TestFoo(t *testing.T) {
+//       testingnoleak.ReportOnTimeout(t, noleak.Goroutines())
+//       ...
+//   }
+//
+// When t finishes in time, no report is written. ReportOnTimeout does nothing
+// if t has no deadline.
+func ReportOnTimeout(t testing.TB, ignoring ...interface{}) {
+	d, ok := t.(deadliner)
+	if !ok {
+		return
+	}
+	deadline, ok := d.Deadline()
+	if !ok {
+		return
+	}
+	reporter := noleak.ProgressReporter(ignoring...)
+	name := t.Name()
+	timer := time.AfterFunc(time.Until(deadline.Add(-TimeoutMargin)), func() {
+		fmt.Fprintf(timeoutOutput, "test %s is about to time out at %s\n%s\n",
+			name, deadline.Format(time.RFC3339), reporter())
+	})
+	t.Cleanup(func() { timer.Stop() })
+}
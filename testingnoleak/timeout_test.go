@@ -0,0 +1,86 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/thediveo/noleak"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// deadlineT is a testing.TB with a deadline, running its cleanups only when
+// told so.
+type deadlineT struct {
+	testing.TB
+	deadline time.Time
+	cleanups []func()
+}
+
+func (t *deadlineT) Name() string                { return "TestFoo" }
+func (t *deadlineT) Deadline() (time.Time, bool) { return t.deadline, !t.deadline.IsZero() }
+func (t *deadlineT) Cleanup(f func())            { t.cleanups = append(t.cleanups, f) }
+
+func (t *deadlineT) cleanup() {
+	for _, f := range t.cleanups {
+		f()
+	}
+}
+
+var _ = Describe("reporting on timeouts", func() {
+
+	var buff *gbytes.Buffer
+
+	BeforeEach(func() {
+		buff = gbytes.NewBuffer()
+		oldOutput, oldMargin := timeoutOutput, TimeoutMargin
+		timeoutOutput, TimeoutMargin = buff, 0
+		DeferCleanup(func() { timeoutOutput, TimeoutMargin = oldOutput, oldMargin })
+	})
+
+	It("reports hung goroutines before the test times out", func() {
+		snapshot := noleak.Goroutines()
+		done := make(chan struct{})
+		go func() { <-done }()
+		defer func() {
+			close(done)
+			Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked(snapshot))
+		}()
+
+		t := &deadlineT{deadline: time.Now().Add(50 * time.Millisecond)}
+		ReportOnTimeout(t, snapshot)
+		defer t.cleanup()
+		Eventually(buff).Should(gbytes.Say(
+			`test TestFoo is about to time out at .*\nnoleak: 1 goroutine not attributable .*:\n    goroutine \d+ \[chan receive\]`))
+	})
+
+	It("doesn't report tests finishing in time", func() {
+		t := &deadlineT{deadline: time.Now().Add(50 * time.Millisecond)}
+		ReportOnTimeout(t)
+		t.cleanup()
+		Consistently(buff, 100*time.Millisecond).ShouldNot(gbytes.Say("."))
+	})
+
+	It("ignores tests without deadline", func() {
+		t := &deadlineT{}
+		ReportOnTimeout(t)
+		Expect(t.cleanups).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,36 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"testing"
+
+	"github.com/thediveo/noleak"
+)
+
+// VerifyNone fails the test t if noleak.Check finds leaked goroutines, given
+// the specified baseline and options. For instance:
+//
+//   func TestFoo(t *testing.T) {
+//       snapshot := noleak.Goroutines()
+//       defer testingnoleak.VerifyNone(t, snapshot, noleak.CheckOptions{})
+//       ...
+//   }
+func VerifyNone(t testing.TB, baseline interface{}, opts noleak.CheckOptions) {
+	t.Helper()
+	if _, err := noleak.Check(baseline, opts); err != nil {
+		t.Error(err)
+	}
+}
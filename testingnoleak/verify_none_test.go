@@ -0,0 +1,86 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testingnoleak
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/thediveo/noleak"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeTB records the errors reported by VerifyNone and VerifyTest, as well as
+// registered cleanups; all other testing.TB methods aren't used and thus left
+// unimplemented.
+type fakeTB struct {
+	testing.TB
+	name     string
+	errors   []string
+	cleanups []func()
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Name() string { return t.name }
+
+func (t *fakeTB) Error(args ...interface{}) { t.errors = append(t.errors, fmt.Sprint(args...)) }
+
+func (t *fakeTB) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeTB) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+// cleanup runs the registered cleanups in reverse order of registration.
+func (t *fakeTB) cleanup() {
+	for idx := len(t.cleanups) - 1; idx >= 0; idx-- {
+		t.cleanups[idx]()
+	}
+	t.cleanups = nil
+}
+
+// worker is the top function of the goroutines started in the tests, in order
+// to have an easily identifiable leak.
+func worker(done <-chan struct{}) {
+	<-done
+}
+
+var _ = Describe("VerifyNone", func() {
+
+	It("passes without leaks", func() {
+		snapshot := noleak.Goroutines()
+		var t fakeTB
+		VerifyNone(&t, snapshot, noleak.CheckOptions{Timeout: 50 * time.Millisecond})
+		Expect(t.errors).To(BeEmpty())
+	})
+
+	It("fails the test on leaks", func() {
+		snapshot := noleak.TakeSnapshot()
+		done := make(chan struct{})
+		go worker(done)
+		defer func() {
+			close(done)
+			Eventually(noleak.Goroutines).ShouldNot(noleak.HaveLeaked(snapshot))
+		}()
+		var t fakeTB
+		VerifyNone(&t, snapshot, noleak.CheckOptions{Timeout: 50 * time.Millisecond})
+		Expect(t.errors).To(ConsistOf(HavePrefix("found 1 leaked goroutine")))
+	})
+
+})
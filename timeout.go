@@ -0,0 +1,63 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ReportOnDone writes a report of the goroutines not attributable to the
+// testing framework, Go's runtime, or any of the specified goroutines to
+// ignore, to w when the specified context is done before the returned stop
+// function gets called. If w is nil, the report is written to os.Stderr. The
+// goroutines to ignore are specified in the same way as with HaveLeaked.
+// ReportOnDone is meant for contexts that are cancelled when specs time out,
+// such as:
+//
+//   It("doesn't hang", func() {
+//       ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//       defer cancel()
+//       defer noleak.ReportOnDone(ctx, GinkgoWriter, snapshot)()
+//       ...
+//   })
+//
+// Stopping after the context is done has no effect anymore.
+func ReportOnDone(ctx context.Context, w io.Writer, ignoring ...interface{}) (stop func()) {
+	if w == nil {
+		w = os.Stderr
+	}
+	reporter := ProgressReporter(ignoring...)
+	stopped := make(chan struct{})
+	done := make(chan struct{})
+	GoInfrastructure(func() {
+		defer close(done)
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			fmt.Fprintf(w, "context done before completion: %s\n%s\n", ctx.Err(), reporter())
+		}
+	})
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopped)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/onsi/gomega/gbytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("reporting on done contexts", func() {
+
+	var buff *gbytes.Buffer
+
+	BeforeEach(func() {
+		buff = gbytes.NewBuffer()
+	})
+
+	It("reports when a context is done prematurely", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		stop := ReportOnDone(ctx, buff)
+		Eventually(buff).Should(gbytes.Say(`context done before completion: context deadline exceeded\nnoleak: `))
+		stop()
+		stop()
+	})
+
+	It("doesn't report when stopped in time", func() {
+		snapshot := Goroutines()
+		ctx, cancel := context.WithCancel(context.Background())
+		stop := ReportOnDone(ctx, io.Discard)
+		stop()
+		cancel()
+		Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot))
+	})
+
+})
@@ -0,0 +1,100 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	"fmt"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// OnlyPersistentLeaks returns an option to be passed to HaveLeaked in order to
+// only consider persistent goroutines as leaked, that is, goroutines present in
+// all the goroutine lists HaveLeaked has been matched against so far. In
+// contrast, transient goroutines that appeared only later or that disappeared
+// in between are not considered leaked, but are still reported as such in
+// failure messages.
+//
+//   Eventually(Goroutines).ShouldNot(HaveLeaked(snapshot, OnlyPersistentLeaks()))
+func OnlyPersistentLeaks() interface{} {
+	return onlyPersistentLeaks{}
+}
+
+// onlyPersistentLeaks is the type of the OnlyPersistentLeaks option.
+type onlyPersistentLeaks struct{}
+
+// persistenceClassifier classifies the leaked goroutines seen over successive
+// samples into persistent goroutines, seen in every sample, and transient
+// goroutines, which appeared late or disappeared in between.
+type persistenceClassifier struct {
+	samples int                        // number of samples taken so far.
+	seen    map[goroutine.Identity]int // number of samples each leaked goroutine was seen in.
+}
+
+// update updates the classifier with the leaked goroutines of the latest
+// sample.
+func (c *persistenceClassifier) update(leaked []goroutine.Goroutine) {
+	c.samples++
+	seen := make(map[goroutine.Identity]int, len(leaked))
+	for _, g := range leaked {
+		seen[g.Identity()] = c.seen[g.Identity()] + 1
+	}
+	c.seen = seen
+}
+
+// isPersistent returns true if the specified leaked goroutine of the latest
+// sample has been seen in every sample so far.
+func (c *persistenceClassifier) isPersistent(g goroutine.Goroutine) bool {
+	return c.seen[g.Identity()] == c.samples
+}
+
+// split splits the specified leaked goroutines of the latest sample into
+// persistent and transient goroutines.
+func (c *persistenceClassifier) split(leaked []goroutine.Goroutine) (persistent, transient []goroutine.Goroutine) {
+	for _, g := range leaked {
+		if c.isPersistent(g) {
+			persistent = append(persistent, g)
+		} else {
+			transient = append(transient, g)
+		}
+	}
+	return
+}
+
+// persistenceDetails returns the annotations about persistent and transient
+// goroutines among the specified leaked goroutines, as well as about the
+// transient goroutines not considered leaked. As long as there has been only a
+// single sample, all goroutines are persistent and there is nothing to
+// annotate.
+func (matcher *HaveLeakedMatcher) persistenceDetails(leaked []goroutine.Goroutine) (message string) {
+	samples := matcher.classifier.samples
+	if samples < 2 {
+		return ""
+	}
+	persistent, transient := matcher.classifier.split(leaked)
+	if len(transient) > 0 {
+		if len(persistent) > 0 {
+			message += fmt.Sprintf("\nPersistent goroutines (present in all %d samples): goroutines %s",
+				samples, goids(persistent))
+		}
+		message += fmt.Sprintf("\nTransient goroutines (appeared late or disappeared in between): goroutines %s",
+			goids(transient))
+	}
+	if len(matcher.transient) > 0 {
+		message += fmt.Sprintf("\nTransient goroutines not considered leaked (not present in all %d samples):\n%s",
+			samples, matcher.listGoroutines(matcher.transient, 1))
+	}
+	return message
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package noleak
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("transient and persistent leaks", func() {
+
+	early := goroutine.Goroutine{ID: 1<<62 + 1, State: "sleep", TopFunction: "foo.early", CreatorFunction: "foo.main", BornAt: "/foo/main.go:1"}
+	late := goroutine.Goroutine{ID: 1<<62 + 2, State: "sleep", TopFunction: "foo.late", CreatorFunction: "foo.main", BornAt: "/foo/main.go:2"}
+
+	It("classifies leaked goroutines", func() {
+		var c persistenceClassifier
+		c.update([]goroutine.Goroutine{early})
+		c.update([]goroutine.Goroutine{early, late})
+		Expect(c.isPersistent(early)).To(BeTrue())
+		Expect(c.isPersistent(late)).To(BeFalse())
+		c.update([]goroutine.Goroutine{late})
+		c.update([]goroutine.Goroutine{early, late})
+		persistent, transient := c.split([]goroutine.Goroutine{early, late})
+		Expect(persistent).To(BeEmpty())
+		Expect(transient).To(HaveLen(2), "disappeared in between, or appeared late")
+	})
+
+	It("annotates failure messages", func() {
+		m := HaveLeaked().(*HaveLeakedMatcher)
+		Expect(m.Match([]goroutine.Goroutine{early})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).NotTo(ContainSubstring("Transient"))
+		Expect(m.Match([]goroutine.Goroutine{early, late})).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(And(
+			ContainSubstring("\nPersistent goroutines (present in all 2 samples): goroutines 4611686018427387905"),
+			ContainSubstring("\nTransient goroutines (appeared late or disappeared in between): goroutines 4611686018427387906")))
+	})
+
+	It("optionally considers only persistent goroutines leaked", func() {
+		m := HaveLeaked(OnlyPersistentLeaks()).(*HaveLeakedMatcher)
+		Expect(m.Match([]goroutine.Goroutine{early})).To(BeTrue())
+		Expect(m.Match([]goroutine.Goroutine{early, late})).To(BeTrue())
+		Expect(m.leaked).To(ConsistOf(HaveField("TopFunction", "foo.early")))
+		Expect(m.NegatedFailureMessage(nil)).To(MatchRegexp(
+			`\nTransient goroutines not considered leaked \(not present in all 2 samples\):\n    goroutine 4611686018427387906 \[sleep\]`))
+		Expect(m.Match([]goroutine.Goroutine{late})).To(BeFalse())
+	})
+
+})
@@ -0,0 +1,37 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+/*
+
+Package trend records leaked goroutines per test and run in a persistent
+store, in order to tell whether a leak is new, recurring, or regressed after a
+supposed fix. The store is a plain JSON Lines file that can be kept as a CI
+artifact or cache between runs.
+
+    store, err := trend.Open("leaks.jsonl")
+    ...
+    // record each test of each run, including tests without any leaks.
+    err = store.Record(runID, testName, leaked)
+    ...
+    for fingerprint, status := range store.Statuses(testName) {
+        fmt.Printf("%s: %s\n", fingerprint, status)
+    }
+
+Leaks are identified across runs by their goroutine fingerprints (see
+goroutine.Goroutine.Fingerprint). Please note that tests need to be recorded
+also when they didn't leak, as otherwise fixed leaks cannot be told apart from
+tests that simply weren't run.
+
+*/
+package trend
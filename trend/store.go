@@ -0,0 +1,266 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package trend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thediveo/noleak/goroutine"
+)
+
+// Record describes the leaks of a single test in a single run.
+type Record struct {
+	Run          string    `json:"run"`                    // identifies the run, such as a CI build number.
+	Time         time.Time `json:"time"`                   // when the record was made.
+	Test         string    `json:"test"`                   // name of the test.
+	Leaks        int       `json:"leaks"`                  // number of leaked goroutines.
+	Fingerprints []string  `json:"fingerprints,omitempty"` // unique fingerprints of the leaked goroutines, sorted.
+}
+
+// Status tells how a leak of a test developed over the recorded runs.
+type Status int
+
+// The statuses of a leak with respect to the latest recorded run of a test.
+const (
+	Absent    Status = iota // never leaked.
+	New                     // leaked in the latest run for the first time.
+	Recurring               // leaked in the latest as well as in the previous run.
+	Regressed               // leaked in the latest run again, after an earlier run without it.
+	Fixed                   // leaked in an earlier run, but not in the latest run.
+)
+
+// String returns the name of this status.
+func (s Status) String() string {
+	switch s {
+	case Absent:
+		return "absent"
+	case New:
+		return "new"
+	case Recurring:
+		return "recurring"
+	case Regressed:
+		return "regressed"
+	case Fixed:
+		return "fixed"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Store is a file-backed store of leak records. A Store is safe for
+// concurrent use by multiple goroutines, but not by multiple processes.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	records []Record
+}
+
+// Open opens the store with the specified file path, reading any existing
+// records. If the file doesn't exist yet, Open returns an empty store, with
+// the file getting created when recording the first leaks.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("cannot open leak trend store: %w", err)
+	}
+	defer f.Close()
+	// Records have no maximum length, as they list the fingerprints of all
+	// leaked goroutines, so read whole lines of whatever length.
+	br := bufio.NewReader(f)
+	for lineno, done := 1, false; !done; lineno++ {
+		line, err := br.ReadBytes('\n')
+		switch {
+		case err == io.EOF:
+			done = true
+		case err != nil:
+			return nil, fmt.Errorf("cannot read leak trend store: %w", err)
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("invalid leak trend record in line %d: %w", lineno, err)
+		}
+		s.records = append(s.records, r)
+	}
+	return s, nil
+}
+
+// Record records the specified leaked goroutines of a test in a run, appending
+// the record to the store's file. Tests without any leaks should be recorded
+// too, passing no leaked goroutines.
+func (s *Store) Record(run, test string, leaked []goroutine.Goroutine) error {
+	r := Record{
+		Run:          run,
+		Time:         time.Now(),
+		Test:         test,
+		Leaks:        len(leaked),
+		Fingerprints: fingerprints(leaked),
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("cannot encode leak trend record: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("cannot open leak trend store: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("cannot write leak trend record: %w", err)
+	}
+	s.records = append(s.records, r)
+	return nil
+}
+
+// History returns the records of the specified test, in the order they were
+// recorded.
+func (s *Store) History(test string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := []Record{}
+	for _, r := range s.records {
+		if r.Test == test {
+			history = append(history, r)
+		}
+	}
+	return history
+}
+
+// Tests returns the sorted names of all recorded tests.
+func (s *Store) Tests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unique := map[string]struct{}{}
+	for _, r := range s.records {
+		unique[r.Test] = struct{}{}
+	}
+	tests := make([]string, 0, len(unique))
+	for test := range unique {
+		tests = append(tests, test)
+	}
+	sort.Strings(tests)
+	return tests
+}
+
+// Status returns the status of the leak with the specified fingerprint in the
+// latest recorded run of the specified test.
+func (s *Store) Status(test, fingerprint string) Status {
+	return status(s.runs(test), fingerprint)
+}
+
+// Statuses returns the statuses of all leaks ever recorded for the specified
+// test, indexed by their fingerprints.
+func (s *Store) Statuses(test string) map[string]Status {
+	runs := s.runs(test)
+	statuses := map[string]Status{}
+	for _, run := range runs {
+		for fp := range run {
+			statuses[fp] = status(runs, fp)
+		}
+	}
+	return statuses
+}
+
+// runs returns the sets of leak fingerprints of the specified test per run,
+// in the order of the runs. Multiple records of the same test in the same run
+// are merged.
+func (s *Store) runs(test string) []map[string]struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := []map[string]struct{}{}
+	index := map[string]int{}
+	for _, r := range s.records {
+		if r.Test != test {
+			continue
+		}
+		idx, ok := index[r.Run]
+		if !ok {
+			idx = len(runs)
+			index[r.Run] = idx
+			runs = append(runs, map[string]struct{}{})
+		}
+		for _, fp := range r.Fingerprints {
+			runs[idx][fp] = struct{}{}
+		}
+	}
+	return runs
+}
+
+// status returns the status of the specified leak fingerprint in the latest
+// of the specified runs.
+func status(runs []map[string]struct{}, fingerprint string) Status {
+	if len(runs) == 0 {
+		return Absent
+	}
+	leakedBefore := func(runs []map[string]struct{}) bool {
+		for _, run := range runs {
+			if _, ok := run[fingerprint]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	latest := len(runs) - 1
+	if _, ok := runs[latest][fingerprint]; !ok {
+		if leakedBefore(runs[:latest]) {
+			return Fixed
+		}
+		return Absent
+	}
+	switch {
+	case latest == 0 || !leakedBefore(runs[:latest]):
+		return New
+	case leakedBefore(runs[latest-1 : latest]):
+		return Recurring
+	default:
+		return Regressed
+	}
+}
+
+// fingerprints returns the sorted list of unique fingerprints of the specified
+// goroutines.
+func fingerprints(gs []goroutine.Goroutine) []string {
+	unique := map[string]struct{}{}
+	for _, g := range gs {
+		unique[g.Fingerprint()] = struct{}{}
+	}
+	fps := make([]string, 0, len(unique))
+	for fp := range unique {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+	return fps
+}
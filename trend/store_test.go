@@ -0,0 +1,113 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package trend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/thediveo/noleak/goroutine"
+)
+
+var _ = Describe("leak trend store", func() {
+
+	foo := goroutine.Goroutine{ID: 42, TopFunction: "main.foo", CreatorFunction: "main.main", BornAt: "main.go:1"}
+	bar := goroutine.Goroutine{ID: 666, TopFunction: "main.bar", CreatorFunction: "main.main", BornAt: "main.go:2"}
+
+	It("returns an empty store for a missing file", func() {
+		s, err := Open(filepath.Join(GinkgoT().TempDir(), "leaks.jsonl"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Tests()).To(BeEmpty())
+		Expect(s.History("TestFoo")).To(BeEmpty())
+		Expect(s.Status("TestFoo", foo.Fingerprint())).To(Equal(Absent))
+	})
+
+	It("persists records", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "leaks.jsonl")
+		s, err := Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Record("1", "TestFoo", []goroutine.Goroutine{foo, foo, bar})).To(Succeed())
+		Expect(s.Record("1", "TestBar", nil)).To(Succeed())
+
+		s, err = Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Tests()).To(Equal([]string{"TestBar", "TestFoo"}))
+		Expect(s.History("TestFoo")).To(ConsistOf(And(
+			HaveField("Run", "1"),
+			HaveField("Leaks", 3),
+			HaveField("Fingerprints", ConsistOf(foo.Fingerprint(), bar.Fingerprint())))))
+		Expect(s.History("TestBar")).To(ConsistOf(HaveField("Leaks", 0)))
+	})
+
+	It("reads arbitrarily long records", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "leaks.jsonl")
+		fp := strings.Repeat("f", 2*1024*1024)
+		Expect(os.WriteFile(path, []byte(
+			"\n"+`{"run":"1","test":"TestFoo","leaks":1,"fingerprints":["`+fp+`"]}`), 0o644)).To(Succeed())
+		s, err := Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.History("TestFoo")).To(ConsistOf(HaveField("Fingerprints", ConsistOf(fp))))
+	})
+
+	It("tells new, recurring, regressed, and fixed leaks", func() {
+		s, err := Open(filepath.Join(GinkgoT().TempDir(), "leaks.jsonl"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Record("1", "TestFoo", []goroutine.Goroutine{foo})).To(Succeed())
+		Expect(s.Status("TestFoo", foo.Fingerprint())).To(Equal(New))
+
+		Expect(s.Record("2", "TestFoo", []goroutine.Goroutine{foo})).To(Succeed())
+		Expect(s.Record("2", "TestFoo", []goroutine.Goroutine{bar})).To(Succeed())
+		Expect(s.Statuses("TestFoo")).To(Equal(map[string]Status{
+			foo.Fingerprint(): Recurring,
+			bar.Fingerprint(): New,
+		}))
+
+		Expect(s.Record("3", "TestFoo", nil)).To(Succeed())
+		Expect(s.Statuses("TestFoo")).To(Equal(map[string]Status{
+			foo.Fingerprint(): Fixed,
+			bar.Fingerprint(): Fixed,
+		}))
+
+		Expect(s.Record("4", "TestFoo", []goroutine.Goroutine{bar})).To(Succeed())
+		Expect(s.Status("TestFoo", foo.Fingerprint())).To(Equal(Fixed))
+		Expect(s.Status("TestFoo", bar.Fingerprint())).To(Equal(Regressed))
+		Expect(s.Status("TestFoo", "deadbeef")).To(Equal(Absent))
+	})
+
+	It("rejects invalid stores", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "leaks.jsonl")
+		Expect(os.WriteFile(path, []byte("{}\n\nfoo\n"), 0o644)).To(Succeed())
+		Expect(Open(path)).Error().To(MatchError(HavePrefix("invalid leak trend record in line 3: ")))
+
+		Expect(Open(GinkgoT().TempDir())).Error().To(HaveOccurred())
+
+		s, err := Open(filepath.Join(GinkgoT().TempDir(), "missing", "leaks.jsonl"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Record("1", "TestFoo", nil)).To(MatchError(HavePrefix("cannot open leak trend store: ")))
+	})
+
+	It("names statuses", func() {
+		Expect(Absent.String()).To(Equal("absent"))
+		Expect(New.String()).To(Equal("new"))
+		Expect(Recurring.String()).To(Equal("recurring"))
+		Expect(Regressed.String()).To(Equal("regressed"))
+		Expect(Fixed.String()).To(Equal("fixed"))
+		Expect(Status(42).String()).To(Equal("Status(42)"))
+	})
+
+})
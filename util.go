@@ -16,6 +16,7 @@ package noleak
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -25,16 +26,46 @@ import (
 )
 
 // G takes an actual "any" untyped value and returns it as a typed Goroutine, if
-// possible. It returns an error if actual isn't of either type Goroutine or a
-// pointer to it. G is intended to be mainly used by goroutine-related Gomega
-// matchers, such as IgnoringTopFunction, et cetera.
+// possible. G is the stable contract for writing goroutine-related Gomega
+// filter matchers, such as IgnoringTopFunction, et cetera, including
+// third-party filter matchers:
+//
+//   func (m *myMatcher) Match(actual interface{}) (bool, error) {
+//       g, err := noleak.G(actual, "MyMatcher")
+//       if err != nil {
+//           return false, err
+//       }
+//       ...
+//   }
+//
+// G accepts the following forms of actual values:
+//   - goroutine.Goroutine and non-nil *goroutine.Goroutine,
+//   - values of types defined in terms of goroutine.Goroutine (and pointers to
+//     them),
+//   - reflect.Value wrapping any of the above, as well as non-nil interface
+//     values boxing them,
+//   - map entries in form of structs with a Value field holding any of the
+//     above, such as struct{ Key uint64; Value goroutine.Goroutine }.
+//
+// For any other actual value, G returns an error referencing the specified
+// matcher name.
 func G(actual interface{}, matchername string) (goroutine.Goroutine, error) {
 	if actual != nil {
 		switch actual := actual.(type) {
 		case goroutine.Goroutine:
 			return actual, nil
 		case *goroutine.Goroutine:
-			return *actual, nil
+			if actual != nil {
+				return *actual, nil
+			}
+		case reflect.Value:
+			if g, ok := reflectedG(actual, true); ok {
+				return g, nil
+			}
+		default:
+			if g, ok := reflectedG(reflect.ValueOf(actual), true); ok {
+				return g, nil
+			}
 		}
 	}
 	return goroutine.Goroutine{},
@@ -42,6 +73,32 @@ func G(actual interface{}, matchername string) (goroutine.Goroutine, error) {
 			matchername, format.Object(actual, 1))
 }
 
+var gT = reflect.TypeOf(goroutine.Goroutine{})
+
+// reflectedG returns the Goroutine represented by the specified value, after
+// unwrapping interfaces and dereferencing pointers. If entry is true, then v
+// might also be a map entry with a Value field.
+func reflectedG(v reflect.Value, entry bool) (goroutine.Goroutine, bool) {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			return goroutine.Goroutine{}, false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return goroutine.Goroutine{}, false
+	}
+	if v.Type().ConvertibleTo(gT) {
+		return v.Convert(gT).Interface().(goroutine.Goroutine), true
+	}
+	if entry && v.Kind() == reflect.Struct {
+		if value := v.FieldByName("Value"); value.IsValid() && value.CanInterface() {
+			return reflectedG(value, false)
+		}
+	}
+	return goroutine.Goroutine{}, false
+}
+
 // goids returns a (sorted) list of Goroutine IDs in textual format.
 func goids(gs []goroutine.Goroutine) string {
 	ids := make([]uint64, len(gs))
@@ -59,6 +116,15 @@ func goids(gs []goroutine.Goroutine) string {
 	return buff.String()
 }
 
+// counted returns the specified count followed by either the singular or
+// plural noun, such as "1 goroutine" or "2 goroutines".
+func counted(n int, singular string, plural string) string {
+	if n == 1 {
+		return strconv.Itoa(n) + " " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
+
 // Uint64Slice implements the sort.Interface for a []uint64 to sort in
 // increasing order.
 type Uint64Slice []uint64
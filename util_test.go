@@ -15,6 +15,8 @@
 package noleak
 
 import (
+	"reflect"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/thediveo/noleak/goroutine"
@@ -45,6 +47,39 @@ var _ = Describe("utilities", func() {
 			Expect(g.ID).To(Equal(uint64(42)))
 		})
 
+		It("returns a goroutine from other forms", func() {
+			type myGoroutine goroutine.Goroutine
+			type entry struct {
+				Key   uint64
+				Value *goroutine.Goroutine
+			}
+			actual := goroutine.Goroutine{ID: 42}
+			var boxed interface{} = &actual
+			for _, a := range []interface{}{
+				myGoroutine(actual),
+				&myGoroutine{ID: 42},
+				reflect.ValueOf(actual),
+				reflect.ValueOf(&boxed).Elem(),
+				entry{Key: 42, Value: &actual},
+			} {
+				g, err := G(a, "foo")
+				Expect(err).NotTo(HaveOccurred(), "%T", a)
+				Expect(g.ID).To(Equal(uint64(42)))
+			}
+
+			Expect(G((*goroutine.Goroutine)(nil), "foo")).Error().To(HaveOccurred())
+			Expect(G(reflect.Value{}, "foo")).Error().To(HaveOccurred())
+			Expect(G(entry{}, "foo")).Error().To(HaveOccurred())
+			Expect(G(struct {
+				Value struct{ Value goroutine.Goroutine }
+			}{}, "foo")).Error().To(HaveOccurred())
+		})
+
+		It("works with map values", func() {
+			gs := map[uint64]goroutine.Goroutine{42: {ID: 42, TopFunction: "foo.bar"}}
+			Expect(gs).To(ContainElement(IgnoringTopFunction("foo.bar")))
+		})
+
 	})
 
 	It("returns a list of Goroutine IDs in textual format", func() {